@@ -0,0 +1,565 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errArrayUnsupported = errors.New("driver doesn't support array parameters")
+
+// Array wraps a slice or array so that it is passed to a query as a
+// single array parameter, such as a PostgreSQL array. The elements are
+// converted like other arguments and then encoded by a driver
+// implementing driver.ArrayEncoder; other drivers reject the
+// argument.
+//
+// Slices whose elements are booleans, integers, floating point
+// numbers, strings or times are treated as arrays even without
+// Array, unless they implement driver.Valuer. []byte is never an
+// array.
+//
+// Array also reads and writes the PostgreSQL array text format, such
+// as {1,NULL,"a \"b\""}. Given a pointer to a slice or array, it is a
+// Scan destination that parses that format into the pointed-to value;
+// a NULL array scans as a nil slice. As an argument, it is encoded in
+// that format, as a string, for drivers that don't implement
+// driver.ArrayEncoder. The supported element types are booleans,
+// integers, floating point numbers, strings, []byte, time.Time,
+// Scanner and driver.Valuer implementations, slices and arrays of
+// these for nested arrays, structs as in Composite, and pointers to
+// any of them. NULL elements need a pointer, Scanner or other
+// nillable element type.
+
+// Array 包装一个切片或数组，使其作为单个数组形参传给查询，例如 PostgreSQL 的数组。
+// 其元素会像其它实参一样被转换，然后由实现了 driver.ArrayEncoder 的驱动编码；
+// 其它驱动会拒绝该实参。
+//
+// 即使不使用 Array，元素为布尔值、整数、浮点数、字符串或时间的切片也会被视为数组，
+// 除非它们实现了 driver.Valuer。[]byte 永远不会被视为数组。
+//
+// Array 也能读写 PostgreSQL 的数组文本格式，例如 {1,NULL,"a \"b\""}。若给定一个
+// 指向切片或数组的指针，它是一个 Scan 目标，会将该格式解析到所指向的值中；NULL 数组
+// 会被扫描为 nil 切片。作为实参时，对于未实现 driver.ArrayEncoder 的驱动，它会以
+// 该格式编码为字符串。支持的元素类型为布尔值、整数、浮点数、字符串、[]byte、
+// time.Time、Scanner 和 driver.Valuer 的实现、由这些类型构成的切片和数组（用于嵌套数组）、
+// 如 Composite 中的结构体，以及指向以上任何类型的指针。NULL 元素需要指针、Scanner
+// 或其它可为 nil 的元素类型。
+func Array(slice interface{}) interface{} {
+	return arrayArg{slice}
+}
+
+// arrayArg is an argument wrapped by Array.
+type arrayArg struct {
+	slice interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// asArray reports whether arg is an array parameter, and returns its
+// value if so.
+func asArray(arg interface{}) (reflect.Value, bool) {
+	if a, ok := arg.(arrayArg); ok {
+		return a.value(), true
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(arg)
+	if rv.Kind() != reflect.Slice {
+		return reflect.Value{}, false
+	}
+	switch et := rv.Type().Elem(); et.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return rv, true
+	case reflect.Struct:
+		return rv, et == timeType
+	}
+	return reflect.Value{}, false
+}
+
+// encodeArray converts the elements of rv and has the connection
+// behind ds encode them as a single driver Value. arg is the argument
+// rv came from; if it was wrapped by Array, drivers that can't encode
+// arrays are given its text format instead.
+func encodeArray(ds *driverStmt, arg interface{}, rv reflect.Value) (driver.Value, error) {
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, fmt.Errorf("Array of non-slice type %s", rv.Type())
+	}
+	var enc driver.ArrayEncoder
+	if ds != nil {
+		if dc, ok := ds.Locker.(*driverConn); ok {
+			enc, _ = dc.ci.(driver.ArrayEncoder)
+		}
+	}
+	if enc == nil {
+		if a, ok := arg.(arrayArg); ok {
+			return a.Value()
+		}
+		return nil, errArrayUnsupported
+	}
+	elems := make([]driver.Value, rv.Len())
+	for i := range elems {
+		v, err := driver.DefaultParameterConverter.ConvertValue(rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("array element %d: %v", i, err)
+		}
+		elems[i] = v
+	}
+	ds.Lock()
+	v, err := enc.EncodeArray(elems)
+	ds.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !driver.IsValue(v) {
+		return nil, fmt.Errorf("driver EncodeArray returned non-Value type %T", v)
+	}
+	return v, nil
+}
+
+// value returns the slice or array wrapped by a, following a pointer
+// to it.
+func (a arrayArg) value() reflect.Value {
+	rv := reflect.ValueOf(a.slice)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// Value implements the driver.Valuer interface, encoding the wrapped
+// slice or array in the PostgreSQL array text format.
+func (a arrayArg) Value() (driver.Value, error) {
+	rv := a.value()
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, fmt.Errorf("Array of non-slice type %T", a.slice)
+	}
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := appendArrayText(&buf, rv); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+// Scan implements the Scanner interface, parsing an array in the
+// PostgreSQL array text format into the slice or array pointed to.
+func (a arrayArg) Scan(src interface{}) error {
+	rv := reflect.ValueOf(a.slice)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("sql: Array destination %T is not a non-nil pointer", a.slice)
+	}
+	dv := rv.Elem()
+	if k := dv.Kind(); k != reflect.Slice && k != reflect.Array || isBytes(dv.Type()) {
+		return fmt.Errorf("sql: Array destination %T does not point to a slice or array", a.slice)
+	}
+	text, err := textSource(src)
+	if err != nil {
+		return err
+	}
+	if text == nil {
+		return assignElem(dv, nil)
+	}
+	elems, err := parseArrayText(text)
+	if err != nil {
+		return err
+	}
+	return assignArray(dv, elems)
+}
+
+// textSource returns the text of a scanned value, or nil if it is
+// NULL.
+func textSource(src interface{}) ([]byte, error) {
+	switch s := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return s, nil
+	case string:
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("sql: unsupported type %T for a text-encoded value", src)
+}
+
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// parseArrayText parses an array in the PostgreSQL text format. Each
+// element is returned as nil for NULL, as []byte, or as an
+// []interface{} for a nested array.
+func parseArrayText(text []byte) ([]interface{}, error) {
+	// Skip dimension decorations, such as [0:1]={...}.
+	if len(text) > 0 && text[0] == '[' {
+		if i := bytes.IndexByte(text, '='); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+	p := &textParser{s: text}
+	elems, err := p.array()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.i != len(p.s) {
+		return nil, fmt.Errorf("sql: unexpected %q after array", p.s[p.i:])
+	}
+	return elems, nil
+}
+
+// textParser parses the array and composite text formats.
+type textParser struct {
+	s []byte
+	i int
+}
+
+func (p *textParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("sql: parsing %q at offset %d: %s", p.s, p.i, fmt.Sprintf(format, args...))
+}
+
+func (p *textParser) skipSpace() {
+	for p.i < len(p.s) && isSpace(p.s[p.i]) {
+		p.i++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// array parses {elem,...}.
+func (p *textParser) array() ([]interface{}, error) {
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != '{' {
+		return nil, p.errorf("array does not start with '{'")
+	}
+	p.i++
+	elems := []interface{}{}
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == '}' {
+		p.i++
+		return elems, nil
+	}
+	for {
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.s[p.i] {
+		case '{':
+			sub, err := p.array()
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, sub)
+		case '"':
+			v, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, v)
+		default:
+			start := p.i
+			for p.i < len(p.s) && p.s[p.i] != ',' && p.s[p.i] != '}' {
+				p.i++
+			}
+			v := bytes.TrimRight(p.s[start:p.i], " \t\n\r\v\f")
+			if len(v) == 0 {
+				return nil, p.errorf("empty array element")
+			}
+			if bytes.EqualFold(v, []byte("NULL")) {
+				elems = append(elems, nil)
+			} else {
+				elems = append(elems, v)
+			}
+		}
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return elems, nil
+		default:
+			return nil, p.errorf("unexpected %q in array", p.s[p.i])
+		}
+	}
+}
+
+// quoted parses a double-quoted element, in which a backslash escapes
+// the next byte and, in composites, "" stands for a quote.
+func (p *textParser) quoted() ([]byte, error) {
+	p.i++ // opening quote
+	v := []byte{}
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+		switch c {
+		case '\\':
+			if p.i >= len(p.s) {
+				return nil, p.errorf("unterminated quoted element")
+			}
+			v = append(v, p.s[p.i])
+			p.i++
+		case '"':
+			if p.i < len(p.s) && p.s[p.i] == '"' {
+				v = append(v, '"')
+				p.i++
+				continue
+			}
+			return v, nil
+		default:
+			v = append(v, c)
+		}
+	}
+	return nil, p.errorf("unterminated quoted element")
+}
+
+// assignArray stores the parsed elems into dv, a slice or array.
+func assignArray(dv reflect.Value, elems []interface{}) error {
+	switch dv.Kind() {
+	case reflect.Slice:
+		dv.Set(reflect.MakeSlice(dv.Type(), len(elems), len(elems)))
+	case reflect.Array:
+		if dv.Len() != len(elems) {
+			return fmt.Errorf("sql: cannot scan an array of %d elements into %s", len(elems), dv.Type())
+		}
+	default:
+		return fmt.Errorf("sql: cannot scan an array into %s", dv.Type())
+	}
+	for i, e := range elems {
+		if err := assignElem(dv.Index(i), e); err != nil {
+			return fmt.Errorf("sql: element %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// assignElem stores a parsed element, nil, []byte or []interface{},
+// into dv, which must be addressable.
+func assignElem(dv reflect.Value, e interface{}) error {
+	if scanner, ok := dv.Addr().Interface().(Scanner); ok {
+		switch e := e.(type) {
+		case nil:
+			return scanner.Scan(nil)
+		case []byte:
+			return scanner.Scan(e)
+		}
+		return fmt.Errorf("cannot scan a nested array into %s", dv.Type())
+	}
+	if e == nil {
+		switch dv.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		return nullConversionErr(dv.Type())
+	}
+	if dv.Kind() == reflect.Ptr {
+		v := reflect.New(dv.Type().Elem())
+		if err := assignElem(v.Elem(), e); err != nil {
+			return err
+		}
+		dv.Set(v)
+		return nil
+	}
+	switch e := e.(type) {
+	case []interface{}:
+		return assignArray(dv, e)
+	case []byte:
+		switch {
+		case dv.Type() == timeType:
+			t, err := parseTimeText(e)
+			if err != nil {
+				return err
+			}
+			dv.Set(reflect.ValueOf(t))
+			return nil
+		case dv.Kind() == reflect.Struct:
+			fields, err := parseCompositeText(e)
+			if err != nil {
+				return err
+			}
+			return assignComposite(dv, fields)
+		case dv.Kind() == reflect.Array, dv.Kind() == reflect.Slice && !isBytes(dv.Type()):
+			elems, err := parseArrayText(e)
+			if err != nil {
+				return err
+			}
+			return assignArray(dv, elems)
+		}
+		return convertAssign(dv.Addr().Interface(), e)
+	}
+	panic("unreachable")
+}
+
+// textTimeFormat is the layout in which times are written in the
+// array and composite text formats.
+const textTimeFormat = "2006-01-02 15:04:05.999999999Z07:00"
+
+var textTimeLayouts = []string{
+	textTimeFormat,
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+func parseTimeText(text []byte) (time.Time, error) {
+	s := string(text)
+	for _, layout := range textTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a time", s)
+}
+
+// appendArrayText appends the slice or array rv to buf in the array
+// text format.
+func appendArrayText(buf *bytes.Buffer, rv reflect.Value) error {
+	buf.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		ev := rv.Index(i)
+		if k := ev.Kind(); (k == reflect.Slice || k == reflect.Array) && !isBytes(ev.Type()) && !isValuer(ev) {
+			if k == reflect.Slice && ev.IsNil() {
+				buf.WriteString("NULL")
+				continue
+			}
+			if err := appendArrayText(buf, ev); err != nil {
+				return err
+			}
+			continue
+		}
+		s, null, err := elemText(ev)
+		if err != nil {
+			return fmt.Errorf("sql: array element %d: %v", i, err)
+		}
+		switch {
+		case null:
+			buf.WriteString("NULL")
+		case s == "" || strings.EqualFold(s, "NULL") || needsQuote(s, "{},\"\\"):
+			writeQuoted(buf, s, false)
+		default:
+			buf.WriteString(s)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func isValuer(rv reflect.Value) bool {
+	_, ok := rv.Interface().(driver.Valuer)
+	return ok
+}
+
+// needsQuote reports whether s contains white space or any of the
+// bytes in special.
+func needsQuote(s, special string) bool {
+	for i := 0; i < len(s); i++ {
+		if isSpace(s[i]) || strings.IndexByte(special, s[i]) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeQuoted writes s double-quoted, escaping quotes and backslashes
+// by doubling them if double is set, and with a backslash otherwise.
+func writeQuoted(buf *bytes.Buffer, s string, double bool) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' || c == '\\' {
+			if double {
+				buf.WriteByte(c)
+			} else {
+				buf.WriteByte('\\')
+			}
+		}
+		buf.WriteByte(c)
+	}
+	buf.WriteByte('"')
+}
+
+// elemText returns the text of a single element or field rv, or
+// reports that it is NULL.
+func elemText(rv reflect.Value) (s string, null bool, err error) {
+	if !rv.IsValid() {
+		return "", true, nil
+	}
+	if vr, ok := rv.Interface().(driver.Valuer); ok {
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "", true, nil
+		}
+		v, err := vr.Value()
+		if err != nil {
+			return "", false, err
+		}
+		return elemText(reflect.ValueOf(v))
+	}
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return "", true, nil
+		}
+		return elemText(rv.Elem())
+	case reflect.Bool:
+		if rv.Bool() {
+			return "t", false, nil
+		}
+		return "f", false, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), false, nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32), false, nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), false, nil
+	case reflect.String:
+		return rv.String(), false, nil
+	case reflect.Slice, reflect.Array:
+		if isBytes(rv.Type()) {
+			if rv.IsNil() {
+				return "", true, nil
+			}
+			return string(rv.Bytes()), false, nil
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "", true, nil
+		}
+		var buf bytes.Buffer
+		err := appendArrayText(&buf, rv)
+		return buf.String(), false, err
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return rv.Interface().(time.Time).Format(textTimeFormat), false, nil
+		}
+		var buf bytes.Buffer
+		err := appendCompositeText(&buf, rv)
+		return buf.String(), false, err
+	}
+	return "", false, fmt.Errorf("unsupported type %s", rv.Type())
+}
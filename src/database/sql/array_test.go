@@ -0,0 +1,184 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArrayParams(t *testing.T) {
+	db, err := Open("test", fakeDBName+";arrays")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|id=int32,tags=string")
+
+	one := 1
+	tests := []struct {
+		id   int
+		arg  interface{}
+		want string
+	}{
+		{1, []int{1, 2, 3}, "{1,2,3}"},
+		{2, []string{"a", "b"}, "{a,b}"},
+		{3, Array([]*int{&one, nil}), "{1,NULL}"},
+		{4, Array([2]float64{1.5, 2}), "{1.5,2}"},
+		{5, []bool{}, "{}"},
+	}
+	for _, tt := range tests {
+		exec(t, db, "INSERT|t|id=?,tags=?", tt.id, tt.arg)
+	}
+
+	// Prepared statements and transactions take the same path.
+	stmt, err := db.Prepare("INSERT|t|id=?,tags=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(6, []int64{6}); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	tests = append(tests, struct {
+		id   int
+		arg  interface{}
+		want string
+	}{6, nil, "{6}"})
+
+	for _, tt := range tests {
+		var got string
+		if err := db.QueryRow("SELECT|t|tags|id=?", tt.id).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("id %d: stored %q; want %q", tt.id, got, tt.want)
+		}
+	}
+
+	if _, err := db.Exec("INSERT|t|id=?,tags=?", 7, Array(42)); err == nil || !strings.Contains(err.Error(), "Array of non-slice type int") {
+		t.Errorf("Array(42) error = %v; want non-slice error", err)
+	}
+}
+
+func TestArrayParamsUnsupported(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|id=int32,tags=string")
+
+	_, err := db.Exec("INSERT|t|id=?,tags=?", 1, []int{1, 2})
+	if err == nil || !strings.Contains(err.Error(), "driver doesn't support array parameters") {
+		t.Errorf("Exec with []int = %v; want unsupported error", err)
+	}
+
+	// Array falls back to the text format.
+	exec(t, db, "INSERT|t|id=?,tags=?", 2, Array([]string{"a", "b c"}))
+	var got string
+	if err := db.QueryRow("SELECT|t|tags|id=?", 2).Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{a,"b c"}`; got != want {
+		t.Errorf("stored %q; want %q", got, want)
+	}
+
+	// []byte is a Value, not an array.
+	exec(t, db, "INSERT|t|id=?,tags=?", 1, []byte("x"))
+}
+
+func TestArrayText(t *testing.T) {
+	s := "x"
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{[]int{1, -2, 3}, "{1,-2,3}"},
+		{[]string{"", "NULL", `a "b"`, `c\d`, "{e}", "f,g"}, `{"","NULL","a \"b\"","c\\d","{e}","f,g"}`},
+		{[]*string{&s, nil}, "{x,NULL}"},
+		{[][]int{{1, 2}, {3, 4}}, "{{1,2},{3,4}}"},
+		{[]bool{true, false}, "{t,f}"},
+		{[]float64{1.5}, "{1.5}"},
+		{[]NullInt64{{Int64: 7, Valid: true}, {}}, "{7,NULL}"},
+		{[]time.Time{time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)}, `{"2016-01-02 03:04:05Z"}`},
+		{[]int{}, "{}"},
+	}
+	for _, tt := range tests {
+		v, err := Array(tt.v).(driver.Valuer).Value()
+		if err != nil {
+			t.Errorf("Array(%#v).Value: %v", tt.v, err)
+			continue
+		}
+		if v != tt.want {
+			t.Errorf("Array(%#v).Value = %q; want %q", tt.v, v, tt.want)
+			continue
+		}
+
+		// Scanning the text gives back the original.
+		dst := reflect.New(reflect.TypeOf(tt.v))
+		if err := Array(dst.Interface()).(Scanner).Scan([]byte(tt.want)); err != nil {
+			t.Errorf("Scan(%q): %v", tt.want, err)
+			continue
+		}
+		if got := dst.Elem().Interface(); !reflect.DeepEqual(got, tt.v) {
+			t.Errorf("Scan(%q) = %#v; want %#v", tt.want, got, tt.v)
+		}
+	}
+
+	var nilSlice []int
+	if v, err := Array(nilSlice).(driver.Valuer).Value(); v != nil || err != nil {
+		t.Errorf("Array(nil slice).Value = %v, %v; want nil, nil", v, err)
+	}
+}
+
+func TestArrayScan(t *testing.T) {
+	var ints []int
+	if err := convertAssign(Array(&ints), ` { 1 , 2 } `); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ints, []int{1, 2}) {
+		t.Errorf("scanned %v; want [1 2]", ints)
+	}
+	if err := convertAssign(Array(&ints), nil); err != nil || ints != nil {
+		t.Errorf("scanning NULL gave %v, %v; want nil slice", ints, err)
+	}
+
+	var arr [2]string
+	if err := convertAssign(Array(&arr), "[1:2]={a,b}"); err != nil || arr != [2]string{"a", "b"} {
+		t.Errorf("scanning into array gave %q, %v", arr, err)
+	}
+
+	for _, bad := range []string{"{1,NULL}", "{1,x}", "{1", "1,2}", "{1,2}x", "{1,,2}"} {
+		if err := convertAssign(Array(&ints), bad); err == nil {
+			t.Errorf("scanning %q into []int succeeded", bad)
+		}
+	}
+	if err := convertAssign(Array(&arr), "{a}"); err == nil {
+		t.Error("scanning one element into [2]string succeeded")
+	}
+	if err := convertAssign(Array(ints), "{1}"); err == nil {
+		t.Error("scanning into a non-pointer succeeded")
+	}
+}
+
+func TestArrayRoundTrip(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|id=int32,tags=string")
+
+	in := []*int64{new(int64), nil}
+	*in[0] = 42
+	exec(t, db, "INSERT|t|id=?,tags=?", 1, Array(&in))
+
+	var out []*int64
+	if err := db.QueryRow("SELECT|t|tags|id=?", 1).Scan(Array(&out)); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0] == nil || *out[0] != 42 || out[1] != nil {
+		t.Errorf("round trip gave %v", out)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// ErrBatchResultsUnavailable is returned by ExecBatch when the driver
+// ran the batch natively but does not report the result of each
+// statement. The statements have been executed.
+
+// ErrBatchResultsUnavailable 会在驱动以原生方式执行了批处理、却没有报告每条语句的结果时
+// 由 ExecBatch 返回。此时这些语句已被执行。
+var ErrBatchResultsUnavailable = errors.New("sql: driver does not report per-statement batch results")
+
+// A BatchCanceledError is returned by ExecBatchContext when its context
+// is done after some, but not all, of the statements have run. The
+// statements that ran are not undone.
+
+// BatchCanceledError 会在 ExecBatchContext 的上下文于部分（而非全部）语句执行后结束时
+// 返回。已执行的语句不会被撤销。
+type BatchCanceledError struct {
+	Executed int   // number of statements that ran
+	Err      error // the context's error
+}
+
+func (e *BatchCanceledError) Error() string {
+	return fmt.Sprintf("sql: batch canceled after %d statements: %v", e.Executed, e.Err)
+}
+
+// Unwrap returns the context's error.
+func (e *BatchCanceledError) Unwrap() error {
+	return e.Err
+}
+
+// A BatchStmt is one statement of a batch run by ExecBatch.
+
+// BatchStmt 是由 ExecBatch 执行的批处理中的一条语句。
+type BatchStmt struct {
+	Query string
+	Args  []interface{} // for any placeholder parameters in Query
+}
+
+// ExecBatch executes the statements in order, on a single connection,
+// without returning any rows. It returns one Result per statement, so
+// that RowsAffected reports the rows affected by that statement alone.
+//
+// If the driver implements driver.Batcher, the statements are sent in
+// one batch. If it then does not report the result of each statement,
+// ExecBatch returns ErrBatchResultsUnavailable. Otherwise the
+// statements are executed one after the other; if one fails, ExecBatch
+// stops and returns the results of the statements before it together
+// with the error. The statements are not run in a transaction; use
+// Tx for that.
+
+// ExecBatch 在同一个连接上按顺序执行这些语句，而不返回任何行。它为每条语句返回一个
+// Result，因此 RowsAffected 报告的是仅由该语句影响的行数。
+//
+// 若驱动实现了 driver.Batcher，这些语句会以一个批次发送。若驱动随后未报告每条语句的
+// 结果，ExecBatch 会返回 ErrBatchResultsUnavailable。否则，这些语句会被逐条执行；
+// 若其中一条失败，ExecBatch 会停止，并将其之前各语句的结果与该错误一并返回。
+// 这些语句并非在事务中执行；如有需要，请使用 Tx。
+func (db *DB) ExecBatch(stmts []BatchStmt) ([]Result, error) {
+	return db.ExecBatchContext(context.Background(), stmts)
+}
+
+// ExecBatchContext is like ExecBatch, but stops once ctx is done. The
+// context is checked before the batch is sent to a driver.Batcher and
+// between statements otherwise. If it is done before any statement
+// ran, its error is returned; if it is done part way, the results of
+// the statements that ran are returned with a *BatchCanceledError.
+// The connection is returned to the pool either way.
+
+// ExecBatchContext 与 ExecBatch 类似，但会在 ctx 结束后停止。上下文会在批处理发送给
+// driver.Batcher 之前检查，否则会在各语句之间检查。若它在任何语句执行前结束，则返回
+// 其错误；若它在中途结束，则已执行语句的结果会与一个 *BatchCanceledError 一并返回。
+// 无论哪种情况，连接都会被归还给连接池。
+func (db *DB) ExecBatchContext(ctx context.Context, stmts []BatchStmt) ([]Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, db.handleErr("ExecBatch", "", err)
+	}
+	queries := make([]string, len(stmts))
+	for i, st := range stmts {
+		if err := db.checkReadOnly(st.Query); err != nil {
+			return nil, db.handleErr("ExecBatch", st.Query, err)
+		}
+		queries[i] = db.maybeRebind(st.Query)
+	}
+	var res []Result
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		res, err = db.execBatch(ctx, queries, stmts, cachedOrNewConn)
+		if err != driver.ErrBadConn || len(res) > 0 {
+			break
+		}
+	}
+	if err == driver.ErrBadConn && len(res) == 0 {
+		res, err = db.execBatch(ctx, queries, stmts, alwaysNewConn)
+	}
+	if err != nil {
+		query := ""
+		if len(res) < len(queries) {
+			query = queries[len(res)]
+		}
+		err = db.handleErr("ExecBatch", query, err)
+	}
+	return res, err
+}
+
+func (db *DB) execBatch(ctx context.Context, queries []string, stmts []BatchStmt, strategy connReuseStrategy) (res []Result, err error) {
+	dc, err := db.connContext(ctx, strategy)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		db.putConn(dc, err)
+	}()
+
+	if b, ok := dc.ci.(driver.Batcher); ok {
+		args := make([][]driver.Value, len(stmts))
+		for i, st := range stmts {
+			if args[i], err = driverArgs(&driverStmt{Locker: dc}, st.Args); err != nil {
+				return nil, err
+			}
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		var resi driver.Result
+		dc.Lock()
+		resi, err = b.ExecBatch(queries, args)
+		dc.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		br, ok := resi.(driver.BatchResult)
+		if !ok {
+			return nil, ErrBatchResultsUnavailable
+		}
+		all := br.Results()
+		if len(all) != len(queries) {
+			return nil, ErrBatchResultsUnavailable
+		}
+		res = make([]Result, len(all))
+		for i, r := range all {
+			res[i] = driverResult{dc, r}
+		}
+		return res, nil
+	}
+
+	res = make([]Result, 0, len(stmts))
+	for i, st := range stmts {
+		if cerr := ctx.Err(); cerr != nil {
+			if i == 0 {
+				return nil, cerr
+			}
+			return res, &BatchCanceledError{Executed: i, Err: cerr}
+		}
+		var r Result
+		if r, err = execDC(dc, queries[i], st.Args); err != nil {
+			return res, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+var testBatch = []BatchStmt{
+	{Query: "CREATE|t|name=string,age=int32"},
+	{Query: "INSERT|t|name=?,age=?", Args: []interface{}{"Alice", 1}},
+	{Query: "NOSERT|t|name=?,age=?", Args: []interface{}{"Bob", 2}},
+	{Query: "INSERT|t|name=Chris,age=3"},
+}
+
+func checkBatchResults(t *testing.T, res []Result) {
+	if len(res) != len(testBatch) {
+		t.Fatalf("got %d results; want %d", len(res), len(testBatch))
+	}
+	// CREATE reports no rows affected; each insert affects one row.
+	if _, err := res[0].RowsAffected(); err == nil {
+		t.Error("RowsAffected of CREATE succeeded; want error")
+	}
+	for i := 1; i < len(res); i++ {
+		n, err := res[i].RowsAffected()
+		if err != nil || n != 1 {
+			t.Errorf("RowsAffected of statement %d = %d, %v; want 1, nil", i, n, err)
+		}
+	}
+}
+
+func TestExecBatch(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+
+	res, err := db.ExecBatch(testBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBatchResults(t, res)
+	if n := countRows(t, db, "t"); n != 2 {
+		t.Errorf("table has %d rows; want 2", n)
+	}
+}
+
+func TestExecBatchStopsOnError(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+
+	res, err := db.ExecBatch([]BatchStmt{
+		{Query: "CREATE|t|name=string,age=int32"},
+		{Query: "INSERT|t|name=?,age=?", Args: []interface{}{"Alice", 1}},
+		{Query: "INSERT|nosuchtable|name=Bob"},
+		{Query: "INSERT|t|name=Chris,age=3"},
+	})
+	if err == nil {
+		t.Fatal("ExecBatch succeeded; want error")
+	}
+	if len(res) != 2 {
+		t.Errorf("got %d results; want the 2 before the failing statement", len(res))
+	}
+	if n := countRows(t, db, "t"); n != 1 {
+		t.Errorf("table has %d rows; want 1", n)
+	}
+}
+
+func TestExecBatchNative(t *testing.T) {
+	db, err := Open("test", fakeDBName+";batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+
+	res, err := db.ExecBatch(testBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBatchResults(t, res)
+}
+
+func TestExecBatchNativeWithoutResults(t *testing.T) {
+	db, err := Open("test", fakeDBName+";batchsum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+
+	if _, err := db.ExecBatch(testBatch); err != ErrBatchResultsUnavailable {
+		t.Errorf("ExecBatch error = %v; want ErrBatchResultsUnavailable", err)
+	}
+	if n := countRows(t, db, "t"); n != 2 {
+		t.Errorf("table has %d rows; want 2", n)
+	}
+}
+
+// cancelValuer cancels its context when it is converted, that is, as
+// the statement using it runs.
+type cancelValuer struct {
+	v      string
+	cancel context.CancelFunc
+}
+
+func (c cancelValuer) Value() (driver.Value, error) {
+	c.cancel()
+	return c.v, nil
+}
+
+func TestExecBatchContextCancel(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|name=string,age=int32")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	res, err := db.ExecBatchContext(ctx, []BatchStmt{
+		{Query: "INSERT|t|name=?,age=?", Args: []interface{}{cancelValuer{"Alice", cancel}, 1}},
+		{Query: "INSERT|t|name=Bob,age=2"},
+		{Query: "INSERT|t|name=Chris,age=3"},
+	})
+	berr, ok := err.(*BatchCanceledError)
+	if !ok {
+		t.Fatalf("ExecBatchContext error = %v; want *BatchCanceledError", err)
+	}
+	if berr.Executed != 1 || berr.Err != context.Canceled {
+		t.Errorf("BatchCanceledError = %+v; want 1 statement executed and %v", berr, context.Canceled)
+	}
+	if len(res) != 1 {
+		t.Errorf("got %d results; want 1", len(res))
+	}
+	if n := countRows(t, db, "t"); n != 1 {
+		t.Errorf("table has %d rows; want 1", n)
+	}
+	st := db.Stats()
+	if st.InUse != 0 || st.OpenConnections != 1 {
+		t.Errorf("after cancel, %d connections open and %d in use; want 1 and 0", st.OpenConnections, st.InUse)
+	}
+
+	if _, err := db.ExecBatchContext(ctx, testBatch[1:2]); err != context.Canceled {
+		t.Errorf("ExecBatchContext with done ctx = %v; want %v", err, context.Canceled)
+	}
+	if _, err := db.ExecBatch(testBatch[1:2]); err != nil {
+		t.Fatalf("ExecBatch after cancel: %v", err)
+	}
+	if n := countRows(t, db, "t"); n != 2 {
+		t.Errorf("table has %d rows; want 2", n)
+	}
+}
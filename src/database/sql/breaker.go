@@ -0,0 +1,167 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCircuitOpen is returned by operations that need a new connection
+// while the DB's circuit breaker is open. See SetCircuitBreaker.
+
+// ErrCircuitOpen 会在 DB 的断路器处于打开状态时，由需要新连接的操作返回。
+// 见 SetCircuitBreaker。
+var ErrCircuitOpen = errors.New("sql: circuit breaker is open; database connections are failing")
+
+// CircuitState is the state of a DB's circuit breaker.
+
+// CircuitState 是 DB 断路器的状态。
+type CircuitState int
+
+const (
+	// CircuitClosed means new connections are opened normally.
+
+	// CircuitClosed 表示新连接会被正常打开。
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen means new connections are refused with
+	// ErrCircuitOpen until the cooldown has passed.
+
+	// CircuitOpen 表示在冷却期结束前，新连接会以 ErrCircuitOpen 被拒绝。
+	CircuitOpen
+
+	// CircuitHalfOpen means the cooldown has passed and the next
+	// connection attempt decides whether the breaker closes again.
+
+	// CircuitHalfOpen 表示冷却期已过，下一次连接尝试将决定断路器是否重新闭合。
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// breaker tracks consecutive failures to open a connection.
+// It is guarded by db.mu.
+type breaker struct {
+	threshold int           // <= 0 means disabled
+	cooldown  time.Duration // how long to stay open
+	failures  int           // consecutive failed opens
+	openedAt  time.Time     // when failures last reached threshold
+	trial     bool          // a half-open trial open is in flight
+}
+
+func (b *breaker) state() CircuitState {
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return CircuitClosed
+	}
+	if nowFunc().Sub(b.openedAt) < b.cooldown {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// allow reports whether a new connection may be opened. In the
+// half-open state only one trial is let through at a time; trial
+// reports whether this attempt is it, to be passed on to done.
+func (b *breaker) allow() (trial bool, err error) {
+	switch b.state() {
+	case CircuitOpen:
+		return false, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.trial {
+			return false, ErrCircuitOpen
+		}
+		b.trial = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records the outcome of an attempt to open a connection that
+// allow let through. Only the trial itself ends the trial, so that an
+// attempt started before the breaker opened doesn't let another one
+// through.
+func (b *breaker) done(trial bool, err error) {
+	if trial {
+		b.trial = false
+	}
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = nowFunc()
+	}
+}
+
+// SetCircuitBreaker enables a circuit breaker around opening new
+// connections. After threshold consecutive failures to open a
+// connection, operations that need a new connection fail immediately
+// with ErrCircuitOpen instead of contacting the database. Once
+// cooldown has passed, a single trial connection is attempted: if it
+// succeeds the breaker closes, and if it fails the breaker stays open
+// for another cooldown. Idle connections already in the pool are
+// still handed out while the breaker is open.
+//
+// If threshold <= 0, the breaker is disabled. The default is disabled.
+
+// SetCircuitBreaker 启用一个作用于新连接打开过程的断路器。在连续 threshold 次
+// 打开连接失败后，需要新连接的操作会立即以 ErrCircuitOpen 失败，而不会再去连接数据库。
+// 冷却期 cooldown 过后，会尝试一次试探性连接：若成功，断路器闭合；若失败，
+// 断路器会再保持打开一个冷却期。断路器打开期间，池中已有的空闲连接仍会被分发。
+//
+// 若 threshold <= 0，则禁用断路器。默认为禁用。
+func (db *DB) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	db.mu.Lock()
+	db.breaker = breaker{threshold: threshold, cooldown: cooldown}
+	db.mu.Unlock()
+}
+
+// unhealthyOpenFailures is the number of consecutive failures to open
+// a connection after which Healthy reports false when the circuit
+// breaker is disabled.
+const unhealthyOpenFailures = 3
+
+// Healthy reports whether the DB looks able to serve requests, judging
+// only from its internal state: it returns false if the DB is closed
+// or draining, or if the most recent attempts to open a connection
+// all failed. With a circuit breaker, that is while the breaker is not
+// closed; without one, after three consecutive failures. It performs
+// no I/O and doesn't block on other operations for long.
+//
+// Healthy is a best-effort signal suited to frequent liveness or
+// readiness probes. It is not a substitute for Ping: a DB whose idle
+// connections have all broken since their last use is still reported
+// healthy until an operation discovers it.
+
+// Healthy 仅根据 DB 的内部状态报告其是否看起来能够处理请求：若 DB 已关闭或正在排空，
+// 或者最近的若干次打开连接的尝试全部失败，它就返回 false。启用断路器时，即断路器
+// 未处于闭合状态期间；未启用时，即连续失败三次之后。它不执行任何 I/O，也不会长时间
+// 阻塞于其它操作。
+//
+// Healthy 是一个尽力而为的信号，适用于高频的存活或就绪探测。它不能替代 Ping：
+// 若 DB 的空闲连接自上次使用以来全部失效，在某个操作发现这一点之前，它仍会被报告为健康。
+func (db *DB) Healthy() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed || db.draining {
+		return false
+	}
+	if db.breaker.threshold > 0 {
+		return db.breaker.state() == CircuitClosed
+	}
+	return db.breaker.failures < unhealthyOpenFailures
+}
@@ -0,0 +1,183 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t0 := time.Unix(1000000, 0)
+	offset := time.Duration(0)
+	nowFunc = func() time.Time { return t0.Add(offset) }
+	defer func() { nowFunc = time.Now }()
+
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetCircuitBreaker(2, time.Minute)
+
+	errOpen := errors.New("database is down")
+	opens := 0
+	defer setHookOpenErr(nil)
+	setHookOpenErr(func() error {
+		opens++
+		return errOpen
+	})
+
+	expect := func(wantErr error, wantOpens int, wantState CircuitState) {
+		if err := db.Ping(); err != wantErr {
+			t.Fatalf("Ping = %v; want %v", err, wantErr)
+		}
+		if opens != wantOpens {
+			t.Fatalf("driver opens = %d; want %d", opens, wantOpens)
+		}
+		if got := db.Stats().Circuit; got != wantState {
+			t.Fatalf("Circuit = %v; want %v", got, wantState)
+		}
+	}
+
+	expect(errOpen, 1, CircuitClosed)
+	expect(errOpen, 2, CircuitOpen)
+	expect(ErrCircuitOpen, 2, CircuitOpen)
+
+	// After the cooldown, one trial is allowed; its failure reopens
+	// the breaker.
+	offset += time.Minute
+	if got := db.Stats().Circuit; got != CircuitHalfOpen {
+		t.Fatalf("Circuit after cooldown = %v; want half-open", got)
+	}
+	expect(errOpen, 3, CircuitOpen)
+	expect(ErrCircuitOpen, 3, CircuitOpen)
+
+	// A successful trial closes it.
+	offset += time.Minute
+	setHookOpenErr(nil)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Ping after recovery = %v", err)
+	}
+	if got := db.Stats().Circuit; got != CircuitClosed {
+		t.Fatalf("Circuit after recovery = %v; want closed", got)
+	}
+
+	db.SetCircuitBreaker(0, 0)
+	setHookOpenErr(func() error { return errOpen })
+	for i := 0; i < 5; i++ {
+		if err := db.Ping(); err != errOpen {
+			t.Fatalf("Ping with breaker disabled = %v; want %v", err, errOpen)
+		}
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	db := newTestDB(t, "people")
+	db.clearAllConns(t)
+	if !db.Healthy() {
+		t.Fatal("new DB not healthy")
+	}
+
+	errOpen := errors.New("database is down")
+	defer setHookOpenErr(nil)
+	setHookOpenErr(func() error { return errOpen })
+	for i := 1; i <= unhealthyOpenFailures; i++ {
+		db.Ping()
+		if got, want := db.Healthy(), i < unhealthyOpenFailures; got != want {
+			t.Fatalf("Healthy after %d failed opens = %v; want %v", i, got, want)
+		}
+	}
+	setHookOpenErr(nil)
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if !db.Healthy() {
+		t.Error("not healthy after a successful open")
+	}
+
+	// With a breaker, health follows its state.
+	db.clearAllConns(t)
+	db.SetCircuitBreaker(1, time.Minute)
+	setHookOpenErr(func() error { return errOpen })
+	db.Ping()
+	if db.Healthy() {
+		t.Error("healthy with the circuit breaker open")
+	}
+	db.SetCircuitBreaker(0, 0)
+	setHookOpenErr(nil)
+
+	db.Drain(context.Background())
+	if db.Healthy() {
+		t.Error("draining DB healthy")
+	}
+	closeDB(t, db)
+	if db.Healthy() {
+		t.Error("closed DB healthy")
+	}
+}
+
+// The connection opener, which fills the requests of callers waiting
+// for a connection, is subject to the breaker too.
+func TestCircuitBreakerOpener(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetCircuitBreaker(1, time.Minute)
+
+	errOpen := errors.New("database is down")
+	opens := 0
+	defer setHookOpenErr(nil)
+	setHookOpenErr(func() error {
+		opens++
+		return errOpen
+	})
+	if err := db.Ping(); err != errOpen {
+		t.Fatalf("Ping = %v; want %v", err, errOpen)
+	}
+
+	db.mu.Lock()
+	numOpen := db.numOpen
+	db.numOpen++ // as by maybeOpenNewConnections
+	db.mu.Unlock()
+	db.openNewConnection()
+	if opens != 1 {
+		t.Errorf("driver opens = %d; want 1", opens)
+	}
+	db.mu.Lock()
+	if db.numOpen != numOpen {
+		t.Errorf("numOpen = %d; want %d", db.numOpen, numOpen)
+	}
+	db.mu.Unlock()
+}
+
+// An open that was let through before the breaker opened doesn't end
+// a half-open trial when it finishes.
+func TestCircuitBreakerTrial(t *testing.T) {
+	t0 := time.Unix(1000000, 0)
+	offset := time.Duration(0)
+	nowFunc = func() time.Time { return t0.Add(offset) }
+	defer func() { nowFunc = time.Now }()
+
+	b := breaker{threshold: 1, cooldown: time.Minute}
+	early, err := b.allow()
+	if err != nil || early {
+		t.Fatalf("allow = %v, %v; want false, nil", early, err)
+	}
+	b.done(false, errors.New("down"))
+	offset += time.Minute
+	trial, err := b.allow()
+	if err != nil || !trial {
+		t.Fatalf("allow after cooldown = %v, %v; want true, nil", trial, err)
+	}
+	b.done(early, errors.New("down"))
+	if !b.trial {
+		t.Error("an earlier open ended the trial")
+	}
+	b.done(trial, errors.New("down"))
+	if b.trial {
+		t.Error("the trial's outcome didn't end it")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BufferedRows is the result of a query read in full by QueryRows. It
+// holds copies of the rows' values and no connection, so it needs no
+// Close, and may be kept, passed on and iterated over any number of
+// times, also from several goroutines at once.
+//
+// Rows are accessed by index, from 0 to Len()-1:
+//
+//	for i := 0; i < rows.Len(); i++ {
+//	    var id int64
+//	    var name string
+//	    if err := rows.Scan(i, &id, &name); err != nil {
+//	        ...
+//	    }
+//	}
+
+// BufferedRows 是由 QueryRows 完整读取的查询结果。它持有各行值的副本而不持有任何连接，
+// 因此无需 Close，并且可以被保留、传递以及任意多次地遍历，也可同时在多个 goroutine 中使用。
+//
+// 各行按下标访问，范围为 0 至 Len()-1：
+//
+//	for i := 0; i < rows.Len(); i++ {
+//	    var id int64
+//	    var name string
+//	    if err := rows.Scan(i, &id, &name); err != nil {
+//	        ...
+//	    }
+//	}
+type BufferedRows struct {
+	columns    []string
+	values     []interface{} // the rows' values, one row after the other
+	n          int           // number of rows
+	strictness ScanStrictness
+}
+
+// QueryRows runs a query, like QueryContext, and reads all of its rows
+// into a BufferedRows before returning, so that the connection is
+// released at once rather than held until the rows are closed. It
+// suits small results consumed away from the code that ran the query.
+//
+// The whole result is held in memory, as the values Scan into
+// *interface{} would give, for as long as the BufferedRows is
+// reachable: one interface value per column and row, plus a copy of
+// every string and []byte value. Large or unbounded results should be
+// read with Query instead; SetMaxRows bounds the rows QueryRows reads.
+
+// QueryRows 像 QueryContext 一样执行一个查询，并在返回之前将其所有行读入一个
+// BufferedRows，从而立即释放连接，而不必一直持有到这些行被关闭为止。它适用于在执行
+// 查询的代码之外使用的小型结果。
+//
+// 只要 BufferedRows 仍可访问，整个结果就以 Scan 到 *interface{} 时所得的值的形式保存在
+// 内存中：每行每列一个接口值，再加上每个 string 和 []byte 值的副本。大型或无上限的结果
+// 应改用 Query 读取；SetMaxRows 会限制 QueryRows 读取的行数。
+func (db *DB) QueryRows(ctx context.Context, query string, args ...interface{}) (*BufferedRows, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	br := &BufferedRows{columns: cols, strictness: db.scanStrictness()}
+	row := make([]interface{}, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range dest {
+		dest[i] = &row[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		br.values = append(br.values, row...)
+		br.n++
+	}
+	// Close reports the errors of the iteration as well.
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return br, nil
+}
+
+// Columns returns the column names.
+
+// Columns 返回列名。
+func (br *BufferedRows) Columns() []string {
+	return append([]string(nil), br.columns...)
+}
+
+// Len returns the number of rows.
+
+// Len 返回行数。
+func (br *BufferedRows) Len() int {
+	return br.n
+}
+
+// Scan copies the columns of row i into the values pointed at by dest,
+// converting them as Rows.Scan does. The number of values in dest must
+// be the same as the number of columns. *RawBytes destinations are
+// not allowed, since the rows' values must not be changed.
+
+// Scan 将第 i 行的各列复制到 dest 所指向的值中，并像 Rows.Scan 一样对其进行转换。
+// dest 中值的个数必须与列数相同。不允许使用 *RawBytes 类型的目标，因为各行的值不得被修改。
+func (br *BufferedRows) Scan(i int, dest ...interface{}) error {
+	if i < 0 || i >= br.n {
+		return fmt.Errorf("sql: BufferedRows row index %d out of range [0, %d)", i, br.n)
+	}
+	if len(dest) != len(br.columns) {
+		return fmt.Errorf("sql: expected %d destination arguments in Scan, not %d", len(br.columns), len(dest))
+	}
+	row := br.values[i*len(br.columns) : (i+1)*len(br.columns)]
+	for j, d := range dest {
+		if _, ok := d.(*RawBytes); ok {
+			return errors.New("sql: RawBytes isn't allowed on BufferedRows.Scan")
+		}
+		if err := convertAssignWith(d, row[j], br.strictness); err != nil {
+			return fmt.Errorf("sql: Scan error on column index %d: %v", j, err)
+		}
+	}
+	return nil
+}
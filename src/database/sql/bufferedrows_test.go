@@ -0,0 +1,97 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryRows(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	rows, err := db.QueryRows(ctx, "SELECT|people|age,name,photo|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use after QueryRows; want 0", n)
+	}
+	if got, want := rows.Columns(), []string{"age", "name", "photo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns = %q; want %q", got, want)
+	}
+	rows.Columns()[0] = "changed"
+	if rows.Columns()[0] != "age" {
+		t.Error("Columns returned the BufferedRows' own slice")
+	}
+
+	// Iterate twice, changing what Scan returned in between.
+	for pass := 0; pass < 2; pass++ {
+		var names []string
+		for i := 0; i < rows.Len(); i++ {
+			var age int
+			var name string
+			var photo []byte
+			if err := rows.Scan(i, &age, &name, &photo); err != nil {
+				t.Fatalf("Scan(%d): %v", i, err)
+			}
+			if age != i+1 || string(photo) != strings.ToUpper(name[:1])+"PHOTO" {
+				t.Errorf("row %d = %d, %q, %q", i, age, name, photo)
+			}
+			photo[0] = 'X'
+			names = append(names, name)
+		}
+		if want := []string{"Alice", "Bob", "Chris"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("pass %d: names = %q; want %q", pass, names, want)
+		}
+	}
+
+	var age int
+	var name string
+	var photo RawBytes
+	for _, tt := range []struct {
+		i    int
+		dest []interface{}
+		want string
+	}{
+		{-1, []interface{}{&age, &name, &name}, "out of range"},
+		{3, []interface{}{&age, &name, &name}, "out of range"},
+		{0, []interface{}{&age, &name}, "expected 3 destination arguments"},
+		{0, []interface{}{&age, &name, &photo}, "RawBytes"},
+		{0, []interface{}{&name, &age, &name}, "column index 1"},
+	} {
+		if err := rows.Scan(tt.i, tt.dest...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Scan(%d) with %d destinations = %v; want error containing %q", tt.i, len(tt.dest), err, tt.want)
+		}
+	}
+
+	empty, err := db.QueryRows(ctx, "SELECT|people|name|age=?", 9)
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("QueryRows of no rows = %v, %v; want 0 rows", empty, err)
+	}
+	if _, err := db.QueryRows(ctx, "SELECT|people|name|age=?"); err == nil {
+		t.Error("QueryRows with a missing argument succeeded")
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use; want 0", n)
+	}
+}
+
+func TestQueryRowsMaxRows(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxRows(2)
+
+	if _, err := db.QueryRows(context.Background(), "SELECT|people|name|"); err != ErrTooManyRows {
+		t.Errorf("QueryRows = %v; want ErrTooManyRows", err)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use; want 0", n)
+	}
+}
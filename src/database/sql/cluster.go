@@ -0,0 +1,389 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaPolicy selects which replica a Cluster routes a read to.
+
+// ReplicaPolicy 决定 Cluster 将读操作路由到哪个副本。
+type ReplicaPolicy int
+
+const (
+	// RoundRobin rotates reads across the healthy replicas.
+
+	// RoundRobin 在健康的副本之间轮流分配读操作。
+	RoundRobin ReplicaPolicy = iota
+
+	// LeastConn sends each read to the healthy replica with the
+	// fewest open connections, as reported by its Stats.
+
+	// LeastConn 将每个读操作发送到打开连接数最少的健康副本，连接数由其 Stats 报告。
+	LeastConn
+)
+
+// Cluster routes operations across a primary database and a set of
+// read replicas. Query and QueryRow are sent to a replica; Exec,
+// Prepare and Begin are always sent to the primary. A Cluster is
+// safe for concurrent use by multiple goroutines.
+//
+// Replicas are checked with Ping by CheckReplicas, or periodically
+// once SetHealthCheckInterval has been called. A replica whose Ping
+// fails is dropped from rotation until a later check succeeds. If no
+// replica is healthy, reads fall back to the primary.
+//
+// Replicas may lag behind the primary. To read your own writes, pass
+// the ConsistencyToken of the write to QueryContext, or run the query
+// on Primary directly.
+
+// Cluster 在一个主数据库和一组只读副本之间路由操作。Query 和 QueryRow
+// 会被发送到副本；Exec、Prepare 和 Begin 总是被发送到主数据库。
+// 多个 goroutine 并发使用一个 Cluster 是安全的。
+//
+// CheckReplicas 会用 Ping 检查各副本，调用 SetHealthCheckInterval 后也会定期检查。
+// Ping 失败的副本会被移出轮换，直到之后的某次检查成功为止。
+// 若没有健康的副本，读操作会退回到主数据库。
+//
+// 副本的数据可能落后于主数据库。要读取自己刚写入的数据，请将该写操作的 ConsistencyToken
+// 传给 QueryContext，或直接在 Primary 上执行查询。
+type Cluster struct {
+	primary  *DB
+	replicas []*DB
+	next     uint32 // round-robin cursor; atomic
+
+	mu       sync.Mutex // protects following fields
+	policy   ReplicaPolicy
+	healthy  []bool // parallel to replicas
+	checkCh  chan struct{}
+	interval time.Duration
+	closed   bool
+}
+
+// NewCluster returns a Cluster that sends writes to primary and
+// reads to replicas. All replicas start out healthy.
+
+// NewCluster 返回一个 Cluster，它将写操作发送到 primary，读操作发送到 replicas。
+// 所有副本初始时均被视为健康的。
+func NewCluster(primary *DB, replicas ...*DB) *Cluster {
+	c := &Cluster{
+		primary:  primary,
+		replicas: replicas,
+		healthy:  make([]bool, len(replicas)),
+	}
+	for i := range c.healthy {
+		c.healthy[i] = true
+	}
+	return c
+}
+
+// Primary returns the primary database.
+
+// Primary 返回主数据库。
+func (c *Cluster) Primary() *DB {
+	return c.primary
+}
+
+// SetReplicaPolicy sets how reads are distributed across replicas.
+// The default is RoundRobin.
+
+// SetReplicaPolicy 设置读操作在副本之间的分配方式。默认为 RoundRobin。
+func (c *Cluster) SetReplicaPolicy(p ReplicaPolicy) {
+	c.mu.Lock()
+	c.policy = p
+	c.mu.Unlock()
+}
+
+// Replica returns the database the next read will be routed to.
+// It returns the primary if there are no healthy replicas.
+
+// Replica 返回下一个读操作将被路由到的数据库。若没有健康的副本，则返回主数据库。
+func (c *Cluster) Replica() *DB {
+	c.mu.Lock()
+	policy := c.policy
+	var live []*DB
+	for i, db := range c.replicas {
+		if c.healthy[i] {
+			live = append(live, db)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(live) == 0 {
+		return c.primary
+	}
+	if policy == LeastConn {
+		best, bestOpen := live[0], live[0].Stats().OpenConnections
+		for _, db := range live[1:] {
+			if n := db.Stats().OpenConnections; n < bestOpen {
+				best, bestOpen = db, n
+			}
+		}
+		return best
+	}
+	n := atomic.AddUint32(&c.next, 1)
+	return live[(n-1)%uint32(len(live))]
+}
+
+// CheckReplicas pings every replica and updates which of them are in
+// rotation. It returns the number of healthy replicas.
+
+// CheckReplicas 对每个副本执行 Ping，并更新参与轮换的副本。它返回健康副本的数量。
+func (c *Cluster) CheckReplicas() int {
+	healthy := make([]bool, len(c.replicas))
+	n := 0
+	for i, db := range c.replicas {
+		if db.Ping() == nil {
+			healthy[i] = true
+			n++
+		}
+	}
+	c.mu.Lock()
+	c.healthy = healthy
+	c.mu.Unlock()
+	return n
+}
+
+// SetHealthCheckInterval starts checking the replicas every d in a
+// background goroutine. If d <= 0, periodic checking is stopped.
+
+// SetHealthCheckInterval 启动一个后台 goroutine，每隔 d 检查一次副本。
+// 若 d <= 0，则停止定期检查。
+func (c *Cluster) SetHealthCheckInterval(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.interval = d
+	if c.checkCh != nil {
+		// Wake the checker so it picks up the new interval, or exits.
+		select {
+		case c.checkCh <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if d > 0 {
+		c.checkCh = make(chan struct{}, 1)
+		go c.healthChecker(c.checkCh, d)
+	}
+}
+
+func (c *Cluster) healthChecker(ch chan struct{}, d time.Duration) {
+	t := time.NewTimer(d)
+	for {
+		select {
+		case <-t.C:
+			c.CheckReplicas()
+		case _, ok := <-ch:
+			if !ok {
+				t.Stop()
+				return
+			}
+		}
+
+		c.mu.Lock()
+		d = c.interval
+		if c.closed || d <= 0 {
+			if c.checkCh == ch {
+				c.checkCh = nil
+			}
+			c.mu.Unlock()
+			t.Stop()
+			return
+		}
+		c.mu.Unlock()
+		t.Stop()
+		t.Reset(d)
+	}
+}
+
+// Exec executes a query on the primary without returning any rows.
+// The ConsistencyToken of the write can be had from the Result with
+// ConsistencyTokenOf.
+
+// Exec 在主数据库上执行 query 操作，而不返回任何行。可以使用 ConsistencyTokenOf
+// 从其 Result 中获得该写操作的 ConsistencyToken。
+func (c *Cluster) Exec(query string, args ...interface{}) (Result, error) {
+	res, err := c.primary.Exec(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	tok := ConsistencyToken{valid: true}
+	tok.pos, tok.hasPos = c.primary.replicationPosition()
+	return tokenResult{res, tok}, nil
+}
+
+// Prepare creates a prepared statement on the primary.
+
+// Prepare 在主数据库上创建一个预备语句。
+func (c *Cluster) Prepare(query string) (*Stmt, error) {
+	return c.primary.Prepare(query)
+}
+
+// Begin starts a transaction on the primary.
+
+// Begin 在主数据库上开始一个事务。
+func (c *Cluster) Begin() (*Tx, error) {
+	return c.primary.Begin()
+}
+
+// Query executes a query that returns rows on a replica.
+
+// Query 在副本上执行一个返回行的查询操作。
+func (c *Cluster) Query(query string, args ...interface{}) (*Rows, error) {
+	return c.Replica().Query(query, args...)
+}
+
+// QueryContext is like Query, but binds the query to ctx as for
+// DB.QueryContext, and routes it to see the write whose
+// ConsistencyToken ctx carries, if any; see WithConsistencyToken.
+
+// QueryContext 类似于 Query，但如 DB.QueryContext 那样将查询与 ctx 绑定，并且若 ctx
+// 携带了某个写操作的 ConsistencyToken，则会对查询进行路由以使其能看到该写操作；
+// 参见 WithConsistencyToken。
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	return c.replicaFor(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one
+// row on a replica. Errors are deferred until Row's Scan method is
+// called.
+
+// QueryRow 在副本上执行一个至多只返回一行记录的查询操作。
+// 错误会延迟到调用 Row 的 Scan 方法时才返回。
+func (c *Cluster) QueryRow(query string, args ...interface{}) *Row {
+	return c.Replica().QueryRow(query, args...)
+}
+
+// Close stops health checking and closes the primary and all
+// replicas.
+
+// Close 停止健康检查，并关闭主数据库和所有副本。
+func (c *Cluster) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	if c.checkCh != nil {
+		close(c.checkCh)
+		c.checkCh = nil
+	}
+	c.mu.Unlock()
+
+	err := c.primary.Close()
+	for _, db := range c.replicas {
+		if err1 := db.Close(); err1 != nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// A ConsistencyToken records a write made with Cluster.Exec, so that
+// later reads can be made to see it, although replicas may lag behind
+// the primary. The zero value records no write.
+//
+// A read whose context carries a token, from WithConsistencyToken, is
+// routed by Cluster.QueryContext to a database that has the write. If
+// the drivers implement driver.ReplicationPositioner, the token holds
+// the primary's position after the write, and the read goes to the
+// first healthy replica that reports having reached it; otherwise, or
+// if no replica has, the read goes to the primary. Tokens have no
+// effect on a DB's own methods, which always use that one database.
+
+// ConsistencyToken 记录一次通过 Cluster.Exec 进行的写操作，使得之后的读操作能够看到
+// 它，尽管副本的数据可能落后于主数据库。其零值不记录任何写操作。
+//
+// 对于上下文通过 WithConsistencyToken 携带了令牌的读操作，Cluster.QueryContext 会将其
+// 路由到已包含该写操作的数据库。若驱动实现了 driver.ReplicationPositioner，令牌会保存
+// 写操作之后主数据库的位置，读操作会被发送到第一个报告已到达该位置的健康副本；否则，
+// 或者若没有副本到达该位置，读操作会被发送到主数据库。令牌对 DB 自身的方法不起作用，
+// 它们总是使用该数据库本身。
+type ConsistencyToken struct {
+	valid  bool
+	pos    uint64 // primary's replication position after the write
+	hasPos bool   // whether pos was reported
+}
+
+// tokenResult is the Result of Cluster.Exec.
+type tokenResult struct {
+	Result
+	tok ConsistencyToken
+}
+
+// ConsistencyTokenOf returns the token of the write whose result is
+// res, if res was returned by Cluster.Exec, and the zero token
+// otherwise.
+
+// ConsistencyTokenOf 在 res 由 Cluster.Exec 返回时，返回结果为 res 的写操作的令牌，
+// 否则返回零值令牌。
+func ConsistencyTokenOf(res Result) ConsistencyToken {
+	if tr, ok := res.(tokenResult); ok {
+		return tr.tok
+	}
+	return ConsistencyToken{}
+}
+
+type consistencyTokenKey struct{}
+
+// WithConsistencyToken returns a copy of ctx carrying tok, so that the
+// reads made with it by Cluster.QueryContext see the write tok records.
+
+// WithConsistencyToken 返回携带 tok 的 ctx 副本，使得 Cluster.QueryContext 用它进行的
+// 读操作能够看到 tok 所记录的写操作。
+func WithConsistencyToken(ctx context.Context, tok ConsistencyToken) context.Context {
+	return context.WithValue(ctx, consistencyTokenKey{}, tok)
+}
+
+// replicaFor returns the database to run a read with ctx on.
+func (c *Cluster) replicaFor(ctx context.Context) *DB {
+	tok, _ := ctx.Value(consistencyTokenKey{}).(ConsistencyToken)
+	if !tok.valid {
+		return c.Replica()
+	}
+	if tok.hasPos {
+		c.mu.Lock()
+		healthy := c.healthy
+		c.mu.Unlock()
+		for i, db := range c.replicas {
+			if !healthy[i] {
+				continue
+			}
+			if pos, ok := db.replicationPosition(); ok && pos >= tok.pos {
+				return db
+			}
+		}
+	}
+	return c.primary
+}
+
+// replicationPosition returns the position reported by one of db's
+// connections, if it implements driver.ReplicationPositioner.
+func (db *DB) replicationPosition() (pos uint64, ok bool) {
+	dc, err := db.conn(cachedOrNewConn)
+	if err != nil {
+		return 0, false
+	}
+	rp, ok := dc.ci.(driver.ReplicationPositioner)
+	if ok {
+		dc.Lock()
+		pos, err = rp.ReplicationPosition()
+		dc.Unlock()
+	}
+	db.putConn(dc, err)
+	return pos, ok && err == nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"testing"
+)
+
+// newClusterTestDB opens a fake database whose "whoami" table holds
+// a single row naming it, so tests can tell where a query was routed.
+func newClusterTestDB(t *testing.T, name string) *DB {
+	db, err := Open("test", name)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|whoami|name=string")
+	exec(t, db, "INSERT|whoami|name=?", name)
+	return db
+}
+
+func whoami(t *testing.T, c *Cluster) string {
+	var name string
+	if err := c.QueryRow("SELECT|whoami|name|").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestClusterRouting(t *testing.T) {
+	primary := newClusterTestDB(t, "primary")
+	r1 := newClusterTestDB(t, "replica1")
+	r2 := newClusterTestDB(t, "replica2")
+	c := NewCluster(primary, r1, r2)
+	defer c.Close()
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		seen[whoami(t, c)]++
+	}
+	if seen["replica1"] != 2 || seen["replica2"] != 2 {
+		t.Errorf("round-robin reads = %v; want 2 each on replica1 and replica2", seen)
+	}
+
+	if _, err := c.Exec("INSERT|whoami|name=?", "written"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := c.Primary().QueryRow("SELECT|whoami|name|name=?", "written").Scan(&name); err != nil {
+		t.Fatalf("Exec was not routed to the primary: %v", err)
+	}
+
+	tx, err := c.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.db != primary {
+		t.Error("Begin was not routed to the primary")
+	}
+	tx.Rollback()
+}
+
+func TestClusterUnhealthyReplica(t *testing.T) {
+	primary := newClusterTestDB(t, "primary")
+	r1 := newClusterTestDB(t, "replica1")
+	r2 := newClusterTestDB(t, "replica2")
+	c := NewCluster(primary, r1, r2)
+	defer c.Close()
+
+	r2.Close()
+	if n := c.CheckReplicas(); n != 1 {
+		t.Fatalf("CheckReplicas = %d; want 1", n)
+	}
+	for i := 0; i < 3; i++ {
+		if got := whoami(t, c); got != "replica1" {
+			t.Fatalf("read %d routed to %q; want replica1", i, got)
+		}
+	}
+
+	r1.Close()
+	if n := c.CheckReplicas(); n != 0 {
+		t.Fatalf("CheckReplicas = %d; want 0", n)
+	}
+	if got := whoami(t, c); got != "primary" {
+		t.Errorf("with no healthy replicas, read routed to %q; want primary", got)
+	}
+}
+
+func TestClusterLeastConn(t *testing.T) {
+	primary := newClusterTestDB(t, "primary")
+	r1 := newClusterTestDB(t, "replica1")
+	r2 := newClusterTestDB(t, "replica2")
+	c := NewCluster(primary, r1, r2)
+	defer c.Close()
+	c.SetReplicaPolicy(LeastConn)
+
+	// Hold an extra connection open on replica1.
+	rows, err := r1.Query("SELECT|whoami|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	rows2, err := r1.Query("SELECT|whoami|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows2.Close()
+
+	if got := c.Replica(); got != r2 {
+		t.Errorf("LeastConn picked replica with %d open conns; want replica2", got.Stats().OpenConnections)
+	}
+}
+
+func whoamiContext(t *testing.T, c *Cluster, ctx context.Context) string {
+	rows, err := c.QueryContext(ctx, "SELECT|whoami|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var name string
+	if !rows.Next() {
+		t.Fatalf("no rows: %v", rows.Err())
+	}
+	if err := rows.Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestClusterConsistencyToken(t *testing.T) {
+	primary := newClusterTestDB(t, "primary")
+	r1 := newClusterTestDB(t, "replica1")
+	c := NewCluster(primary, r1)
+	defer c.Close()
+
+	res, err := c.Exec("INSERT|whoami|name=?", "written")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		t.Fatalf("RowsAffected = %d, %v; want 1", n, err)
+	}
+	tok := ConsistencyTokenOf(res)
+	if tok == (ConsistencyToken{}) {
+		t.Fatal("Cluster.Exec result carries no token")
+	}
+
+	ctx := WithConsistencyToken(context.Background(), tok)
+	for i := 0; i < 3; i++ {
+		if got := whoamiContext(t, c, ctx); got != "primary" {
+			t.Fatalf("read %d with token routed to %q; want primary", i, got)
+		}
+	}
+	if got := whoamiContext(t, c, context.Background()); got != "replica1" {
+		t.Errorf("read without token routed to %q; want replica1", got)
+	}
+	ctx = WithConsistencyToken(context.Background(), ConsistencyToken{})
+	if got := whoamiContext(t, c, ctx); got != "replica1" {
+		t.Errorf("read with zero token routed to %q; want replica1", got)
+	}
+
+	// A DB ignores tokens.
+	ctx = WithConsistencyToken(context.Background(), tok)
+	rows, err := r1.QueryContext(ctx, "SELECT|whoami|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	res, err = primary.Exec("INSERT|whoami|name=?", "direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ConsistencyTokenOf(res) != (ConsistencyToken{}) {
+		t.Error("DB.Exec result carries a token")
+	}
+}
+
+func TestClusterConsistencyTokenPosition(t *testing.T) {
+	open := func(name string) *DB {
+		db, err := Open("test", name+";replpos")
+		if err != nil {
+			t.Fatal(err)
+		}
+		exec(t, db, "WIPE")
+		exec(t, db, "CREATE|whoami|name=string")
+		exec(t, db, "INSERT|whoami|name=?", name)
+		return db
+	}
+	setPos := func(name string, pos uint64) {
+		fdb := fdriver.(*fakeDriver).getDB(name)
+		fdb.mu.Lock()
+		fdb.replPos = pos
+		fdb.mu.Unlock()
+	}
+	primary := open("posprimary")
+	r1 := open("posreplica1")
+	r2 := open("posreplica2")
+	c := NewCluster(primary, r1, r2)
+	defer c.Close()
+
+	setPos("posprimary", 10)
+	setPos("posreplica1", 5)
+	setPos("posreplica2", 10)
+	res, err := c.Exec("INSERT|whoami|name=?", "written")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithConsistencyToken(context.Background(), ConsistencyTokenOf(res))
+	if got := whoamiContext(t, c, ctx); got != "posreplica2" {
+		t.Errorf("read routed to %q; want caught-up posreplica2", got)
+	}
+
+	setPos("posreplica2", 9)
+	if got := whoamiContext(t, c, ctx); got != "posprimary" {
+		t.Errorf("with no caught-up replica, read routed to %q; want posprimary", got)
+	}
+}
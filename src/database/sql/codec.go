@@ -0,0 +1,139 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	codecsMu    sync.RWMutex
+	scanCodecs  = make(map[string]func(src []byte, dest interface{}) error)
+	valueCodecs = make(map[string]func(v interface{}) ([]byte, error))
+)
+
+// RegisterScanCodec registers decode for the columns whose database
+// type name, as reported by a driver implementing
+// driver.RowsColumnTypeDatabaseTypeName, is databaseTypeName, compared
+// without regard to case. It lets Scan read types the standard
+// conversions don't know, such as the geometry and other spatial types
+// of many databases, into the program's own types.
+//
+// Scan calls decode only for a column whose value it can't store into
+// dest itself, with the column's value as bytes; a string value is
+// converted. decode should store the decoded value into dest, which is
+// the destination passed to Scan, or return an error saying why it
+// can't. If RegisterScanCodec is called twice with the same name or if
+// decode is nil, it panics.
+
+// RegisterScanCodec 为数据库类型名为 databaseTypeName（不区分大小写）的列注册
+// decode，类型名由实现了 driver.RowsColumnTypeDatabaseTypeName 的驱动报告。
+// 它使 Scan 能够将标准转换所不认识的类型，例如许多数据库中的几何类型及其他空间类型，
+// 读取到程序自己的类型中。
+//
+// 只有当 Scan 无法自行将某列的值存储到 dest 中时，才会以字节形式的列值调用 decode；
+// 字符串值会被转换。decode 应将解码后的值存储到 dest 中，dest 即传给 Scan 的目标值，
+// 否则返回一个说明原因的错误。如果使用同样的名字调用两次 RegisterScanCodec，
+// 或者 decode 为 nil，RegisterScanCodec 会 panic。
+func RegisterScanCodec(databaseTypeName string, decode func(src []byte, dest interface{}) error) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if decode == nil {
+		panic("sql: RegisterScanCodec decode is nil")
+	}
+	name := strings.ToUpper(databaseTypeName)
+	if _, dup := scanCodecs[name]; dup {
+		panic("sql: RegisterScanCodec called twice for type " + databaseTypeName)
+	}
+	scanCodecs[name] = decode
+}
+
+// RegisterValueCodec registers encode for the values passed as
+// arguments wrapped by Encoded with databaseTypeName, compared without
+// regard to case. encode returns the value as the database expects it
+// for that type, which is passed to the driver as a []byte. If
+// RegisterValueCodec is called twice with the same name or if encode is
+// nil, it panics.
+
+// RegisterValueCodec 为以 databaseTypeName（不区分大小写）经 Encoded 包装后
+// 作为实参传入的值注册 encode。encode 返回数据库对该类型所期望的值，
+// 该值会以 []byte 的形式传给驱动。如果使用同样的名字调用两次 RegisterValueCodec，
+// 或者 encode 为 nil，RegisterValueCodec 会 panic。
+func RegisterValueCodec(databaseTypeName string, encode func(v interface{}) ([]byte, error)) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if encode == nil {
+		panic("sql: RegisterValueCodec encode is nil")
+	}
+	name := strings.ToUpper(databaseTypeName)
+	if _, dup := valueCodecs[name]; dup {
+		panic("sql: RegisterValueCodec called twice for type " + databaseTypeName)
+	}
+	valueCodecs[name] = encode
+}
+
+// Encoded wraps v so that, as an argument, it is encoded by the codec
+// registered with RegisterValueCodec for databaseTypeName. A nil v is
+// passed as NULL.
+
+// Encoded 包装 v，使其作为实参时由通过 RegisterValueCodec 为 databaseTypeName
+// 注册的编解码器进行编码。nil 的 v 会作为 NULL 传递。
+func Encoded(databaseTypeName string, v interface{}) interface{} {
+	return encodedArg{databaseTypeName, v}
+}
+
+// encodedArg is a value wrapped by Encoded.
+type encodedArg struct {
+	typeName string
+	v        interface{}
+}
+
+// Value implements the driver.Valuer interface.
+func (e encodedArg) Value() (driver.Value, error) {
+	if e.v == nil {
+		return nil, nil
+	}
+	codecsMu.RLock()
+	encode := valueCodecs[strings.ToUpper(e.typeName)]
+	codecsMu.RUnlock()
+	if encode == nil {
+		return nil, fmt.Errorf("sql: no value codec registered for type %q", e.typeName)
+	}
+	b, err := encode(e.v)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// decodeColumn stores sv, the value of column i, into dest with the
+// codec registered for the column's database type, if any, once the
+// standard conversion has failed with err. It returns err if there is
+// no such codec.
+func (rs *Rows) decodeColumn(dest interface{}, sv driver.Value, i int, err error) error {
+	tn, ok := rs.rowsi.(driver.RowsColumnTypeDatabaseTypeName)
+	if !ok {
+		return err
+	}
+	var src []byte
+	switch v := sv.(type) {
+	case []byte:
+		src = v
+	case string:
+		src = []byte(v)
+	default:
+		return err
+	}
+	codecsMu.RLock()
+	decode := scanCodecs[strings.ToUpper(tn.ColumnTypeDatabaseTypeName(i))]
+	codecsMu.RUnlock()
+	if decode == nil {
+		return err
+	}
+	return decode(src, dest)
+}
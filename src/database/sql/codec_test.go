@@ -0,0 +1,118 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testPoint is a geometry value, read and written as the text
+// POINT(x y).
+type testPoint struct {
+	X, Y float64
+}
+
+func decodeTestPoint(src []byte, dest interface{}) error {
+	p, ok := dest.(*testPoint)
+	if !ok {
+		return fmt.Errorf("can't decode geometry into %T", dest)
+	}
+	_, err := fmt.Sscanf(string(src), "POINT(%g %g)", &p.X, &p.Y)
+	return err
+}
+
+func encodeTestPoint(v interface{}) ([]byte, error) {
+	p, ok := v.(testPoint)
+	if !ok {
+		return nil, fmt.Errorf("can't encode %T as geometry", v)
+	}
+	return []byte(fmt.Sprintf("POINT(%g %g)", p.X, p.Y)), nil
+}
+
+func TestScanCodec(t *testing.T) {
+	RegisterScanCodec("GEOMETRY", decodeTestPoint)
+	RegisterValueCodec("geometry", encodeTestPoint)
+	defer func() {
+		codecsMu.Lock()
+		delete(scanCodecs, "GEOMETRY")
+		delete(valueCodecs, "GEOMETRY")
+		codecsMu.Unlock()
+	}()
+
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|places|id=int32,name=string,shape=geometry")
+	exec(t, db, "INSERT|places|id=?,name=?,shape=?", 1, "home", Encoded("Geometry", testPoint{1, 2.5}))
+	exec(t, db, "INSERT|places|id=?,name=?,shape=?", 2, "nowhere", Encoded("geometry", nil))
+
+	var p testPoint
+	if err := db.QueryRow("SELECT|places|shape|id=?", 1).Scan(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p != (testPoint{1, 2.5}) {
+		t.Errorf("scanned point = %v; want {1 2.5}", p)
+	}
+
+	// The standard conversions come first.
+	var text string
+	if err := db.QueryRow("SELECT|places|shape|id=?", 1).Scan(&text); err != nil || text != "POINT(1 2.5)" {
+		t.Errorf("Scan into *string = %q, %v; want %q", text, err, "POINT(1 2.5)")
+	}
+
+	for _, tt := range []struct {
+		column string
+		id     int
+		dest   interface{}
+		want   string
+	}{
+		// The codec's own error.
+		{"shape", 1, new(int), "can't decode geometry into *int"},
+		// NULL isn't decoded.
+		{"shape", 2, &p, "converting NULL"},
+		// Nor are columns of other types.
+		{"name", 1, &p, "unsupported Scan"},
+	} {
+		err := db.QueryRow("SELECT|places|"+tt.column+"|id=?", tt.id).Scan(tt.dest)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Scan of %s %d into %T = %v; want error containing %q", tt.column, tt.id, tt.dest, err, tt.want)
+		}
+	}
+
+	if _, err := db.Exec("INSERT|places|id=?,name=?,shape=?", 3, "x", Encoded("polygon", testPoint{})); err == nil || !strings.Contains(err.Error(), `no value codec registered for type "polygon"`) {
+		t.Errorf("Exec with an unregistered value codec = %v", err)
+	}
+}
+
+func TestRegisterCodecPanics(t *testing.T) {
+	RegisterScanCodec("box", decodeTestPoint)
+	RegisterValueCodec("box", encodeTestPoint)
+	defer func() {
+		codecsMu.Lock()
+		delete(scanCodecs, "BOX")
+		delete(valueCodecs, "BOX")
+		codecsMu.Unlock()
+	}()
+
+	for _, tt := range []struct {
+		name string
+		fn   func()
+	}{
+		{"RegisterScanCodec nil", func() { RegisterScanCodec("circle", nil) }},
+		{"RegisterScanCodec twice", func() { RegisterScanCodec("BOX", decodeTestPoint) }},
+		{"RegisterValueCodec nil", func() { RegisterValueCodec("circle", nil) }},
+		{"RegisterValueCodec twice", func() { RegisterValueCodec("Box", encodeTestPoint) }},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s didn't panic", tt.name)
+				}
+			}()
+			tt.fn()
+		}()
+	}
+}
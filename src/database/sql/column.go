@@ -0,0 +1,61 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// QueryColumn runs a query that returns a single column, such as a
+// SELECT of ids, and appends the column's value in every row to the
+// slice that dest points to. Each value is converted to the slice's
+// element type as Scan would convert it. The query is bound to ctx as
+// in QueryContext.
+//
+// QueryColumn returns an error if dest is not a non-nil pointer to a
+// slice, or if the query returns more or fewer than one column. If
+// scanning a row fails, the slice is left as it was.
+
+// QueryColumn 执行一个返回单列的查询（例如查询 id 的 SELECT），并将每一行中该列的值
+// 追加到 dest 所指向的切片中。每个值都会像 Scan 那样被转换为该切片的元素类型。
+// 该查询如 QueryContext 一样与 ctx 绑定。
+//
+// 若 dest 不是指向切片的非 nil 指针，或查询返回的列数不为一，QueryColumn 会返回错误。
+// 若扫描某一行失败，该切片会保持原样。
+func (db *DB) QueryColumn(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return errors.New("sql: QueryColumn destination not a pointer to a slice")
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(cols) != 1 {
+		return db.handleErr("QueryColumn", query, fmt.Errorf("sql: QueryColumn expected 1 column, not %d", len(cols)))
+	}
+	sv := dv.Elem()
+	elem := sv.Type().Elem()
+	for rows.Next() {
+		ev := reflect.New(elem)
+		if err := rows.Scan(ev.Interface()); err != nil {
+			return err
+		}
+		sv = reflect.Append(sv, ev.Elem())
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	dv.Elem().Set(sv)
+	return nil
+}
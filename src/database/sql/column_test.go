@@ -0,0 +1,60 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryColumn(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	var ages []int64
+	if err := db.QueryColumn(ctx, &ages, "SELECT|people|age|"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{1, 2, 3}; !reflect.DeepEqual(ages, want) {
+		t.Errorf("ages = %v; want %v", ages, want)
+	}
+
+	names := []string{"Zed"}
+	if err := db.QueryColumn(ctx, &names, "SELECT|people|name|age=?", 2); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Zed", "Bob"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q; want %q", names, want)
+	}
+
+	var none []string
+	if err := db.QueryColumn(ctx, &none, "SELECT|people|name|age=?", 99); err != nil || len(none) != 0 {
+		t.Errorf("QueryColumn of no rows = %q, %v; want empty, nil", none, err)
+	}
+
+	// Ages convert to strings, but names don't convert to integers.
+	var strs []string
+	if err := db.QueryColumn(ctx, &strs, "SELECT|people|age|"); err != nil || len(strs) != 3 || strs[0] != "1" {
+		t.Errorf("QueryColumn of ages into strings = %q, %v", strs, err)
+	}
+	ages = nil
+	if err := db.QueryColumn(ctx, &ages, "SELECT|people|name|"); err == nil || ages != nil {
+		t.Errorf("QueryColumn of names into int64s = %v, %v; want nil, error", ages, err)
+	}
+
+	err := db.QueryColumn(ctx, &ages, "SELECT|people|name,age|")
+	if err == nil || !strings.Contains(err.Error(), "expected 1 column") {
+		t.Errorf("QueryColumn of two columns = %v; want column count error", err)
+	}
+	if err := db.QueryColumn(ctx, ages, "SELECT|people|age|"); err == nil {
+		t.Error("QueryColumn into a slice rather than a pointer succeeded")
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use; want 0", n)
+	}
+}
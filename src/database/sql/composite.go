@@ -0,0 +1,185 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+)
+
+// Composite wraps a pointer to a struct so that it reads and writes a
+// composite value, such as a PostgreSQL row type, in the PostgreSQL
+// composite text format, such as (1,,"a b"). The struct's exported
+// fields correspond, in order, to the composite's fields; an empty
+// field is NULL.
+//
+// As a Scan destination, Composite parses that format into the
+// struct. As an argument, it is encoded in that format, as a string;
+// a struct value may be passed in place of the pointer. The supported
+// field types are those of Array elements, including slices for array
+// fields and structs for nested composites.
+
+// Composite 包装一个指向结构体的指针，使其以 PostgreSQL 的复合类型文本格式，
+// 例如 (1,,"a b")，读写一个复合值，例如 PostgreSQL 的行类型。结构体的导出字段
+// 按顺序对应于复合值的字段；空字段为 NULL。
+//
+// 作为 Scan 目标时，Composite 会将该格式解析到结构体中。作为实参时，它会以该格式
+// 编码为字符串；也可以传入结构体值来代替指针。支持的字段类型与 Array 的元素相同，
+// 包括用于数组字段的切片以及用于嵌套复合值的结构体。
+func Composite(ptr interface{}) interface{} {
+	return compositeArg{ptr}
+}
+
+// compositeArg is a value wrapped by Composite.
+type compositeArg struct {
+	ptr interface{}
+}
+
+// Value implements the driver.Valuer interface.
+func (c compositeArg) Value() (driver.Value, error) {
+	rv := reflect.ValueOf(c.ptr)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sql: Composite of non-struct type %T", c.ptr)
+	}
+	var buf bytes.Buffer
+	if err := appendCompositeText(&buf, rv); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+// Scan implements the Scanner interface.
+func (c compositeArg) Scan(src interface{}) error {
+	rv := reflect.ValueOf(c.ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("sql: Composite destination %T is not a non-nil pointer to a struct", c.ptr)
+	}
+	text, err := textSource(src)
+	if err != nil {
+		return err
+	}
+	if text == nil {
+		return nullConversionErr(rv.Elem().Type())
+	}
+	fields, err := parseCompositeText(text)
+	if err != nil {
+		return err
+	}
+	return assignComposite(rv.Elem(), fields)
+}
+
+// parseCompositeText parses a composite in the PostgreSQL text format.
+// Each field is returned as nil for NULL or as []byte.
+func parseCompositeText(text []byte) ([]interface{}, error) {
+	p := &textParser{s: text}
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != '(' {
+		return nil, p.errorf("composite does not start with '('")
+	}
+	p.i++
+	var fields []interface{}
+	for {
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated composite")
+		}
+		var field []byte
+		switch p.s[p.i] {
+		case ',', ')':
+			// An empty field is NULL.
+		case '"':
+			v, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			field = v
+		default:
+			start := p.i
+			for p.i < len(p.s) && p.s[p.i] != ',' && p.s[p.i] != ')' && p.s[p.i] != '"' {
+				p.i++
+			}
+			field = p.s[start:p.i]
+		}
+		if field == nil {
+			fields = append(fields, nil)
+		} else {
+			fields = append(fields, field)
+		}
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated composite")
+		}
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case ')':
+			p.i++
+			p.skipSpace()
+			if p.i != len(p.s) {
+				return nil, p.errorf("unexpected %q after composite", p.s[p.i:])
+			}
+			return fields, nil
+		default:
+			return nil, p.errorf("unexpected %q in composite", p.s[p.i])
+		}
+	}
+}
+
+// compositeFields returns the indexes of the exported fields of the
+// struct type t.
+func compositeFields(t reflect.Type) []int {
+	var idx []int
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// assignComposite stores the parsed fields into dv, an addressable
+// struct.
+func assignComposite(dv reflect.Value, fields []interface{}) error {
+	idx := compositeFields(dv.Type())
+	if len(idx) != len(fields) {
+		return fmt.Errorf("sql: cannot scan a composite of %d fields into %s with %d exported fields", len(fields), dv.Type(), len(idx))
+	}
+	for i, f := range fields {
+		if err := assignElem(dv.Field(idx[i]), f); err != nil {
+			return fmt.Errorf("sql: field %s: %v", dv.Type().Field(idx[i]).Name, err)
+		}
+	}
+	return nil
+}
+
+// appendCompositeText appends the struct rv to buf in the composite
+// text format.
+func appendCompositeText(buf *bytes.Buffer, rv reflect.Value) error {
+	buf.WriteByte('(')
+	for i, fi := range compositeFields(rv.Type()) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		s, null, err := elemText(rv.Field(fi))
+		if err != nil {
+			return fmt.Errorf("sql: field %s: %v", rv.Type().Field(fi).Name, err)
+		}
+		switch {
+		case null:
+		case s == "" || needsQuote(s, "(),\"\\"):
+			writeQuoted(buf, s, true)
+		default:
+			buf.WriteString(s)
+		}
+	}
+	buf.WriteByte(')')
+	return nil
+}
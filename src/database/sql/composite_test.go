@@ -0,0 +1,94 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type compositeAddress struct {
+	Street string
+	Number *int
+	Tags   []string
+	hidden int
+}
+
+type compositePerson struct {
+	Name    string
+	Age     int
+	Home    compositeAddress
+	Friends NullString
+}
+
+func TestCompositeText(t *testing.T) {
+	n := 5
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{&compositeAddress{Street: "Main St", Number: &n, Tags: []string{"a", "b"}}, `("Main St",5,"{a,b}")`},
+		{&compositeAddress{Street: `say "hi"`}, `("say ""hi""",,)`},
+		{&compositeAddress{Street: ""}, `("",,)`},
+		{
+			&compositePerson{Name: "Bob", Age: 30, Home: compositeAddress{Street: "x,y"}, Friends: NullString{String: "Al", Valid: true}},
+			`(Bob,30,"(""x,y"",,)",Al)`,
+		},
+	}
+	for _, tt := range tests {
+		v, err := Composite(tt.v).(driver.Valuer).Value()
+		if err != nil {
+			t.Errorf("Composite(%+v).Value: %v", tt.v, err)
+			continue
+		}
+		if v != tt.want {
+			t.Errorf("Composite(%+v).Value = %q; want %q", tt.v, v, tt.want)
+			continue
+		}
+
+		dst := reflect.New(reflect.TypeOf(tt.v).Elem())
+		if err := Composite(dst.Interface()).(Scanner).Scan(tt.want); err != nil {
+			t.Errorf("Scan(%q): %v", tt.want, err)
+			continue
+		}
+		if got := dst.Interface(); !reflect.DeepEqual(got, tt.v) {
+			t.Errorf("Scan(%q) = %+v; want %+v", tt.want, got, tt.v)
+		}
+	}
+}
+
+func TestCompositeScanErrors(t *testing.T) {
+	var a compositeAddress
+	for _, bad := range []string{"(a,1)", "(a,1,{},x)", "(a,x,)", "a,1,", "(a,1,", `(a,1,"{})`} {
+		if err := convertAssign(Composite(&a), bad); err == nil {
+			t.Errorf("scanning %q succeeded", bad)
+		}
+	}
+	if err := convertAssign(Composite(&a), nil); err == nil {
+		t.Error("scanning NULL into a struct succeeded")
+	}
+	if err := convertAssign(Composite(a), "(a,,)"); err == nil {
+		t.Error("scanning into a non-pointer succeeded")
+	}
+}
+
+func TestCompositeRoundTrip(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|id=int32,addr=string")
+
+	n := 12
+	in := compositeAddress{Street: "Elm (North)", Number: &n}
+	exec(t, db, "INSERT|t|id=?,addr=?", 1, Composite(in))
+
+	var out compositeAddress
+	if err := db.QueryRow("SELECT|t|addr|id=?", 1).Scan(Composite(&out)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip gave %+v; want %+v", out, in)
+	}
+}
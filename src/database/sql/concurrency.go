@@ -0,0 +1,98 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrTooManyQueries is returned, when SetNonBlocking is on, by Exec
+// and Query calls made while the limit set by SetMaxConcurrentQueries
+// is reached.
+
+// ErrTooManyQueries 会在 SetNonBlocking 开启时，由在已达到 SetMaxConcurrentQueries
+// 所设置的上限时发起的 Exec 和 Query 调用返回。
+var ErrTooManyQueries = errors.New("sql: too many concurrent queries")
+
+// SetMaxConcurrentQueries sets the maximum number of Exec and Query
+// calls, and the like, such as QueryRow and QueryContext, that may run
+// on the DB at once. A query runs until its Rows are closed. Further
+// calls wait for one to finish, or until the context they run under is
+// done; with SetNonBlocking on, they fail with ErrTooManyQueries
+// instead. DBStats reports the number running and of waits.
+//
+// It is a coarser backpressure than SetMaxOpenConns, bounding the
+// load put on the database rather than the connections, which matters
+// when a driver pipelines several queries on a connection. Statements
+// and transactions are not limited, except as their connections are.
+// A new limit applies to the calls made after it is set, without
+// counting those already running. If n <= 0, there is no limit, which
+// is the default.
+
+// SetMaxConcurrentQueries 设置可以同时在 DB 上运行的 Exec 和 Query 调用（以及
+// QueryRow、QueryContext 等类似调用）的最大数量。一个查询会一直运行到其 Rows 被关闭为止。
+// 更多的调用会等待某个调用结束，或直到其运行所在的上下文结束；若开启了 SetNonBlocking，
+// 它们则会以 ErrTooManyQueries 失败。DBStats 会报告正在运行的调用数以及等待的次数。
+//
+// 它是比 SetMaxOpenConns 更粗粒度的背压手段，限制的是施加到数据库上的负载而非连接数，
+// 这在驱动于一个连接上流水线式地执行多个查询时很重要。语句和事务不受其限制，除非通过
+// 它们的连接间接受限。新的上限适用于设置之后发起的调用，不计入已在运行的调用。
+// 若 n <= 0，则没有限制，这也是默认设置。
+func (db *DB) SetMaxConcurrentQueries(n int) {
+	var sem chan struct{}
+	if n > 0 {
+		sem = make(chan struct{}, n)
+	}
+	db.mu.Lock()
+	db.querySem = sem
+	db.mu.Unlock()
+}
+
+// querySlot is a place among the queries running on a DB, taken by
+// acquireQuery and given back by release.
+type querySlot struct {
+	db  *DB
+	sem chan struct{} // the semaphore of SetMaxConcurrentQueries, if any
+}
+
+// acquireQuery takes a slot for an Exec or Query call, waiting for one
+// while the limit set by SetMaxConcurrentQueries is reached, or until
+// ctx is done.
+func (db *DB) acquireQuery(ctx context.Context) (querySlot, error) {
+	db.mu.Lock()
+	sem, nonBlocking := db.querySem, db.nonBlocking
+	db.mu.Unlock()
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+		default:
+			if nonBlocking {
+				return querySlot{}, ErrTooManyQueries
+			}
+			atomic.AddInt64(&db.queryWaits, 1)
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return querySlot{}, ctx.Err()
+			}
+		}
+	}
+	atomic.AddInt64(&db.queriesInFlight, 1)
+	return querySlot{db: db, sem: sem}, nil
+}
+
+// release gives s back, if it was taken.
+func (s *querySlot) release() {
+	if s.db == nil {
+		return
+	}
+	atomic.AddInt64(&s.db.queriesInFlight, -1)
+	if s.sem != nil {
+		<-s.sem
+	}
+	*s = querySlot{}
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentQueries(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxConcurrentQueries(2)
+
+	const query = "SELECT|people|name|"
+	r1, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := db.Stats().QueriesInFlight; n != 2 {
+		t.Errorf("QueriesInFlight = %d; want 2", n)
+	}
+
+	// A third query waits for one of them to be closed.
+	done := make(chan error)
+	go func() {
+		_, err := db.Exec("INSERT|people|name=Dave,age=?", 4)
+		done <- err
+	}()
+	waitCondition(t, "the Exec to wait", func() bool {
+		return db.Stats().QueryWaits == 1
+	})
+	select {
+	case err := <-done:
+		t.Fatalf("Exec over the limit returned %v without waiting", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	r1.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The wait ends with the context, or doesn't happen in
+	// non-blocking mode.
+	r1, err = db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := db.QueryContext(ctx, query); err != context.DeadlineExceeded {
+		t.Errorf("QueryContext over the limit = %v; want context.DeadlineExceeded", err)
+	}
+	db.SetNonBlocking(true)
+	var name string
+	if err := db.QueryRow(query).Scan(&name); err != ErrTooManyQueries {
+		t.Errorf("QueryRow over the limit = %v; want ErrTooManyQueries", err)
+	}
+	db.SetNonBlocking(false)
+
+	r1.Close()
+	r2.Close()
+	stats := db.ResetStats()
+	if stats.QueriesInFlight != 0 || stats.QueryWaits != 2 {
+		t.Errorf("QueriesInFlight, QueryWaits = %d, %d; want 0, 2", stats.QueriesInFlight, stats.QueryWaits)
+	}
+	if n := db.Stats().QueryWaits; n != 0 {
+		t.Errorf("QueryWaits after ResetStats = %d; want 0", n)
+	}
+}
+
+// TestMaxConcurrentQueriesCap runs many queries at once and checks that
+// no more than the limit ever run together.
+func TestMaxConcurrentQueriesCap(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	const limit = 3
+	db.SetMaxConcurrentQueries(limit)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				rows, err := db.Query("SELECT|people|name|")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				n := atomic.AddInt32(&running, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				if n := db.Stats().QueriesInFlight; n > limit {
+					t.Errorf("QueriesInFlight = %d; want at most %d", n, limit)
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				rows.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if peak > limit {
+		t.Errorf("%d queries ran at once; want at most %d", peak, limit)
+	}
+	if n := db.Stats().QueriesInFlight; n != 0 {
+		t.Errorf("QueriesInFlight = %d after all queries; want 0", n)
+	}
+}
@@ -10,8 +10,12 @@ package sql
 
 import (
 	"database/sql/driver"
+	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
 	"reflect"
 	"strconv"
 	"time"
@@ -19,16 +23,56 @@ import (
 
 var errNilPtr = errors.New("destination pointer is nil") // embedded in descriptive error
 
+// ArgError is returned when a query argument can't be converted to a
+// driver.Value, for example because of its type or because its Value
+// method failed. It identifies the offending argument among many.
+
+// ArgError 会在查询实参无法转换为 driver.Value 时返回，例如由于其类型，或由于其
+// Value 方法失败。它能在众多实参中指出出错的那一个。
+type ArgError struct {
+	Index int    // index of the argument, starting at 0
+	Type  string // Go type of the argument, as printed by %T
+	Err   error  // reason for the failure
+}
+
+func newArgError(n int, arg interface{}, err error) *ArgError {
+	return &ArgError{Index: n, Type: fmt.Sprintf("%T", arg), Err: err}
+}
+
+func (e *ArgError) Error() string {
+	return fmt.Sprintf("sql: converting argument #%d's type: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the reason for the failure.
+func (e *ArgError) Unwrap() error {
+	return e.Err
+}
+
 // driverArgs converts arguments from callers of Stmt.Exec and
 // Stmt.Query into driver Values.
 //
-// The statement ds may be nil, if no statement is available.
+// The statement ds may be nil, if no statement is available, and
+// ds.si may be nil if only the connection is known. Array arguments
+// are encoded by the connection, if ds has one; see Array. Arguments
+// that can't be converted are reported with an *ArgError.
 
 // driverArgs 将Stmt.Exec和Stmt.Query的调用参数转换成为driver中定义的值。
 //
-// 若没有语句可用，则语句 ds 为 nil。
+// 若没有语句可用，则语句 ds 为 nil；若只知道连接，则 ds.si 为 nil。
+// 数组实参由 ds 的连接（如果有的话）编码；见 Array。无法转换的实参会以 *ArgError 报告。
 func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
-	dargs := make([]driver.Value, len(args))
+	return driverArgsInto(nil, ds, args)
+}
+
+// driverArgsInto is like driverArgs, but returns the values in buf if
+// it is long enough.
+func driverArgsInto(buf []driver.Value, ds *driverStmt, args []interface{}) ([]driver.Value, error) {
+	var dargs []driver.Value
+	if len(args) <= len(buf) {
+		dargs = buf[:len(args):len(args)]
+	} else {
+		dargs = make([]driver.Value, len(args))
+	}
 	var si driver.Stmt
 	if ds != nil {
 		si = ds.si
@@ -38,10 +82,18 @@ func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
 	// Normal path, for a driver.Stmt that is not a ColumnConverter.
 	if !ok {
 		for n, arg := range args {
+			if rv, ok := asArray(arg); ok {
+				var err error
+				dargs[n], err = encodeArray(ds, arg, rv)
+				if err != nil {
+					return nil, newArgError(n, arg, err)
+				}
+				continue
+			}
 			var err error
 			dargs[n], err = driver.DefaultParameterConverter.ConvertValue(arg)
 			if err != nil {
-				return nil, fmt.Errorf("sql: converting Exec argument #%d's type: %v", n, err)
+				return nil, newArgError(n, arg, err)
 			}
 		}
 		return dargs, nil
@@ -49,16 +101,27 @@ func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
 
 	// Let the Stmt convert its own arguments.
 	for n, arg := range args {
+		// Arrays are encoded by the connection, not by a column.
+		if rv, ok := asArray(arg); ok {
+			var err error
+			dargs[n], err = encodeArray(ds, arg, rv)
+			if err != nil {
+				return nil, newArgError(n, arg, err)
+			}
+			continue
+		}
+		orig := arg
+
 		// First, see if the value itself knows how to convert
 		// itself to a driver type. For example, a NullString
 		// struct changing into a string or nil.
 		if svi, ok := arg.(driver.Valuer); ok {
 			sv, err := svi.Value()
 			if err != nil {
-				return nil, fmt.Errorf("sql: argument index %d from Value: %v", n, err)
+				return nil, newArgError(n, orig, err)
 			}
 			if !driver.IsValue(sv) {
-				return nil, fmt.Errorf("sql: argument index %d: non-subset type %T returned from Value", n, sv)
+				return nil, newArgError(n, orig, fmt.Errorf("non-subset type %T returned from Value", sv))
 			}
 			arg = sv
 		}
@@ -75,11 +138,11 @@ func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
 		dargs[n], err = cc.ColumnConverter(n).ConvertValue(arg)
 		ds.Unlock()
 		if err != nil {
-			return nil, fmt.Errorf("sql: converting argument #%d's type: %v", n, err)
+			return nil, newArgError(n, orig, err)
 		}
 		if !driver.IsValue(dargs[n]) {
-			return nil, fmt.Errorf("sql: driver ColumnConverter error converted %T to unsupported type %T",
-				arg, dargs[n])
+			return nil, newArgError(n, orig, fmt.Errorf("driver ColumnConverter converted %T to unsupported type %T",
+				arg, dargs[n]))
 		}
 	}
 
@@ -94,6 +157,12 @@ func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
 // 如果转换过程中出现数据信息的丢失，就会返回error。
 // dest应该是一个类型指针。
 func convertAssign(dest, src interface{}) error {
+	return convertAssignWith(dest, src, ScanStrict)
+}
+
+// convertAssignWith is like convertAssign, but converts numeric values
+// as set by strictness.
+func convertAssignWith(dest, src interface{}, strictness ScanStrictness) error {
 	// Common cases, without reflect.
 	switch s := src.(type) {
 	case string:
@@ -110,6 +179,12 @@ func convertAssign(dest, src interface{}) error {
 			}
 			*d = []byte(s)
 			return nil
+		case *json.RawMessage:
+			if d == nil {
+				return errNilPtr
+			}
+			*d = json.RawMessage(s)
+			return nil
 		}
 	case []byte:
 		switch d := dest.(type) {
@@ -137,6 +212,14 @@ func convertAssign(dest, src interface{}) error {
 			}
 			*d = s
 			return nil
+		case *json.RawMessage:
+			// Not through UnmarshalJSON, which would reuse the
+			// memory of a RawMessage scanned into before.
+			if d == nil {
+				return errNilPtr
+			}
+			*d = cloneBytes(s)
+			return nil
 		}
 	case time.Time:
 		switch d := dest.(type) {
@@ -149,6 +232,12 @@ func convertAssign(dest, src interface{}) error {
 			}
 			*d = []byte(s.Format(time.RFC3339Nano))
 			return nil
+		case *int64:
+			if d == nil {
+				return errNilPtr
+			}
+			*d = unixTime(s, time.Second)
+			return nil
 		}
 	case nil:
 		switch d := dest.(type) {
@@ -170,6 +259,12 @@ func convertAssign(dest, src interface{}) error {
 			}
 			*d = nil
 			return nil
+		case *json.RawMessage:
+			if d == nil {
+				return errNilPtr
+			}
+			*d = nil
+			return nil
 		}
 	}
 
@@ -199,19 +294,60 @@ func convertAssign(dest, src interface{}) error {
 			return nil
 		}
 	case *bool:
+		if src == nil {
+			return nullConversionErr(reflect.TypeOf(*d))
+		}
 		bv, err := driver.Bool.ConvertValue(src)
 		if err == nil {
 			*d = bv.(bool)
 		}
 		return err
+	case *time.Duration:
+		switch s := src.(type) {
+		case int64:
+			*d = time.Duration(s)
+			return nil
+		case string, []byte:
+			// A plain integer is nanoseconds, as for int64
+			// sources; anything else must parse as a duration.
+			str := asString(s)
+			if i64, err := strconv.ParseInt(str, 10, 64); err == nil {
+				*d = time.Duration(i64)
+				return nil
+			}
+			dur, err := time.ParseDuration(str)
+			if err != nil {
+				return fmt.Errorf("converting driver.Value type %T (%q) to a time.Duration: %v", src, str, err)
+			}
+			*d = dur
+			return nil
+		}
+	case *url.URL:
+		switch src.(type) {
+		case string, []byte:
+			// url.URL has no UnmarshalText method.
+			str := asString(src)
+			u, err := url.Parse(str)
+			if err != nil {
+				return fmt.Errorf("converting driver.Value type %T (%q) to a url.URL: %v", src, str, err)
+			}
+			*d = *u
+			return nil
+		}
 	case *interface{}:
 		*d = src
 		return nil
 	}
 
+	if ns, ok := dest.(nullScanner); ok {
+		return ns.scanWith(src, strictness)
+	}
 	if scanner, ok := dest.(Scanner); ok {
 		return scanner.Scan(src)
 	}
+	if ok, err := unmarshalAssign(dest, src); ok {
+		return err
+	}
 
 	dpv := reflect.ValueOf(dest)
 	if dpv.Kind() != reflect.Ptr {
@@ -226,6 +362,9 @@ func convertAssign(dest, src interface{}) error {
 	}
 
 	dv := reflect.Indirect(dpv)
+	if src == nil && dv.Kind() != reflect.Ptr {
+		return nullConversionErr(dv.Type())
+	}
 	if sv.IsValid() && sv.Type().AssignableTo(dv.Type()) {
 		switch b := src.(type) {
 		case []byte:
@@ -248,11 +387,29 @@ func convertAssign(dest, src interface{}) error {
 			return nil
 		} else {
 			dv.Set(reflect.New(dv.Type().Elem()))
-			return convertAssign(dv.Interface(), src)
+			return convertAssignWith(dv.Interface(), src, strictness)
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if b, ok := src.(bool); ok {
+			// Some drivers report BIT and BOOLEAN columns as
+			// bool; let them scan into integers as 0 or 1.
+			dv.SetInt(int64(boolToUint(b)))
+			return nil
+		}
+		iv, isInt := integerValue(src)
+		if isInt {
+			if i64, ok := intOf(iv); ok && !dv.OverflowInt(i64) {
+				dv.SetInt(i64)
+				return nil
+			}
+			// Out of range: report it, or clamp it, as for text.
+			src = iv.Interface()
+		}
 		s := asString(src)
 		i64, err := strconv.ParseInt(s, 10, dv.Type().Bits())
+		if err != nil && strictness == ScanLenient {
+			i64, err = lenientInt(s, dv.Type().Bits())
+		}
 		if err != nil {
 			err = strconvErr(err)
 			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, s, dv.Kind(), err)
@@ -260,8 +417,27 @@ func convertAssign(dest, src interface{}) error {
 		dv.SetInt(i64)
 		return nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if b, ok := src.(bool); ok {
+			dv.SetUint(boolToUint(b))
+			return nil
+		}
+		if iv, ok := integerValue(src); ok {
+			if u64, ok := uintOf(iv); ok && !dv.OverflowUint(u64) {
+				dv.SetUint(u64)
+				return nil
+			}
+			src = iv.Interface()
+		}
 		s := asString(src)
+		if len(s) > 0 && s[0] == '-' && strictness == ScanStrict {
+			// Report this plainly, rather than as the syntax
+			// error ParseUint would give.
+			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: value is negative", src, s, dv.Kind())
+		}
 		u64, err := strconv.ParseUint(s, 10, dv.Type().Bits())
+		if err != nil && strictness == ScanLenient {
+			u64, err = lenientUint(s, dv.Type().Bits())
+		}
 		if err != nil {
 			err = strconvErr(err)
 			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, s, dv.Kind(), err)
@@ -271,17 +447,187 @@ func convertAssign(dest, src interface{}) error {
 	case reflect.Float32, reflect.Float64:
 		s := asString(src)
 		f64, err := strconv.ParseFloat(s, dv.Type().Bits())
+		if err != nil && strictness == ScanLenient && strconvErr(err) == strconv.ErrRange {
+			// ParseFloat returned an infinity; clamp it.
+			f64, err = math.MaxFloat64, nil
+			if dv.Kind() == reflect.Float32 {
+				f64 = math.MaxFloat32
+			}
+			if s[0] == '-' {
+				f64 = -f64
+			}
+		}
 		if err != nil {
 			err = strconvErr(err)
 			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, s, dv.Kind(), err)
 		}
 		dv.SetFloat(f64)
 		return nil
+	case reflect.String:
+		// User-defined string types, such as "type Status string",
+		// accept what *string does.
+		switch v := src.(type) {
+		case string:
+			dv.SetString(v)
+			return nil
+		case []byte:
+			dv.SetString(string(v))
+			return nil
+		case time.Time:
+			dv.SetString(v.Format(time.RFC3339Nano))
+			return nil
+		}
+		switch sv.Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			dv.SetString(asString(src))
+			return nil
+		}
+	case reflect.Bool:
+		bv, err := driver.Bool.ConvertValue(src)
+		if err != nil {
+			return err
+		}
+		dv.SetBool(bv.(bool))
+		return nil
+	case reflect.Array:
+		// Fixed-length binary values, such as a UUID scanned
+		// into a [16]byte.
+		if dv.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		var b []byte
+		switch v := src.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		default:
+			return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, dest)
+		}
+		if len(b) != dv.Len() {
+			return fmt.Errorf("converting driver.Value type %T of length %d to a %s: length mismatch", src, len(b), dv.Type())
+		}
+		reflect.Copy(dv, reflect.ValueOf(b))
+		return nil
 	}
 
 	return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, dest)
 }
 
+// nullConversionErr returns the error for scanning a NULL into a
+// destination of type t, which cannot represent it, pointing the
+// caller at a type that can.
+func nullConversionErr(t reflect.Type) error {
+	var null string
+	switch t.Kind() {
+	case reflect.String:
+		null = "NullString"
+	case reflect.Bool:
+		null = "NullBool"
+	case reflect.Float32, reflect.Float64:
+		null = "NullFloat64"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		null = "NullInt64"
+	}
+	if t == reflect.TypeOf(time.Duration(0)) {
+		null = "NullDuration"
+	}
+	if null == "" {
+		return fmt.Errorf("converting NULL to %s is unsupported; use *%s", t, t)
+	}
+	return fmt.Errorf("converting NULL to %s is unsupported; use %s or *%s", t, null, t)
+}
+
+func boolToUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// integerValue returns the integer src, of any signed or unsigned kind,
+// or the one a non-nil pointer src points to. Drivers differ in the
+// types they give small integer columns, such as TINYINT.
+func integerValue(src interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(src)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv, true
+	}
+	return reflect.Value{}, false
+}
+
+// intOf returns the value of the integer rv as an int64, if it fits.
+func intOf(rv reflect.Value) (int64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	}
+	u := rv.Uint()
+	return int64(u), u <= math.MaxInt64
+}
+
+// uintOf returns the value of the integer rv as a uint64, if it is not
+// negative.
+func uintOf(rv reflect.Value) (uint64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		return uint64(i), i >= 0
+	}
+	return rv.Uint(), true
+}
+
+// lenientInt parses s as a signed integer of the given size for
+// ScanLenient: a fractional part is truncated toward zero and a value
+// out of range is clamped.
+func lenientInt(s string, bits int) (int64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && strconvErr(err) != strconv.ErrRange {
+		return 0, err
+	}
+	if math.IsNaN(f) {
+		return 0, errors.New("value is NaN")
+	}
+	max := int64(1)<<uint(bits-1) - 1
+	min := -max - 1
+	switch {
+	case f >= float64(max):
+		return max, nil
+	case f <= float64(min):
+		return min, nil
+	}
+	return int64(f), nil
+}
+
+// lenientUint is like lenientInt for unsigned integers; negative
+// values become 0.
+func lenientUint(s string, bits int) (uint64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && strconvErr(err) != strconv.ErrRange {
+		return 0, err
+	}
+	if math.IsNaN(f) {
+		return 0, errors.New("value is NaN")
+	}
+	max := uint64(1)<<uint(bits) - 1
+	switch {
+	case f <= 0:
+		return 0, nil
+	case f >= float64(max):
+		return max, nil
+	}
+	return uint64(f), nil
+}
+
 func strconvErr(err error) error {
 	if ne, ok := err.(*strconv.NumError); ok {
 		return ne.Err
@@ -289,6 +635,34 @@ func strconvErr(err error) error {
 	return err
 }
 
+// unmarshalAssign stores a string or []byte src into a dest that
+// implements encoding.TextUnmarshaler, encoding.BinaryUnmarshaler (for
+// []byte sources only) or json.Unmarshaler, preferring them in that
+// order. It reports whether dest and src were of such types.
+func unmarshalAssign(dest, src interface{}) (bool, error) {
+	var b []byte
+	switch s := src.(type) {
+	case string:
+		b = []byte(s)
+	case []byte:
+		b = s
+	default:
+		return false, nil
+	}
+	if u, ok := dest.(encoding.TextUnmarshaler); ok {
+		return true, u.UnmarshalText(b)
+	}
+	if u, ok := dest.(encoding.BinaryUnmarshaler); ok {
+		if _, isBytes := src.([]byte); isBytes {
+			return true, u.UnmarshalBinary(b)
+		}
+	}
+	if u, ok := dest.(json.Unmarshaler); ok {
+		return true, u.UnmarshalJSON(b)
+	}
+	return false, nil
+}
+
 func cloneBytes(b []byte) []byte {
 	if b == nil {
 		return nil
@@ -336,6 +710,11 @@ func asBytes(buf []byte, rv reflect.Value) (b []byte, ok bool) {
 		return strconv.AppendBool(buf, rv.Bool()), true
 	case reflect.String:
 		s := rv.String()
+		if buf == nil {
+			// An empty string is a value, not NULL, so it must
+			// not come back as a nil slice.
+			buf = make([]byte, 0, len(s))
+		}
 		return append(buf, s...), true
 	}
 	return
@@ -6,9 +6,16 @@ package sql
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -51,6 +58,8 @@ var (
 	scanint32  int32
 	scanuint8  uint8
 	scanuint16 uint16
+	scanuint   uint
+	scanuint64 uint64
 	scanbool   bool
 	scanf32    float32
 	scanf64    float64
@@ -123,6 +132,17 @@ var conversionTests = []conversionTest{
 	{s: int64(256), d: &scanuint16, wantuint: 256},
 	{s: int64(65536), d: &scanuint16, wanterr: "converting driver.Value type int64 (\"65536\") to a uint16: value out of range"},
 
+	// Unsigned destinations
+	{s: "18446744073709551615", d: &scanuint64, wantuint: 18446744073709551615},
+	{s: []byte("18446744073709551615"), d: &scanuint64, wantuint: 18446744073709551615},
+	{s: "18446744073709551616", d: &scanuint64, wanterr: "converting driver.Value type string (\"18446744073709551616\") to a uint64: value out of range"},
+	{s: int64(9223372036854775807), d: &scanuint64, wantuint: 9223372036854775807},
+	{s: int64(42), d: &scanuint, wantuint: 42},
+	{s: int64(-1), d: &scanuint64, wanterr: "converting driver.Value type int64 (\"-1\") to a uint64: value is negative"},
+	{s: int64(-1), d: &scanuint8, wanterr: "converting driver.Value type int64 (\"-1\") to a uint8: value is negative"},
+	{s: "-5", d: &scanuint, wanterr: "converting driver.Value type string (\"-5\") to a uint: value is negative"},
+	{s: []byte("-5"), d: &scanuint16, wanterr: "converting driver.Value type []uint8 (\"-5\") to a uint16: value is negative"},
+
 	// True bools
 	{s: true, d: &scanbool, wantbool: true},
 	{s: "True", d: &scanbool, wantbool: true},
@@ -131,6 +151,8 @@ var conversionTests = []conversionTest{
 	{s: 1, d: &scanbool, wantbool: true},
 	{s: int64(1), d: &scanbool, wantbool: true},
 	{s: uint16(1), d: &scanbool, wantbool: true},
+	{s: "t", d: &scanbool, wantbool: true},
+	{s: []byte("1"), d: &scanbool, wantbool: true},
 
 	// False bools
 	{s: false, d: &scanbool, wantbool: false},
@@ -140,10 +162,14 @@ var conversionTests = []conversionTest{
 	{s: 0, d: &scanbool, wantbool: false},
 	{s: int64(0), d: &scanbool, wantbool: false},
 	{s: uint16(0), d: &scanbool, wantbool: false},
+	{s: "f", d: &scanbool, wantbool: false},
+	{s: []byte("false"), d: &scanbool, wantbool: false},
 
 	// Not bools
 	{s: "yup", d: &scanbool, wanterr: `sql/driver: couldn't convert "yup" into type bool`},
 	{s: 2, d: &scanbool, wanterr: `sql/driver: couldn't convert 2 into type bool`},
+	{s: int64(-1), d: &scanbool, wanterr: `sql/driver: couldn't convert -1 into type bool`},
+	{s: []byte("2"), d: &scanbool, wanterr: `sql/driver: couldn't convert "2" into type bool`},
 
 	// Floats
 	{s: float64(1.5), d: &scanf64, wantf64: float64(1.5)},
@@ -262,6 +288,134 @@ func TestConversions(t *testing.T) {
 	}
 }
 
+type (
+	userString  string
+	userInt     int
+	userInt8    int8
+	userInt64   int64
+	userUint16  uint16
+	userFloat32 float32
+	userBool    bool
+)
+
+func TestNamedScalarConversions(t *testing.T) {
+	tests := []struct {
+		s, d interface{} // source and destination
+		want interface{}
+	}{
+		{"active", new(userString), userString("active")},
+		{[]byte("active"), new(userString), userString("active")},
+		{int64(42), new(userString), userString("42")},
+		{float64(1.5), new(userString), userString("1.5")},
+		{true, new(userString), userString("true")},
+		{time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), new(userString), userString("2006-01-02T15:04:05Z")},
+
+		{int64(7), new(userInt), userInt(7)},
+		{"7", new(userInt), userInt(7)},
+		{[]byte("7"), new(userInt), userInt(7)},
+		{int64(-8), new(userInt8), userInt8(-8)},
+		{[]byte("-8"), new(userInt8), userInt8(-8)},
+		{int64(1 << 40), new(userInt64), userInt64(1 << 40)},
+		{"1099511627776", new(userInt64), userInt64(1 << 40)},
+		{int64(65535), new(userUint16), userUint16(65535)},
+		{[]byte("65535"), new(userUint16), userUint16(65535)},
+
+		{float64(1.5), new(userFloat32), userFloat32(1.5)},
+		{"1.5", new(userFloat32), userFloat32(1.5)},
+		{[]byte("1.5"), new(userFloat32), userFloat32(1.5)},
+
+		{true, new(userBool), userBool(true)},
+		{int64(1), new(userBool), userBool(true)},
+		{"false", new(userBool), userBool(false)},
+		{[]byte("true"), new(userBool), userBool(true)},
+	}
+	for n, tt := range tests {
+		if err := convertAssign(tt.d, tt.s); err != nil {
+			t.Errorf("%d. convertAssign(%T, %T(%v)) = %v", n, tt.d, tt.s, tt.s, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.d).Elem().Interface(); got != tt.want {
+			t.Errorf("%d. convertAssign(%T, %T(%v)) stored %#v; want %#v", n, tt.d, tt.s, tt.s, got, tt.want)
+		}
+	}
+
+	for n, tt := range []struct {
+		s, d interface{}
+	}{
+		{int64(128), new(userInt8)},
+		{"x", new(userInt)},
+		{int64(2), new(userBool)},
+	} {
+		if err := convertAssign(tt.d, tt.s); err == nil {
+			t.Errorf("%d. convertAssign(%T, %T(%v)) succeeded; want error", n, tt.d, tt.s, tt.s)
+		}
+	}
+}
+
+// status is an enum backed by an integer, as a program would declare
+// one with iota.
+type status int
+
+const (
+	statusPending status = iota
+	statusActive
+	statusDeleted
+)
+
+func (s status) String() string {
+	return [...]string{"pending", "active", "deleted"}[s]
+}
+
+func TestIntegerEnumConversions(t *testing.T) {
+	type (
+		enumInt16  int16
+		enumInt32  int32
+		enumUint   uint
+		enumUint8  uint8
+		enumUint32 uint32
+		enumUint64 uint64
+	)
+	dests := []interface{}{
+		new(status), new(userInt8), new(enumInt16), new(enumInt32), new(userInt64),
+		new(enumUint), new(enumUint8), new(userUint16), new(enumUint32), new(enumUint64),
+	}
+	for _, src := range []interface{}{int64(2), "2", []byte("2")} {
+		for _, d := range dests {
+			if err := convertAssign(d, src); err != nil {
+				t.Errorf("convertAssign(%T, %#v): %v", d, src, err)
+				continue
+			}
+			v := reflect.ValueOf(d).Elem()
+			var got uint64
+			if k := v.Kind(); k >= reflect.Uint && k <= reflect.Uint64 {
+				got = v.Uint()
+			} else {
+				got = uint64(v.Int())
+			}
+			if got != 2 {
+				t.Errorf("convertAssign(%T, %#v) = %d; want 2", d, src, got)
+			}
+		}
+	}
+
+	var st status
+	if err := convertAssign(&st, int64(1)); err != nil || st != statusActive {
+		t.Errorf("convertAssign(*status, 1) = %v, %v; want active", st, err)
+	}
+	for _, tt := range []struct {
+		s, d interface{}
+	}{
+		{int64(40000), new(enumInt16)},
+		{"256", new(enumUint8)},
+		{int64(-2), new(enumUint32)},
+		{[]byte("active"), new(status)},
+	} {
+		if err := convertAssign(tt.d, tt.s); err == nil {
+			t.Errorf("convertAssign(%T, %#v) succeeded; want error", tt.d, tt.s)
+		}
+	}
+}
+
 func TestNullString(t *testing.T) {
 	var ns NullString
 	convertAssign(&ns, []byte("foo"))
@@ -378,6 +532,386 @@ func TestRawBytesAllocs(t *testing.T) {
 	}
 }
 
+func TestEmptyBytesConversions(t *testing.T) {
+	tests := []struct {
+		s       interface{}
+		wantNil bool
+	}{
+		{"", false},
+		{[]byte{}, false},
+		{nil, true},
+		{[]byte(nil), true},
+	}
+	for _, tt := range tests {
+		var b []byte
+		if err := convertAssign(&b, tt.s); err != nil {
+			t.Fatalf("convertAssign(*[]byte, %#v) = %v", tt.s, err)
+		}
+		if (b == nil) != tt.wantNil || len(b) != 0 {
+			t.Errorf("convertAssign(*[]byte, %#v) stored %#v; want nil = %v", tt.s, b, tt.wantNil)
+		}
+		var raw RawBytes
+		if err := convertAssign(&raw, tt.s); err != nil {
+			t.Fatalf("convertAssign(*RawBytes, %#v) = %v", tt.s, err)
+		}
+		if (raw == nil) != tt.wantNil || len(raw) != 0 {
+			t.Errorf("convertAssign(*RawBytes, %#v) stored %#v; want nil = %v", tt.s, raw, tt.wantNil)
+		}
+	}
+}
+
+func TestNullIntoScalar(t *testing.T) {
+	tests := []struct {
+		d    interface{}
+		want string
+	}{
+		{new(string), "converting NULL to string is unsupported; use NullString or *string"},
+		{new(bool), "converting NULL to bool is unsupported; use NullBool or *bool"},
+		{new(int), "converting NULL to int is unsupported; use NullInt64 or *int"},
+		{new(int8), "converting NULL to int8 is unsupported; use NullInt64 or *int8"},
+		{new(int16), "converting NULL to int16 is unsupported; use NullInt64 or *int16"},
+		{new(int32), "converting NULL to int32 is unsupported; use NullInt64 or *int32"},
+		{new(int64), "converting NULL to int64 is unsupported; use NullInt64 or *int64"},
+		{new(uint), "converting NULL to uint is unsupported; use NullInt64 or *uint"},
+		{new(uint8), "converting NULL to uint8 is unsupported; use NullInt64 or *uint8"},
+		{new(uint16), "converting NULL to uint16 is unsupported; use NullInt64 or *uint16"},
+		{new(uint32), "converting NULL to uint32 is unsupported; use NullInt64 or *uint32"},
+		{new(uint64), "converting NULL to uint64 is unsupported; use NullInt64 or *uint64"},
+		{new(float32), "converting NULL to float32 is unsupported; use NullFloat64 or *float32"},
+		{new(float64), "converting NULL to float64 is unsupported; use NullFloat64 or *float64"},
+		{new(time.Time), "converting NULL to time.Time is unsupported; use *time.Time"},
+		{new(userString), "converting NULL to sql.userString is unsupported; use NullString or *sql.userString"},
+	}
+	for _, tt := range tests {
+		err := convertAssign(tt.d, nil)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("convertAssign(%T, nil) = %v; want %q", tt.d, err, tt.want)
+		}
+	}
+
+	// Destinations that can hold NULL still accept it.
+	var ps *string
+	if err := convertAssign(&ps, nil); err != nil || ps != nil {
+		t.Errorf("convertAssign(**string, nil) = %v, %v; want nil pointer", ps, err)
+	}
+	var ns NullString
+	if err := convertAssign(&ns, nil); err != nil || ns.Valid {
+		t.Errorf("convertAssign(*NullString, nil) = %+v, %v; want invalid", ns, err)
+	}
+}
+
+func TestBoolToIntConversions(t *testing.T) {
+	dests := []interface{}{new(int), new(int8), new(int64), new(uint8), new(uint64), new(userInt)}
+	for _, b := range []bool{true, false} {
+		want := int64(0)
+		if b {
+			want = 1
+		}
+		for _, d := range dests {
+			// Start from a value that neither 0 nor 1 matches.
+			reflect.ValueOf(d).Elem().Set(reflect.ValueOf(7).Convert(reflect.TypeOf(d).Elem()))
+			if err := convertAssign(d, b); err != nil {
+				t.Errorf("convertAssign(%T, %v): %v", d, b, err)
+				continue
+			}
+			got := reflect.ValueOf(d).Elem()
+			var g int64
+			if k := got.Kind(); k >= reflect.Uint && k <= reflect.Uint64 {
+				g = int64(got.Uint())
+			} else {
+				g = got.Int()
+			}
+			if g != want {
+				t.Errorf("convertAssign(%T, %v) = %d; want %d", d, b, g, want)
+			}
+		}
+	}
+}
+
+func TestIntegerWidening(t *testing.T) {
+	type tinyint int8
+	small := int16(-300)
+	tests := []struct {
+		src     interface{}
+		dest    interface{}
+		want    int64
+		wantErr bool
+	}{
+		{int8(-8), new(int), -8, false},
+		{int8(-8), new(int32), -8, false},
+		{int8(-8), new(int64), -8, false},
+		{uint8(200), new(int), 200, false},
+		{uint8(200), new(int32), 200, false},
+		{int16(-32768), new(int64), -32768, false},
+		{uint16(65535), new(int), 65535, false},
+		{uint16(65535), new(int32), 65535, false},
+		{int32(-70000), new(int), -70000, false},
+		{uint32(math.MaxUint32), new(int64), math.MaxUint32, false},
+		{uint32(math.MaxUint32), new(int32), 0, true},
+		{int64(1 << 40), new(int64), 1 << 40, false},
+		{int64(1 << 40), new(int32), 0, true},
+		{int64(math.MinInt32), new(int32), math.MinInt32, false},
+		{int64(math.MinInt32 - 1), new(int32), 0, true},
+		{uint64(math.MaxInt64), new(int64), math.MaxInt64, false},
+		{uint64(1 << 63), new(int64), 0, true},
+		{uint64(1 << 63), new(int), 0, true},
+		{uint(7), new(int32), 7, false},
+		{tinyint(-3), new(int), -3, false},
+		{tinyint(-3), new(int32), -3, false},
+		{&small, new(int), -300, false},
+		{&small, new(int64), -300, false},
+		{(*int16)(nil), new(int64), 0, true},
+	}
+	for _, tt := range tests {
+		err := convertAssign(tt.dest, tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("convertAssign(%T, %T(%v)) succeeded; want error", tt.dest, tt.src, tt.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertAssign(%T, %T(%v)): %v", tt.dest, tt.src, tt.src, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.dest).Elem().Int(); got != tt.want {
+			t.Errorf("convertAssign(%T, %T(%v)) = %d; want %d", tt.dest, tt.src, tt.src, got, tt.want)
+		}
+	}
+
+	// The range errors name the integer, not the pointer to it.
+	var i8 int8
+	err := convertAssign(&i8, &small)
+	if err == nil || !strings.Contains(err.Error(), `"-300"`) {
+		t.Errorf("convertAssign(*int8, *int16) = %v; want a range error for -300", err)
+	}
+	var u8 uint8
+	if err := convertAssign(&u8, &small); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("convertAssign(*uint8, *int16) = %v; want a negative value error", err)
+	}
+	if err := convertAssign(&u8, uint16(255)); err != nil || u8 != 255 {
+		t.Errorf("convertAssign(*uint8, uint16(255)) = %d, %v; want 255", u8, err)
+	}
+}
+
+func TestByteArrayConversions(t *testing.T) {
+	type uuid [16]byte
+	id := []byte("0123456789abcdef")
+
+	var u uuid
+	if err := convertAssign(&u, id); err != nil || string(u[:]) != string(id) {
+		t.Errorf("convertAssign(*uuid, []byte) = %q, %v; want %q", u[:], err, id)
+	}
+	var a [4]byte
+	if err := convertAssign(&a, "abcd"); err != nil || a != [4]byte{'a', 'b', 'c', 'd'} {
+		t.Errorf("convertAssign(*[4]byte, string) = %q, %v; want \"abcd\"", a[:], err)
+	}
+
+	tests := []struct {
+		s       interface{}
+		d       interface{}
+		wanterr string
+	}{
+		{[]byte("012345678"), new(uuid), "converting driver.Value type []uint8 of length 9 to a sql.uuid: length mismatch"},
+		{"0123456789abcdef0", new(uuid), "converting driver.Value type string of length 17 to a sql.uuid: length mismatch"},
+		{[]byte{}, new([4]byte), "converting driver.Value type []uint8 of length 0 to a [4]uint8: length mismatch"},
+		{int64(1), new([4]byte), "unsupported Scan, storing driver.Value type int64 into type *[4]uint8"},
+		{[]byte("abcd"), new([4]int), "unsupported Scan, storing driver.Value type []uint8 into type *[4]int"},
+	}
+	for _, tt := range tests {
+		before := reflect.ValueOf(tt.d).Elem().Interface()
+		err := convertAssign(tt.d, tt.s)
+		if err == nil || err.Error() != tt.wanterr {
+			t.Errorf("convertAssign(%T, %#v) = %v; want %q", tt.d, tt.s, err, tt.wanterr)
+		}
+		if after := reflect.ValueOf(tt.d).Elem().Interface(); after != before {
+			t.Errorf("convertAssign(%T, %#v) modified the destination on error", tt.d, tt.s)
+		}
+	}
+}
+
+func TestScanStrictness(t *testing.T) {
+	tests := []struct {
+		s    interface{}
+		d    interface{}
+		want interface{} // with ScanLenient; ScanStrict must fail
+	}{
+		{float64(2.9), new(int), int(2)},
+		{float64(-2.9), new(int64), int64(-2)},
+		{"2.5", new(int32), int32(2)},
+		{[]byte("1e3"), new(int16), int16(1000)},
+		{int64(300), new(int8), int8(127)},
+		{int64(-300), new(int8), int8(-128)},
+		{float64(1e30), new(int64), int64(math.MaxInt64)},
+		{"-1e30", new(int64), int64(math.MinInt64)},
+		{"99999999999999999999", new(int64), int64(math.MaxInt64)},
+		{int64(-5), new(uint), uint(0)},
+		{float64(-0.5), new(uint8), uint8(0)},
+		{float64(255.7), new(uint8), uint8(255)},
+		{int64(70000), new(uint16), uint16(math.MaxUint16)},
+		{"1e30", new(uint64), uint64(math.MaxUint64)},
+		{float64(1e300), new(float32), float32(math.MaxFloat32)},
+		{"-1e300", new(float32), float32(-math.MaxFloat32)},
+		{"1e400", new(float64), float64(math.MaxFloat64)},
+	}
+	for _, tt := range tests {
+		if err := convertAssignWith(tt.d, tt.s, ScanStrict); err == nil {
+			t.Errorf("strict convertAssign(%T, %#v) succeeded with %v; want error", tt.d, tt.s, reflect.ValueOf(tt.d).Elem())
+		}
+		if err := convertAssignWith(tt.d, tt.s, ScanLenient); err != nil {
+			t.Errorf("lenient convertAssign(%T, %#v): %v", tt.d, tt.s, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.d).Elem().Interface(); got != tt.want {
+			t.Errorf("lenient convertAssign(%T, %#v) = %v; want %v", tt.d, tt.s, got, tt.want)
+		}
+	}
+
+	// Still rejected, whatever the policy.
+	for _, src := range []interface{}{"NaN", "abc", float64(math.NaN())} {
+		var i int
+		if err := convertAssignWith(&i, src, ScanLenient); err == nil {
+			t.Errorf("lenient convertAssign(*int, %#v) succeeded; want error", src)
+		}
+	}
+
+	// Pointer destinations follow the policy too.
+	var p *int
+	if err := convertAssignWith(&p, float64(3.5), ScanLenient); err != nil || p == nil || *p != 3 {
+		t.Errorf("lenient convertAssign(**int, 3.5) = %v, %v; want 3", p, err)
+	}
+}
+
+func TestDBScanStrictness(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|f=float64")
+	exec(t, db, "INSERT|t|f=?", 2.75)
+
+	var n int
+	if err := db.QueryRow("SELECT|t|f|").Scan(&n); err == nil {
+		t.Errorf("Scan of 2.75 into *int succeeded with %d by default; want error", n)
+	}
+	db.SetScanStrictness(ScanLenient)
+	if err := db.QueryRow("SELECT|t|f|").Scan(&n); err != nil || n != 2 {
+		t.Errorf("lenient Scan of 2.75 into *int = %d, %v; want 2", n, err)
+	}
+}
+
+func TestDurationConversions(t *testing.T) {
+	tests := []struct {
+		s       interface{}
+		want    time.Duration
+		wanterr string
+	}{
+		{s: int64(1500), want: 1500},
+		{s: int64(-3), want: -3},
+		{s: "1500", want: 1500},
+		{s: []byte("2000000000"), want: 2 * time.Second},
+		{s: "1h30m", want: 90 * time.Minute},
+		{s: []byte("250ms"), want: 250 * time.Millisecond},
+		{s: "soon", wanterr: `converting driver.Value type string ("soon") to a time.Duration: `},
+		{s: []byte(""), wanterr: `converting driver.Value type []uint8 ("") to a time.Duration: `},
+		{s: nil, wanterr: "converting NULL to time.Duration is unsupported; use NullDuration or *time.Duration"},
+	}
+	for _, tt := range tests {
+		var d time.Duration
+		err := convertAssign(&d, tt.s)
+		if tt.wanterr != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wanterr) {
+				t.Errorf("convertAssign(%#v) error = %v; want prefix %q", tt.s, err, tt.wanterr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertAssign(%#v): %v", tt.s, err)
+			continue
+		}
+		if d != tt.want {
+			t.Errorf("convertAssign(%#v) = %v; want %v", tt.s, d, tt.want)
+		}
+	}
+}
+
+func TestNullDuration(t *testing.T) {
+	var n NullDuration
+	if err := n.Scan(int64(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if !n.Valid || n.Duration != time.Second {
+		t.Errorf("Scan(1e9) = %+v; want valid 1s", n)
+	}
+	v, err := n.Value()
+	if err != nil || v != int64(time.Second) {
+		t.Errorf("Value = %#v, %v; want int64(1e9)", v, err)
+	}
+	if err := n.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n.Valid {
+		t.Errorf("Scan(nil) = %+v; want invalid", n)
+	}
+	if v, _ := n.Value(); v != nil {
+		t.Errorf("Value of NULL = %#v; want nil", v)
+	}
+}
+
+// nullValuer is implemented by the Null types.
+type nullValuer interface {
+	Scanner
+	driver.Valuer
+}
+
+func TestNullTypesBoundaries(t *testing.T) {
+	ts := time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)
+	tests := []struct {
+		n       nullValuer
+		src     interface{}
+		want    driver.Value // from Value after Scan; nil for NULL
+		wantErr bool
+	}{
+		{new(NullString), nil, nil, false},
+		{new(NullString), "", "", false},
+		{new(NullString), []byte("héllo"), "héllo", false},
+		{new(NullInt64), nil, nil, false},
+		{new(NullInt64), int64(math.MaxInt64), int64(math.MaxInt64), false},
+		{new(NullInt64), "-9223372036854775808", int64(math.MinInt64), false},
+		{new(NullInt64), "9223372036854775808", nil, true},
+		{new(NullInt64), float64(1.5), nil, true},
+		{new(NullFloat64), nil, nil, false},
+		{new(NullFloat64), -math.MaxFloat64, -math.MaxFloat64, false},
+		{new(NullFloat64), []byte("4.9406564584124654e-324"), math.SmallestNonzeroFloat64, false},
+		{new(NullFloat64), "1e400", nil, true},
+		{new(NullTime), nil, nil, false},
+		{new(NullTime), time.Time{}, time.Time{}, false},
+		{new(NullTime), ts, ts, false},
+		{new(NullTime), "2016-01-01", nil, true},
+	}
+	for _, tt := range tests {
+		err := tt.n.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%T.Scan(%#v) succeeded; want error", tt.n, tt.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%T.Scan(%#v): %v", tt.n, tt.src, err)
+			continue
+		}
+		v, err := tt.n.Value()
+		if err != nil || v != tt.want {
+			t.Errorf("%T.Scan(%#v) then Value = %#v, %v; want %#v", tt.n, tt.src, v, err, tt.want)
+		}
+	}
+
+	// Scanning NULL resets a previously valid value to the zero value.
+	n := NullInt64{Int64: 7, Valid: true}
+	if err := n.Scan(nil); err != nil || n.Valid || n.Int64 != 0 {
+		t.Errorf("NullInt64.Scan(nil) = %+v, %v; want invalid zero value", n, err)
+	}
+}
+
 // https://github.com/golang/go/issues/13905
 func TestUserDefinedBytes(t *testing.T) {
 	type userDefinedBytes []byte
@@ -389,3 +923,185 @@ func TestUserDefinedBytes(t *testing.T) {
 		t.Fatal("userDefinedBytes got potentially dirty driver memory")
 	}
 }
+
+func TestArgError(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|a=int32,b=int32,c=int32,d=int32,e=int32")
+
+	type unsupported struct{}
+	args := []interface{}{1, 2, unsupported{}, 4, 5}
+	check := func(what string, err error) {
+		ae, ok := err.(*ArgError)
+		if !ok {
+			t.Errorf("%s: error = %v (%T); want *ArgError", what, err, err)
+			return
+		}
+		if ae.Index != 2 || ae.Type != "sql.unsupported" || ae.Err == nil {
+			t.Errorf("%s: ArgError = %+v; want Index 2, Type sql.unsupported and a reason", what, ae)
+		}
+	}
+
+	const insert = "INSERT|t|a=?,b=?,c=?,d=?,e=?"
+	_, err := db.Exec(insert, args...)
+	check("DB.Exec", err)
+
+	stmt, err := db.Prepare(insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(args...)
+	check("Stmt.Exec", err)
+
+	_, err = db.Query("SELECT|t|a|a=?,b=?,c=?,d=?,e=?", args...)
+	check("DB.Query", err)
+
+	// A failing Value method is reported with its error.
+	valueErr := errors.New("bad value")
+	args[2] = valuerFunc(func() (driver.Value, error) { return nil, valueErr })
+	_, err = stmt.Exec(args...)
+	if ae, ok := err.(*ArgError); !ok || ae.Index != 2 || ae.Err != valueErr {
+		t.Errorf("Stmt.Exec with failing Valuer = %v; want ArgError for index 2 wrapping %v", err, valueErr)
+	}
+}
+
+type valuerFunc func() (driver.Value, error)
+
+func (f valuerFunc) Value() (driver.Value, error) { return f() }
+
+// textID is a TextUnmarshaler such as a UUID type, holding the text
+// it's given in upper case.
+type textID string
+
+func (id *textID) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		return errors.New("empty id")
+	}
+	*id = textID(strings.ToUpper(string(b)))
+	return nil
+}
+
+// binaryID is a BinaryUnmarshaler holding the bytes it's given.
+type binaryID struct{ b []byte }
+
+func (id *binaryID) UnmarshalBinary(b []byte) error {
+	id.b = cloneBytes(b)
+	return nil
+}
+
+// scannerTextID implements both Scanner and TextUnmarshaler.
+type scannerTextID struct{ via string }
+
+func (id *scannerTextID) Scan(src interface{}) error {
+	id.via = "Scan"
+	return nil
+}
+
+func (id *scannerTextID) UnmarshalText(b []byte) error {
+	id.via = "UnmarshalText"
+	return nil
+}
+
+// jsonPoint is a json.Unmarshaler.
+type jsonPoint struct{ X, Y int }
+
+func (p *jsonPoint) UnmarshalJSON(b []byte) error {
+	var v [2]int
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	p.X, p.Y = v[0], v[1]
+	return nil
+}
+
+func TestUnmarshalerConversions(t *testing.T) {
+	for _, src := range []interface{}{"ab-12", []byte("ab-12")} {
+		var id textID
+		if err := convertAssign(&id, src); err != nil || id != "AB-12" {
+			t.Errorf("textID from %T = %q, %v; want AB-12, nil", src, id, err)
+		}
+		if err := convertAssign(&id, reflect.ValueOf(src).Slice(0, 0).Interface()); err == nil {
+			t.Errorf("textID from empty %T succeeded", src)
+		}
+	}
+
+	var bid binaryID
+	if err := convertAssign(&bid, []byte{1, 2}); err != nil || !reflect.DeepEqual(bid.b, []byte{1, 2}) {
+		t.Errorf("binaryID from []byte = %v, %v; want [1 2], nil", bid.b, err)
+	}
+	if err := convertAssign(&bid, "x"); err == nil {
+		t.Error("binaryID from string succeeded")
+	}
+
+	var both scannerTextID
+	if err := convertAssign(&both, "x"); err != nil || both.via != "Scan" {
+		t.Errorf("Scanner and TextUnmarshaler scanned via %q, %v; want Scan", both.via, err)
+	}
+
+	var p jsonPoint
+	if err := convertAssign(&p, []byte("[3,4]")); err != nil || p != (jsonPoint{3, 4}) {
+		t.Errorf("jsonPoint from []byte = %v, %v; want {3 4}, nil", p, err)
+	}
+
+	var tm time.Time
+	if err := convertAssign(&tm, "2016-01-02T03:04:05Z"); err != nil || !tm.Equal(time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("time.Time from string = %v, %v", tm, err)
+	}
+}
+
+func TestScanTextUnmarshaler(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var id textID
+	if err := db.QueryRow("SELECT|people|name|age=?", 2).Scan(&id); err != nil || id != "BOB" {
+		t.Errorf("Scan into textID = %q, %v; want BOB, nil", id, err)
+	}
+}
+
+// TestStdlibTypeConversions checks the standard library types listed
+// in the documentation of Rows.Scan.
+func TestStdlibTypeConversions(t *testing.T) {
+	for _, src := range []interface{}{"2016-01-02T03:04:05Z", []byte("2016-01-02T03:04:05Z")} {
+		var tm time.Time
+		if err := convertAssign(&tm, src); err != nil || !tm.Equal(time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("time.Time from %T = %v, %v", src, tm, err)
+		}
+	}
+	var d time.Duration
+	if err := convertAssign(&d, "1m30s"); err != nil || d != 90*time.Second {
+		t.Errorf("time.Duration = %v, %v; want 1m30s, nil", d, err)
+	}
+	for _, src := range []interface{}{"192.0.2.1", []byte("2001:db8::1")} {
+		var ip net.IP
+		if err := convertAssign(&ip, src); err != nil || ip.String() != asString(src) {
+			t.Errorf("net.IP from %T = %v, %v; want %s", src, ip, err, src)
+		}
+	}
+	var ip net.IP
+	if err := convertAssign(&ip, "not an ip"); err == nil {
+		t.Errorf("net.IP from invalid text = %v; want error", ip)
+	}
+	var n big.Int
+	if err := convertAssign(&n, "123456789012345678901234567890"); err != nil || n.String() != "123456789012345678901234567890" {
+		t.Errorf("big.Int = %v, %v", &n, err)
+	}
+	for _, src := range []interface{}{"https://example.com/a?b=c", []byte("https://example.com/a?b=c")} {
+		var u url.URL
+		if err := convertAssign(&u, src); err != nil || u.Host != "example.com" || u.RawQuery != "b=c" {
+			t.Errorf("url.URL from %T = %v, %v", src, &u, err)
+		}
+	}
+	var u url.URL
+	if err := convertAssign(&u, "%zz"); err == nil {
+		t.Errorf("url.URL from invalid text = %v; want error", &u)
+	}
+	var pu *url.URL
+	if err := convertAssign(&pu, "/path"); err != nil || pu == nil || pu.Path != "/path" {
+		t.Errorf("*url.URL = %v, %v; want /path", pu, err)
+	}
+	if err := convertAssign(&pu, nil); err != nil || pu != nil {
+		t.Errorf("*url.URL from NULL = %v, %v; want nil, nil", pu, err)
+	}
+}
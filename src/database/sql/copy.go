@@ -0,0 +1,183 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// ErrCopyNotSupported is returned by CopyFrom when the driver does not
+// implement driver.Copier. Callers may fall back to batched inserts.
+
+// ErrCopyNotSupported 会在驱动未实现 driver.Copier 时由 CopyFrom 返回。
+// 调用者可退而使用分批插入。
+var ErrCopyNotSupported = errors.New("sql: driver does not support bulk copy")
+
+var errCopyClosed = errors.New("sql: CopyIn is closed")
+
+// CopyIn is a bulk copy into a table, started by DB.CopyFrom.
+//
+// A CopyIn holds a connection of its own until Exec or Close is
+// called, and must end with one of them. It is not safe for
+// concurrent use by multiple goroutines.
+
+// CopyIn 是由 DB.CopyFrom 启动的、向表中进行的批量复制。
+//
+// CopyIn 会独占一个连接，直到 Exec 或 Close 被调用，且必须以两者之一结束。
+// 多个 goroutine 并发使用 CopyIn 是不安全的。
+type CopyIn struct {
+	db  *DB
+	ctx context.Context
+	dc  *driverConn
+	ci  driver.CopyIn
+
+	done bool // Exec or Close has been called
+}
+
+// CopyFrom starts a bulk copy of rows into the given columns of table,
+// using the bulk load protocol of a driver implementing driver.Copier.
+// Bulk loading is typically much faster than inserting the rows one
+// at a time. If the driver does not implement driver.Copier,
+// CopyFrom returns ErrCopyNotSupported.
+//
+// The copy is not started if ctx is done before it gets a connection,
+// including while it waits for one from a busy pool. Once it has started,
+// ctx is checked before each row and before the copy completes; if it
+// is done, the copy is abandoned and ctx.Err() is returned. As with
+// Close, the rows added so far are discarded and the connection is
+// returned to the pool.
+
+// CopyFrom 使用实现了 driver.Copier 的驱动所提供的批量加载协议，启动一次向 table
+// 的指定列 columns 复制行的批量复制。批量加载通常比逐行插入快得多。若驱动未实现
+// driver.Copier，CopyFrom 会返回 ErrCopyNotSupported。
+//
+// 若 ctx 在获得连接之前（包括在等待繁忙的连接池分配连接期间）结束，则不会开始复制。
+// 复制开始后，每一行之前以及复制完成之前都会检查 ctx；若其已结束，复制会被放弃，
+// 并返回 ctx.Err()。与 Close 一样，此前已添加的行会被丢弃，连接会被归还给连接池。
+func (db *DB) CopyFrom(ctx context.Context, table string, columns []string) (*CopyIn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// A copy inserts rows.
+	if err := db.checkReadOnly("INSERT INTO " + table); err != nil {
+		return nil, db.handleErr("CopyFrom", table, err)
+	}
+	var cp *CopyIn
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		cp, err = db.copyFrom(ctx, table, columns, cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		cp, err = db.copyFrom(ctx, table, columns, alwaysNewConn)
+	}
+	return cp, db.handleErr("CopyFrom", table, err)
+}
+
+func (db *DB) copyFrom(ctx context.Context, table string, columns []string, strategy connReuseStrategy) (*CopyIn, error) {
+	dc, err := db.connContext(ctx, strategy)
+	if err != nil {
+		return nil, err
+	}
+	copier, ok := dc.ci.(driver.Copier)
+	if !ok {
+		db.putConn(dc, nil)
+		return nil, ErrCopyNotSupported
+	}
+	dc.Lock()
+	ci, err := copier.CopyFrom(table, columns)
+	dc.Unlock()
+	if err != nil {
+		db.putConn(dc, err)
+		return nil, err
+	}
+	return &CopyIn{db: db, ctx: ctx, dc: dc, ci: ci}, nil
+}
+
+// AddRow sends one row to the copy, with a value for each column
+// passed to CopyFrom. The values are converted like query arguments.
+// The driver may buffer rows, so an error caused by a row may instead
+// be reported by a later AddRow or by Exec.
+
+// AddRow 向复制发送一行数据，为传给 CopyFrom 的每一列提供一个值。这些值会像查询实参
+// 一样被转换。驱动可能会缓冲这些行，因此由某一行引起的错误可能会改由之后的 AddRow
+// 或 Exec 报告。
+func (cp *CopyIn) AddRow(values ...interface{}) error {
+	if cp.done {
+		return errCopyClosed
+	}
+	if err := cp.ctx.Err(); err != nil {
+		cp.close(err)
+		return err
+	}
+	dargs, err := driverArgs(&driverStmt{Locker: cp.dc}, values)
+	if err != nil {
+		return err
+	}
+	cp.dc.Lock()
+	err = cp.ci.AddRow(dargs)
+	cp.dc.Unlock()
+	if err == driver.ErrBadConn {
+		cp.close(err)
+	}
+	return err
+}
+
+// Exec completes the copy, releases its connection and returns the
+// result reported by the driver.
+
+// Exec 完成复制，释放其连接，并返回驱动报告的结果。
+func (cp *CopyIn) Exec() (Result, error) {
+	if cp.done {
+		return nil, errCopyClosed
+	}
+	if err := cp.ctx.Err(); err != nil {
+		cp.close(err)
+		return nil, err
+	}
+	cp.dc.Lock()
+	resi, err := cp.ci.Exec()
+	cp.dc.Unlock()
+	if cerr := cp.close(err); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return driverResult{cp.dc, resi}, nil
+}
+
+// Close abandons the copy, if Exec has not been called, and releases
+// its connection. Rows already added are discarded. Close after Exec
+// does nothing, so it may be deferred.
+
+// Close 在 Exec 尚未被调用时放弃复制，并释放其连接。已添加的行会被丢弃。
+// 在 Exec 之后调用 Close 不会做任何事，因此可以使用 defer 调用它。
+func (cp *CopyIn) Close() error {
+	if cp.done {
+		return nil
+	}
+	return cp.close(nil)
+}
+
+// close closes the driver's copy and returns the connection to the
+// pool. err is the error that ended the copy, if any. If the driver
+// fails to close the copy, the connection may still be in the middle
+// of it, so it is discarded rather than reused.
+func (cp *CopyIn) close(err error) error {
+	cp.done = true
+	cp.dc.Lock()
+	cerr := cp.ci.Close()
+	cp.dc.Unlock()
+	if cerr != nil {
+		err = driver.ErrBadConn
+	}
+	cp.db.putConn(cp.dc, err)
+	return cerr
+}
@@ -0,0 +1,205 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func countRows(t *testing.T, db *DB, table string) int {
+	rows, err := db.Query("SELECT|" + table + "|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCopyFrom(t *testing.T) {
+	db, err := Open("test", fakeDBName+";copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string,age=int32")
+
+	cp, err := db.CopyFrom(context.Background(), "t", []string{"name", "age"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if n := db.Stats().InUse; n != 1 {
+		t.Errorf("connections in use during copy = %d; want 1", n)
+	}
+	for i, name := range []string{"Alice", "Bob", "Chris"} {
+		if err := cp.AddRow(name, i+1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countRows(t, db, "t"); n != 0 {
+		t.Errorf("%d rows visible before Exec; want 0", n)
+	}
+	res, err := cp.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 3 {
+		t.Errorf("RowsAffected = %d, %v; want 3", n, err)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("connections in use after Exec = %d; want 0", n)
+	}
+	if err := cp.AddRow("Dave", 4); err == nil {
+		t.Error("AddRow after Exec succeeded")
+	}
+
+	var age int
+	if err := db.QueryRow("SELECT|t|age|name=?", "Bob").Scan(&age); err != nil || age != 2 {
+		t.Errorf("Bob's age = %d, %v; want 2", age, err)
+	}
+}
+
+func TestCopyFromClose(t *testing.T) {
+	db, err := Open("test", fakeDBName+";copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string")
+
+	cp, err := db.CopyFrom(context.Background(), "t", []string{"name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.AddRow("Alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, db, "t"); n != 0 {
+		t.Errorf("%d rows after Close; want 0", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cp, err = db.CopyFrom(ctx, "t", []string{"name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := cp.AddRow("Bob"); err != context.Canceled {
+		t.Errorf("AddRow after cancel = %v; want %v", err, context.Canceled)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("connections in use after cancel = %d; want 0", n)
+	}
+	if _, err := db.CopyFrom(ctx, "t", []string{"name"}); err != context.Canceled {
+		t.Errorf("CopyFrom with done ctx = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestCopyFromWait(t *testing.T) {
+	db, err := Open("test", fakeDBName+";copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string")
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := db.CopyFrom(ctx, "t", []string{"name"}); err != context.DeadlineExceeded {
+		t.Errorf("CopyFrom on a saturated pool = %v; want %v", err, context.DeadlineExceeded)
+	}
+	tx.Rollback()
+
+	db.SetReadOnly(true)
+	if _, err := db.CopyFrom(context.Background(), "t", []string{"name"}); err != ErrReadOnly {
+		t.Errorf("CopyFrom in read-only mode = %v; want %v", err, ErrReadOnly)
+	}
+}
+
+func TestCopyFromNotSupported(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	cp, err := db.CopyFrom(context.Background(), "people", []string{"name"})
+	if err != ErrCopyNotSupported {
+		t.Fatalf("CopyFrom = %v, %v; want %v", cp, err, ErrCopyNotSupported)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("connections in use = %d; want 0", n)
+	}
+}
+
+func TestCopyFromCancelMidway(t *testing.T) {
+	db, err := Open("test", fakeDBName+";copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cp, err := db.CopyFrom(ctx, "t", []string{"name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Alice", "Bob"} {
+		if err := cp.AddRow(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	if err := cp.AddRow("Chris"); err != context.Canceled {
+		t.Fatalf("AddRow after cancel = %v; want %v", err, context.Canceled)
+	}
+	if _, err := cp.Exec(); err == nil {
+		t.Error("Exec after cancel succeeded")
+	}
+	if n := countRows(t, db, "t"); n != 0 {
+		t.Errorf("%d rows after cancel; want 0", n)
+	}
+	st := db.Stats()
+	if st.InUse != 0 || st.OpenConnections != 1 {
+		t.Errorf("after cancel, %d connections open and %d in use; want 1 and 0", st.OpenConnections, st.InUse)
+	}
+
+	// The pooled connection is left in a state to run another copy.
+	cp, err = db.CopyFrom(context.Background(), "t", []string{"name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.AddRow("Dave"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cp.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, db, "t"); n != 1 {
+		t.Errorf("%d rows after second copy; want 1", n)
+	}
+	if n := db.Stats().OpenConnections; n != 1 {
+		t.Errorf("%d connections open; want 1", n)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Exact decimal numbers.
+
+// 精确的十进制数。
+
+package sql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number, such as the value of a NUMERIC,
+// DECIMAL or MONEY column, which float64 can't always represent. Its
+// value is Coefficient() × 10^Exponent(). The zero value is 0.
+//
+// Decimal implements Scanner, parsing string and []byte sources
+// exactly, and Valuer, sending the value as a decimal string. Decimal
+// values are immutable: arithmetic returns a new Decimal.
+
+// Decimal 是一个精确的十进制数，例如 NUMERIC、DECIMAL 或 MONEY 列的值，float64
+// 并不总能表示这些值。其值为 Coefficient() × 10^Exponent()。零值为 0。
+//
+// Decimal 实现了 Scanner，会精确地解析 string 和 []byte 来源值；它也实现了 Valuer，
+// 以十进制字符串的形式发送其值。Decimal 值是不可变的：算术运算会返回一个新的 Decimal。
+type Decimal struct {
+	coef *big.Int // nil means 0
+	exp  int32
+}
+
+// NewDecimal returns the Decimal coef × 10^exp.
+
+// NewDecimal 返回 Decimal coef × 10^exp。
+func NewDecimal(coef int64, exp int32) Decimal {
+	return Decimal{coef: big.NewInt(coef), exp: exp}
+}
+
+// ParseDecimal parses s, a decimal number with an optional sign,
+// fractional part and exponent, such as "-12.50" or "1.5e3". The
+// digits after the decimal point, including trailing zeros, are kept,
+// so String returns "-12.50" for the former.
+
+// ParseDecimal 解析 s，它是一个可带符号、小数部分和指数的十进制数，例如 "-12.50"
+// 或 "1.5e3"。小数点之后的数字（包括末尾的零）会被保留，因此对于前者，String 会
+// 返回 "-12.50"。
+func ParseDecimal(s string) (Decimal, error) {
+	mant, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		mant = s[:i]
+		exp, err = strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("sql: invalid decimal %q", s)
+		}
+	}
+	digits := mant
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		exp -= int64(len(digits) - i - 1)
+		digits = digits[:i] + digits[i+1:]
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" || exp < -1<<31 {
+		return Decimal{}, fmt.Errorf("sql: invalid decimal %q", s)
+	}
+	coef, _ := new(big.Int).SetString(digits, 10)
+	if mant[0] == '-' {
+		coef.Neg(coef)
+	}
+	return Decimal{coef: coef, exp: int32(exp)}, nil
+}
+
+// Coefficient returns a new big.Int holding d's coefficient.
+
+// Coefficient 返回一个新的 big.Int，其中存放 d 的系数。
+func (d Decimal) Coefficient() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.coef)
+}
+
+// Exponent returns d's exponent.
+
+// Exponent 返回 d 的指数。
+func (d Decimal) Exponent() int32 {
+	return d.exp
+}
+
+// String returns d in plain decimal notation, with as many digits after
+// the decimal point as -d.Exponent(), such as "-12.50".
+
+// String 以普通的十进制表示法返回 d，小数点之后的位数为 -d.Exponent()，例如 "-12.50"。
+func (d Decimal) String() string {
+	s := d.Coefficient().String()
+	if d.exp >= 0 {
+		if d.exp == 0 || s == "0" {
+			return s
+		}
+		return s + strings.Repeat("0", int(d.exp))
+	}
+	neg := s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+	n := int(-d.exp)
+	if len(s) <= n {
+		s = strings.Repeat("0", n-len(s)+1) + s
+	}
+	s = s[:len(s)-n] + "." + s[len(s)-n:]
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// Float64 returns the float64 nearest to d, and whether it round-trips:
+// whether the float64's shortest decimal representation, as formatted
+// by strconv.FormatFloat, equals d. So 0.1 round-trips, although no
+// float64 is exactly 0.1, but 0.12345678901234567890 does not. Values
+// out of the range of float64 give ±Inf and don't round-trip.
+
+// Float64 返回最接近 d 的 float64，以及它能否往返转换：即该 float64 由
+// strconv.FormatFloat 格式化得到的最短十进制表示是否等于 d。因此，尽管没有 float64
+// 恰好等于 0.1，0.1 仍能往返转换，但 0.12345678901234567890 不能。超出 float64
+// 范围的值会得到 ±Inf，且不能往返转换。
+func (d Decimal) Float64() (f float64, roundTrips bool) {
+	f, err := strconv.ParseFloat(d.String(), 64)
+	if err != nil {
+		return f, false
+	}
+	back, err := ParseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+	return f, err == nil && back.Cmp(d) == 0
+}
+
+// Cmp compares d and e and returns -1 if d < e, 0 if d == e, and
+// +1 if d > e. Values differing only in trailing zeros, such as 1.5
+// and 1.50, are equal.
+
+// Cmp 比较 d 和 e：若 d < e 则返回 -1，若 d == e 则返回 0，若 d > e 则返回 +1。
+// 仅在末尾的零上不同的值（例如 1.5 和 1.50）是相等的。
+func (d Decimal) Cmp(e Decimal) int {
+	x, y, _ := alignDecimals(d, e)
+	return x.Cmp(y)
+}
+
+// Add returns d + e, with the smaller of their exponents.
+
+// Add 返回 d + e，其指数为两者中较小的那个。
+func (d Decimal) Add(e Decimal) Decimal {
+	x, y, exp := alignDecimals(d, e)
+	return Decimal{coef: x.Add(x, y), exp: exp}
+}
+
+// Sub returns d - e, with the smaller of their exponents.
+
+// Sub 返回 d - e，其指数为两者中较小的那个。
+func (d Decimal) Sub(e Decimal) Decimal {
+	x, y, exp := alignDecimals(d, e)
+	return Decimal{coef: x.Sub(x, y), exp: exp}
+}
+
+// Mul returns d × e, with the sum of their exponents.
+
+// Mul 返回 d × e，其指数为两者指数之和。
+func (d Decimal) Mul(e Decimal) Decimal {
+	coef := d.Coefficient()
+	return Decimal{coef: coef.Mul(coef, e.Coefficient()), exp: d.exp + e.exp}
+}
+
+// alignDecimals returns new copies of the coefficients of d and e
+// scaled to their common, smaller exponent, and that exponent.
+func alignDecimals(d, e Decimal) (x, y *big.Int, exp int32) {
+	x, y = d.Coefficient(), e.Coefficient()
+	switch {
+	case d.exp > e.exp:
+		x.Mul(x, pow10(int64(d.exp)-int64(e.exp)))
+		return x, y, e.exp
+	case e.exp > d.exp:
+		y.Mul(y, pow10(int64(e.exp)-int64(d.exp)))
+	}
+	return x, y, d.exp
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+}
+
+// Scan implements the Scanner interface. String and []byte sources are
+// parsed exactly with ParseDecimal; integer sources are exact too.
+// Float sources are converted through their shortest decimal
+// representation, so they are only as exact as the float64 was. NULL
+// is an error; scan into a pointer to a Decimal to allow it.
+
+// Scan 实现了 Scanner 接口。string 和 []byte 来源值会用 ParseDecimal 精确地解析；
+// 整数来源值同样是精确的。浮点数来源值会经由其最短的十进制表示进行转换，因此其精确
+// 程度仅与该 float64 相同。NULL 会导致错误；若要允许 NULL，请扫描到指向 Decimal 的
+// 指针中。
+func (d *Decimal) Scan(value interface{}) error {
+	var err error
+	switch v := value.(type) {
+	case string:
+		*d, err = ParseDecimal(v)
+	case []byte:
+		*d, err = ParseDecimal(string(v))
+	case int64:
+		*d = NewDecimal(v, 0)
+	case float64:
+		*d, err = ParseDecimal(strconv.FormatFloat(v, 'g', -1, 64))
+	case nil:
+		err = fmt.Errorf("converting NULL to sql.Decimal is unsupported")
+	default:
+		err = fmt.Errorf("converting driver.Value type %T to sql.Decimal is unsupported", value)
+	}
+	return err
+}
+
+// Value implements the driver Valuer interface, returning d.String().
+
+// Value 实现了驱动的 Valuer 接口，返回 d.String()。
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// LossyFloat64 scans an exact number, such as a NUMERIC or DECIMAL
+// column, into a float64, recording whether precision was lost. Make
+// one with Float64Lossy.
+
+// LossyFloat64 将一个精确的数（例如 NUMERIC 或 DECIMAL 列的值）扫描到 float64 中，
+// 并记录是否损失了精度。使用 Float64Lossy 创建它。
+type LossyFloat64 struct {
+	f    *float64
+	lost *bool
+}
+
+// Float64Lossy returns a Scanner that stores the float64 nearest to
+// the scanned value in *f, and sets *lost to whether that float64
+// doesn't round-trip to the value, as described for Decimal.Float64:
+//
+//	var f float64
+//	var lost bool
+//	err := row.Scan(sql.Float64Lossy(&f, &lost))
+//
+// Integer sources lose precision beyond 2^53 in magnitude; float64
+// sources never do.
+
+// Float64Lossy 返回一个 Scanner，它将最接近被扫描值的 float64 存入 *f，并按
+// Decimal.Float64 所述，将该 float64 能否往返转换为该值的结果存入 *lost（不能时为
+// true）：
+//
+//	var f float64
+//	var lost bool
+//	err := row.Scan(sql.Float64Lossy(&f, &lost))
+//
+// 整数来源值在绝对值超过 2^53 时会损失精度；float64 来源值从不损失精度。
+func Float64Lossy(f *float64, lost *bool) LossyFloat64 {
+	return LossyFloat64{f, lost}
+}
+
+// Scan implements the Scanner interface.
+
+// Scan 实现了 Scanner 接口。
+func (l LossyFloat64) Scan(value interface{}) error {
+	if l.f == nil || l.lost == nil {
+		return errNilPtr
+	}
+	switch v := value.(type) {
+	case float64:
+		*l.f, *l.lost = v, false
+		return nil
+	case int64:
+		f := float64(v)
+		*l.f, *l.lost = f, f >= 1<<63 || int64(f) != v
+		return nil
+	case string, []byte:
+		d, err := ParseDecimal(asString(v))
+		if err != nil {
+			return fmt.Errorf("converting driver.Value type %T (%q) to a float64: %v", value, asString(v), err)
+		}
+		var ok bool
+		*l.f, ok = d.Float64()
+		*l.lost = !ok
+		return nil
+	}
+	*l.lost = false
+	return convertAssign(l.f, value)
+}
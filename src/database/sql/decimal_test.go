@@ -0,0 +1,178 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"0", "0"},
+		{"-12.50", "-12.50"},
+		{"+7", "7"},
+		{".5", "0.5"},
+		{"-0.005", "-0.005"},
+		{"1.5e3", "1500"},
+		{"15E-4", "0.0015"},
+		{"12345678901234567890.123456789012345", "12345678901234567890.123456789012345"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): %v", tt.in, err)
+			continue
+		}
+		if got := d.String(); got != tt.want {
+			t.Errorf("ParseDecimal(%q) = %s; want %s", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "-", ".", "1.2.3", "1e", "abc", "NaN", "1e99999999999"} {
+		if d, err := ParseDecimal(in); err == nil {
+			t.Errorf("ParseDecimal(%q) = %s; want error", in, d)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	dec := func(s string) Decimal {
+		d, err := ParseDecimal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	if got := dec("0.1").Add(dec("0.2")); got.String() != "0.3" || got.Cmp(dec("0.30")) != 0 {
+		t.Errorf("0.1 + 0.2 = %s; want 0.3", got)
+	}
+	if got := dec("10").Sub(dec("0.01")); got.String() != "9.99" {
+		t.Errorf("10 - 0.01 = %s; want 9.99", got)
+	}
+	if got := dec("1.10").Mul(dec("-3")); got.String() != "-3.30" {
+		t.Errorf("1.10 × -3 = %s; want -3.30", got)
+	}
+	if got := NewDecimal(5, 2).Add(Decimal{}); got.String() != "500" {
+		t.Errorf("500 + 0 = %s; want 500", got)
+	}
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.5", "1.50", 0},
+		{"-1", "0.001", -1},
+		{"1e2", "99.999", 1},
+	} {
+		if got := dec(tt.a).Cmp(dec(tt.b)); got != tt.want {
+			t.Errorf("Cmp(%s, %s) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalScanValue(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|name=string,amount=string")
+
+	// This value loses precision as a float64.
+	const amount = "90071992547409931.000000000000000001"
+	if f, _ := strconv.ParseFloat(amount, 64); strconv.FormatFloat(f, 'f', -1, 64) == amount {
+		t.Fatalf("%s is exact as a float64", amount)
+	}
+	want, err := ParseDecimal(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, "INSERT|t|name=a,amount=?", want)
+
+	var got Decimal
+	if err := db.QueryRow("SELECT|t|amount|name=?", "a").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != amount || got.Cmp(want) != 0 {
+		t.Errorf("scanned %s; want %s", got, amount)
+	}
+
+	for _, tt := range []struct {
+		src  interface{}
+		want string
+	}{
+		{[]byte("-1.25"), "-1.25"},
+		{int64(42), "42"},
+		{0.1, "0.1"},
+	} {
+		var d Decimal
+		if err := convertAssign(&d, tt.src); err != nil || d.String() != tt.want {
+			t.Errorf("Decimal from %T %v = %s, %v; want %s", tt.src, tt.src, d, err, tt.want)
+		}
+	}
+	var d Decimal
+	if err := d.Scan(nil); err == nil {
+		t.Error("Decimal from NULL succeeded")
+	}
+	var pd *Decimal
+	if err := convertAssign(&pd, nil); err != nil || pd != nil {
+		t.Errorf("*Decimal from NULL = %v, %v; want nil, nil", pd, err)
+	}
+}
+
+func TestFloat64Lossy(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want float64
+		lost bool
+	}{
+		{"12.50", 12.5, false},
+		{[]byte("-0.1"), -0.1, false},
+		{"0.12345678901234567890", 0.12345678901234568, true},
+		{"9007199254740993", 9007199254740992, true},
+		{"1e400", math.Inf(1), true},
+		{int64(1 << 53), 1 << 53, false},
+		{int64(1<<53 + 1), 1 << 53, true},
+		{float64(0.3), 0.3, false},
+	}
+	for _, tt := range tests {
+		var f float64
+		lost := !tt.lost
+		if err := Float64Lossy(&f, &lost).Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): %v", tt.src, err)
+			continue
+		}
+		if f != tt.want || lost != tt.lost {
+			t.Errorf("Scan(%v) = %v, lost %v; want %v, lost %v", tt.src, f, lost, tt.want, tt.lost)
+		}
+	}
+	var f float64
+	var lost bool
+	if err := Float64Lossy(&f, &lost).Scan("abc"); err == nil {
+		t.Error("Scan of non-numeric text succeeded")
+	}
+	if err := Float64Lossy(&f, &lost).Scan(nil); err == nil {
+		t.Error("Scan of NULL succeeded")
+	}
+}
+
+func TestScanFloat64Lossy(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|prices|exact=string,approx=string")
+	exec(t, db, "INSERT|prices|exact=?,approx=?", "19.99", "3.14159265358979323846")
+
+	var exact, approx float64
+	var exactLost, approxLost bool
+	err := db.QueryRow("SELECT|prices|exact,approx|").Scan(Float64Lossy(&exact, &exactLost), Float64Lossy(&approx, &approxLost))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact != 19.99 || exactLost {
+		t.Errorf("exact = %v, lost %v; want 19.99, false", exact, exactLost)
+	}
+	if approx != math.Pi || !approxLost {
+		t.Errorf("approx = %v, lost %v; want %v, true", approx, approxLost, math.Pi)
+	}
+}
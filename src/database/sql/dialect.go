@@ -0,0 +1,252 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Placeholder translation between SQL dialects.
+
+// SQL 方言之间的占位符转换。
+
+package sql
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Dialect describes the SQL syntax spoken by a database driver.
+
+// Dialect 描述了数据库驱动所使用的 SQL 语法。
+type Dialect struct {
+	// Name identifies the dialect, for debugging.
+	Name string
+
+	// Placeholder returns the native placeholder for the parameter
+	// at position n, counting from 1. If Placeholder is nil, the
+	// dialect uses "?" for every parameter.
+	Placeholder func(n int) string
+
+	// Explain is the prefix that turns a query into a request for its
+	// plan, as used by DB.Explain. If Explain is empty, the dialect
+	// has no portable way to ask for a plan.
+	Explain string
+
+	// SetConstraints is the start of the statement that sets when a
+	// transaction's deferrable constraints are checked, as used by
+	// Tx.DeferConstraints; "DEFERRED" or "IMMEDIATE" completes it. If
+	// SetConstraints is empty, the dialect can't defer constraints.
+	SetConstraints string
+
+	// BackslashEscapes reports whether a backslash escapes the next
+	// character inside quoted strings, as in MySQL's 'it\'s'.
+	BackslashEscapes bool
+
+	// DollarQuotes reports whether the dialect has PostgreSQL's
+	// dollar-quoted strings, such as $$it's$$ or $fn$...$fn$, and its
+	// E'...' strings, in which a backslash escapes the next character.
+	DollarQuotes bool
+}
+
+// Dialects for common placeholder styles.
+
+// 常见占位符风格的方言。
+var (
+	// QuestionDialect uses ? for every parameter, as MySQL and
+	// SQLite do.
+	QuestionDialect = &Dialect{Name: "question", Explain: "EXPLAIN "}
+
+	// DollarDialect uses $1, $2, ..., as PostgreSQL does.
+	DollarDialect = &Dialect{Name: "dollar", Placeholder: numberedPlaceholder("$"), Explain: "EXPLAIN ", SetConstraints: "SET CONSTRAINTS ALL ", DollarQuotes: true}
+
+	// ColonDialect uses :1, :2, ..., as Oracle does.
+	ColonDialect = &Dialect{Name: "colon", Placeholder: numberedPlaceholder(":"), SetConstraints: "SET CONSTRAINTS ALL "}
+
+	// AtDialect uses @p1, @p2, ..., as SQL Server does.
+	AtDialect = &Dialect{Name: "at", Placeholder: numberedPlaceholder("@p")}
+)
+
+func numberedPlaceholder(prefix string) func(int) string {
+	return func(n int) string {
+		return prefix + strconv.Itoa(n)
+	}
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = make(map[string]*Dialect)
+)
+
+// RegisterDialect records the dialect spoken by the driver registered
+// under name. Databases opened with that driver afterwards use it for
+// Rebind. If RegisterDialect is called twice with the same name or if
+// d is nil, it panics.
+
+// RegisterDialect 记录以 name 注册的驱动所使用的方言。此后使用该驱动打开的数据库
+// 会在 Rebind 中使用它。如果使用同样的名字调用两次 RegisterDialect，或者 d 为 nil，
+// RegisterDialect 会 panic。
+func RegisterDialect(name string, d *Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	if d == nil {
+		panic("sql: RegisterDialect dialect is nil")
+	}
+	if _, dup := dialects[name]; dup {
+		panic("sql: RegisterDialect called twice for driver " + name)
+	}
+	dialects[name] = d
+}
+
+func lookupDialect(driverName string) *Dialect {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	if d, ok := dialects[driverName]; ok {
+		return d
+	}
+	return QuestionDialect
+}
+
+// SetDialect overrides the dialect registered for the database's
+// driver. A nil d means QuestionDialect.
+
+// SetDialect 覆盖为数据库驱动注册的方言。d 为 nil 表示 QuestionDialect。
+func (db *DB) SetDialect(d *Dialect) {
+	if d == nil {
+		d = QuestionDialect
+	}
+	db.mu.Lock()
+	db.dialect = d
+	db.mu.Unlock()
+}
+
+// Dialect returns the dialect used by Rebind.
+
+// Dialect 返回 Rebind 所使用的方言。
+func (db *DB) Dialect() *Dialect {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.dialect
+}
+
+// SetAutoRebind sets whether queries passed to Exec, Query, QueryRow
+// and Prepare, on the DB and on its transactions, are first passed
+// through Rebind. The default is false.
+
+// SetAutoRebind 设置传给 DB 及其事务的 Exec、Query、QueryRow 和 Prepare 的查询
+// 是否先经过 Rebind 处理。默认为 false。
+func (db *DB) SetAutoRebind(on bool) {
+	db.mu.Lock()
+	db.autoRebind = on
+	db.mu.Unlock()
+}
+
+// Rebind rewrites the ? placeholders in query into the native form of
+// the database's dialect. Question marks inside quoted strings,
+// quoted identifiers and comments are left untouched; what counts as
+// quoted follows the dialect's BackslashEscapes and DollarQuotes.
+
+// Rebind 将 query 中的 ? 占位符改写为数据库方言的原生形式。
+// 引号中的字符串、带引号的标识符以及注释中的问号不会被改动；何为引号中的内容
+// 取决于方言的 BackslashEscapes 和 DollarQuotes。
+func (db *DB) Rebind(query string) string {
+	return db.Dialect().rebind(query)
+}
+
+// maybeRebind rebinds query if SetAutoRebind is on.
+func (db *DB) maybeRebind(query string) string {
+	db.mu.Lock()
+	d, on := db.dialect, db.autoRebind
+	db.mu.Unlock()
+	if !on {
+		return query
+	}
+	return d.rebind(query)
+}
+
+func (d *Dialect) rebind(query string) string {
+	if d == nil || d.Placeholder == nil {
+		return query
+	}
+	var buf []byte
+	n := 0
+	last := 0
+	for i := 0; i < len(query); i++ {
+		if j := d.skip(query, i); j > i {
+			i = j - 1
+			continue
+		}
+		if query[i] == '?' {
+			n++
+			buf = append(buf, query[last:i]...)
+			buf = append(buf, d.Placeholder(n)...)
+			last = i + 1
+		}
+	}
+	if buf == nil {
+		return query
+	}
+	return string(append(buf, query[last:]...))
+}
+
+// skip returns the index just past the quoted string, quoted
+// identifier or comment starting at query[i], or i if none starts
+// there. An unterminated one runs to the end of query.
+func (d *Dialect) skip(query string, i int) int {
+	switch c := query[i]; c {
+	case '\'', '"', '`':
+		// A doubled quote inside the literal ends it and starts
+		// another, which comes to the same.
+		escapes := d.BackslashEscapes && c != '`' ||
+			d.DollarQuotes && c == '\'' && isEscapeStringPrefix(query, i)
+		for j := i + 1; j < len(query); j++ {
+			switch query[j] {
+			case '\\':
+				if escapes {
+					j++
+				}
+			case c:
+				return j + 1
+			}
+		}
+		return len(query)
+	case '-':
+		if strings.HasPrefix(query[i:], "--") {
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				return i + j + 1
+			}
+			return len(query)
+		}
+	case '/':
+		if strings.HasPrefix(query[i:], "/*") {
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				return i + 2 + j + 2
+			}
+			return len(query)
+		}
+	case '$':
+		if !d.DollarQuotes {
+			break
+		}
+		if tag := dollarTag(query[i:]); tag != "" {
+			if j := strings.Index(query[i+len(tag):], tag); j >= 0 {
+				return i + len(tag) + j + len(tag)
+			}
+			return len(query)
+		}
+	}
+	return i
+}
+
+// isEscapeStringPrefix reports whether the quote at query[i] opens a
+// PostgreSQL escape string, E'...', rather than ending an identifier
+// such as name'.
+func isEscapeStringPrefix(query string, i int) bool {
+	if i == 0 || query[i-1] != 'E' && query[i-1] != 'e' {
+		return false
+	}
+	return i == 1 || !isIdentByte(query[i-2])
+}
+
+// isIdentByte reports whether c may be part of an unquoted identifier.
+func isIdentByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= 0x80
+}
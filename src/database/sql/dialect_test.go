@@ -0,0 +1,120 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"testing"
+)
+
+// backslashDialect numbers its placeholders and has MySQL's backslash
+// escapes.
+var backslashDialect = &Dialect{Name: "backslash", Placeholder: numberedPlaceholder("$"), BackslashEscapes: true}
+
+var rebindTests = []struct {
+	d     *Dialect
+	query string
+	want  string
+}{
+	{QuestionDialect, "SELECT a FROM t WHERE b = ? AND c = ?", "SELECT a FROM t WHERE b = ? AND c = ?"},
+	{DollarDialect, "SELECT a FROM t WHERE b = ? AND c = ?", "SELECT a FROM t WHERE b = $1 AND c = $2"},
+	{ColonDialect, "INSERT INTO t VALUES (?, ?, ?)", "INSERT INTO t VALUES (:1, :2, :3)"},
+	{AtDialect, "UPDATE t SET a = ?", "UPDATE t SET a = @p1"},
+	{DollarDialect, "SELECT 1", "SELECT 1"},
+
+	// Question marks that aren't placeholders.
+	{DollarDialect, "SELECT '?', a FROM t WHERE b = ?", "SELECT '?', a FROM t WHERE b = $1"},
+	{DollarDialect, "SELECT 'it''s ?' WHERE b = ?", "SELECT 'it''s ?' WHERE b = $1"},
+	{DollarDialect, `SELECT "odd?name" FROM t WHERE b = ?`, `SELECT "odd?name" FROM t WHERE b = $1`},
+	{DollarDialect, "SELECT `odd?name` FROM t WHERE b = ?", "SELECT `odd?name` FROM t WHERE b = $1"},
+	{DollarDialect, "SELECT a -- why?\nFROM t WHERE b = ?", "SELECT a -- why?\nFROM t WHERE b = $1"},
+	{DollarDialect, "SELECT a /* why? */ FROM t WHERE b = ?", "SELECT a /* why? */ FROM t WHERE b = $1"},
+	{DollarDialect, "SELECT a - ? FROM t", "SELECT a - $1 FROM t"},
+	{DollarDialect, "SELECT a / ? FROM t", "SELECT a / $1 FROM t"},
+	{DollarDialect, "SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	{DollarDialect, "SELECT a /* unterminated ?", "SELECT a /* unterminated ?"},
+
+	// Backslash escapes.
+	{backslashDialect, `SELECT 'a\'?' WHERE b = ?`, `SELECT 'a\'?' WHERE b = $1`},
+	{backslashDialect, `SELECT "a\"?" WHERE b = ?`, `SELECT "a\"?" WHERE b = $1`},
+	{backslashDialect, `SELECT 'a\\' WHERE b = ?`, `SELECT 'a\\' WHERE b = $1`},
+	{backslashDialect, "SELECT `a\\` WHERE b = ?", "SELECT `a\\` WHERE b = $1"},
+	{DollarDialect, `SELECT 'a\' WHERE b = ?`, `SELECT 'a\' WHERE b = $1`},
+	{DollarDialect, `SELECT E'a\'?' WHERE b = ?`, `SELECT E'a\'?' WHERE b = $1`},
+	{DollarDialect, `SELECT name'a\' WHERE b = ?`, `SELECT name'a\' WHERE b = $1`},
+
+	// Dollar quotes.
+	{DollarDialect, "SELECT $$it's ?$$ WHERE b = ?", "SELECT $$it's ?$$ WHERE b = $1"},
+	{DollarDialect, "SELECT $fn$ $$?$$ ?$fn$, ?", "SELECT $fn$ $$?$$ ?$fn$, $1"},
+	{DollarDialect, "SELECT $1, ?", "SELECT $1, $1"},
+	{DollarDialect, "SELECT $$unterminated ?", "SELECT $$unterminated ?"},
+	{backslashDialect, "SELECT $$?$$", "SELECT $$$1$$"},
+}
+
+func TestRebind(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	for i, tt := range rebindTests {
+		db.SetDialect(tt.d)
+		if got := db.Rebind(tt.query); got != tt.want {
+			t.Errorf("%d. %s Rebind(%q) = %q; want %q", i, tt.d.Name, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterDialect(t *testing.T) {
+	RegisterDialect("test", ColonDialect)
+	defer func() {
+		dialectsMu.Lock()
+		delete(dialects, "test")
+		dialectsMu.Unlock()
+	}()
+
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	if got := db.Dialect(); got != ColonDialect {
+		t.Errorf("Dialect = %s; want colon", got.Name)
+	}
+	if got, want := db.Rebind("a = ?"), "a = :1"; got != want {
+		t.Errorf("Rebind = %q; want %q", got, want)
+	}
+}
+
+func TestAutoRebind(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t1|name=string")
+
+	// The fake driver stores a non-? value as a literal, so a
+	// rebound placeholder ends up in the table.
+	db.SetDialect(DollarDialect)
+	db.SetAutoRebind(true)
+	exec(t, db, "INSERT|t1|name=?")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT|t1|name=?"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetAutoRebind(false)
+	var n int
+	rows, err := db.Query("SELECT|t1|name|name=?", "$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("found %d rebound rows; want 2", n)
+	}
+}
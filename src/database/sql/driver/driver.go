@@ -8,7 +8,10 @@
 // Most code should use package sql.
 package driver
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // Value is a value that drivers must be able to handle.
 // It is either nil or an instance of one of these types:
@@ -54,6 +57,14 @@ var ErrSkip = errors.New("driver: skip fast-path; continue as if unimplemented")
 // you shouldn't return ErrBadConn.
 var ErrBadConn = errors.New("driver: bad connection")
 
+// DSNRedacter is an optional interface that may be implemented by a
+// Driver to hide the credentials in its data source names, for
+// sql.DB.DataSourceName. RedactDSN returns name with any password,
+// and anything else secret, masked, in the driver's own format.
+type DSNRedacter interface {
+	RedactDSN(name string) string
+}
+
 // Execer is an optional interface that may be implemented by a Conn.
 //
 // If a Conn does not implement Execer, the sql package's DB.Exec will
@@ -76,6 +87,101 @@ type Queryer interface {
 	Query(query string, args []Value) (Rows, error)
 }
 
+// ArrayEncoder is an optional interface that may be implemented by a
+// Conn to accept arrays as query parameters. See sql.Array.
+//
+// EncodeArray is given the elements of the array, each already
+// converted to a Value or nil, and returns the single Value, such as
+// a string in the database's array literal syntax, to send in their
+// place.
+type ArrayEncoder interface {
+	EncodeArray(elems []Value) (Value, error)
+}
+
+// ServerVersioner is an optional interface that may be implemented by
+// a Conn to report the version of the database server it is connected
+// to, in the server's own format.
+//
+// The sql package calls ServerVersion at most once per Conn and
+// caches the result.
+type ServerVersioner interface {
+	ServerVersion() (string, error)
+}
+
+// Pinger is an optional interface that may be implemented by a Conn
+// to check that the connection to the database is still alive, for
+// sql.DB.Ping and for keeping idle connections alive; see
+// sql.DB.SetKeepAlive.
+//
+// If Ping returns ErrBadConn, or any error when keeping idle
+// connections alive, the sql package discards the Conn.
+type Pinger interface {
+	Ping() error
+}
+
+// Explainer is an optional interface that may be implemented by a
+// Conn to describe how the database would run a query, without
+// running it. Explain returns the rows of the query plan, one string
+// each, in the database's own format. See sql.DB.Explain.
+type Explainer interface {
+	Explain(query string, args []Value) ([]string, error)
+}
+
+// Interrupter is an optional interface that may be implemented by
+// Rows. When the context of a query run with sql.DB.QueryContext is
+// done, Interrupt is called from another goroutine, possibly while
+// Next is blocked waiting for the database. It should make such a
+// Next return promptly with an error. Interrupt may be called after
+// Next has returned, but not after Close.
+type Interrupter interface {
+	Interrupt()
+}
+
+// Copier is an optional interface that may be implemented by a Conn
+// to load rows into a table with the database's bulk copy protocol.
+// See sql.DB.CopyFrom.
+//
+// CopyFrom starts copying into the named columns of table. The Conn
+// is not used for anything else until the returned CopyIn is closed.
+type Copier interface {
+	CopyFrom(table string, columns []string) (CopyIn, error)
+}
+
+// Batcher is an optional interface that may be implemented by a Conn
+// to run several statements in a single round trip. See
+// sql.DB.ExecBatch.
+//
+// ExecBatch runs each query with the args at the same index, in
+// order. If the returned Result also implements BatchResult, the sql
+// package reports the result of each statement; otherwise only the
+// batch as a whole succeeded or failed.
+type Batcher interface {
+	ExecBatch(queries []string, args [][]Value) (Result, error)
+}
+
+// BatchResult may be implemented by the Result returned by
+// Batcher.ExecBatch to expose the result of each statement.
+type BatchResult interface {
+	// Results returns one Result per statement of the batch, in
+	// the order the statements were given.
+	Results() []Result
+}
+
+// CopyIn is a bulk copy in progress, started by Copier.CopyFrom.
+type CopyIn interface {
+	// AddRow sends one row, with a value for each column. The
+	// driver may buffer rows and report errors from AddRow or Exec.
+	AddRow(args []Value) error
+
+	// Exec completes the copy and reports its result.
+	Exec() (Result, error)
+
+	// Close releases the copy. It is always called exactly once,
+	// after Exec or instead of it; when Exec was not called, the
+	// copied rows should be discarded.
+	Close() error
+}
+
 // Conn is a connection to a database. It is not used concurrently
 // by multiple goroutines.
 //
@@ -110,6 +216,97 @@ type Result interface {
 	RowsAffected() (int64, error)
 }
 
+// ResultErrer is an optional interface that may be implemented by a
+// Result whose command can fail after Exec has returned, for example
+// when the server's final status is only read later.
+//
+// Err returns that error, or nil if the command completed
+// successfully.
+type ResultErrer interface {
+	Err() error
+}
+
+// RowsAffecter is an optional interface that may be implemented by
+// Rows returned by a statement that both modifies rows and returns
+// some, such as PostgreSQL's UPDATE ... RETURNING. See
+// sql.DB.ExecReturning.
+//
+// RowsAffected returns the number of rows the statement affected. It
+// is called once Next has returned io.EOF, before Close.
+type RowsAffecter interface {
+	RowsAffected() (int64, error)
+}
+
+// StatementTimeouter is an optional interface that may be implemented
+// by a Conn that can bound the running time of its statements
+// natively, such as with PostgreSQL's statement_timeout setting. See
+// sql.Tx.SetStatementTimeout.
+//
+// SetStatementTimeout limits each statement subsequently executed on
+// the connection to d. A d of zero removes the limit; the sql package
+// calls it so before the connection is reused outside the
+// transaction that set a limit.
+type StatementTimeouter interface {
+	SetStatementTimeout(d time.Duration) error
+}
+
+// ConstraintDeferrer is an optional interface that may be implemented
+// by a Conn to a database with deferrable constraints. See
+// sql.Tx.DeferConstraints.
+//
+// SetConstraintsDeferred makes the deferrable constraints of the
+// connection's current transaction checked at commit, if deferred is
+// true, or after each statement, if false. The setting must end with
+// the transaction.
+type ConstraintDeferrer interface {
+	SetConstraintsDeferred(deferred bool) error
+}
+
+// FetchSizer is an optional interface that may be implemented by a
+// Conn that can tune how many rows its queries fetch from the server
+// per round trip. See sql.DB.QueryWithOptions.
+//
+// SetFetchSize sets that number for the queries subsequently executed
+// on the connection. An n of zero restores the driver's default; the
+// sql package calls it so before the connection is reused for other
+// queries.
+type FetchSizer interface {
+	SetFetchSize(n int) error
+}
+
+// QueryInputCounter is an optional interface that may be implemented
+// by a Conn whose statements can't report their number of
+// placeholders. When a Stmt's NumInput returns -1, the sql package
+// calls NumQueryInput with the query the statement was prepared from
+// and, unless it also returns -1, checks argument counts against it
+// before the statement's Exec or Query methods are called.
+type QueryInputCounter interface {
+	NumQueryInput(query string) int
+}
+
+// ReplicationPositioner is an optional interface that may be
+// implemented by a Conn to a database taking part in replication.
+// ReplicationPosition returns the connection's server position in the
+// replication stream, such as a PostgreSQL WAL location: on a primary,
+// the position just after its latest write; on a replica, the position
+// up to which it has applied the primary's writes. Positions only
+// increase. See sql.ConsistencyToken.
+type ReplicationPositioner interface {
+	ReplicationPosition() (uint64, error)
+}
+
+// RetryableError is an optional interface that may be implemented by
+// the errors a driver returns. Retryable reports whether the error is
+// a transient conflict with other transactions, such as a
+// serialization failure or a deadlock, so that running the whole
+// transaction again may succeed. The sql package treats errors that
+// don't implement it as not retryable. See sql.IsRetryable and
+// sql.DB.RunTx.
+type RetryableError interface {
+	error
+	Retryable() bool
+}
+
 // Stmt is a prepared statement. It is bound to a Conn and not
 // used by multiple goroutines concurrently.
 type Stmt interface {
@@ -127,7 +324,8 @@ type Stmt interface {
 	//
 	// NumInput may also return -1, if the driver doesn't know
 	// its number of placeholders. In that case, the sql package
-	// will not sanity check Exec or Query argument counts.
+	// will not sanity check Exec or Query argument counts, unless
+	// the Conn implements QueryInputCounter.
 	NumInput() int
 
 	// Exec executes a query that doesn't return rows, such
@@ -166,9 +364,28 @@ type Rows interface {
 	// size as the Columns() are wide.
 	//
 	// Next should return io.EOF when there are no more rows.
+	//
+	// For a large column, such as a BLOB, Next may store an
+	// io.Reader in dest instead of a []byte, to let the value be
+	// streamed rather than held in memory. The reader must remain
+	// readable until the next call to Next or Close. The sql
+	// package reads it at most once: it copies it to the writer of
+	// a sql.WriterTo scan destination, and reads it into a []byte
+	// for any other destination.
 	Next(dest []Value) error
 }
 
+// RowsColumnTypeDatabaseTypeName is an optional interface that may be
+// implemented by Rows. ColumnTypeDatabaseTypeName returns the
+// database's name for the type of the column at index, in upper case,
+// such as "VARCHAR" or "GEOMETRY", or "" if it isn't known. The sql
+// package uses it to find the codec registered with
+// sql.RegisterScanCodec for a column.
+type RowsColumnTypeDatabaseTypeName interface {
+	Rows
+	ColumnTypeDatabaseTypeName(index int) string
+}
+
 // Tx is a transaction.
 type Tx interface {
 	Commit() error
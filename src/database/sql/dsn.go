@@ -0,0 +1,124 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Redacted data source names.
+
+// 隐去凭据的数据源名称。
+
+package sql
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// redactedSecret replaces the secrets in a redacted data source name.
+const redactedSecret = "xxxxx"
+
+// DataSourceName returns the data source name the database was opened
+// with, with its password masked, so it can be shown in logs and admin
+// pages. If the driver implements driver.DSNRedacter, it does the
+// masking. Otherwise, a best-effort rule replaces the password in a
+// "user:password@" prefix, as in URLs and MySQL DSNs, and the values of
+// password, pwd and passwd keys, as in PostgreSQL and ODBC DSNs. User
+// names are left visible.
+
+// DataSourceName 返回打开数据库时使用的数据源名称，其中的密码会被屏蔽，以便在日志
+// 和管理页面中显示。若驱动实现了 driver.DSNRedacter，则由它进行屏蔽。否则，会按照
+// 尽力而为的规则，替换 "user:password@" 前缀中的密码（如 URL 和 MySQL DSN 中），
+// 以及 password、pwd 和 passwd 键的值（如 PostgreSQL 和 ODBC DSN 中）。用户名保持
+// 可见。
+func (db *DB) DataSourceName() string {
+	if r, ok := db.driver.(driver.DSNRedacter); ok {
+		return r.RedactDSN(db.dsn)
+	}
+	return redactDSN(db.dsn)
+}
+
+// redactDSN masks the passwords in dsn, as described for
+// DataSourceName.
+func redactDSN(dsn string) string {
+	// user:password@host, after any scheme. The password may hold
+	// '@' and '?', so the userinfo ends at the last '@' before the
+	// path that follows the first '@', or before the query if there
+	// is no path.
+	start := 0
+	if i := strings.Index(dsn, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if first := strings.IndexByte(dsn[start:], '@'); first >= 0 {
+		end := len(dsn)
+		if i := strings.IndexByte(dsn[start+first:], '/'); i >= 0 {
+			end = start + first + i
+		} else if i := strings.IndexByte(dsn[start+first:], '?'); i >= 0 {
+			end = start + first + i
+		}
+		at := strings.LastIndex(dsn[start:end], "@")
+		userinfo := dsn[start : start+at]
+		if c := strings.IndexByte(userinfo, ':'); c >= 0 && !strings.ContainsAny(userinfo[:c], "/ ;=") {
+			dsn = dsn[:start+c+1] + redactedSecret + dsn[start+at:]
+		}
+	}
+
+	// password=secret, in space, semicolon or ampersand separated
+	// key-value lists.
+	var buf []byte
+	last := 0
+	for i := 0; i < len(dsn); i++ {
+		if i > 0 && !strings.ContainsRune(" ;&?", rune(dsn[i-1])) {
+			continue
+		}
+		eq := strings.IndexByte(dsn[i:], '=')
+		if eq < 0 {
+			break
+		}
+		switch strings.ToLower(strings.TrimSpace(dsn[i : i+eq])) {
+		case "password", "pwd", "passwd":
+		default:
+			continue
+		}
+		v := i + eq + 1
+		j := v
+		if j < len(dsn) && dsn[j] == '{' {
+			// An ODBC value in braces, which may hold separators;
+			// "}}" stands for a '}'.
+			for j++; j < len(dsn); j++ {
+				if dsn[j] == '}' {
+					if j+1 < len(dsn) && dsn[j+1] == '}' {
+						j++
+						continue
+					}
+					j++
+					break
+				}
+			}
+		} else if j < len(dsn) && (dsn[j] == '\'' || dsn[j] == '"') {
+			// A quoted value, which may hold separators.
+			q := dsn[j]
+			for j++; j < len(dsn) && dsn[j] != q; j++ {
+				if dsn[j] == '\\' {
+					j++
+				}
+			}
+			if j < len(dsn) {
+				j++
+			}
+		} else {
+			for j < len(dsn) && !strings.ContainsRune(" ;&", rune(dsn[j])) {
+				j++
+			}
+		}
+		if j > len(dsn) {
+			j = len(dsn)
+		}
+		buf = append(buf, dsn[last:v]...)
+		buf = append(buf, redactedSecret...)
+		last = j
+		i = j - 1
+	}
+	if buf == nil {
+		return dsn
+	}
+	return string(append(buf, dsn[last:]...))
+}
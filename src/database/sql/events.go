@@ -0,0 +1,203 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Pool and query events.
+
+// 连接池与查询事件。
+
+package sql
+
+import "time"
+
+// PoolEventType is the kind of a PoolEvent.
+
+// PoolEventType 是 PoolEvent 的种类。
+type PoolEventType int
+
+const (
+	// ConnectionOpened is sent after a physical connection is
+	// opened. Conn describes it.
+
+	// ConnectionOpened 在一个物理连接被打开之后发送。Conn 描述该连接。
+	ConnectionOpened PoolEventType = iota + 1
+
+	// ConnectionClosed is sent after a physical connection is
+	// closed. Conn describes it.
+
+	// ConnectionClosed 在一个物理连接被关闭之后发送。Conn 描述该连接。
+	ConnectionClosed
+
+	// ConnectionReused is sent when a connection that had already
+	// been used is handed out again by the pool. Conn describes it.
+
+	// ConnectionReused 在一个已被使用过的连接被连接池再次分发时发送。Conn 描述该连接。
+	ConnectionReused
+
+	// WaitStarted is sent when an operation starts waiting for a
+	// connection because SetMaxOpenConns has been reached. Conn.Tag
+	// is the tag of the waiting handle; see DB.WithTag.
+
+	// WaitStarted 在某个操作因达到 SetMaxOpenConns 的限制而开始等待连接时发送。
+	// Conn.Tag 为等待中的句柄的标签；见 DB.WithTag。
+	WaitStarted
+
+	// WaitEnded is sent when that wait ends. Duration is how long
+	// it lasted, and Err is set if no connection was obtained.
+	// Conn.Tag is set as for WaitStarted.
+
+	// WaitEnded 在该等待结束时发送。Duration 为其持续的时间；若未获得连接，则设置 Err。
+	// Conn.Tag 的设置与 WaitStarted 相同。
+	WaitEnded
+
+	// QueryStarted is sent before a statement is passed to the
+	// driver for execution. Query is the statement, and Conn
+	// describes the connection running it.
+
+	// QueryStarted 在语句被交给驱动执行之前发送。Query 为该语句，Conn 描述执行它的连接。
+	QueryStarted
+
+	// QueryEnded is sent when the driver has executed the
+	// statement. Duration is how long it took, not counting the
+	// reading of rows, and Err is the driver's error, if any. Conn
+	// is set as for QueryStarted. A
+	// statement that the driver declined with driver.ErrSkip is
+	// then prepared and sent again, with new events.
+
+	// QueryEnded 在驱动执行完该语句时发送。Duration 为其所用的时间，不包括读取行的时间；
+	// Err 为驱动返回的错误（若有）。Conn 的设置与 QueryStarted 相同。被驱动以 driver.ErrSkip 拒绝的语句随后会被准备并
+	// 再次发送，并产生新的事件。
+	QueryEnded
+
+	// ConnectionReturned is sent when a connection that was handed
+	// out is given back to the DB. Conn describes it, and Err is the
+	// error that ended its use, if any; a connection returned with
+	// driver.ErrBadConn is then closed.
+
+	// ConnectionReturned 在一个被分发出去的连接被交还给 DB 时发送。Conn 描述该连接，
+	// Err 为结束其使用的错误（若有）；以 driver.ErrBadConn 交还的连接随后会被关闭。
+	ConnectionReturned
+
+	// StmtLeaked is sent when a statement prepared on the DB is
+	// garbage collected without having been closed, before it is
+	// closed. Query is the statement's query.
+
+	// StmtLeaked 在一个于 DB 上准备的语句未被关闭就被垃圾回收时、在将其关闭之前发送。
+	// Query 为该语句的查询。
+	StmtLeaked
+
+	// TxLeaked is sent when a transaction is garbage collected
+	// without Commit or Rollback, before it is rolled back. Conn
+	// describes the transaction's connection.
+
+	// TxLeaked 在一个事务未经 Commit 或 Rollback 就被垃圾回收时、在将其回滚之前发送。
+	// Conn 描述该事务的连接。
+	TxLeaked
+)
+
+func (t PoolEventType) String() string {
+	switch t {
+	case ConnectionOpened:
+		return "ConnectionOpened"
+	case ConnectionClosed:
+		return "ConnectionClosed"
+	case ConnectionReused:
+		return "ConnectionReused"
+	case WaitStarted:
+		return "WaitStarted"
+	case WaitEnded:
+		return "WaitEnded"
+	case QueryStarted:
+		return "QueryStarted"
+	case QueryEnded:
+		return "QueryEnded"
+	case ConnectionReturned:
+		return "ConnectionReturned"
+	case StmtLeaked:
+		return "StmtLeaked"
+	case TxLeaked:
+		return "TxLeaked"
+	}
+	return "unknown"
+}
+
+// PoolEvent is an event delivered on the channel returned by
+// DB.Events. The fields other than Type and Time are set only for the
+// types documented to use them.
+
+// PoolEvent 是在 DB.Events 所返回的通道上传递的事件。除 Type 和 Time 之外的字段，
+// 仅对文档中说明会使用它们的事件类型才会设置。
+type PoolEvent struct {
+	Type PoolEventType
+	Time time.Time // when the event happened
+
+	Conn     ConnInfo      // the connection concerned
+	Query    string        // the statement concerned
+	Duration time.Duration // how long the wait or statement took
+	Err      error         // the error that ended the wait or statement
+}
+
+// eventBufferSize is the capacity of the channel returned by Events.
+const eventBufferSize = 256
+
+// Events returns a channel on which the DB delivers events about its
+// connections and the statements it executes, for tracing and metrics.
+// Events are only produced once Events has been called, and every call
+// returns the same channel, so a single consumer should read it.
+//
+// Events about a connection carry its ConnInfo, whose ID and
+// CheckoutID tie together the events of one connection and of one use
+// of it, such as "connection 3 was handed out, ran a query and was
+// returned". The same IDs are given to the hook set by
+// SetConnCloseHook and to the slow query function; see
+// ConnInfoFromContext.
+//
+// The channel is buffered. If it is full, because the consumer is
+// slower than the DB, new events are dropped rather than delaying the
+// DB, and counted in DBStats.EventsDropped. The channel is closed when
+// the DB is closed; events happening during or after Close, such as
+// the closing of the remaining connections, are not delivered.
+
+// Events 返回一个通道，DB 会在其上传递关于其连接以及其所执行的语句的事件，用于追踪和
+// 度量。只有在调用了 Events 之后才会产生事件，并且每次调用都返回同一个通道，因此应由
+// 单个消费者读取它。
+//
+// 关于连接的事件会携带其 ConnInfo，其中的 ID 和 CheckoutID 可将同一连接、以及对该连接
+// 的同一次使用的各个事件关联起来，例如“连接 3 被分发出去，执行了一个查询，然后被交还”。
+// SetConnCloseHook 所设置的钩子和慢查询函数也会得到同样的 ID；参见 ConnInfoFromContext。
+//
+// 该通道是带缓冲的。若因消费者比 DB 慢而导致通道已满，新的事件会被丢弃而不会拖慢 DB，
+// 并会计入 DBStats.EventsDropped。该通道会在 DB 关闭时被关闭；在 Close 期间或之后
+// 发生的事件（例如剩余连接的关闭）不会被传递。
+func (db *DB) Events() <-chan PoolEvent {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.events == nil {
+		db.events = make(chan PoolEvent, eventBufferSize)
+		if db.closed {
+			close(db.events)
+		}
+	}
+	return db.events
+}
+
+// emitLocked delivers ev on db.events, if Events has been called,
+// setting its time. The db.mu must be held.
+func (db *DB) emitLocked(ev PoolEvent) {
+	if db.events == nil || db.closed {
+		return
+	}
+	ev.Time = nowFunc()
+	select {
+	case db.events <- ev:
+	default:
+		db.eventsDropped++
+	}
+}
+
+// emit is like emitLocked but acquires db.mu.
+func (db *DB) emit(ev PoolEvent) {
+	db.mu.Lock()
+	db.emitLocked(ev)
+	db.mu.Unlock()
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+// drainEvents returns the events buffered on ch.
+func drainEvents(ch <-chan PoolEvent) []PoolEvent {
+	var evs []PoolEvent
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return evs
+			}
+			evs = append(evs, ev)
+		default:
+			return evs
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	db := newTestDB(t, "people")
+	db.clearAllConns(t)
+	db.SetMaxIdleConns(1)
+	events := db.Events()
+	if db.Events() != events {
+		t.Fatal("Events returned different channels")
+	}
+
+	const query = "SELECT|people|name|age=?"
+	var name string
+	for i := 0; i < 2; i++ {
+		if err := db.QueryRow(query, 1).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.SetMaxIdleConns(0)
+
+	var types []PoolEventType
+	for _, ev := range drainEvents(events) {
+		if ev.Time.IsZero() {
+			t.Errorf("%v event has no time", ev.Type)
+		}
+		switch ev.Type {
+		case QueryStarted, QueryEnded:
+			if ev.Query != query {
+				t.Errorf("%v event for %q; want %q", ev.Type, ev.Query, query)
+			}
+			if ev.Err == driver.ErrSkip {
+				// The fake driver declines the fast path; leave
+				// out the attempt.
+				types = types[:len(types)-1]
+				continue
+			}
+			if ev.Err != nil {
+				t.Errorf("%v event with error %v", ev.Type, ev.Err)
+			}
+		case ConnectionReused:
+			if ev.Conn.Reuses != 1 {
+				t.Errorf("ConnectionReused with %d reuses; want 1", ev.Conn.Reuses)
+			}
+		}
+		types = append(types, ev.Type)
+	}
+	want := []PoolEventType{
+		ConnectionOpened, QueryStarted, QueryEnded, ConnectionReturned,
+		ConnectionReused, QueryStarted, QueryEnded, ConnectionReturned,
+		ConnectionClosed,
+	}
+	if len(types) != len(want) {
+		t.Fatalf("events = %v; want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events = %v; want %v", types, want)
+		}
+	}
+
+	closeDB(t, db)
+	if _, ok := <-events; ok {
+		t.Error("events channel not closed by DB.Close")
+	}
+}
+
+func TestEventsCheckoutIDs(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetMaxIdleConns(1)
+	events := db.Events()
+
+	var slow []ConnInfo
+	db.SetSlowQueryThreshold(time.Nanosecond, func(ctx context.Context, query string, args []interface{}, dur time.Duration) {
+		info, ok := ConnInfoFromContext(ctx)
+		if !ok {
+			t.Error("slow query context has no ConnInfo")
+		}
+		slow = append(slow, info)
+	})
+	var closed []ConnInfo
+	db.SetConnCloseHook(func(info ConnInfo) {
+		closed = append(closed, info)
+	})
+
+	var name string
+	for i := 0; i < 2; i++ {
+		if err := db.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.SetMaxIdleConns(0)
+
+	// Every event but ConnectionOpened belongs to one of the two
+	// checkouts of the same connection.
+	var checkouts []uint64
+	var connID uint64
+	for _, ev := range drainEvents(events) {
+		if connID == 0 {
+			connID = ev.Conn.ID
+		}
+		if ev.Conn.ID != connID || connID == 0 {
+			t.Errorf("%v event for connection %d; want %d", ev.Type, ev.Conn.ID, connID)
+		}
+		if ev.Type == ConnectionOpened || ev.Type == ConnectionClosed {
+			continue
+		}
+		if n := len(checkouts); n == 0 || checkouts[n-1] != ev.Conn.CheckoutID {
+			checkouts = append(checkouts, ev.Conn.CheckoutID)
+		}
+	}
+	if len(checkouts) != 2 || checkouts[0] == 0 || checkouts[1] <= checkouts[0] {
+		t.Fatalf("checkout IDs = %v; want two increasing IDs", checkouts)
+	}
+	if len(slow) == 0 || slow[len(slow)-1].ID != connID || slow[len(slow)-1].CheckoutID != checkouts[1] {
+		t.Errorf("slow query ConnInfo = %+v; want connection %d, checkout %d", slow, connID, checkouts[1])
+	}
+	if len(closed) != 1 || closed[0].ID != connID || closed[0].CheckoutID != checkouts[1] {
+		t.Errorf("close hook ConnInfo = %+v; want connection %d, checkout %d", closed, connID, checkouts[1])
+	}
+}
+
+func TestEventsWait(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+	events := db.Events()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- db.Ping()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tx.Rollback()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	var started, ended bool
+	for _, ev := range drainEvents(events) {
+		switch ev.Type {
+		case WaitStarted:
+			started = true
+		case WaitEnded:
+			ended = true
+			if ev.Err != nil || ev.Duration <= 0 {
+				t.Errorf("WaitEnded with duration %v and error %v", ev.Duration, ev.Err)
+			}
+		}
+	}
+	if !started || !ended {
+		t.Errorf("WaitStarted seen = %v, WaitEnded seen = %v; want both", started, ended)
+	}
+}
+
+func TestEventsDropped(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.Events()
+
+	var name string
+	for i := 0; i < eventBufferSize; i++ {
+		if err := db.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := db.Stats().EventsDropped; n == 0 {
+		t.Error("no events dropped with a full channel")
+	}
+	if n := db.ResetStats().EventsDropped; n == 0 {
+		t.Error("ResetStats reported no dropped events")
+	}
+	if n := db.Stats().EventsDropped; n != 0 {
+		t.Errorf("EventsDropped after ResetStats = %d; want 0", n)
+	}
+}
+
+func TestEventsAfterClose(t *testing.T) {
+	db := newTestDB(t, "people")
+	closeDB(t, db)
+	if _, ok := <-db.Events(); ok {
+		t.Error("Events of closed DB delivered an event")
+	}
+}
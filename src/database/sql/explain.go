@@ -0,0 +1,113 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Query plans.
+
+// 查询计划。
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// ErrExplainNotSupported is returned by Explain when neither the
+// driver nor the database's dialect offers a way to ask for a plan.
+
+// ErrExplainNotSupported 会在驱动和数据库方言都没有提供获取查询计划的方式时由
+// Explain 返回。
+var ErrExplainNotSupported = errors.New("sql: driver does not support EXPLAIN")
+
+// Explain returns the plan the database would use to run query with
+// args, one string per row of the plan, without running the query.
+//
+// If the driver implements driver.Explainer, it produces the plan.
+// Otherwise, query is prefixed with the Explain prefix of the
+// database's dialect, such as "EXPLAIN ", and run, and the columns of
+// each row it returns are joined with tabs; a NULL column is empty. The
+// prefix is best-effort: a plain EXPLAIN doesn't run the query on
+// PostgreSQL or MySQL, but dialects are shared by databases that may
+// differ on this. If the dialect has no prefix, Explain returns
+// ErrExplainNotSupported.
+
+// Explain 返回数据库将用于以 args 执行 query 的计划，计划的每一行对应一个字符串，
+// 而不会实际执行该查询。
+//
+// 若驱动实现了 driver.Explainer，则由它生成计划。否则，query 会被加上数据库方言的
+// Explain 前缀（例如 "EXPLAIN "）后执行，其返回的每一行的各列以制表符连接；NULL 列
+// 为空。该前缀是尽力而为的：在 PostgreSQL 或 MySQL 上，普通的 EXPLAIN 不会执行查询，
+// 但方言会被不同的数据库共享，而它们在这一点上可能有所不同。若方言没有前缀，Explain
+// 返回 ErrExplainNotSupported。
+func (db *DB) Explain(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	query = db.maybeRebind(query)
+	var plan []string
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		plan, err = db.explain(ctx, query, args, cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		plan, err = db.explain(ctx, query, args, alwaysNewConn)
+	}
+	if err != nil && err != ErrExplainNotSupported {
+		return nil, db.handleErr("Explain", query, err)
+	}
+	return plan, err
+}
+
+func (db *DB) explain(ctx context.Context, query string, args []interface{}, strategy connReuseStrategy) ([]string, error) {
+	dc, err := db.connContext(ctx, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if ex, ok := dc.ci.(driver.Explainer); ok {
+		dargs, err := driverArgs(&driverStmt{Locker: dc}, args)
+		if err != nil {
+			dc.releaseConn(err)
+			return nil, err
+		}
+		dc.Lock()
+		plan, err := ex.Explain(query, dargs)
+		dc.Unlock()
+		dc.releaseConn(err)
+		return plan, err
+	}
+	prefix := db.Dialect().Explain
+	if prefix == "" {
+		dc.releaseConn(nil)
+		return nil, ErrExplainNotSupported
+	}
+	rows, err := db.queryConn(dc, dc.releaseConn, prefix+query, args)
+	if err != nil {
+		return nil, err
+	}
+	rows.watchContext(ctx)
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]NullString, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	var plan []string
+	fields := make([]string, len(cols))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, v := range vals {
+			fields[i] = v.String
+		}
+		plan = append(plan, strings.Join(fields, "\t"))
+	}
+	return plan, rows.Err()
+}
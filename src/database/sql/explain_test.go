@@ -0,0 +1,59 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	db.SetDialect(&Dialect{Name: "fake", Explain: "EXPLAIN|"})
+	plan, err := db.Explain(ctx, "SELECT|people|name|age=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"scan SELECT\tpeople"}; !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan = %q; want %q", plan, want)
+	}
+	if _, err := db.Explain(ctx, "SELECT|people|name"); err == nil {
+		t.Error("Explain of a bad query succeeded")
+	}
+
+	// EXPLAIN doesn't run the query.
+	if _, err := db.Explain(ctx, "INSERT|people|name=Eve,age=9"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT|people|age|name=?", "Eve").Scan(&n); err != ErrNoRows {
+		t.Errorf("explained INSERT ran: got %d, %v", n, err)
+	}
+
+	db.SetDialect(ColonDialect)
+	if _, err := db.Explain(ctx, "SELECT|people|name|"); err != ErrExplainNotSupported {
+		t.Errorf("Explain with no dialect prefix = %v; want ErrExplainNotSupported", err)
+	}
+}
+
+func TestExplainDriver(t *testing.T) {
+	db, err := Open("test", "explain;explain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetDialect(ColonDialect)
+	plan, err := db.Explain(context.Background(), "SELECT|t|x|y=?", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"query SELECT|t|x|y=?", "args [7]"}; !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan = %q; want %q", plan, want)
+	}
+}
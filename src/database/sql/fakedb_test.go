@@ -5,6 +5,7 @@
 package sql
 
 import (
+	"bytes"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -53,6 +54,7 @@ type fakeDB struct {
 	mu      sync.Mutex
 	tables  map[string]*table
 	badConn bool
+	replPos uint64 // reported by replPosFakeConn
 }
 
 type table struct {
@@ -85,10 +87,23 @@ type fakeConn struct {
 	stmtsMade   int
 	stmtsClosed int
 	numPrepare  int
+	numPing     int
 
 	// bad connection tests; see isBad()
 	bad       bool
 	stickyBad bool
+
+	// if set, statements report NumInput -1; see inputCountingFakeConn
+	hideNumInput bool
+
+	// set by timeoutFakeConn.SetStatementTimeout; guarded by mu
+	stmtTimeout time.Duration
+
+	// set by fetchSizeFakeConn.SetFetchSize; guarded by mu
+	fetchSize int
+
+	// set by deferrableFakeConn.SetConstraintsDeferred; guarded by mu
+	constraintsDeferred bool
 }
 
 func (c *fakeConn) incrStat(v *int) {
@@ -97,6 +112,30 @@ func (c *fakeConn) incrStat(v *int) {
 	c.mu.Unlock()
 }
 
+// hookPingErr, if non-nil, supplies the result of fakeConn.Ping.
+var hookPingErr struct {
+	sync.Mutex
+	fn func() error
+}
+
+func setHookPingErr(fn func() error) {
+	hookPingErr.Lock()
+	defer hookPingErr.Unlock()
+	hookPingErr.fn = fn
+}
+
+// Ping implements driver.Pinger.
+func (c *fakeConn) Ping() error {
+	c.incrStat(&c.numPing)
+	hookPingErr.Lock()
+	fn := hookPingErr.fn
+	hookPingErr.Unlock()
+	if fn != nil {
+		return fn()
+	}
+	return nil
+}
+
 type fakeTx struct {
 	c *fakeConn
 }
@@ -164,9 +203,24 @@ func setHookOpenErr(fn func() error) {
 
 // Supports dsn forms:
 //    <dbname>
-//    <dbname>;<opts>  (only currently supported option is `badConn`,
-//                      which causes driver.ErrBadConn to be returned on
-//                      every other conn.Begin())
+//    <dbname>;<opts>  (supported options are `badConn`, which causes
+//                      driver.ErrBadConn to be returned on every other
+//                      conn.Begin(); `version=<v>`, which makes the
+//                      conn a driver.ServerVersioner reporting <v>;
+//                      `arrays`, which makes it a driver.ArrayEncoder;
+//                      `copy`, which makes it a driver.Copier; and
+//                      `batch` or `batchsum`, which make it a
+//                      driver.Batcher with or without per-statement
+//                      results; `countInputs`, which makes its
+//                      statements report NumInput -1 and the conn a
+//                      driver.QueryInputCounter; `stmtTimeout`,
+//                      which makes it a driver.StatementTimeouter;
+//                      `replpos`, which makes it a
+//                      driver.ReplicationPositioner reporting the
+//                      fakeDB's replPos; `explain`, which makes it a
+//                      driver.Explainer; `deferrable`, which makes it
+//                      a driver.ConstraintDeferrer; and `queryer`, whose
+//                      driver.Queryer runs queries without ErrSkip)
 func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
 	hookOpenErr.Lock()
 	fn := hookOpenErr.fn
@@ -198,9 +252,301 @@ func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
 		d.waitCh = nil
 		d.waitingCh = nil
 	}
+	if len(parts) >= 2 && strings.HasPrefix(parts[1], "version=") {
+		return &versionedFakeConn{fakeConn: conn, version: strings.TrimPrefix(parts[1], "version=")}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "arrays" {
+		return arrayFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "copy" {
+		return copyFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "stmtTimeout" {
+		return timeoutFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "fetchSize" {
+		return fetchSizeFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "queryer" {
+		return queryerFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "deferrable" {
+		return deferrableFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "explain" {
+		return explainFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "replpos" {
+		return replPosFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && parts[1] == "countInputs" {
+		conn.hideNumInput = true
+		return inputCountingFakeConn{conn}, nil
+	}
+	if len(parts) >= 2 && (parts[1] == "batch" || parts[1] == "batchsum") {
+		return batchFakeConn{conn, parts[1] == "batch"}, nil
+	}
 	return conn, nil
 }
 
+// timeoutFakeConn is a fakeConn that implements
+// driver.StatementTimeouter. It only records the timeout.
+type timeoutFakeConn struct {
+	*fakeConn
+}
+
+func (c timeoutFakeConn) SetStatementTimeout(d time.Duration) error {
+	c.mu.Lock()
+	c.stmtTimeout = d
+	c.mu.Unlock()
+	return nil
+}
+
+// queryerFakeConn is a fakeConn whose driver.Queryer runs queries
+// rather than returning driver.ErrSkip, so the sql package doesn't
+// prepare them.
+type queryerFakeConn struct {
+	*fakeConn
+}
+
+func (c queryerFakeConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	si, err := c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := si.Query(args)
+	if err != nil {
+		si.Close()
+		return nil, err
+	}
+	return stmtClosingRows{rows.(*rowsCursor), si}, nil
+}
+
+// stmtClosingRows closes the statement its rows came from with them.
+type stmtClosingRows struct {
+	*rowsCursor
+	si driver.Stmt
+}
+
+func (r stmtClosingRows) Close() error {
+	err := r.rowsCursor.Close()
+	r.si.Close()
+	return err
+}
+
+// deferrableFakeConn is a fakeConn that implements
+// driver.ConstraintDeferrer. It only records the setting, which
+// Commit and Rollback clear.
+type deferrableFakeConn struct {
+	*fakeConn
+}
+
+func (c deferrableFakeConn) SetConstraintsDeferred(deferred bool) error {
+	c.mu.Lock()
+	c.constraintsDeferred = deferred
+	c.mu.Unlock()
+	return nil
+}
+
+// fetchSizeFakeConn is a fakeConn that implements driver.FetchSizer.
+// It only records the fetch size.
+type fetchSizeFakeConn struct {
+	*fakeConn
+}
+
+func (c fetchSizeFakeConn) SetFetchSize(n int) error {
+	c.mu.Lock()
+	c.fetchSize = n
+	c.mu.Unlock()
+	return nil
+}
+
+// explainFakeConn is a fakeConn that implements driver.Explainer. Its
+// plan echoes the query and its arguments.
+type explainFakeConn struct {
+	*fakeConn
+}
+
+func (c explainFakeConn) Explain(query string, args []driver.Value) ([]string, error) {
+	return []string{"query " + query, fmt.Sprintf("args %v", args)}, nil
+}
+
+// replPosFakeConn is a fakeConn that implements
+// driver.ReplicationPositioner.
+type replPosFakeConn struct {
+	*fakeConn
+}
+
+func (c replPosFakeConn) ReplicationPosition() (uint64, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.db.replPos, nil
+}
+
+// inputCountingFakeConn is a fakeConn that implements
+// driver.QueryInputCounter, for statements that hide their NumInput.
+type inputCountingFakeConn struct {
+	*fakeConn
+}
+
+func (c inputCountingFakeConn) NumQueryInput(query string) int {
+	return strings.Count(query, "?")
+}
+
+// batchFakeConn is a fakeConn that implements driver.Batcher. With
+// perStmt it reports the result of each statement; otherwise only
+// the total number of rows affected.
+type batchFakeConn struct {
+	*fakeConn
+	perStmt bool
+}
+
+func (c batchFakeConn) ExecBatch(queries []string, args [][]driver.Value) (driver.Result, error) {
+	var res fakeBatchResult
+	for i, query := range queries {
+		stmt, err := c.Prepare(query)
+		if err != nil {
+			return nil, err
+		}
+		r, err := stmt.Exec(args[i])
+		stmt.Close()
+		if err != nil {
+			return nil, err
+		}
+		res.results = append(res.results, r)
+	}
+	if !c.perStmt {
+		var n int64
+		for _, r := range res.results {
+			if ra, err := r.RowsAffected(); err == nil {
+				n += ra
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return res, nil
+}
+
+type fakeBatchResult struct {
+	results []driver.Result
+}
+
+func (r fakeBatchResult) LastInsertId() (int64, error) {
+	return r.results[len(r.results)-1].LastInsertId()
+}
+
+func (r fakeBatchResult) RowsAffected() (int64, error) {
+	return r.results[len(r.results)-1].RowsAffected()
+}
+
+func (r fakeBatchResult) Results() []driver.Result {
+	return r.results
+}
+
+// copyFakeConn is a fakeConn that implements driver.Copier. Rows are
+// buffered and only appended to the table by Exec.
+type copyFakeConn struct {
+	*fakeConn
+}
+
+func (c copyFakeConn) CopyFrom(table string, columns []string) (driver.CopyIn, error) {
+	c.db.mu.Lock()
+	t, ok := c.db.table(table)
+	c.db.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakedb: table %q doesn't exist", table)
+	}
+	colidx := make([]int, len(columns))
+	for i, name := range columns {
+		if colidx[i] = t.columnIndex(name); colidx[i] == -1 {
+			return nil, fmt.Errorf("fakedb: column %q doesn't exist", name)
+		}
+	}
+	return &fakeCopyIn{t: t, colidx: colidx}, nil
+}
+
+var errCopyInClosed = errors.New("fakedb: CopyIn has been closed")
+
+type fakeCopyIn struct {
+	t      *table
+	colidx []int
+	rows   []*row
+	closed bool
+}
+
+func (ci *fakeCopyIn) AddRow(args []driver.Value) error {
+	if ci.closed {
+		return errCopyInClosed
+	}
+	if len(args) != len(ci.colidx) {
+		return fmt.Errorf("fakedb: got %d values for %d columns", len(args), len(ci.colidx))
+	}
+	cols := make([]interface{}, len(ci.t.colname))
+	for i, v := range args {
+		cols[ci.colidx[i]] = v
+	}
+	ci.rows = append(ci.rows, &row{cols: cols})
+	return nil
+}
+
+func (ci *fakeCopyIn) Exec() (driver.Result, error) {
+	if ci.closed {
+		return nil, errCopyInClosed
+	}
+	ci.t.mu.Lock()
+	ci.t.rows = append(ci.t.rows, ci.rows...)
+	ci.t.mu.Unlock()
+	n := len(ci.rows)
+	ci.rows = nil
+	return driver.RowsAffected(n), nil
+}
+
+func (ci *fakeCopyIn) Close() error {
+	if ci.closed {
+		return errors.New("fakedb: CopyIn closed twice")
+	}
+	ci.closed = true
+	return nil
+}
+
+// arrayFakeConn is a fakeConn that implements driver.ArrayEncoder,
+// encoding arrays as strings like {1,NULL,3}.
+type arrayFakeConn struct {
+	*fakeConn
+}
+
+func (arrayFakeConn) EncodeArray(elems []driver.Value) (driver.Value, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, v := range elems {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		switch v := v.(type) {
+		case nil:
+			buf.WriteString("NULL")
+		case []byte:
+			buf.Write(v)
+		default:
+			fmt.Fprint(&buf, v)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+// versionedFakeConn is a fakeConn that implements driver.ServerVersioner.
+type versionedFakeConn struct {
+	*fakeConn
+	version    string
+	numVersion int
+}
+
+func (c *versionedFakeConn) ServerVersion() (string, error) {
+	c.incrStat(&c.numVersion)
+	return c.version, nil
+}
+
 func (d *fakeDriver) getDB(name string) *fakeDB {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -482,6 +828,9 @@ func (c *fakeConn) prepareInsert(stmt *fakeStmt, parts []string) (driver.Stmt, e
 // hook to simulate broken connections
 var hookPrepareBadConn func() bool
 
+// hook to simulate a Prepare that fails on some connections
+var hookPrepareErr func(c *fakeConn, query string) error
+
 func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
 	c.numPrepare++
 	if c.db == nil {
@@ -491,6 +840,11 @@ func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
 	if c.stickyBad || (hookPrepareBadConn != nil && hookPrepareBadConn()) {
 		return nil, driver.ErrBadConn
 	}
+	if hookPrepareErr != nil {
+		if err := hookPrepareErr(c, query); err != nil {
+			return nil, err
+		}
+	}
 
 	parts := strings.Split(query, "|")
 	if len(parts) < 1 {
@@ -519,6 +873,19 @@ func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
 		// Do all the prep-work like for an INSERT but don't actually insert the row.
 		// Used for some of the concurrent tests.
 		return c.prepareInsert(stmt, parts)
+	case "EXPLAIN":
+		// EXPLAIN|<query>: a one-row plan naming the query's
+		// command and table, without running the query.
+		inner, err := c.Prepare(strings.Join(parts, "|"))
+		if err != nil {
+			stmt.Close()
+			return nil, err
+		}
+		is := inner.(*fakeStmt)
+		stmt.table = is.table
+		stmt.colName = []string{is.cmd}
+		stmt.placeholders = is.placeholders
+		inner.Close()
 	default:
 		stmt.Close()
 		return nil, errf("unsupported command type %q", cmd)
@@ -662,6 +1029,19 @@ func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 		return nil, err
 	}
 
+	if s.cmd == "INSERT" {
+		return s.queryInsert(args)
+	}
+	if s.cmd == "EXPLAIN" {
+		return &rowsCursor{
+			stmt:   s,
+			pos:    -1,
+			cols:   []string{"op", "table"},
+			rows:   []*row{{cols: []interface{}{"scan " + s.colName[0], s.table}}},
+			errPos: -1,
+		}, nil
+	}
+
 	db := s.c.db
 	if len(args) != s.placeholders {
 		panic("error in pkg db; should only get here if size is correct")
@@ -721,30 +1101,94 @@ rows:
 		mrows = append(mrows, mrow)
 	}
 
+	colType := make([]string, len(s.colName))
+	for seli, name := range s.colName {
+		colType[seli] = t.coltype[colIdx[name]]
+	}
 	cursor := &rowsCursor{
-		pos:    -1,
-		rows:   mrows,
-		cols:   s.colName,
-		errPos: -1,
+		stmt:    s,
+		pos:     -1,
+		rows:    mrows,
+		cols:    s.colName,
+		colType: colType,
+		errPos:  -1,
 	}
 	return cursor, nil
 }
 
+// queryInsert runs an INSERT through Query, returning the inserted
+// columns like INSERT ... RETURNING.
+func (s *fakeStmt) queryInsert(args []driver.Value) (driver.Rows, error) {
+	if _, err := s.execInsert(args, true); err != nil {
+		return nil, err
+	}
+	mrow := &row{cols: make([]interface{}, len(s.colName))}
+	argPos := 0
+	for n := range s.colName {
+		if strvalue, ok := s.colValue[n].(string); ok && strvalue == "?" {
+			mrow.cols[n] = args[argPos]
+			argPos++
+		} else {
+			mrow.cols[n] = s.colValue[n]
+		}
+	}
+	return &returningCursor{
+		rowsCursor: &rowsCursor{
+			stmt:   s,
+			pos:    -1,
+			rows:   []*row{mrow},
+			cols:   s.colName,
+			errPos: -1,
+		},
+		affected: 1,
+	}, nil
+}
+
+// returningCursor is the cursor of an INSERT run through Query. It
+// implements driver.RowsAffecter.
+type returningCursor struct {
+	*rowsCursor
+	affected int64
+}
+
+func (rc *returningCursor) RowsAffected() (int64, error) {
+	return rc.affected, nil
+}
+
 func (s *fakeStmt) NumInput() int {
 	if s.panic == "NumInput" {
 		panic(s.panic)
 	}
+	if s.c.hideNumInput {
+		return -1
+	}
 	return s.placeholders
 }
 
 // hook to simulate broken connections
 var hookCommitBadConn func() bool
 
+// hook to simulate commit failures, such as serialization failures
+var hookCommitErr func() error
+
+// fakeConflictError is a retryable error, as returned by databases
+// for serialization failures.
+type fakeConflictError struct{}
+
+func (fakeConflictError) Error() string   { return "fakedb: could not serialize access" }
+func (fakeConflictError) Retryable() bool { return true }
+
 func (tx *fakeTx) Commit() error {
 	tx.c.currTx = nil
+	tx.c.mu.Lock()
+	tx.c.constraintsDeferred = false
+	tx.c.mu.Unlock()
 	if hookCommitBadConn != nil && hookCommitBadConn() {
 		return driver.ErrBadConn
 	}
+	if hookCommitErr != nil {
+		return hookCommitErr()
+	}
 	return nil
 }
 
@@ -753,14 +1197,25 @@ var hookRollbackBadConn func() bool
 
 func (tx *fakeTx) Rollback() error {
 	tx.c.currTx = nil
+	tx.c.mu.Lock()
+	tx.c.constraintsDeferred = false
+	tx.c.mu.Unlock()
 	if hookRollbackBadConn != nil && hookRollbackBadConn() {
 		return driver.ErrBadConn
 	}
+	if hookRollbackErr != nil {
+		return hookRollbackErr()
+	}
 	return nil
 }
 
+// hook to simulate a rollback that fails with an arbitrary error
+var hookRollbackErr func() error
+
 type rowsCursor struct {
-	cols   []string
+	stmt    *fakeStmt // the statement the rows came from
+	cols    []string
+	colType []string // the fakedb types of cols, if known
 	pos    int
 	rows   []*row
 	closed bool
@@ -789,8 +1244,27 @@ func (rc *rowsCursor) Columns() []string {
 	return rc.cols
 }
 
+// ColumnTypeDatabaseTypeName implements
+// driver.RowsColumnTypeDatabaseTypeName.
+func (rc *rowsCursor) ColumnTypeDatabaseTypeName(index int) string {
+	if rc.colType == nil {
+		return ""
+	}
+	return strings.ToUpper(rc.colType[index])
+}
+
 var rowsCursorNextHook func(dest []driver.Value) error
 
+// rowsCursorInterruptHook, if non-nil, is called by Interrupt.
+var rowsCursorInterruptHook func()
+
+// Interrupt implements driver.Interrupter.
+func (rc *rowsCursor) Interrupt() {
+	if fn := rowsCursorInterruptHook; fn != nil {
+		fn()
+	}
+}
+
 func (rc *rowsCursor) Next(dest []driver.Value) error {
 	if rowsCursorNextHook != nil {
 		return rowsCursorNextHook(dest)
@@ -799,6 +1273,9 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 	if rc.closed {
 		return errors.New("fakedb: cursor is closed")
 	}
+	if rc.stmt.closed {
+		return errors.New("fakedb: statement closed before its rows")
+	}
 	rc.pos++
 	if rc.pos == rc.errPos {
 		return rc.err
@@ -815,7 +1292,7 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 		// messing up conversions or doing them differently.
 		dest[i] = v
 
-		if bs, ok := v.([]byte); ok {
+		if bs, ok := v.([]byte); ok && len(bs) > 0 {
 			if rc.bytesClone == nil {
 				rc.bytesClone = make(map[*byte][]byte)
 			}
@@ -879,6 +1356,8 @@ func converterForType(typ string) driver.ValueConverter {
 		return driver.Null{Converter: driver.DefaultParameterConverter}
 	case "datetime":
 		return driver.DefaultParameterConverter
+	case "geometry":
+		return driver.Null{Converter: driver.DefaultParameterConverter}
 	}
 	panic("invalid fakedb column type of " + typ)
 }
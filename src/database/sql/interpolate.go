@@ -0,0 +1,150 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Queries with their arguments written in, for debugging.
+
+// 写入了参数的查询，用于调试。
+
+package sql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interpolate returns query with each placeholder replaced by a SQL
+// literal for the matching argument in args, for logging and debugging
+// only: the result is meant to be read, or pasted into a database
+// console, and must never be executed by the program, as placeholders
+// exist to keep arguments apart from the SQL text.
+//
+// Placeholders are the native form of the database's dialect, such as
+// $1, or ? if the dialect has no Placeholder function; a ? in a dialect
+// with numbered placeholders is an operator, such as PostgreSQL's
+// jsonb ?, and is left alone. So are placeholders inside quoted
+// strings, quoted identifiers and comments, as in Rebind. Arguments
+// are converted as for Exec, through driver.Valuer where implemented.
+// Strings become quoted literals with quotes doubled, and backslashes
+// too in dialects with BackslashEscapes; []byte becomes a hexadecimal
+// literal such as X'00ff', or '\x00ff'::bytea in dialects with
+// DollarQuotes, as PostgreSQL reads X'00ff' as a bit string; times
+// become quoted text in UTC, booleans TRUE and FALSE, and nil NULL.
+// Interpolate returns an error if the arguments don't match the
+// placeholders.
+
+// Interpolate 返回将每个占位符替换为 args 中对应参数的 SQL 字面量之后的 query，
+// 仅用于日志记录和调试：其结果用于阅读，或粘贴到数据库控制台中，绝不能由程序执行，
+// 因为占位符的存在正是为了将参数与 SQL 文本分开。
+//
+// 占位符为数据库方言的原生形式，例如 $1；若方言没有 Placeholder 函数，则为 ?。
+// 在使用编号占位符的方言中，? 是一个运算符（例如 PostgreSQL 的 jsonb ?），不会被
+// 改动。引号中的字符串、带引号的标识符以及注释中的占位符同样不会被改动，这与 Rebind
+// 相同。参数的转换方式与 Exec 相同，若实现了 driver.Valuer 则经由它进行转换。字符串
+// 会成为将引号加倍的带引号字面量（在设置了 BackslashEscapes 的方言中，反斜杠也会
+// 加倍）；[]byte 会成为十六进制字面量，例如 X'00ff'，在设置了 DollarQuotes 的方言中
+// 则为 '\x00ff'::bytea，因为 PostgreSQL 会将 X'00ff' 读作位串；时间会成为 UTC 的
+// 带引号文本，布尔值会成为 TRUE 和 FALSE，nil 会成为 NULL。若参数与占位符不匹配，
+// Interpolate 会返回错误。
+func (db *DB) Interpolate(query string, args ...interface{}) (string, error) {
+	dargs, err := driverArgs(nil, args)
+	if err != nil {
+		return "", err
+	}
+	d := db.Dialect()
+	var prefix string
+	if d.Placeholder != nil {
+		prefix = strings.TrimSuffix(d.Placeholder(1), "1")
+	}
+	var buf []byte
+	used := make([]bool, len(dargs))
+	seq := 0
+	last := 0
+	for i := 0; i < len(query); i++ {
+		if j := d.skip(query, i); j > i {
+			i = j - 1
+			continue
+		}
+		n := 0 // 1-based argument of the placeholder at i
+		end := i
+		switch {
+		case query[i] == '?' && prefix == "":
+			seq++
+			n, end = seq, i+1
+		case prefix != "" && strings.HasPrefix(query[i:], prefix):
+			j := i + len(prefix)
+			for j < len(query) && '0' <= query[j] && query[j] <= '9' {
+				j++
+			}
+			if j == i+len(prefix) {
+				continue
+			}
+			n, _ = strconv.Atoi(query[i+len(prefix) : j])
+			end = j
+		default:
+			continue
+		}
+		if n < 1 || n > len(dargs) {
+			return "", fmt.Errorf("sql: placeholder %s has no argument; got %d", query[i:end], len(dargs))
+		}
+		used[n-1] = true
+		buf = append(buf, query[last:i]...)
+		buf = d.appendLiteral(buf, dargs[n-1])
+		last = end
+		i = end - 1
+	}
+	for n, u := range used {
+		if !u {
+			return "", fmt.Errorf("sql: argument %d has no placeholder", n+1)
+		}
+	}
+	return string(append(buf, query[last:]...)), nil
+}
+
+// appendLiteral appends the SQL literal for v in d to buf.
+func (d *Dialect) appendLiteral(buf []byte, v driver.Value) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "NULL"...)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case bool:
+		if v {
+			return append(buf, "TRUE"...)
+		}
+		return append(buf, "FALSE"...)
+	case []byte:
+		if d.DollarQuotes {
+			// PostgreSQL's bytea hex format.
+			return append(buf, fmt.Sprintf(`'\x%x'::bytea`, v)...)
+		}
+		return append(buf, fmt.Sprintf("X'%x'", v)...)
+	case time.Time:
+		return d.appendQuoted(buf, v.UTC().Format("2006-01-02 15:04:05.999999999Z07:00"))
+	case string:
+		return d.appendQuoted(buf, v)
+	}
+	return d.appendQuoted(buf, fmt.Sprint(v))
+}
+
+// appendQuoted appends s to buf as a string literal of d, doubling
+// its quotes, and its backslashes if they are escapes in d.
+func (d *Dialect) appendQuoted(buf []byte, s string) []byte {
+	buf = append(buf, '\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			buf = append(buf, "''"...)
+		case c == '\\' && d.BackslashEscapes:
+			buf = append(buf, `\\`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '\'')
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+
+	tests := []struct {
+		query string
+		args  []interface{}
+		want  string
+	}{
+		{"SELECT * FROM t WHERE name = ?", []interface{}{"O'Brien"}, "SELECT * FROM t WHERE name = 'O''Brien'"},
+		{"SELECT ?, ?, ?", []interface{}{42, 1.5, int8(-3)}, "SELECT 42, 1.5, -3"},
+		{"UPDATE t SET a = ?, b = ?", []interface{}{nil, true}, "UPDATE t SET a = NULL, b = TRUE"},
+		{"INSERT INTO t VALUES (?)", []interface{}{[]byte{0, 0xff, 'a'}}, "INSERT INTO t VALUES (X'00ff61')"},
+		{"SELECT ?", []interface{}{time.Date(2016, 3, 4, 5, 6, 7, 0, time.FixedZone("X", 3600))}, "SELECT '2016-03-04 04:06:07Z'"},
+		{"SELECT ?", []interface{}{NullString{}}, "SELECT NULL"},
+		{"SELECT ?", []interface{}{NullString{String: "x", Valid: true}}, "SELECT 'x'"},
+		{"SELECT '?', \"?\", ? -- ?\n/* ? */", []interface{}{1}, "SELECT '?', \"?\", 1 -- ?\n/* ? */"},
+	}
+	for _, tt := range tests {
+		got, err := db.Interpolate(tt.query, tt.args...)
+		if err != nil {
+			t.Errorf("Interpolate(%q, %v): %v", tt.query, tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Interpolate(%q, %v) = %q; want %q", tt.query, tt.args, got, tt.want)
+		}
+	}
+
+	for _, args := range [][]interface{}{{}, {1, 2}} {
+		if got, err := db.Interpolate("SELECT ?", args...); err == nil {
+			t.Errorf("Interpolate with %d args for one placeholder = %q; want error", len(args), got)
+		}
+	}
+
+	db.SetDialect(DollarDialect)
+	got, err := db.Interpolate("SELECT $2, $1, $1 WHERE x = '$3'", "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT 'b', 'a', 'a' WHERE x = '$3'"; got != want {
+		t.Errorf("Interpolate with DollarDialect = %q; want %q", got, want)
+	}
+	// ? is PostgreSQL's jsonb key-exists operator, not a placeholder,
+	// and bytea is written in its hex format.
+	got, err = db.Interpolate("SELECT $1, data ? 'k'", []byte{0, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `SELECT '\x00ff'::bytea, data ? 'k'`; got != want {
+		t.Errorf("Interpolate with DollarDialect = %q; want %q", got, want)
+	}
+	if _, err := db.Interpolate("SELECT $3", "a", "b", "c", "d"); err == nil {
+		t.Error("Interpolate with unused arguments succeeded")
+	}
+}
+
+// Tests that Interpolate reads and writes quoted strings as the
+// dialect does.
+func TestInterpolateDialectQuotes(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+
+	mysql := &Dialect{Name: "mysql", BackslashEscapes: true}
+	tests := []struct {
+		dialect *Dialect
+		query   string
+		args    []interface{}
+		want    string
+	}{
+		{mysql, `SELECT 'a\'?' WHERE b = ?`, []interface{}{1}, `SELECT 'a\'?' WHERE b = 1`},
+		{mysql, "SELECT ?", []interface{}{`a\' OR 1=1 -- `}, `SELECT 'a\\'' OR 1=1 -- '`},
+		{QuestionDialect, "SELECT ?", []interface{}{`a\b`}, `SELECT 'a\b'`},
+		{DollarDialect, "SELECT $$it's $1$$, $1", []interface{}{"x"}, "SELECT $$it's $1$$, 'x'"},
+		{DollarDialect, `SELECT E'a\'$1', $1`, []interface{}{`a\b`}, `SELECT E'a\'$1', 'a\b'`},
+	}
+	for _, tt := range tests {
+		db.SetDialect(tt.dialect)
+		got, err := db.Interpolate(tt.query, tt.args...)
+		if err != nil {
+			t.Errorf("%s: Interpolate(%q, %v): %v", tt.dialect.Name, tt.query, tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Interpolate(%q, %v) = %q; want %q", tt.dialect.Name, tt.query, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// QueryJSON executes a query that returns rows and encodes all of them
+// as a JSON array with one object per row. Each object maps the column
+// names to the row's values, in column order.
+//
+// Values are encoded according to the type the driver provides: NULL
+// becomes null, integers and floats become numbers, booleans become
+// true or false, []byte and strings become strings, and times become
+// strings in RFC 3339 format. A query returning no rows encodes as
+// an empty array.
+//
+// The query and the iteration over its rows observe ctx, as for
+// QueryContext.
+
+// QueryJSON 执行一个返回行的查询，并将所有行编码为一个 JSON 数组，每行对应一个对象。
+// 每个对象按列的顺序将列名映射到该行的值。
+//
+// 值会根据驱动提供的类型进行编码：NULL 变为 null，整数和浮点数变为数字，布尔值变为
+// true 或 false，[]byte 和字符串变为字符串，时间则变为 RFC 3339 格式的字符串。
+// 不返回任何行的查询会被编码为空数组。
+//
+// 与 QueryContext 一样，该查询及对其行的迭代会遵循 ctx。
+func (db *DB) QueryJSON(ctx context.Context, query string, args ...interface{}) ([]byte, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	names := make([][]byte, len(cols))
+	for i, col := range cols {
+		if names[i], err = json.Marshal(col); err != nil {
+			return nil, err
+		}
+	}
+	dest := rows.MakeDestinations()
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for n := 0; rows.Next(); n++ {
+		if err := rows.ScanSlice(dest); err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for i, d := range dest {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(names[i])
+			buf.WriteByte(':')
+			v := *d.(*interface{})
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			enc, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("sql: QueryJSON: column %q: %v", cols[i], err)
+			}
+			buf.Write(enc)
+		}
+		buf.WriteByte('}')
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestQueryJSON(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	got, err := db.QueryJSON(ctx, "SELECT|people|name,age,photo,dead|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"name":"Alice","age":1,"photo":"APHOTO","dead":null},` +
+		`{"name":"Bob","age":2,"photo":"BPHOTO","dead":null},` +
+		`{"name":"Chris","age":3,"photo":"CPHOTO","dead":null}]`
+	if string(got) != want {
+		t.Errorf("QueryJSON =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = db.QueryJSON(ctx, "SELECT|people|name|name=?", "Nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "[]" {
+		t.Errorf("QueryJSON with no rows = %s; want []", got)
+	}
+
+	exec(t, db, "CREATE|flags|on=bool,ratio=float64")
+	exec(t, db, "INSERT|flags|on=?,ratio=?", true, 0.5)
+	got, err = db.QueryJSON(ctx, "SELECT|flags|on,ratio|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"on":true,"ratio":0.5}]`; string(got) != want {
+		t.Errorf("QueryJSON = %s; want %s", got, want)
+	}
+
+	if _, err := db.QueryJSON(ctx, "SELECT|nosuchtable|name|"); err == nil {
+		t.Error("QueryJSON on missing table succeeded")
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := db.QueryJSON(cctx, "SELECT|people|name|"); err != context.Canceled {
+		t.Errorf("QueryJSON with cancelled context = %v; want context.Canceled", err)
+	}
+}
+
+func TestScanRawMessage(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|docs|id=int32,body=string")
+	exec(t, db, "INSERT|docs|id=?,body=?", 1, `{"a": [1, 2]}`)
+	exec(t, db, "INSERT|docs|id=?,body=?", 2, `{"b": null}`)
+
+	rows, err := db.Query("SELECT|docs|body|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []json.RawMessage
+	var m json.RawMessage
+	for rows.Next() {
+		if err := rows.Scan(&m); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || string(got[0]) != `{"a": [1, 2]}` || string(got[1]) != `{"b": null}` {
+		t.Errorf("scanned %q; want each row's JSON verbatim", got)
+	}
+
+	src := []byte(`[true]`)
+	if err := convertAssign(&m, src); err != nil {
+		t.Fatal(err)
+	}
+	src[1] = 'X'
+	if string(m) != `[true]` {
+		t.Errorf("RawMessage = %q after changing the source; want a copy", m)
+	}
+	if err := convertAssign(&m, nil); err != nil || m != nil {
+		t.Errorf("scanning NULL = %q, %v; want nil RawMessage", m, err)
+	}
+}
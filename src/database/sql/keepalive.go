@@ -0,0 +1,108 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// SetKeepAlive sets how often idle connections in the pool are pinged
+// with the driver's driver.Pinger, to keep them from being dropped
+// during quiet periods, for instance by a firewall, and to discard
+// those that have gone stale before a query runs into them.
+// Connections that fail the ping are closed. Connections in use are
+// never pinged, and drivers that don't implement driver.Pinger are
+// not affected.
+//
+// If d <= 0, idle connections are not pinged. The default is 0.
+
+// SetKeepAlive 设置使用驱动的 driver.Pinger 对池中空闲连接进行 ping 的频率，
+// 以防止它们在空闲期间被（例如防火墙）断开，并在查询遇到它们之前丢弃已失效的连接。
+// ping 失败的连接会被关闭。正在使用的连接永远不会被 ping，而未实现 driver.Pinger
+// 的驱动不受影响。
+//
+// 若 d <= 0，则不会 ping 空闲连接。默认为 0。
+func (db *DB) SetKeepAlive(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.keepAlive = d
+	if db.closed {
+		return
+	}
+	if db.keepAliveCh != nil {
+		// Wake keepAliver to pick up the new interval.
+		select {
+		case db.keepAliveCh <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if d > 0 {
+		db.keepAliveCh = make(chan struct{}, 1)
+		go db.keepAliver(d, db.keepAliveCh)
+	}
+}
+
+// keepAliver pings idle connections every db.keepAlive until it is
+// disabled or the DB is closed. wake is db.keepAliveCh.
+func (db *DB) keepAliver(d time.Duration, wake <-chan struct{}) {
+	t := time.NewTimer(d)
+	for {
+		select {
+		case <-t.C:
+		case <-wake: // keepAlive was changed or db was closed.
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+		}
+
+		db.mu.Lock()
+		d = db.keepAlive
+		if db.closed || d <= 0 {
+			db.keepAliveCh = nil
+			db.mu.Unlock()
+			return
+		}
+		// Take the idle connections out of the pool while pinging
+		// them, so that they aren't handed out meanwhile.
+		var idle []*driverConn
+		kept := db.freeConn[:0]
+		for _, dc := range db.freeConn {
+			if _, ok := dc.ci.(driver.Pinger); ok {
+				idle = append(idle, dc)
+			} else {
+				kept = append(kept, dc)
+			}
+		}
+		for i := len(kept); i < len(db.freeConn); i++ {
+			db.freeConn[i] = nil
+		}
+		db.freeConn = kept
+		db.mu.Unlock()
+
+		for _, dc := range idle {
+			dc.Lock()
+			err := dc.ci.(driver.Pinger).Ping()
+			dc.Unlock()
+			db.mu.Lock()
+			if err == nil && db.putConnDBLocked(dc, nil) {
+				db.mu.Unlock()
+				continue
+			}
+			db.maybeOpenNewConnections()
+			db.mu.Unlock()
+			dc.Close()
+		}
+
+		t.Reset(d)
+	}
+}
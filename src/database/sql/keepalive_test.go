@@ -0,0 +1,78 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+// waitCondition polls fn until it reports true or a generous timeout
+// passes.
+func waitCondition(t *testing.T, what string, fn func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	defer setHookPingErr(nil)
+
+	db.mu.Lock()
+	fc := db.freeConn[0].ci.(*fakeConn)
+	db.mu.Unlock()
+	pings := func() int {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		return fc.numPing
+	}
+
+	db.SetKeepAlive(time.Millisecond)
+	waitCondition(t, "idle connection to be pinged", func() bool { return pings() >= 2 })
+	if n := db.Stats().OpenConnections; n != 1 {
+		t.Fatalf("OpenConnections = %d; want 1", n)
+	}
+
+	// A connection in use is left alone, even while pings fail.
+	rows, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setHookPingErr(func() error { return driver.ErrBadConn })
+	before := pings()
+	time.Sleep(20 * time.Millisecond)
+	if n := pings(); n != before {
+		t.Errorf("connection in use was pinged %d times", n-before)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	// Once idle, it fails its ping and is discarded.
+	waitCondition(t, "failing connection to be closed", func() bool {
+		return db.Stats().OpenConnections == 0
+	})
+	setHookPingErr(nil)
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetKeepAlive(0)
+	waitCondition(t, "keepalive goroutine to stop", func() bool {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return db.keepAliveCh == nil
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tolerant conversions for the Null types.
+
+// Null 类型的宽容转换。
+
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nullScanner is implemented by the Null types, so that Scan can apply
+// the DB's ScanStrictness to them. Their Scan methods use ScanStrict.
+// The conversions below beyond convertAssignWith's are made only with
+// ScanLenient.
+type nullScanner interface {
+	scanWith(src interface{}, strictness ScanStrictness) error
+}
+
+// trimmedText returns src without surrounding spaces, if it is text.
+func trimmedText(src interface{}) (string, bool) {
+	switch s := src.(type) {
+	case string:
+		return strings.TrimSpace(s), true
+	case []byte:
+		return strings.TrimSpace(string(s)), true
+	}
+	return "", false
+}
+
+func (ns *NullString) scanWith(src interface{}, strictness ScanStrictness) error {
+	if src == nil {
+		*ns = NullString{}
+		return nil
+	}
+	err := convertAssignWith(&ns.String, src, strictness)
+	if st, ok := src.(fmt.Stringer); ok && err != nil && strictness == ScanLenient {
+		ns.String, err = st.String(), nil
+	}
+	ns.Valid = err == nil
+	return err
+}
+
+func (n *NullInt64) scanWith(src interface{}, strictness ScanStrictness) error {
+	if src == nil {
+		*n = NullInt64{}
+		return nil
+	}
+	err := convertAssignWith(&n.Int64, src, strictness)
+	if text, ok := trimmedText(src); ok && err != nil && strictness == ScanLenient {
+		if err = convertAssignWith(&n.Int64, text, strictness); err != nil {
+			// Text such as "4.0" or "1e3", held to the same
+			// rules as a float64 source.
+			if f, ferr := strconv.ParseFloat(text, 64); ferr == nil {
+				err = convertAssignWith(&n.Int64, f, strictness)
+			}
+		}
+	}
+	n.Valid = err == nil
+	return err
+}
+
+func (n *NullFloat64) scanWith(src interface{}, strictness ScanStrictness) error {
+	if src == nil {
+		*n = NullFloat64{}
+		return nil
+	}
+	err := convertAssignWith(&n.Float64, src, strictness)
+	if err != nil && strictness == ScanLenient {
+		if text, ok := trimmedText(src); ok {
+			err = convertAssignWith(&n.Float64, text, strictness)
+		} else if b, ok := src.(bool); ok {
+			n.Float64, err = 0, nil
+			if b {
+				n.Float64 = 1
+			}
+		}
+	}
+	n.Valid = err == nil
+	return err
+}
+
+func (n *NullBool) scanWith(src interface{}, strictness ScanStrictness) error {
+	if src == nil {
+		*n = NullBool{}
+		return nil
+	}
+	err := convertAssignWith(&n.Bool, src, strictness)
+	if text, ok := trimmedText(src); ok && err != nil && strictness == ScanLenient {
+		switch strings.ToLower(text) {
+		case "y", "yes", "on":
+			n.Bool, err = true, nil
+		case "n", "no", "off":
+			n.Bool, err = false, nil
+		default:
+			err = convertAssignWith(&n.Bool, text, strictness)
+		}
+	}
+	n.Valid = err == nil
+	return err
+}
+
+func (n *NullDuration) scanWith(src interface{}, strictness ScanStrictness) error {
+	if src == nil {
+		*n = NullDuration{}
+		return nil
+	}
+	err := convertAssignWith(&n.Duration, src, strictness)
+	if text, ok := trimmedText(src); ok && err != nil && strictness == ScanLenient {
+		err = convertAssignWith(&n.Duration, text, strictness)
+	}
+	n.Valid = err == nil
+	return err
+}
+
+// nullTimeLayouts are the text forms, besides RFC 3339, that NullTime
+// accepts; those without a zone are taken to be in UTC.
+var nullTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func (n *NullTime) scanWith(src interface{}, strictness ScanStrictness) error {
+	if src == nil {
+		*n = NullTime{}
+		return nil
+	}
+	err := convertAssignWith(&n.Time, src, strictness)
+	if text, ok := trimmedText(src); ok && err != nil && strictness == ScanLenient {
+		for _, layout := range nullTimeLayouts {
+			if t, terr := time.Parse(layout, text); terr == nil {
+				n.Time, err = t, nil
+				break
+			}
+		}
+	}
+	n.Valid = err == nil
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+type testStringer struct{}
+
+func (testStringer) String() string { return "stringer" }
+
+type nullScanValuer interface {
+	nullScanner
+	driver.Valuer
+}
+
+func TestNullTypesCoercion(t *testing.T) {
+	tests := []struct {
+		n          nullScanValuer
+		src        interface{}
+		strictness ScanStrictness
+		want       driver.Value
+		wantErr    bool
+	}{
+		// Standard conversions, whatever the strictness.
+		{new(NullString), int64(42), ScanStrict, "42", false},
+		{new(NullString), 2.5, ScanStrict, "2.5", false},
+		{new(NullString), true, ScanStrict, "true", false},
+		{new(NullInt64), "42", ScanStrict, int64(42), false},
+		{new(NullBool), "t", ScanStrict, true, false},
+
+		// Coercions made only with ScanLenient.
+		{new(NullString), testStringer{}, ScanStrict, nil, true},
+		{new(NullString), testStringer{}, ScanLenient, "stringer", false},
+		{new(NullInt64), " 4 ", ScanStrict, nil, true},
+		{new(NullInt64), []byte(" 42  "), ScanLenient, int64(42), false},
+		{new(NullInt64), "4.0", ScanLenient, int64(4), false},
+		{new(NullInt64), "1e3", ScanLenient, int64(1000), false},
+		{new(NullInt64), "4.5", ScanLenient, int64(4), false},
+		{new(NullInt64), "forty", ScanLenient, nil, true},
+		{new(NullFloat64), " 1.25 ", ScanStrict, nil, true},
+		{new(NullFloat64), " 1.25 ", ScanLenient, 1.25, false},
+		{new(NullFloat64), true, ScanStrict, nil, true},
+		{new(NullFloat64), true, ScanLenient, 1.0, false},
+		{new(NullBool), "yes", ScanStrict, nil, true},
+		{new(NullBool), "YES", ScanLenient, true, false},
+		{new(NullBool), []byte("off"), ScanLenient, false, false},
+		{new(NullBool), " t ", ScanLenient, true, false},
+		{new(NullBool), "maybe", ScanLenient, nil, true},
+		{new(NullTime), "2016-01-02", ScanStrict, nil, true},
+		{new(NullTime), "2016-01-02 03:04:05", ScanLenient, time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{new(NullTime), "2016-01-02", ScanLenient, time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{new(NullDuration), " 1500ms ", ScanStrict, nil, true},
+		{new(NullDuration), " 1500ms ", ScanLenient, 1500 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		err := tt.n.scanWith(tt.src, tt.strictness)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%T scan of %#v with strictness %d succeeded; want error", tt.n, tt.src, tt.strictness)
+			}
+			if v, _ := tt.n.Value(); v != nil {
+				t.Errorf("%T scan of %#v with strictness %d failed but left the value valid: %#v", tt.n, tt.src, tt.strictness, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%T scan of %#v with strictness %d: %v", tt.n, tt.src, tt.strictness, err)
+			continue
+		}
+		v, err := tt.n.Value()
+		if d, ok := tt.want.(time.Duration); ok {
+			tt.want = int64(d)
+		}
+		if t0, ok := tt.want.(time.Time); ok {
+			if got, _ := v.(time.Time); !got.Equal(t0) {
+				t.Errorf("%T scan of %#v then Value = %v; want %v", tt.n, tt.src, v, t0)
+			}
+			continue
+		}
+		if err != nil || v != tt.want {
+			t.Errorf("%T scan of %#v then Value = %#v, %v; want %#v", tt.n, tt.src, v, err, tt.want)
+		}
+	}
+}
+
+func TestNullScanStrictness(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|nums|s=string")
+	exec(t, db, "INSERT|nums|s=?", " 4 ")
+
+	var n NullInt64
+	if err := db.QueryRow("SELECT|nums|s|").Scan(&n); err == nil {
+		t.Errorf("strict Scan of \" 4 \" into NullInt64 = %+v; want error", n)
+	}
+	var b NullBool
+	if err := b.Scan("yes"); err == nil {
+		t.Errorf("NullBool.Scan(\"yes\") = %+v; want error", b)
+	}
+	db.SetScanStrictness(ScanLenient)
+	if err := db.QueryRow("SELECT|nums|s|").Scan(&n); err != nil || n != (NullInt64{4, true}) {
+		t.Errorf("lenient Scan of \" 4 \" into NullInt64 = %+v, %v; want 4", n, err)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Pluggable connection pools.
+
+// 可插拔的连接池。
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// Pool is a connection pool supplied to OpenWithPool. It replaces the
+// DB's built-in pool, letting callers choose their own strategy, such
+// as sharding connections or serving some callers first, while keeping
+// the DB API.
+//
+// The pool opens and closes the driver connections itself, typically
+// with the driver's Open method. Its methods may be called
+// concurrently. Connections must be comparable, as pointers are.
+
+// Pool 是提供给 OpenWithPool 的连接池。它替代 DB 内置的连接池，使调用者能在保留
+// DB API 的同时选择自己的策略，例如对连接分片或优先服务某些调用者。
+//
+// 连接池自行打开和关闭驱动连接，通常使用驱动的 Open 方法。其方法可被并发调用。
+// 连接必须是可比较的，例如指针。
+type Pool interface {
+	// Get returns a connection for the exclusive use of the DB
+	// until it is passed to Put. It should give up with ctx.Err()
+	// if ctx is done before a connection is available.
+	Get(ctx context.Context) (driver.Conn, error)
+
+	// Put returns a connection obtained from Get. If err is
+	// driver.ErrBadConn, the DB has discarded c and the pool must
+	// close it and not hand it out again; err is otherwise nil.
+	// Connections in use when the DB is closed are discarded
+	// this way after Close.
+	Put(c driver.Conn, err error)
+
+	// Close closes the pool and its idle connections. It is called
+	// once, by DB.Close.
+	Close() error
+
+	// Stats returns the pool's statistics. DB.Stats calls it
+	// without holding the DB's locks.
+	Stats() PoolStats
+}
+
+// PoolStats contains the statistics of a Pool. DB.Stats reports them
+// in DBStats.
+
+// PoolStats 包含 Pool 的统计信息。DB.Stats 会在 DBStats 中报告它们。
+type PoolStats struct {
+	// OpenConnections is the number of connections the pool has
+	// open, both idle and handed out.
+	OpenConnections int
+}
+
+// OpenWithPool is like Open but takes connections from pool instead of
+// the DB's built-in pool. The settings of the built-in pool, such as
+// SetMaxIdleConns, SetMaxOpenConns, SetConnMaxLifetime,
+// SetNonBlocking, SetKeepAlive and SetCircuitBreaker, have no effect;
+// the pool applies its own.
+//
+// The DB keeps state for a connection, such as the statements
+// prepared on it, only while it holds the connection: each connection
+// returned by Get is new to the DB, which prepares statements on it
+// again as needed, and the state is dropped, closing those statements,
+// before the connection is passed to Put. To the DB, and in its
+// events, Get opens a connection and Put closes it. Since the DB keeps
+// nothing for the connections the pool holds, the pool may close its
+// idle connections whenever it likes.
+
+// OpenWithPool 类似于 Open，但从 pool 而非 DB 内置的连接池中获取连接。内置连接池的
+// 设置，例如 SetMaxIdleConns、SetMaxOpenConns、SetConnMaxLifetime、SetNonBlocking、
+// SetKeepAlive 和 SetCircuitBreaker，均不起作用；连接池会应用其自己的设置。
+//
+// DB 仅在持有某个连接期间为其保存状态，例如在其上准备的语句：Get 返回的每个连接
+// 对 DB 而言都是新的，DB 会按需在其上重新准备语句；在连接被传给 Put 之前，该状态会
+// 被丢弃，这些语句也随之关闭。对 DB 及其事件而言，Get 打开一个连接，Put 关闭一个
+// 连接。由于 DB 不为连接池所持有的连接保存任何内容，连接池可随时关闭其空闲连接。
+func OpenWithPool(driverName, dataSourceName string, pool Pool) (*DB, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("sql: nil Pool")
+	}
+	db, err := Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	db.pool = pool
+	return db, nil
+}
+
+// poolConnLocked gets a connection from db.pool, in a driverConn of
+// its own. The db.mu must be held; it is released.
+func (db *DB) poolConnLocked(ctx context.Context) (*driverConn, error) {
+	db.mu.Unlock()
+	ci, err := db.pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		db.pool.Put(ci, driver.ErrBadConn)
+		return nil, errDBClosed
+	}
+	dc := &driverConn{
+		db:         db,
+		createdAt:  nowFunc(),
+		ci:         ci,
+		generation: db.generation,
+		id:         db.newConnIDLocked(),
+	}
+	db.addDepLocked(dc, dc)
+	db.numOpen++
+	db.emitLocked(PoolEvent{Type: ConnectionOpened, Conn: dc.infoLocked()})
+	dc.checkoutLocked(contextTag(ctx))
+	return dc, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lifoPool is a minimal Pool that reuses the most recently returned
+// connection first.
+type lifoPool struct {
+	mu     sync.Mutex
+	idle   []driver.Conn
+	open   int
+	gets   int
+	bad    int
+	closed bool
+}
+
+func (p *lifoPool) Get(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gets++
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		return c, nil
+	}
+	c, err := fdriver.Open(fakeDBName)
+	if err != nil {
+		return nil, err
+	}
+	p.open++
+	return c, nil
+}
+
+func (p *lifoPool) Put(c driver.Conn, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || p.closed {
+		p.bad++
+		p.open--
+		c.Close()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+func (p *lifoPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		c.Close()
+		p.open--
+	}
+	p.idle = nil
+	return nil
+}
+
+func (p *lifoPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{OpenConnections: p.open}
+}
+
+func TestOpenWithPool(t *testing.T) {
+	pool := new(lifoPool)
+	db, err := OpenWithPool("test", fakeDBName, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string")
+	exec(t, db, "INSERT|t|name=?", "Alice")
+
+	var name string
+	if err := db.QueryRow("SELECT|t|name|").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Alice" {
+		t.Errorf("name = %q; want Alice", name)
+	}
+
+	// A statement is prepared again each time the pool hands out its
+	// single connection.
+	stmt, err := db.Prepare("SELECT|t|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := stmt.QueryRow().Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stmt.Close()
+
+	if got := db.Stats().OpenConnections; got != 1 {
+		t.Errorf("OpenConnections = %d; want 1", got)
+	}
+	if pool.gets < 5 {
+		t.Errorf("pool served %d connections; want at least 5", pool.gets)
+	}
+
+	closeDB(t, db)
+	if !pool.closed || pool.open != 0 {
+		t.Errorf("after DB.Close, pool closed = %v with %d open connections; want closed with 0", pool.closed, pool.open)
+	}
+}
+
+func TestOpenWithPoolBadConn(t *testing.T) {
+	pool := new(lifoPool)
+	db, err := OpenWithPool("test", fakeDBName, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hookRollbackErr = func() error { return driver.ErrBadConn }
+	defer func() { hookRollbackErr = nil }()
+	tx.Rollback()
+
+	if pool.bad != 1 || len(pool.idle) != 0 {
+		t.Errorf("pool got %d bad connections and holds %d idle; want 1 and 0", pool.bad, len(pool.idle))
+	}
+}
+
+// Tests that the DB keeps no state for the connections the pool holds,
+// so that a pool may close them itself.
+func TestOpenWithPoolConnState(t *testing.T) {
+	pool := new(lifoPool)
+	db, err := OpenWithPool("test", fakeDBName, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string")
+	stmt, err := db.Prepare("SELECT|t|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if len(pool.idle) != 1 {
+		t.Fatalf("pool holds %d idle connections; want 1", len(pool.idle))
+	}
+	fc := pool.idle[0].(*fakeConn)
+	fc.mu.Lock()
+	made, closed := fc.stmtsMade, fc.stmtsClosed
+	fc.mu.Unlock()
+	if made == 0 || made != closed {
+		t.Errorf("idle connection has %d statements prepared and %d closed; want all closed", made, closed)
+	}
+	db.mu.Lock()
+	numOpen := db.numOpen
+	var conns int
+	for x := range db.dep {
+		if _, ok := x.(*driverConn); ok {
+			conns++
+		}
+	}
+	db.mu.Unlock()
+	if numOpen != 0 || conns != 0 {
+		t.Errorf("DB counts %d open connections and has dependencies on %d; want 0 and 0", numOpen, conns)
+	}
+}
+
+// statsPool is a lifoPool whose Stats calls back into the DB.
+type statsPool struct {
+	lifoPool
+	db *DB
+}
+
+func (p *statsPool) Stats() PoolStats {
+	p.db.Healthy()
+	return p.lifoPool.Stats()
+}
+
+func TestOpenWithPoolStats(t *testing.T) {
+	pool := new(statsPool)
+	db, err := OpenWithPool("test", fakeDBName, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	pool.db = db
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan DBStats, 2)
+	go func() {
+		done <- db.Stats()
+		done <- db.ResetStats()
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case st := <-done:
+			if st.OpenConnections != 1 {
+				t.Errorf("OpenConnections = %d; want 1", st.OpenConnections)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Stats deadlocked on a pool calling back into the DB")
+		}
+	}
+}
+
+func TestOpenWithPoolNil(t *testing.T) {
+	if _, err := OpenWithPool("test", fakeDBName, nil); err == nil {
+		t.Error("OpenWithPool with nil Pool succeeded")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Per-query options.
+
+// 针对单个查询的选项。
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// QueryOptions holds settings that QueryWithOptions applies to a
+// single query. The zero value leaves the driver's and the DB's
+// defaults.
+
+// QueryOptions 包含 QueryWithOptions 针对单个查询所应用的设置。
+// 其零值会保留驱动及 DB 的默认设置。
+type QueryOptions struct {
+	// FetchSize is the number of rows to fetch from the server per
+	// round trip, if the driver supports it; see driver.FetchSizer.
+	// A large value suits big scans, and a small one cheap lookups.
+	// Zero means the driver's default.
+	FetchSize int
+
+	// MaxRows is the maximum number of rows the query may return,
+	// enforced while iterating as described for SetMaxRows. Zero
+	// means the limit set by SetMaxRows; a negative value means no
+	// limit.
+	MaxRows int
+
+	// FreshConn runs the query on a newly opened connection rather
+	// than an idle one, as an escape from connection session state,
+	// such as variables or temporary tables, left by earlier use. At
+	// the MaxOpenConns limit, an idle connection is closed to make
+	// room for it.
+	FreshConn bool
+
+	// DiscardConn closes the query's connection once the query is
+	// done, rather than returning it to the idle pool.
+	DiscardConn bool
+}
+
+// QueryWithOptions is like QueryContext but applies opts to the
+// connection the query runs on, for the duration of the query. Options
+// the driver's connection doesn't support are ignored. The connection
+// is set back to the driver's defaults before it is reused.
+
+// QueryWithOptions 类似于 QueryContext，但会在查询期间将 opts 应用于执行该查询的
+// 连接。驱动连接不支持的选项会被忽略。该连接在被重用之前会恢复为驱动的默认设置。
+func (db *DB) QueryWithOptions(ctx context.Context, opts QueryOptions, query string, args ...interface{}) (*Rows, error) {
+	if opts == (QueryOptions{}) {
+		return db.QueryContext(ctx, query, args...)
+	}
+	rows, err := db.queryOptionsRetry(ctx, opts, query, args)
+	if err != nil {
+		return nil, db.handleErr("Query", query, err)
+	}
+	rows.maxRows = int64(opts.MaxRows)
+	rows.watchContext(ctx)
+	return rows, nil
+}
+
+func (db *DB) queryOptionsRetry(ctx context.Context, opts QueryOptions, query string, args []interface{}) (*Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := db.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	query = db.maybeRebind(query)
+	var rows *Rows
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		rows, err = db.queryOptions(ctx, opts, query, args, cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		return db.queryOptions(ctx, opts, query, args, alwaysNewConn)
+	}
+	return rows, err
+}
+
+func (db *DB) queryOptions(ctx context.Context, opts QueryOptions, query string, args []interface{}, strategy connReuseStrategy) (*Rows, error) {
+	var dc *driverConn
+	var err error
+	if opts.FreshConn {
+		dc, err = db.freshConn(ctx)
+	} else {
+		dc, err = db.connContext(ctx, strategy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	release := dc.releaseConn
+	if opts.DiscardConn {
+		release = func(error) {
+			dc.releaseConn(driver.ErrBadConn)
+		}
+	}
+	fs, ok := dc.ci.(driver.FetchSizer)
+	if !ok || opts.FetchSize <= 0 {
+		return db.queryConn(dc, release, query, args)
+	}
+	dc.Lock()
+	err = fs.SetFetchSize(opts.FetchSize)
+	dc.Unlock()
+	if err != nil {
+		release(err)
+		return nil, err
+	}
+	releaseConn := func(err error) {
+		dc.Lock()
+		rerr := fs.SetFetchSize(0)
+		dc.Unlock()
+		if rerr != nil && err == nil {
+			// Don't hand out a connection with a leftover setting.
+			err = driver.ErrBadConn
+		}
+		release(err)
+	}
+	return db.queryConn(dc, releaseConn, query, args)
+}
+
+// freshConn is like connContext, but only returns a connection that
+// was never handed out before. At the MaxOpenConns limit, it closes an
+// idle connection to make room, and so it does with reused connections
+// it is handed while waiting.
+func (db *DB) freshConn(ctx context.Context) (*driverConn, error) {
+	db.mu.Lock()
+	var idle *driverConn
+	if n := len(db.freeConn); db.pool == nil && db.maxOpen > 0 && db.numOpen >= db.maxOpen && n > 0 {
+		idle = db.freeConn[n-1]
+		db.freeConn = db.freeConn[:n-1]
+	}
+	db.mu.Unlock()
+	if idle != nil {
+		idle.Close()
+	}
+	for {
+		dc, err := db.connContext(ctx, alwaysNewConn)
+		if err != nil {
+			return nil, err
+		}
+		db.mu.Lock()
+		reused := dc.checkouts > 1
+		db.mu.Unlock()
+		if !reused {
+			return dc, nil
+		}
+		db.putConn(dc, driver.ErrBadConn)
+	}
+}
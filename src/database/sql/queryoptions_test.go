@@ -0,0 +1,194 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryWithOptions(t *testing.T) {
+	db, err := Open("test", fakeDBName+";fetchSize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|people|name=string,age=int32")
+	exec(t, db, "INSERT|people|name=Alice,age=1")
+	ctx := context.Background()
+
+	rows, err := db.QueryWithOptions(ctx, QueryOptions{FetchSize: 500}, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := rows.dc.ci.(fetchSizeFakeConn).fakeConn
+	fetchSize := func() int {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		return fc.fetchSize
+	}
+	if n := fetchSize(); n != 500 {
+		t.Errorf("fetch size during query = %d; want 500", n)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetchSize(); n != 0 {
+		t.Errorf("fetch size after query = %d; want 0", n)
+	}
+
+	// The connection is reused, with the driver's default.
+	rows, err = db.QueryWithOptions(ctx, QueryOptions{}, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.dc.ci.(fetchSizeFakeConn).fakeConn != fc {
+		t.Error("connection not reused")
+	}
+	if n := fetchSize(); n != 0 {
+		t.Errorf("fetch size without options = %d; want 0", n)
+	}
+	rows.Close()
+}
+
+func TestQueryWithOptionsUnsupported(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var n int
+	rows, err := db.QueryWithOptions(context.Background(), QueryOptions{FetchSize: 10}, "SELECT|people|age|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d rows; want 3", n)
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	count := func(rows *Rows, err error) (int, error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		return n, rows.Err()
+	}
+
+	db.SetMaxRows(2)
+	if n, err := count(db.Query("SELECT|people|name|")); n != 2 || err != ErrTooManyRows {
+		t.Errorf("with SetMaxRows(2), iterated %d rows, err %v; want 2, ErrTooManyRows", n, err)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use after ErrTooManyRows; want 0", n)
+	}
+	// Exactly as many rows as allowed is fine.
+	if n, err := count(db.Query("SELECT|people|name|age=?", 1)); n != 1 || err != nil {
+		t.Errorf("one row under SetMaxRows(2) = %d, %v; want 1, nil", n, err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT|people|name|age=?", 3).Scan(&name); err != nil || name != "Chris" {
+		t.Errorf("QueryRow under SetMaxRows(2) = %q, %v; want Chris, nil", name, err)
+	}
+
+	// QueryOptions.MaxRows overrides the DB's limit.
+	if n, err := count(db.QueryWithOptions(ctx, QueryOptions{MaxRows: 1}, "SELECT|people|name|")); n != 1 || err != ErrTooManyRows {
+		t.Errorf("with MaxRows 1, iterated %d rows, err %v; want 1, ErrTooManyRows", n, err)
+	}
+	if n, err := count(db.QueryWithOptions(ctx, QueryOptions{MaxRows: 3}, "SELECT|people|name|")); n != 3 || err != nil {
+		t.Errorf("with MaxRows 3, iterated %d rows, err %v; want 3, nil", n, err)
+	}
+	if n, err := count(db.QueryWithOptions(ctx, QueryOptions{MaxRows: -1}, "SELECT|people|name|")); n != 3 || err != nil {
+		t.Errorf("with MaxRows -1, iterated %d rows, err %v; want 3, nil", n, err)
+	}
+
+	db.SetMaxRows(0)
+	if n, err := count(db.Query("SELECT|people|name|")); n != 3 || err != nil {
+		t.Errorf("with no limit, iterated %d rows, err %v; want 3, nil", n, err)
+	}
+}
+
+func TestQueryWithOptionsFreshConn(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	idle := func() []*driverConn {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return append([]*driverConn(nil), db.freeConn...)
+	}
+	contains := func(dcs []*driverConn, dc *driverConn) bool {
+		for _, c := range dcs {
+			if c == dc {
+				return true
+			}
+		}
+		return false
+	}
+	query := func(opts QueryOptions) *driverConn {
+		before := idle()
+		if len(before) == 0 {
+			t.Fatal("no idle connection before the query")
+		}
+		rows, err := db.QueryWithOptions(ctx, opts, "SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dc := rows.dc
+		if contains(before, dc) {
+			t.Errorf("%+v: query ran on a connection from the idle pool", opts)
+		}
+		for rows.Next() {
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return dc
+	}
+
+	dc := query(QueryOptions{FreshConn: true})
+	if !contains(idle(), dc) {
+		t.Error("fresh connection was not returned to the pool")
+	}
+	dc = query(QueryOptions{FreshConn: true, DiscardConn: true})
+	if contains(idle(), dc) {
+		t.Error("discarded connection was returned to the pool")
+	}
+	dc.Lock()
+	closed := dc.closed
+	dc.Unlock()
+	if !closed {
+		t.Error("discarded connection is not closed")
+	}
+
+	// At the MaxOpenConns limit, the idle connection makes room.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	opens0 := db.driver.(*fakeDriver).openCount
+	query(QueryOptions{FreshConn: true})
+	if n := db.driver.(*fakeDriver).openCount - opens0; n != 1 {
+		t.Errorf("opened %d connections; want 1", n)
+	}
+	if n := db.Stats().OpenConnections; n != 1 {
+		t.Errorf("%d open connections; want 1", n)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Read-only mode.
+
+// 只读模式。
+
+package sql
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrReadOnly is returned for statements rejected because the DB is in
+// read-only mode.
+
+// ErrReadOnly 会在 DB 处于只读模式而拒绝语句时返回。
+var ErrReadOnly = errors.New("sql: write statement rejected by read-only database")
+
+// writeKeywords are the leading keywords of statements that modify
+// data or schema.
+var writeKeywords = map[string]bool{
+	"ALTER":    true,
+	"CREATE":   true,
+	"DELETE":   true,
+	"DROP":     true,
+	"GRANT":    true,
+	"INSERT":   true,
+	"MERGE":    true,
+	"RENAME":   true,
+	"REPLACE":  true,
+	"REVOKE":   true,
+	"TRUNCATE": true,
+	"UPDATE":   true,
+	"UPSERT":   true,
+}
+
+// SetReadOnly sets whether the database rejects statements that write.
+// While on, Exec, Query, QueryRow and Prepare, on the DB, its
+// transactions and its statements, return ErrReadOnly for statements
+// whose first keyword, after any leading whitespace and comments, is
+// one of INSERT, UPDATE, DELETE, MERGE, REPLACE, UPSERT, CREATE, ALTER,
+// DROP, TRUNCATE, RENAME, GRANT or REVOKE. CopyFrom is rejected too.
+// The default is false.
+//
+// The check only inspects the text of the statement and is a guardrail
+// against accidental writes, not a security boundary: writes hidden in
+// common table expressions, stored procedures or functions with side
+// effects are not detected. Use a database account without write
+// privileges where writes must be impossible.
+
+// SetReadOnly 设置数据库是否拒绝写入语句。开启后，对于在开头的空白和注释之后，
+// 第一个关键字为 INSERT、UPDATE、DELETE、MERGE、REPLACE、UPSERT、CREATE、
+// ALTER、DROP、TRUNCATE、RENAME、GRANT 或 REVOKE 之一的语句，DB、其事务及其语句的
+// Exec、Query、QueryRow 和 Prepare 会返回 ErrReadOnly。CopyFrom 同样会被拒绝。
+// 默认为 false。
+//
+// 该检查只检视语句的文本，它是防止意外写入的护栏，而非安全边界：隐藏在公共表表达式、
+// 存储过程或具有副作用的函数中的写入不会被检测到。在必须杜绝写入的地方，
+// 请使用没有写权限的数据库账号。
+func (db *DB) SetReadOnly(on bool) {
+	db.mu.Lock()
+	db.readOnly = on
+	db.mu.Unlock()
+}
+
+// checkReadOnly returns ErrReadOnly if the DB is in read-only mode and
+// query looks like a write.
+func (db *DB) checkReadOnly(query string) error {
+	db.mu.Lock()
+	on := db.readOnly
+	db.mu.Unlock()
+	if on && isWriteStatement(query) {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// isWriteStatement reports whether the first keyword of query, skipping
+// whitespace and comments, starts a statement that writes.
+func isWriteStatement(query string) bool {
+	i := 0
+	for i < len(query) {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == ';' || c == '(':
+			i++
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i += 2; i < len(query) && query[i] != '\n'; i++ {
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			for i += 2; i < len(query) && !(query[i] == '*' && i+1 < len(query) && query[i+1] == '/'); i++ {
+			}
+			i += 2
+		default:
+			j := i
+			for j < len(query) && isLetter(query[j]) {
+				j++
+			}
+			return writeKeywords[strings.ToUpper(query[i:j])]
+		}
+	}
+	return false
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import "testing"
+
+func TestIsWriteStatement(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT 1", false},
+		{"select * from t where name = 'INSERT'", false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", false},
+		{"", false},
+		{"  -- just a comment", false},
+		{"INSERT INTO t VALUES (1)", true},
+		{"insert into t values (1)", true},
+		{"  \n\tUpdate t SET a = 1", true},
+		{"-- remove old rows\nDELETE FROM t", true},
+		{"/* multi\nline */ DROP TABLE t", true},
+		{"/* a */ -- b\n /* c */ truncate t", true},
+		{"; CREATE TABLE t (a int)", true},
+		{"(INSERT INTO t VALUES (1))", true},
+		{"ALTER TABLE t ADD b int", true},
+		{"INSERT|people|name=?", true},
+		{"SELECT|people|name|", false},
+		{"INSERTED", false},
+	}
+	for _, tt := range tests {
+		if got := isWriteStatement(tt.query); got != tt.want {
+			t.Errorf("isWriteStatement(%q) = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	stmt, err := db.Prepare("INSERT|people|name=?,age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	db.SetReadOnly(true)
+
+	if _, err := db.Exec("INSERT|people|name=Dave,age=?", 4); err != ErrReadOnly {
+		t.Errorf("Exec error = %v; want ErrReadOnly", err)
+	}
+	if _, err := db.Prepare("INSERT|people|name=?"); err != ErrReadOnly {
+		t.Errorf("Prepare error = %v; want ErrReadOnly", err)
+	}
+	if _, err := stmt.Exec("Dave", 4); err != ErrReadOnly {
+		t.Errorf("Stmt.Exec error = %v; want ErrReadOnly", err)
+	}
+	if _, err := db.Query("/* no */ CREATE|t|name=string"); err != ErrReadOnly {
+		t.Errorf("Query error = %v; want ErrReadOnly", err)
+	}
+
+	var age int
+	if err := db.QueryRow("SELECT|people|age|name=?", "Alice").Scan(&age); err != nil {
+		t.Fatalf("QueryRow: %v", err)
+	}
+	if age != 1 {
+		t.Errorf("age = %d; want 1", age)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Dave,age=?", 4); err != ErrReadOnly {
+		t.Errorf("Tx.Exec error = %v; want ErrReadOnly", err)
+	}
+	if _, err := tx.Stmt(stmt).Exec("Dave", 4); err != ErrReadOnly {
+		t.Errorf("Tx.Stmt Exec error = %v; want ErrReadOnly", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetReadOnly(false)
+	if _, err := stmt.Exec("Dave", 4); err != nil {
+		t.Errorf("Stmt.Exec after SetReadOnly(false): %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Recycling the connection pool.
+
+// 回收连接池。
+
+package sql
+
+// Reset replaces every connection in the pool, for example after
+// rotating credentials or a schema change that requires fresh
+// sessions. Idle connections are closed immediately, and connections
+// in use, by transactions, statements being executed and open Rows,
+// are closed when they are returned instead of going back to the pool.
+// Operations started after Reset get newly opened connections. Unlike
+// Close, Reset keeps the DB usable, with its settings.
+//
+// With a Pool from OpenWithPool, Reset replaces the connections in
+// use, which are passed back to the pool as bad. The connections the
+// pool holds idle are its own, and it must replace them itself.
+//
+// Reset returns an error only if the DB is closed.
+
+// Reset 替换连接池中的每一个连接，例如在轮换凭据或进行需要全新会话的模式变更之后。
+// 空闲连接会被立即关闭；正在使用中的连接（被事务、正在执行的语句以及打开的 Rows
+// 占用的连接）会在归还时被关闭，而不会回到连接池中。在 Reset 之后开始的操作会获得
+// 新打开的连接。与 Close 不同，Reset 会保持 DB 及其设置可用。
+//
+// 对于来自 OpenWithPool 的 Pool，Reset 会替换正在使用中的连接，它们会作为坏连接
+// 被交还给连接池。连接池所持有的空闲连接属于连接池自身，需由它自行替换。
+//
+// 仅当 DB 已关闭时，Reset 才会返回错误。
+func (db *DB) Reset() error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return errDBClosed
+	}
+	db.generation++
+	closing := db.freeConn
+	db.freeConn = nil
+	db.mu.Unlock()
+	for _, dc := range closing {
+		dc.Close()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import "testing"
+
+func TestReset(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	// One connection idle and one in use by a transaction.
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	old := map[*driverConn]bool{tx.dc: true}
+	db.mu.Lock()
+	for _, dc := range db.freeConn {
+		old[dc] = true
+	}
+	db.mu.Unlock()
+	if len(old) != 2 {
+		t.Fatalf("%d connections before Reset; want 2", len(old))
+	}
+
+	if err := db.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.numFreeConns(); n != 0 {
+		t.Errorf("%d free connections after Reset; want 0", n)
+	}
+	// The transaction keeps working on its connection.
+	var name string
+	if err := tx.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if n := db.numFreeConns(); n != 0 {
+		t.Errorf("%d free connections after returning an old one; want 0", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		dc, err := db.conn(cachedOrNewConn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if old[dc] {
+			t.Fatal("connection from before Reset reused")
+		}
+		db.putConn(dc, nil)
+	}
+	for dc := range old {
+		if !dc.closed {
+			t.Error("connection from before Reset not closed")
+		}
+	}
+
+	closeDB(t, db)
+	if err := db.Reset(); err == nil {
+		t.Error("Reset of closed DB succeeded")
+	}
+}
+
+func TestResetWithPool(t *testing.T) {
+	pool := new(lifoPool)
+	db, err := OpenWithPool("test", fakeDBName, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Reset()
+	tx.Rollback()
+	if pool.bad != 1 || len(pool.idle) != 0 {
+		t.Errorf("after Reset, pool got %d bad connections and holds %d idle; want 1 and 0", pool.bad, len(pool.idle))
+	}
+}
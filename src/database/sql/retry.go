@@ -0,0 +1,62 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// maxRunTxAttempts is the number of times RunTx runs a transaction
+// that keeps failing with retryable errors.
+const maxRunTxAttempts = 5
+
+// IsRetryable reports whether err marks a transient conflict with
+// other transactions, such as a serialization failure or a deadlock,
+// after which running the whole transaction again may succeed. It
+// holds for errors the driver marks so by implementing
+// driver.RetryableError, and for errors wrapping them, such as an
+// *ArgError. Other errors are not retryable.
+
+// IsRetryable 报告 err 是否表示与其他事务之间的暂时冲突，例如序列化失败或死锁，
+// 在此之后再次运行整个事务可能会成功。对于驱动通过实现 driver.RetryableError
+// 而如此标记的错误，以及包装了这些错误的错误（例如 *ArgError），它都成立。
+// 其他错误均不可重试。
+func IsRetryable(err error) bool {
+	for err != nil {
+		if r, ok := err.(driver.RetryableError); ok {
+			return r.Retryable()
+		}
+		u, ok := err.(interface {
+			Unwrap() error
+		})
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
+
+// RunTx is like WithTx, but runs the transaction again, in a new
+// transaction, if it fails with an error for which IsRetryable
+// reports true. fn must therefore be safe to run more than once. RunTx
+// gives up after 5 attempts, returning the last error, and stops as
+// soon as ctx is done. With a driver that marks no errors retryable,
+// RunTx is the same as WithTx.
+
+// RunTx 类似于 WithTx，但若事务因 IsRetryable 报告为 true 的错误而失败，它会在一个
+// 新的事务中再次运行该事务。因此 fn 必须能够安全地多次运行。RunTx 在尝试 5 次后放弃，
+// 并返回最后一个错误；一旦 ctx 结束，它便会停止。若驱动未将任何错误标记为可重试，
+// RunTx 与 WithTx 相同。
+func (db *DB) RunTx(ctx context.Context, fn func(*Tx) error) error {
+	var err error
+	for i := 0; i < maxRunTxAttempts; i++ {
+		if err = db.WithTx(ctx, fn); !IsRetryable(err) {
+			break
+		}
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{ErrTxDone, false},
+		{fakeConflictError{}, true},
+		{&ArgError{Err: fakeConflictError{}}, true},
+		{&ArgError{Err: errors.New("boom")}, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRunTx(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	defer func() { hookCommitErr = nil }()
+	ctx := context.Background()
+
+	// Two serialization failures, then success.
+	conflicts := 2
+	hookCommitErr = func() error {
+		if conflicts > 0 {
+			conflicts--
+			return fakeConflictError{}
+		}
+		return nil
+	}
+	calls := 0
+	err := db.RunTx(ctx, func(tx *Tx) error {
+		calls++
+		_, err := tx.Exec("INSERT|people|name=Dave,age=?", 4)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("fn ran %d times; want 3", calls)
+	}
+
+	// Errors that aren't retryable end RunTx at once.
+	boom := errors.New("boom")
+	hookCommitErr = func() error { return boom }
+	calls = 0
+	if err := db.RunTx(ctx, func(*Tx) error { calls++; return nil }); err != boom || calls != 1 {
+		t.Errorf("RunTx = %v after %d calls; want %v after 1", err, calls, boom)
+	}
+	hookCommitErr = nil
+	calls = 0
+	if err := db.RunTx(ctx, func(*Tx) error { calls++; return boom }); err != boom || calls != 1 {
+		t.Errorf("RunTx = %v after %d calls; want %v after 1", err, calls, boom)
+	}
+
+	// A retryable error from fn is retried too, up to the limit.
+	calls = 0
+	err = db.RunTx(ctx, func(*Tx) error { calls++; return fakeConflictError{} })
+	if !IsRetryable(err) || calls != maxRunTxAttempts {
+		t.Errorf("RunTx = %v after %d calls; want a retryable error after %d", err, calls, maxRunTxAttempts)
+	}
+
+	// Cancellation stops the retries.
+	cctx, cancel := context.WithCancel(ctx)
+	calls = 0
+	err = db.RunTx(cctx, func(*Tx) error {
+		calls++
+		cancel()
+		return fakeConflictError{}
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Errorf("RunTx = %v after %d calls; want %v after 1", err, calls, context.Canceled)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use; want 0", n)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import "testing"
+
+func TestExecReturning(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.ExecReturning("INSERT|people|name=Dave,age=?", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rows.RowsAffected(); err == nil {
+		t.Error("RowsAffected before reading the rows succeeded")
+	}
+	var (
+		name string
+		age  int
+		n    int
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &age); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || name != "Dave" || age != 4 {
+		t.Errorf("returned %d rows, last %q, %d; want 1 row Dave, 4", n, name, age)
+	}
+	if affected, err := rows.RowsAffected(); err != nil || affected != 1 {
+		t.Errorf("RowsAffected = %d, %v; want 1, nil", affected, err)
+	}
+
+	if err := db.QueryRow("SELECT|people|age|name=?", "Dave").Scan(&age); err != nil || age != 4 {
+		t.Errorf("inserted row: age = %d, %v; want 4", age, err)
+	}
+}
+
+// Without driver.RowsAffecter, RowsAffected counts the rows read.
+func TestRowsAffectedCountsRows(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if affected, err := rows.RowsAffected(); err != nil || affected != 3 {
+		t.Errorf("RowsAffected = %d, %v; want 3, nil", affected, err)
+	}
+
+	rows, err = db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Next()
+	rows.Close()
+	if _, err := rows.RowsAffected(); err == nil {
+		t.Error("RowsAffected after closing early succeeded")
+	}
+}
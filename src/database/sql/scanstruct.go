@@ -0,0 +1,172 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ScanStruct copies the columns in the current row into the fields of
+// the struct pointed to by dest. Each column is stored in the exported
+// field whose "sql" tag names the column or, for fields without a tag,
+// whose name matches the column's, ignoring case. Fields tagged
+// `sql:"-"` are never used. Every column must have a field, but fields
+// without a column are left unchanged.
+//
+// A field whose address implements Scanner, such as a NullString, is
+// given the value from the driver through its Scan method. Other
+// fields are converted as by Scan.
+//
+// Columns also map to the fields of nested structs. The exported
+// fields of an untagged embedded struct are treated as fields of the
+// outer struct, unless the outer struct has a field for the column
+// itself. A column that no field matches may name a field of a struct
+// field, prefixed by the struct field's tag or name and the separator
+// set by SetStructSeparator, "_" by default: column "address_city"
+// maps to User.Address.City, and with the tag `sql:"addr"` on
+// Address, column "addr_city" does. As for other fields, the prefix of
+// an untagged field matches ignoring case. An embedded struct with a
+// tag is treated like a named one. Nesting may be any number of levels
+// deep.
+
+// ScanStruct 将当前行的列复制到 dest 所指向的结构体的字段中。每一列都会被存储到
+// 其 "sql" 标签为该列名的导出字段中；对于没有标签的字段，则存储到名字与列名匹配
+// （忽略大小写）的字段中。带有 `sql:"-"` 标签的字段永远不会被使用。每一列都必须有
+// 对应的字段，而没有对应列的字段会保持不变。
+//
+// 若某字段的地址实现了 Scanner（例如 NullString），驱动提供的值会通过其 Scan
+// 方法传给它。其它字段会像 Scan 那样进行转换。
+//
+// 列也会映射到嵌套结构体的字段上。没有标签的嵌入结构体，其导出字段会被当作外层结构体
+// 的字段，除非外层结构体本身就有与该列对应的字段。没有任何字段匹配的列，可以指定某个
+// 结构体字段中的字段，此时列名以该结构体字段的标签或名字，加上由 SetStructSeparator
+// 设置的分隔符（默认为 "_"）作为前缀：列 "address_city" 映射到 User.Address.City；
+// 若 Address 带有标签 `sql:"addr"`，则由列 "addr_city" 映射到它。与其它字段一样，
+// 没有标签的字段的前缀在匹配时忽略大小写。带有标签的嵌入结构体会被视为具名的结构体字段。
+// 嵌套的层数不受限制。
+func (rs *Rows) ScanStruct(dest interface{}) error {
+	if rs.closed {
+		return errors.New("sql: Rows are closed")
+	}
+	if rs.lastcols == nil {
+		return errors.New("sql: Scan called without calling Next")
+	}
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("sql: ScanStruct destination must be a non-nil pointer to a struct, not %T", dest)
+	}
+	if err := rs.markScanned(); err != nil {
+		return err
+	}
+	db := rs.dc.db
+	db.mu.Lock()
+	sep := db.structSep
+	db.mu.Unlock()
+	sv := dv.Elem()
+	for i, col := range rs.rowsi.Columns() {
+		f, ok := fieldForColumn(sv, col, sep)
+		if !ok {
+			return fmt.Errorf("sql: ScanStruct: no field of %s for column %q", sv.Type(), col)
+		}
+		fp := f.Addr().Interface()
+		v, err := rs.columnValue(fp, i)
+		if err == nil {
+			if scanner, ok := fp.(Scanner); ok {
+				err = scanner.Scan(v)
+			} else {
+				err = rs.assign(fp, v)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("sql: Scan error on column %q: %v", col, err)
+		}
+	}
+	return nil
+}
+
+// SetStructSeparator sets the separator between the prefix naming a
+// struct field and the rest of a column name, with which ScanStruct
+// maps columns to the fields of nested structs. The default is "_";
+// "." suits queries that alias columns as "address.city". An empty
+// separator maps no columns to nested structs, other than through
+// embedded ones.
+
+// SetStructSeparator 设置 ScanStruct 将列映射到嵌套结构体字段时所用的分隔符，它位于
+// 指明结构体字段的前缀与列名其余部分之间。默认为 "_"；"." 适用于将列别名为
+// "address.city" 的查询。若分隔符为空，则除了通过嵌入结构体之外，不会有任何列被映射到
+// 嵌套结构体上。
+func (db *DB) SetStructSeparator(sep string) {
+	db.mu.Lock()
+	db.structSep = sep
+	db.mu.Unlock()
+}
+
+// fieldForColumn returns the field of the struct v that receives the
+// column named col, looking into nested structs as described for
+// ScanStruct, with sep as the separator.
+func fieldForColumn(v reflect.Value, col, sep string) (reflect.Value, bool) {
+	t := v.Type()
+	match := -1
+	var embedded, nested []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("sql")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			// Its exported fields are promoted, even if the
+			// embedded type itself is unexported.
+			embedded = append(embedded, i)
+			continue
+		}
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		switch {
+		case tag != "":
+			if tag == col {
+				return v.Field(i), true
+			}
+		case match < 0 && strings.EqualFold(f.Name, col):
+			match = i
+		}
+		if f.Type.Kind() == reflect.Struct {
+			nested = append(nested, i)
+		}
+	}
+	if match >= 0 {
+		return v.Field(match), true
+	}
+	for _, i := range embedded {
+		if f, ok := fieldForColumn(v.Field(i), col, sep); ok {
+			return f, true
+		}
+	}
+	if sep == "" {
+		return reflect.Value{}, false
+	}
+	for _, i := range nested {
+		f := t.Field(i)
+		prefix, fold := f.Tag.Get("sql"), false
+		if prefix == "" {
+			prefix, fold = f.Name, true
+		}
+		n := len(prefix) + len(sep)
+		if len(col) <= n || col[len(prefix):n] != sep {
+			continue
+		}
+		if p := col[:len(prefix)]; p != prefix && !(fold && strings.EqualFold(p, prefix)) {
+			continue
+		}
+		if f, ok := fieldForColumn(v.Field(i), col[n:], sep); ok {
+			return f, true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,192 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upperScanner is a custom Scanner that records NULLs and upper-cases
+// other values.
+type upperScanner struct {
+	s    string
+	null bool
+}
+
+func (u *upperScanner) Scan(src interface{}) error {
+	if src == nil {
+		u.s, u.null = "", true
+		return nil
+	}
+	u.s, u.null = strings.ToUpper(fmt.Sprintf("%s", src)), false
+	return nil
+}
+
+type scanStructPerson struct {
+	Name     string
+	Nick     NullString
+	Born     NullTime `sql:"bdate"`
+	Motto    upperScanner
+	Ignored  string `sql:"-"`
+	Unmapped int
+}
+
+func TestScanStruct(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|name=string,nick=nullstring,bdate=datetime,motto=nullstring")
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	exec(t, db, "INSERT|t|name=?,nick=?,bdate=?,motto=?", "alice", "Al", born, "carpe diem")
+	exec(t, db, "INSERT|t|name=?,nick=?,bdate=?,motto=?", "bob", nil, nil, nil)
+
+	rows, err := db.Query("SELECT|t|name,nick,bdate,motto|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []scanStructPerson
+	for rows.Next() {
+		// Start from non-zero values to check they are reset.
+		p := scanStructPerson{
+			Nick:     NullString{"stale", true},
+			Born:     NullTime{born, true},
+			Motto:    upperScanner{"STALE", false},
+			Unmapped: 7,
+		}
+		if err := rows.ScanStruct(&p); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []scanStructPerson{
+		{Name: "alice", Nick: NullString{"Al", true}, Born: NullTime{born, true}, Motto: upperScanner{"CARPE DIEM", false}, Unmapped: 7},
+		{Name: "bob", Motto: upperScanner{"", true}, Unmapped: 7},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestScanStructErrors(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|name,age|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	var p scanStructPerson
+	if err := rows.ScanStruct(&p); err == nil || !strings.Contains(err.Error(), `no field of sql.scanStructPerson for column "age"`) {
+		t.Errorf("ScanStruct with unmapped column = %v", err)
+	}
+	var name string
+	if err := rows.ScanStruct(&name); err == nil {
+		t.Error("ScanStruct into *string succeeded")
+	}
+	var age struct {
+		Name string
+		Age  time.Time
+	}
+	if err := rows.ScanStruct(&age); err == nil || !strings.Contains(err.Error(), `column "age"`) {
+		t.Errorf("ScanStruct with unconvertible column = %v", err)
+	}
+}
+
+type scanStructAddress struct {
+	City string
+	Zip  string `sql:"postcode"`
+}
+
+type scanStructAudit struct {
+	Created string
+	Name    string // shadowed by scanStructUser.Name
+}
+
+type scanStructUser struct {
+	scanStructAudit
+	Name    string
+	Address scanStructAddress
+	Work    scanStructAddress `sql:"office"`
+}
+
+func TestScanStructNested(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|name=string,created=string,address_city=string,Address_postcode=string,office_city=string")
+	exec(t, db, "INSERT|t|name=alice,created=monday,address_city=Paris,Address_postcode=75001,office_city=Lyon")
+
+	rows, err := db.Query("SELECT|t|name,created,address_city,Address_postcode,office_city|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	var u scanStructUser
+	if err := rows.ScanStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	want := scanStructUser{
+		scanStructAudit: scanStructAudit{Created: "monday"},
+		Name:            "alice",
+		Address:         scanStructAddress{City: "Paris", Zip: "75001"},
+		Work:            scanStructAddress{City: "Lyon"},
+	}
+	if u != want {
+		t.Errorf("ScanStruct = %+v; want %+v", u, want)
+	}
+}
+
+func TestScanStructSeparator(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|name=string,address.city=string,address_city=string")
+	exec(t, db, "INSERT|t|name=bob,address.city=Rome,address_city=Oslo")
+
+	scan := func(cols string) (scanStructUser, error) {
+		rows, err := db.Query("SELECT|t|" + cols + "|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			t.Fatal("no rows")
+		}
+		var u scanStructUser
+		err = rows.ScanStruct(&u)
+		return u, err
+	}
+
+	db.SetStructSeparator(".")
+	if u, err := scan("name,address.city"); err != nil || u.Address.City != "Rome" {
+		t.Errorf("with separator \".\", ScanStruct = %+v, %v; want Address.City Rome", u, err)
+	}
+	if _, err := scan("address_city"); err == nil {
+		t.Error(`with separator ".", column "address_city" was mapped`)
+	}
+	db.SetStructSeparator("")
+	if _, err := scan("address.city"); err == nil {
+		t.Error("with no separator, column \"address.city\" was mapped")
+	}
+	if u, err := scan("name"); err != nil || u.Name != "bob" {
+		t.Errorf("with no separator, ScanStruct = %+v, %v; want Name bob", u, err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Single-value queries.
+
+// 单值查询。
+
+package sql
+
+import (
+	"context"
+	"time"
+)
+
+// QueryValue runs a query that is expected to return a single row
+// with a single column, and returns that column's value, as Scan would
+// store it in an *interface{}: one of the driver.Value types, with
+// []byte values copied. If no row matches the query,
+// QueryValue returns ErrNoRows. The query is bound to ctx as in
+// QueryContext.
+//
+// To get the value as a particular Go type, use QueryRow with one of
+// Row's ScanInt64, ScanFloat64, ScanString, ScanBool or ScanTime.
+
+// QueryValue 执行一个预期返回单行单列的查询，并返回该列的值，其形式与 Scan 将其
+// 存入 *interface{} 时相同：为 driver.Value 的类型之一，其中 []byte 值会被复制。
+// 若没有行满足查询条件，QueryValue 返回 ErrNoRows。该查询如 QueryContext 一样与
+// ctx 绑定。
+//
+// 要以特定的 Go 类型获取该值，请使用 QueryRow 及 Row 的 ScanInt64、ScanFloat64、
+// ScanString、ScanBool 或 ScanTime 之一。
+func (db *DB) QueryValue(ctx context.Context, query string, args ...interface{}) (interface{}, error) {
+	var v interface{}
+	err := db.queryRowContext(ctx, "QueryValue", query, args).Scan(&v)
+	return v, err
+}
+
+// Count runs a query, such as a SELECT COUNT(*), that is expected to
+// return a single row with a single integer column, and returns that
+// column's value. It returns ErrNoRows if no row matches the query,
+// and an error if the row doesn't have exactly one column or its value
+// doesn't convert to an int64 as for Scan. The query is bound to ctx as
+// in QueryContext.
+
+// Count 执行一个预期返回单行单个整数列的查询（例如 SELECT COUNT(*)），并返回该列的值。
+// 若没有行满足查询条件，它返回 ErrNoRows；若该行并非恰好有一列，或其值无法像 Scan
+// 那样转换为 int64，则返回错误。该查询如 QueryContext 一样与 ctx 绑定。
+func (db *DB) Count(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var n int64
+	err := db.queryRowContext(ctx, "Count", query, args).Scan(&n)
+	return n, err
+}
+
+// Sum is like Count but returns the column's value as a float64, for
+// queries such as a SELECT SUM(amount). A NULL value, which SUM yields
+// when it aggregates no rows, is returned as 0.
+
+// Sum 类似于 Count，但以 float64 返回该列的值，适用于诸如 SELECT SUM(amount) 的查询。
+// NULL 值（SUM 在没有聚合任何行时会产生它）会作为 0 返回。
+func (db *DB) Sum(ctx context.Context, query string, args ...interface{}) (float64, error) {
+	var f NullFloat64
+	err := db.queryRowContext(ctx, "Sum", query, args).Scan(&f)
+	return f.Float64, err
+}
+
+// ScanBool is like Count but returns the column's value as a bool, for
+// queries such as a SELECT EXISTS(...). A NULL value is an error.
+
+// ScanBool 类似于 Count，但以 bool 返回该列的值，适用于诸如 SELECT EXISTS(...)
+// 的查询。NULL 值会导致错误。
+func (db *DB) ScanBool(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	var b bool
+	err := db.queryRowContext(ctx, "ScanBool", query, args).Scan(&b)
+	return b, err
+}
+
+// queryRowContext is like QueryRow, reporting errors as from op, but
+// binds the query to ctx as in QueryContext.
+func (db *DB) queryRowContext(ctx context.Context, op, query string, args []interface{}) *Row {
+	var rows *Rows
+	err := ctx.Err()
+	if err == nil {
+		rows, err = db.queryRetry(ctx, query, args)
+	}
+	if err == nil {
+		rows.watchContext(ctx)
+	}
+	return db.newRow(op, query, rows, err)
+}
+
+// ScanInt64 scans the single column of the matched row into an int64
+// and returns it. It returns ErrNoRows if no row matches the query,
+// and an error if the row doesn't have exactly one column. The
+// conversion is that of Scan.
+
+// ScanInt64 将符合的行的唯一一列扫描到 int64 中并返回它。若没有行满足查询条件，
+// 它返回 ErrNoRows；若该行并非恰好有一列，则返回错误。其转换与 Scan 相同。
+func (r *Row) ScanInt64() (int64, error) {
+	var v int64
+	err := r.Scan(&v)
+	return v, err
+}
+
+// ScanFloat64 is like ScanInt64 but scans into a float64.
+
+// ScanFloat64 类似于 ScanInt64，但扫描到 float64 中。
+func (r *Row) ScanFloat64() (float64, error) {
+	var v float64
+	err := r.Scan(&v)
+	return v, err
+}
+
+// ScanString is like ScanInt64 but scans into a string.
+
+// ScanString 类似于 ScanInt64，但扫描到 string 中。
+func (r *Row) ScanString() (string, error) {
+	var v string
+	err := r.Scan(&v)
+	return v, err
+}
+
+// ScanBool is like ScanInt64 but scans into a bool.
+
+// ScanBool 类似于 ScanInt64，但扫描到 bool 中。
+func (r *Row) ScanBool() (bool, error) {
+	var v bool
+	err := r.Scan(&v)
+	return v, err
+}
+
+// ScanTime is like ScanInt64 but scans into a time.Time.
+
+// ScanTime 类似于 ScanInt64，但扫描到 time.Time 中。
+func (r *Row) ScanTime() (time.Time, error) {
+	var v time.Time
+	err := r.Scan(&v)
+	return v, err
+}
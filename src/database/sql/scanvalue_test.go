@@ -0,0 +1,117 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRowScanValue(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	age, err := db.QueryRow("SELECT|people|age|name=?", "Bob").ScanInt64()
+	if err != nil || age != 2 {
+		t.Errorf("ScanInt64 = %d, %v; want 2, nil", age, err)
+	}
+	name, err := db.QueryRow("SELECT|people|name|age=?", 3).ScanString()
+	if err != nil || name != "Chris" {
+		t.Errorf("ScanString = %q, %v; want Chris, nil", name, err)
+	}
+	dead, err := db.QueryRow("SELECT|people|dead|name=?", "Alice").ScanBool()
+	if err == nil {
+		t.Errorf("ScanBool of NULL = %v, nil; want error", dead)
+	}
+	bdate, err := db.QueryRow("SELECT|people|bdate|name=?", "Chris").ScanTime()
+	if err != nil || !bdate.Equal(chrisBirthday) {
+		t.Errorf("ScanTime = %v, %v; want %v, nil", bdate, err, chrisBirthday)
+	}
+
+	exec(t, db, "INSERT|people|name=Dave,age=?,dead=?", 4, true)
+	dead, err = db.QueryRow("SELECT|people|dead|name=?", "Dave").ScanBool()
+	if err != nil || !dead {
+		t.Errorf("ScanBool = %v, %v; want true, nil", dead, err)
+	}
+
+	if _, err := db.QueryRow("SELECT|people|age|name=?", "Nobody").ScanInt64(); err != ErrNoRows {
+		t.Errorf("ScanInt64 of no rows: err = %v; want ErrNoRows", err)
+	}
+	if _, err := db.QueryRow("SELECT|people|age,name|name=?", "Bob").ScanInt64(); err == nil {
+		t.Error("ScanInt64 of two columns succeeded")
+	}
+}
+
+func TestQueryValue(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	v, err := db.QueryValue(ctx, "SELECT|people|age|name=?", "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != int64(1) {
+		t.Errorf("QueryValue = %#v; want int64(1)", v)
+	}
+	v, err = db.QueryValue(ctx, "SELECT|people|name|age=?", 2)
+	if b, ok := v.([]byte); err != nil || !ok || string(b) != "Bob" {
+		t.Errorf("QueryValue = %#v, %v; want []byte(\"Bob\"), nil", v, err)
+	}
+
+	if _, err := db.QueryValue(ctx, "SELECT|people|age|name=?", "Nobody"); err != ErrNoRows {
+		t.Errorf("QueryValue of no rows: err = %v; want ErrNoRows", err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := db.QueryValue(cctx, "SELECT|people|age|name=?", "Alice"); err != context.Canceled {
+		t.Errorf("QueryValue with done context: err = %v; want context.Canceled", err)
+	}
+}
+
+func TestAggregateValues(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	n, err := db.Count(ctx, "SELECT|people|age|name=?", "Chris")
+	if err != nil || n != 3 {
+		t.Errorf("Count = %d, %v; want 3, nil", n, err)
+	}
+	if _, err := db.Count(ctx, "SELECT|people|age|name=?", "Nobody"); err != ErrNoRows {
+		t.Errorf("Count of no rows: err = %v; want ErrNoRows", err)
+	}
+	if _, err := db.Count(ctx, "SELECT|people|age,name|name=?", "Chris"); err == nil {
+		t.Error("Count of two columns succeeded")
+	}
+
+	sum, err := db.Sum(ctx, "SELECT|people|age|name=?", "Bob")
+	if err != nil || sum != 2 {
+		t.Errorf("Sum = %v, %v; want 2, nil", sum, err)
+	}
+	sum, err = db.Sum(ctx, "SELECT|people|dead|name=?", "Bob")
+	if err != nil || sum != 0 {
+		t.Errorf("Sum of NULL = %v, %v; want 0, nil", sum, err)
+	}
+	if _, err := db.Sum(ctx, "SELECT|people|age|name=?", "Nobody"); err != ErrNoRows {
+		t.Errorf("Sum of no rows: err = %v; want ErrNoRows", err)
+	}
+	if _, err := db.Sum(ctx, "SELECT|people|age,name|name=?", "Bob"); err == nil {
+		t.Error("Sum of two columns succeeded")
+	}
+
+	exec(t, db, "INSERT|people|name=Dave,age=?,dead=?", 4, true)
+	b, err := db.ScanBool(ctx, "SELECT|people|dead|name=?", "Dave")
+	if err != nil || !b {
+		t.Errorf("ScanBool = %v, %v; want true, nil", b, err)
+	}
+	if _, err := db.ScanBool(ctx, "SELECT|people|dead|name=?", "Nobody"); err != ErrNoRows {
+		t.Errorf("ScanBool of no rows: err = %v; want ErrNoRows", err)
+	}
+	if _, err := db.ScanBool(ctx, "SELECT|people|dead,name|name=?", "Dave"); err == nil {
+		t.Error("ScanBool of two columns succeeded")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Running SQL scripts.
+
+// 执行 SQL 脚本。
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+)
+
+// ExecScript executes the statements of script, such as a migration
+// file, one after the other on a single connection. Statements are
+// separated by semicolons; semicolons inside quoted strings, quoted
+// identifiers, comments and dollar-quoted blocks such as $$ ... $$ or
+// $body$ ... $body$ don't separate statements. Statements consisting
+// only of comments are skipped.
+//
+// If progress is not nil, it is called after each statement with the
+// statement's index among those executed, its text, and its result or
+// error. If a statement fails, ExecScript stops and returns its error;
+// if progress returns false, ExecScript stops and returns nil. Once
+// ctx is done, no further statement is started and ctx.Err() is
+// returned.
+//
+// The statements are not run in a transaction, so those executed
+// before a failure stay in effect unless the script itself begins and
+// commits a transaction.
+
+// ExecScript 在同一个连接上逐条执行 script（例如迁移文件）中的语句。语句以分号分隔；
+// 位于引号字符串、带引号的标识符、注释以及诸如 $$ ... $$ 或 $body$ ... $body$ 的
+// 美元符号引用块中的分号不会分隔语句。仅由注释构成的语句会被跳过。
+//
+// 若 progress 不为 nil，则在每条语句执行之后调用它，并传入该语句在已执行语句中的索引、
+// 其文本以及其结果或错误。若某条语句失败，ExecScript 会停止并返回其错误；若 progress
+// 返回 false，ExecScript 会停止并返回 nil。一旦 ctx 结束，就不会再开始执行任何语句，
+// 并返回 ctx.Err()。
+//
+// 这些语句并非在事务中执行，因此除非脚本本身开始并提交了事务，否则在失败之前已执行的
+// 语句仍然有效。
+func (db *DB) ExecScript(ctx context.Context, script string, progress func(index int, statement string, res Result, err error) bool) error {
+	stmts := splitScript(script)
+	for _, st := range stmts {
+		if err := db.checkReadOnly(st); err != nil {
+			return db.handleErr("ExecScript", st, err)
+		}
+	}
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	var dc *driverConn
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		dc, err = db.connContext(ctx, cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		dc, err = db.connContext(ctx, alwaysNewConn)
+	}
+	if err != nil {
+		return db.handleErr("ExecScript", "", err)
+	}
+	defer func() {
+		db.putConn(dc, err)
+	}()
+
+	for i, st := range stmts {
+		if err = ctx.Err(); err != nil {
+			return db.handleErr("ExecScript", st, err)
+		}
+		var res Result
+		res, err = execDC(dc, st, nil)
+		if progress != nil && !progress(i, st, res, err) && err == nil {
+			return nil
+		}
+		if err != nil {
+			return db.handleErr("ExecScript", st, err)
+		}
+	}
+	return nil
+}
+
+// splitScript splits script into its statements, without their
+// terminating semicolons and surrounding white space, leaving out
+// those that are empty or contain only comments.
+func splitScript(script string) []string {
+	var stmts []string
+	start := 0
+	code := false // whether the current statement has more than comments
+	end := func(i int) {
+		if code {
+			stmts = append(stmts, strings.TrimSpace(script[start:i]))
+		}
+		start = i + 1
+		code = false
+	}
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; c {
+		case ';':
+			end(i)
+		case '\'', '"', '`':
+			code = true
+			// Skip to the closing quote. A doubled quote inside
+			// the literal toggles out and back in again.
+			for i++; i < len(script) && script[i] != c; i++ {
+			}
+		case '-':
+			if i+1 < len(script) && script[i+1] == '-' {
+				for i += 2; i < len(script) && script[i] != '\n'; i++ {
+				}
+			} else {
+				code = true
+			}
+		case '/':
+			if i+1 < len(script) && script[i+1] == '*' {
+				// Block comments nest, as in PostgreSQL.
+				depth := 1
+				for i += 2; i < len(script) && depth > 0; i++ {
+					switch {
+					case strings.HasPrefix(script[i:], "/*"):
+						depth++
+						i++
+					case strings.HasPrefix(script[i:], "*/"):
+						depth--
+						i++
+					}
+				}
+				i--
+			} else {
+				code = true
+			}
+		case '$':
+			code = true
+			if tag := dollarTag(script[i:]); tag != "" {
+				j := strings.Index(script[i+len(tag):], tag)
+				if j < 0 {
+					i = len(script)
+				} else {
+					i += len(tag) + j + len(tag) - 1
+				}
+			}
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+		default:
+			code = true
+		}
+	}
+	end(len(script))
+	return stmts
+}
+
+// dollarTag returns the dollar-quote tag, such as $$ or $body$, that s
+// starts with, or "" if s doesn't start with one. Positional
+// parameters such as $1 are not tags.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80:
+		case '0' <= c && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"", nil},
+		{" ; ;\n", nil},
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;\nSELECT 2;\n", []string{"SELECT 1", "SELECT 2"}},
+		{"SELECT 'a;b''c;'; SELECT 2", []string{"SELECT 'a;b''c;'", "SELECT 2"}},
+		{`SELECT "x;y", ` + "`z;`" + `; SELECT 2`, []string{`SELECT "x;y", ` + "`z;`", "SELECT 2"}},
+		{"-- only a comment;\n; SELECT 1 -- trailing; comment\n", []string{"SELECT 1 -- trailing; comment"}},
+		{"/* a; /* nested; */ still; */ SELECT 1; /* alone */", []string{"/* a; /* nested; */ still; */ SELECT 1"}},
+		{"SELECT 1 - 2 / 3; SELECT 4", []string{"SELECT 1 - 2 / 3", "SELECT 4"}},
+		{
+			"CREATE FUNCTION f() AS $$ BEGIN; END; $$; CREATE FUNCTION g() AS $body$ a; $$; $body$;",
+			[]string{"CREATE FUNCTION f() AS $$ BEGIN; END; $$", "CREATE FUNCTION g() AS $body$ a; $$; $body$"},
+		},
+		{"SELECT $1; SELECT $2", []string{"SELECT $1", "SELECT $2"}},
+		{"SELECT 'unterminated;", []string{"SELECT 'unterminated;"}},
+	}
+	for _, tt := range tests {
+		if got := splitScript(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitScript(%q) = %q; want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	script := `
+-- Create and fill the table.
+;
+CREATE|t|name=string,age=int32;
+INSERT|t|name=Alice,age=1;
+INSERT|t|name=Bob,age=2;
+`
+	var got []string
+	err := db.ExecScript(ctx, script, func(i int, stmt string, res Result, err error) bool {
+		if err != nil {
+			t.Errorf("statement %d (%q): %v", i, stmt, err)
+		}
+		got = append(got, stmt)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"CREATE|t|name=string,age=int32",
+		"INSERT|t|name=Alice,age=1",
+		"INSERT|t|name=Bob,age=2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("progress saw %q; want %q", got, want)
+	}
+	var age int
+	if err := db.QueryRow("SELECT|t|age|name=?", "Bob").Scan(&age); err != nil || age != 2 {
+		t.Errorf("Bob's age = %d, %v; want 2, nil", age, err)
+	}
+	if got := db.Stats().OpenConnections; got != 1 {
+		t.Errorf("OpenConnections = %d; want 1", got)
+	}
+
+	// A failing statement stops the script.
+	calls := 0
+	err = db.ExecScript(ctx, "INSERT|t|name=Chris,age=3; BOGUS; INSERT|t|name=Dave,age=4", func(i int, stmt string, res Result, err error) bool {
+		calls++
+		return true
+	})
+	if err == nil {
+		t.Error("ExecScript with a bad statement succeeded")
+	}
+	if calls != 2 {
+		t.Errorf("progress called %d times; want 2", calls)
+	}
+
+	// Returning false from progress stops the script without error.
+	calls = 0
+	err = db.ExecScript(ctx, "INSERT|t|name=Eve,age=5; INSERT|t|name=Frank,age=6", func(i int, stmt string, res Result, err error) bool {
+		calls++
+		return false
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("ExecScript stopped by progress = %v after %d calls; want nil after 1", err, calls)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := db.ExecScript(cctx, "INSERT|t|name=Gina,age=7", nil); err != context.Canceled {
+		t.Errorf("ExecScript with done context: err = %v; want context.Canceled", err)
+	}
+}
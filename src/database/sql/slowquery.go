@@ -0,0 +1,132 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// slowQuery holds the settings of SetSlowQueryThreshold and
+// SetSlowQueryRedactor.
+type slowQuery struct {
+	threshold time.Duration
+	fn        func(ctx context.Context, query string, args []interface{}, dur time.Duration)
+	redact    func(query string, args []interface{}) []interface{}
+}
+
+// SetSlowQueryThreshold sets fn to be called for each statement whose
+// execution by the driver takes longer than d. The duration measured
+// is that of the driver's Exec or Query call; for a query, it doesn't
+// include reading the rows. Time spent waiting for a connection or
+// preparing the statement is not included either.
+//
+// fn is called synchronously, after the driver returns and whether or
+// not it returned an error, with the query and its arguments as passed
+// to the driver, after any redaction set by SetSlowQueryRedactor.
+// Statement execution in this package does not carry a context, so
+// ctx is context.Background(), with only a description of the
+// connection that ran the statement; see ConnInfoFromContext. fn must
+// not use the DB while handling the call in a way that could block on
+// the connection in use.
+//
+// If d <= 0 or fn is nil, slow queries are not reported. This is the
+// default.
+
+// SetSlowQueryThreshold 设置 fn，使其在驱动执行某条语句所用的时间超过 d 时被调用。
+// 所测量的时间为驱动的 Exec 或 Query 调用所用的时间；对于查询，它不包括读取行的
+// 时间。等待连接或准备语句所用的时间也不包括在内。
+//
+// fn 会在驱动返回之后同步调用，无论驱动是否返回了错误。传给它的查询及实参与传给驱动的
+// 相同，但已经过 SetSlowQueryRedactor 所设置的脱敏处理。本包中的语句执行不携带
+// context，因此 ctx 为 context.Background()，仅附带对执行该语句的连接的描述；
+// 参见 ConnInfoFromContext。fn 在处理调用时不得以可能阻塞于正在使用的连接的方式
+// 使用该 DB。
+//
+// 若 d <= 0 或 fn 为 nil，则不报告慢查询。默认即是如此。
+func (db *DB) SetSlowQueryThreshold(d time.Duration, fn func(ctx context.Context, query string, args []interface{}, dur time.Duration)) {
+	db.mu.Lock()
+	db.slowQuery.threshold = d
+	db.slowQuery.fn = fn
+	db.mu.Unlock()
+}
+
+// SetSlowQueryRedactor sets a function that rewrites the arguments of
+// a slow query before they are passed to the function set by
+// SetSlowQueryThreshold, for example to hide passwords or personal
+// data. It is given the query and its arguments, and must not modify
+// args in place; it should return a new slice instead. If redact is
+// nil, the arguments are passed unchanged.
+
+// SetSlowQueryRedactor 设置一个函数，它会在慢查询的实参被传给 SetSlowQueryThreshold
+// 所设置的函数之前改写这些实参，例如用于隐藏密码或个人数据。它接收查询及其实参，
+// 且不得原地修改 args，而应返回一个新的切片。若 redact 为 nil，实参会原样传递。
+func (db *DB) SetSlowQueryRedactor(redact func(query string, args []interface{}) []interface{}) {
+	db.mu.Lock()
+	db.slowQuery.redact = redact
+	db.mu.Unlock()
+}
+
+type connInfoKey struct{}
+
+// ConnInfoFromContext returns the description of the connection that
+// ran a slow statement, from the context passed to the function set
+// by SetSlowQueryThreshold. Its ID and CheckoutID match those of the
+// DB's events for that connection; see DB.Events. ok is false for
+// other contexts.
+
+// ConnInfoFromContext 从传给 SetSlowQueryThreshold 所设置函数的上下文中，返回对执行
+// 该慢语句的连接的描述。其 ID 和 CheckoutID 与 DB 针对该连接的事件中的相同；参见
+// DB.Events。对于其他上下文，ok 为 false。
+func ConnInfoFromContext(ctx context.Context) (info ConnInfo, ok bool) {
+	info, ok = ctx.Value(connInfoKey{}).(ConnInfo)
+	return info, ok
+}
+
+// startQuery notes that the driver is about to run query on dc,
+// returning the start time to pass to noteQuery.
+func (dc *driverConn) startQuery(query string) time.Time {
+	db := dc.db
+	db.mu.Lock()
+	db.emitLocked(PoolEvent{Type: QueryStarted, Conn: dc.infoLocked(), Query: query})
+	db.mu.Unlock()
+	return time.Now()
+}
+
+// noteQuery notes that the driver call that ran query on dc, started
+// at start, returned err, reporting query to the slow query function
+// if the call took longer than the threshold.
+func (dc *driverConn) noteQuery(query string, args []interface{}, start time.Time, err error) {
+	dur := time.Since(start)
+	db := dc.db
+	db.mu.Lock()
+	sq := db.slowQuery
+	info := dc.infoLocked()
+	db.emitLocked(PoolEvent{Type: QueryEnded, Conn: info, Query: query, Duration: dur, Err: err})
+	db.mu.Unlock()
+	if err == driver.ErrSkip || sq.fn == nil || sq.threshold <= 0 || dur <= sq.threshold {
+		return
+	}
+	if sq.redact != nil {
+		args = sq.redact(query, args)
+	}
+	sq.fn(context.WithValue(context.Background(), connInfoKey{}, info), query, args, dur)
+}
+
+// startStmtQuery is like startQuery for a statement run through ds.
+func startStmtQuery(ds driverStmt, query string) time.Time {
+	if dc, ok := ds.Locker.(*driverConn); ok {
+		return dc.startQuery(query)
+	}
+	return time.Now()
+}
+
+// noteStmtQuery is like noteQuery for a statement run through ds.
+func noteStmtQuery(ds driverStmt, query string, args []interface{}, start time.Time, err error) {
+	if dc, ok := ds.Locker.(*driverConn); ok {
+		dc.noteQuery(query, args, start, err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryThreshold(t *testing.T) {
+	db := newTestDB(t, "magicquery")
+	defer closeDB(t, db)
+
+	type report struct {
+		query string
+		args  []interface{}
+		dur   time.Duration
+	}
+	var reports []report
+	db.SetSlowQueryThreshold(5*time.Millisecond, func(ctx context.Context, query string, args []interface{}, dur time.Duration) {
+		reports = append(reports, report{query, args, dur})
+	})
+
+	// The fake driver sleeps for millis while running the query.
+	const sleepQuery = "SELECT|magicquery|op|op=?,millis=?"
+	run := func(rows *Rows, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+	run(db.Query(sleepQuery, "sleep", 20))
+	// Fast statements are not reported.
+	run(db.Query(sleepQuery, "sleep", 0))
+	exec(t, db, "INSERT|magicquery|op=?,millis=?", "nothing", 0)
+
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports; want 1: %v", len(reports), reports)
+	}
+	r := reports[0]
+	if r.query != sleepQuery || !reflect.DeepEqual(r.args, []interface{}{"sleep", 20}) || r.dur < 20*time.Millisecond {
+		t.Errorf("report = %+v; want %q with args [sleep 20] and at least 20ms", r, sleepQuery)
+	}
+
+	db.SetSlowQueryRedactor(func(query string, args []interface{}) []interface{} {
+		redacted := make([]interface{}, len(args))
+		for i := range redacted {
+			redacted[i] = "?"
+		}
+		return redacted
+	})
+	reports = nil
+	stmt, err := db.Prepare(sleepQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	run(stmt.Query("sleep", 20))
+	if len(reports) != 1 || !reflect.DeepEqual(reports[0].args, []interface{}{"?", "?"}) {
+		t.Errorf("reports with redaction = %+v; want one with args [? ?]", reports)
+	}
+
+	db.SetSlowQueryThreshold(0, nil)
+	reports = nil
+	run(db.Query(sleepQuery, "sleep", 20))
+	if len(reports) != 0 {
+		t.Errorf("got %d reports after disabling; want 0", len(reports))
+	}
+}
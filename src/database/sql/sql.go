@@ -18,10 +18,12 @@
 package sql
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"runtime"
 	"sort"
 	"sync"
@@ -117,12 +119,7 @@ type NullString struct {
 
 // Scan实现了Scanner接口。
 func (ns *NullString) Scan(value interface{}) error {
-	if value == nil {
-		ns.String, ns.Valid = "", false
-		return nil
-	}
-	ns.Valid = true
-	return convertAssign(&ns.String, value)
+	return ns.scanWith(value, ScanStrict)
 }
 
 // Value implements the driver Valuer interface.
@@ -150,12 +147,7 @@ type NullInt64 struct {
 
 // Scan实现了Scaner接口。
 func (n *NullInt64) Scan(value interface{}) error {
-	if value == nil {
-		n.Int64, n.Valid = 0, false
-		return nil
-	}
-	n.Valid = true
-	return convertAssign(&n.Int64, value)
+	return n.scanWith(value, ScanStrict)
 }
 
 // Value implements the driver Valuer interface.
@@ -183,12 +175,7 @@ type NullFloat64 struct {
 
 // Scan实现了Scanner接口。
 func (n *NullFloat64) Scan(value interface{}) error {
-	if value == nil {
-		n.Float64, n.Valid = 0, false
-		return nil
-	}
-	n.Valid = true
-	return convertAssign(&n.Float64, value)
+	return n.scanWith(value, ScanStrict)
 }
 
 // Value implements the driver Valuer interface.
@@ -216,12 +203,7 @@ type NullBool struct {
 
 // Scan实现了Scanner接口。
 func (n *NullBool) Scan(value interface{}) error {
-	if value == nil {
-		n.Bool, n.Valid = false, false
-		return nil
-	}
-	n.Valid = true
-	return convertAssign(&n.Bool, value)
+	return n.scanWith(value, ScanStrict)
 }
 
 // Value implements the driver Valuer interface.
@@ -234,6 +216,65 @@ func (n NullBool) Value() (driver.Value, error) {
 	return n.Bool, nil
 }
 
+// NullDuration represents a time.Duration that may be null.
+// NullDuration implements the Scanner interface so it can be used as
+// a scan destination, similar to NullString, and the driver Valuer
+// interface, which writes a valid Duration as an int64 count of
+// nanoseconds.
+
+// NullDuration 代表了可空的 time.Duration 类型。
+// NullDuration 实现了 Scanner 接口，所以它和 NullString 一样可以被当做 scan 的目标变量；
+// 它也实现了 driver Valuer 接口，会将有效的 Duration 写为以纳秒计数的 int64。
+type NullDuration struct {
+	Duration time.Duration
+	Valid    bool // Valid is true if Duration is not NULL
+}
+
+// Scan implements the Scanner interface.
+
+// Scan 实现了 Scanner 接口。
+func (n *NullDuration) Scan(value interface{}) error {
+	return n.scanWith(value, ScanStrict)
+}
+
+// Value implements the driver Valuer interface.
+
+// Value 实现了 driver Valuer 接口。
+func (n NullDuration) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return int64(n.Duration), nil
+}
+
+// NullTime represents a time.Time that may be null.
+// NullTime implements the Scanner interface so
+// it can be used as a scan destination, similar to NullString.
+
+// NullTime 代表了可空的 time.Time 类型。
+// NullTime 实现了 Scanner 接口，所以它和 NullString 一样可以被当做 scan 的目标变量。
+type NullTime struct {
+	Time  time.Time
+	Valid bool // Valid is true if Time is not NULL
+}
+
+// Scan implements the Scanner interface.
+
+// Scan 实现了 Scanner 接口。
+func (n *NullTime) Scan(value interface{}) error {
+	return n.scanWith(value, ScanStrict)
+}
+
+// Value implements the driver Valuer interface.
+
+// Value 实现了 driver Valuer 接口。
+func (n NullTime) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
 // Scanner is an interface used by Scan.
 
 // Scanner是被Scan使用的接口。
@@ -298,6 +339,11 @@ type DB struct {
 	// connections in Stmt.css.
 	numClosed uint64
 
+	// queriesInFlight and queryWaits are atomic counters, kept here
+	// for their alignment; see DBStats.
+	queriesInFlight int64
+	queryWaits      int64
+
 	mu           sync.Mutex // protects following fields // 用于保护以下字段
 	freeConn     []*driverConn
 	connRequests []chan connRequest
@@ -311,10 +357,71 @@ type DB struct {
 	closed      bool
 	dep         map[finalCloser]depSet
 	lastPut     map[*driverConn]string // stacktrace of last conn's put; debug only
-	maxIdle     int                    // zero means defaultMaxIdleConns; negative means 0
+	maxIdle     int                    // zero means defaultIdle; negative means 0
 	maxOpen     int                    // <= 0 means unlimited
 	maxLifetime time.Duration          // maximum amount of time a connection may be reused
 	cleanerCh   chan struct{}
+	numReused   int64          // checkouts of connections that had been used before
+	closeHook   func(ConnInfo) // if non-nil, called after each connection is closed
+	dialect     *Dialect       // used by Rebind
+	autoRebind  bool           // whether queries are rebound before use
+	numInUse    int            // connections handed out and not yet returned
+	draining    bool           // set by Drain; no new checkouts are allowed
+	drainCh     chan struct{}  // closed once draining and numInUse reaches 0
+	breaker     breaker        // see SetCircuitBreaker
+	errHandler  func(op, query string, err error) error
+	openTimeout time.Duration  // bounds each driver Open; zero means none
+	scanLoc     *time.Location // if non-nil, location of scanned times
+	reinterpret bool           // keep the wall clock of scanned times in scanLoc
+	stmtConns   int            // max connections per Stmt; <= 0 means unlimited
+	stmtCache   stmtCache      // see SetMaxStmtCacheSize
+	keepAlive   time.Duration  // see SetKeepAlive; <= 0 means disabled
+	keepAliveCh chan struct{}  // non-nil while keepAliver runs
+	slowQuery   slowQuery      // see SetSlowQueryThreshold
+	readOnly    bool           // see SetReadOnly
+	nonBlocking bool           // see SetNonBlocking
+	strictness  ScanStrictness // see SetScanStrictness
+	strictScan  bool           // see SetStrictRowScan
+	pool        Pool           // if non-nil, replaces freeConn; see OpenWithPool
+
+	// validationQuery is run on each connection before it is
+	// handed out; see SetValidationQuery.
+	validationQuery string
+
+	// prepared records the statements prepared on the DB that have
+	// not been closed; see PreparedStatements.
+	prepared map[*preparedStmt]bool
+
+	events        chan PoolEvent // non-nil once Events is called
+	eventsDropped int64          // events not delivered on a full channel
+
+	// generation is incremented by Reset; connections from an
+	// earlier generation are not reused.
+	generation uint64
+
+	// defaultIdle is the package default of idle connections when
+	// the DB was opened; see SetDefaultMaxIdleConns.
+	defaultIdle int
+
+	maxRows int64 // see SetMaxRows; <= 0 means unlimited
+
+	structSep string // see SetStructSeparator
+
+	// lastConnID and lastCheckoutID are the last IDs given to a
+	// connection and to a checkout; see ConnInfo.
+	lastConnID     uint64
+	lastCheckoutID uint64
+
+	cancelTimeout time.Duration // see SetCancelTimeout; <= 0 means none
+
+	discardOnError func(error) bool // see SetDiscardOnError; nil means none
+
+	inUseByTag map[string]int              // see InUseByTag
+	waiterTags map[chan connRequest]string // tags of the tagged connRequests
+
+	prepareTimeout time.Duration // see SetPrepareTimeout; <= 0 means none
+
+	querySem chan struct{} // see SetMaxConcurrentQueries; nil means no limit
 }
 
 // connReuseStrategy determines how (*DB).conn returns database connections.
@@ -337,17 +444,67 @@ const (
 type driverConn struct {
 	db        *DB
 	createdAt time.Time
+	id        uint64 // see ConnInfo.ID
 
 	sync.Mutex  // guards following
 	ci          driver.Conn
 	closed      bool
 	finalClosed bool // ci.Close has been called
 	openStmt    map[driver.Stmt]bool
+	version     string // cached ServerVersion; valid if haveVersion
+	haveVersion bool
 
 	// guarded by db.mu
 	inUse      bool
 	onPut      []func() // code (with db.mu held) run when conn is next returned
 	dbmuClosed bool     // same as closed, but guarded by db.mu, for removeClosedStmtLocked
+	checkouts  int64    // number of times the conn has been handed out by the pool
+	poolOK     bool     // returned healthy, to be passed back to db.pool; see OpenWithPool
+	generation uint64   // db.generation when the conn was opened
+	checkoutID uint64   // see ConnInfo.CheckoutID
+	tag        string   // see ConnInfo.Tag
+	cancelHung bool     // a driver Interrupt outlived SetCancelTimeout; discard on return
+}
+
+// checkoutLocked marks dc as in use, by a handle with the given tag.
+// The db.mu must be held.
+func (dc *driverConn) checkoutLocked(tag string) {
+	dc.inUse = true
+	dc.db.numInUse++
+	dc.checkouts++
+	dc.db.lastCheckoutID++
+	dc.checkoutID = dc.db.lastCheckoutID
+	dc.tag = tag
+	if tag != "" {
+		if dc.db.inUseByTag == nil {
+			dc.db.inUseByTag = make(map[string]int)
+		}
+		dc.db.inUseByTag[tag]++
+	}
+	if dc.checkouts > 1 {
+		dc.db.numReused++
+		dc.db.emitLocked(PoolEvent{Type: ConnectionReused, Conn: dc.infoLocked()})
+	}
+}
+
+// checkinLocked marks dc as no longer in use, and wakes Drain if it
+// was the last connection out. The db.mu must be held.
+func (dc *driverConn) checkinLocked() {
+	db := dc.db
+	dc.inUse = false
+	db.numInUse--
+	if dc.tag != "" {
+		if db.inUseByTag[dc.tag]--; db.inUseByTag[dc.tag] == 0 {
+			delete(db.inUseByTag, dc.tag)
+		}
+	}
+	if db.draining && db.numInUse == 0 {
+		select {
+		case <-db.drainCh:
+		default:
+			close(db.drainCh)
+		}
+	}
 }
 
 func (dc *driverConn) releaseConn(err error) {
@@ -420,6 +577,15 @@ func (dc *driverConn) Close() error {
 }
 
 func (dc *driverConn) finalClose() error {
+	poolErr := driver.ErrBadConn
+	if dc.db.pool != nil {
+		dc.db.mu.Lock()
+		if dc.poolOK {
+			poolErr = nil
+		}
+		dc.db.mu.Unlock()
+	}
+
 	dc.Lock()
 
 	for si := range dc.openStmt {
@@ -427,7 +593,15 @@ func (dc *driverConn) finalClose() error {
 	}
 	dc.openStmt = nil
 
-	err := dc.ci.Close()
+	var err error
+	ci := dc.ci
+	if pool := dc.db.pool; pool != nil {
+		// The connection goes back to the pool, which closes it
+		// if it is bad.
+		pool.Put(ci, poolErr)
+	} else {
+		err = ci.Close()
+	}
 	dc.ci = nil
 	dc.finalClosed = true
 	dc.Unlock()
@@ -435,12 +609,27 @@ func (dc *driverConn) finalClose() error {
 	dc.db.mu.Lock()
 	dc.db.numOpen--
 	dc.db.maybeOpenNewConnections()
+	hook := dc.db.closeHook
+	info := dc.infoLocked()
+	dc.db.emitLocked(PoolEvent{Type: ConnectionClosed, Conn: info})
 	dc.db.mu.Unlock()
 
 	atomic.AddUint64(&dc.db.numClosed, 1)
+	if hook != nil {
+		hook(info)
+	}
 	return err
 }
 
+// infoLocked returns a description of dc. The db.mu must be held.
+func (dc *driverConn) infoLocked() ConnInfo {
+	info := ConnInfo{CreatedAt: dc.createdAt, ID: dc.id, CheckoutID: dc.checkoutID, Tag: dc.tag}
+	if dc.checkouts > 1 {
+		info.Reuses = dc.checkouts - 1
+	}
+	return info
+}
+
 // driverStmt associates a driver.Stmt with the
 // *driverConn from which it came, so the driverConn's lock can be
 // held during calls.
@@ -560,6 +749,70 @@ var connectionRequestQueueSize = 1000000
 //
 // TODO：待译
 func Open(driverName, dataSourceName string) (*DB, error) {
+	return OpenWithOptions(driverName, dataSourceName, Options{})
+}
+
+// Options holds connection pool settings applied by OpenWithOptions.
+// The zero value gives the same pool as Open.
+
+// Options 包含 OpenWithOptions 所应用的连接池设置。
+// 其零值给出的连接池与 Open 相同。
+type Options struct {
+	// MaxIdleConns is the maximum number of connections in the idle
+	// connection pool. Zero means the default; a negative value
+	// means no idle connections are retained.
+	MaxIdleConns int
+
+	// MaxOpenConns is the maximum number of open connections to the
+	// database. Zero means no limit.
+	MaxOpenConns int
+
+	// ConnMaxLifetime is the maximum amount of time a connection may
+	// be reused. Zero means connections are reused forever.
+	ConnMaxLifetime time.Duration
+
+	// ConnOpenTimeout bounds the time spent opening each new
+	// connection. Zero means no timeout. See SetConnOpenTimeout.
+	ConnOpenTimeout time.Duration
+}
+
+func (o *Options) validate() error {
+	if o.MaxOpenConns < 0 {
+		return fmt.Errorf("sql: negative MaxOpenConns %d", o.MaxOpenConns)
+	}
+	if o.ConnMaxLifetime < 0 {
+		return fmt.Errorf("sql: negative ConnMaxLifetime %v", o.ConnMaxLifetime)
+	}
+	if o.ConnOpenTimeout < 0 {
+		return fmt.Errorf("sql: negative ConnOpenTimeout %v", o.ConnOpenTimeout)
+	}
+	if o.MaxOpenConns > 0 && o.MaxIdleConns > o.MaxOpenConns {
+		return fmt.Errorf("sql: MaxIdleConns %d exceeds MaxOpenConns %d", o.MaxIdleConns, o.MaxOpenConns)
+	}
+	return nil
+}
+
+// OpenWithOptions is like Open but configures the connection pool
+// from opts before the DB is returned, so no connection is ever
+// opened under the default settings. It is equivalent to calling
+// SetMaxIdleConns, SetMaxOpenConns and SetConnMaxLifetime right
+// after Open, except that nonsensical combinations, such as
+// MaxIdleConns greater than a non-zero MaxOpenConns, are reported as
+// an error instead of being adjusted.
+//
+// If MaxOpenConns is set but MaxIdleConns is left at zero, the
+// default number of idle connections is reduced to MaxOpenConns.
+
+// OpenWithOptions 类似于 Open，但会在返回 DB 之前根据 opts 配置连接池，
+// 因此不会有任何连接在默认设置下被打开。它等价于在 Open 之后立即调用
+// SetMaxIdleConns、SetMaxOpenConns 和 SetConnMaxLifetime，区别在于不合理的组合，
+// 例如 MaxIdleConns 大于非零的 MaxOpenConns，会作为错误报告，而不会被自动调整。
+//
+// 若设置了 MaxOpenConns 而 MaxIdleConns 为零，默认的空闲连接数会被降低到 MaxOpenConns。
+func OpenWithOptions(driverName, dataSourceName string, opts Options) (*DB, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
 	driversMu.RLock()
 	driveri, ok := drivers[driverName]
 	driversMu.RUnlock()
@@ -567,36 +820,123 @@ func Open(driverName, dataSourceName string) (*DB, error) {
 		return nil, fmt.Errorf("sql: unknown driver %q (forgotten import?)", driverName)
 	}
 	db := &DB{
-		driver:   driveri,
-		dsn:      dataSourceName,
-		openerCh: make(chan struct{}, connectionRequestQueueSize),
-		lastPut:  make(map[*driverConn]string),
+		driver:      driveri,
+		dsn:         dataSourceName,
+		openerCh:    make(chan struct{}, connectionRequestQueueSize),
+		lastPut:     make(map[*driverConn]string),
+		dialect:     lookupDialect(driverName),
+		maxIdle:     opts.MaxIdleConns,
+		maxOpen:     opts.MaxOpenConns,
+		maxLifetime: opts.ConnMaxLifetime,
+		openTimeout: opts.ConnOpenTimeout,
+		structSep:   "_",
+	}
+	db.defaultIdle = defaultMaxIdle()
+	if db.maxIdle < 0 {
+		db.maxIdle = -1
+	}
+	if db.maxOpen > 0 && db.maxIdleConnsLocked() > db.maxOpen {
+		db.maxIdle = db.maxOpen
 	}
 	go db.connectionOpener()
 	return db, nil
 }
 
 // Ping verifies a connection to the database is still alive,
-// establishing a connection if necessary.
+// establishing a connection if necessary. If the driver implements
+// driver.Pinger, the connection is also checked with it.
 // TODO：待译
 func (db *DB) Ping() error {
-	// TODO(bradfitz): give drivers an optional hook to implement
-	// this in a more efficient or more reliable way, if they
-	// have one.
-	dc, err := db.conn(cachedOrNewConn)
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		err = db.ping(cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		err = db.ping(alwaysNewConn)
+	}
+	return err
+}
+
+func (db *DB) ping(strategy connReuseStrategy) error {
+	dc, err := db.conn(strategy)
 	if err != nil {
 		return err
 	}
-	db.putConn(dc, nil)
-	return nil
+	if pinger, ok := dc.ci.(driver.Pinger); ok {
+		dc.Lock()
+		err = pinger.Ping()
+		dc.Unlock()
+	}
+	db.putConn(dc, err)
+	return err
+}
+
+// ErrServerVersionUnsupported is returned by ServerVersion when the
+// driver cannot report the database server's version.
+
+// ErrServerVersionUnsupported 会在驱动无法报告数据库服务器版本时由 ServerVersion 返回。
+var ErrServerVersionUnsupported = errors.New("sql: driver does not support ServerVersion")
+
+// ServerVersion returns the version of the database server, as
+// reported by the driver in the server's own format. The version is
+// fetched at most once per connection and cached for the life of the
+// connection. If the driver does not implement
+// driver.ServerVersioner, ServerVersion returns
+// ErrServerVersionUnsupported.
+
+// ServerVersion 返回数据库服务器的版本，其格式由驱动按服务器自身的格式报告。
+// 每个连接至多获取一次版本，并在该连接的生命周期内缓存。
+// 若驱动未实现 driver.ServerVersioner，ServerVersion 返回 ErrServerVersionUnsupported。
+func (db *DB) ServerVersion() (string, error) {
+	var v string
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		v, err = db.serverVersion(cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		return db.serverVersion(alwaysNewConn)
+	}
+	return v, err
+}
+
+func (db *DB) serverVersion(strategy connReuseStrategy) (string, error) {
+	dc, err := db.conn(strategy)
+	if err != nil {
+		return "", err
+	}
+	dc.Lock()
+	if !dc.haveVersion {
+		sv, ok := dc.ci.(driver.ServerVersioner)
+		if !ok {
+			dc.Unlock()
+			db.putConn(dc, nil)
+			return "", ErrServerVersionUnsupported
+		}
+		dc.version, err = sv.ServerVersion()
+		dc.haveVersion = err == nil
+	}
+	v := dc.version
+	dc.Unlock()
+	db.putConn(dc, err)
+	return v, err
 }
 
-// Close closes the database, releasing any open resources.
+// Close closes the database, releasing any open resources. The driver
+// statements of statements prepared on the DB that have not been
+// closed are closed along with their connections; the statements then
+// return errors.
 //
 // It is rare to Close a DB, as the DB handle is meant to be
 // long-lived and shared between many goroutines.
 
-// Close关闭数据库，释放一些使用中的资源。
+// Close关闭数据库，释放一些使用中的资源。在DB上准备且尚未关闭的语句，其驱动语句会随其连接
+// 一起被关闭；此后这些语句会返回错误。
 // TODO: 待译
 func (db *DB) Close() error {
 	db.mu.Lock()
@@ -608,17 +948,32 @@ func (db *DB) Close() error {
 	if db.cleanerCh != nil {
 		close(db.cleanerCh)
 	}
+	if db.keepAliveCh != nil {
+		close(db.keepAliveCh)
+	}
 	var err error
 	fns := make([]func() error, 0, len(db.freeConn))
 	for _, dc := range db.freeConn {
 		fns = append(fns, dc.closeDBLocked())
 	}
 	db.freeConn = nil
+	if db.pool != nil {
+		fns = append(fns, db.pool.Close)
+	}
+	cached := db.stmtCache.clear()
+	db.prepared = nil
 	db.closed = true
+	if db.events != nil {
+		close(db.events)
+	}
 	for _, req := range db.connRequests {
 		close(req)
 	}
+	db.waiterTags = nil
 	db.mu.Unlock()
+	for _, s := range cached {
+		s.Close()
+	}
 	for _, fn := range fns {
 		err1 := fn()
 		if err1 != nil {
@@ -628,14 +983,140 @@ func (db *DB) Close() error {
 	return err
 }
 
+// PreparedStatements returns the queries of the statements prepared
+// with the DB's Prepare method, including those of the statement cache,
+// that have not been closed, in no particular order. A query prepared
+// more than once appears once for each statement. Statements prepared
+// by transactions are not included. It lets long-running programs audit
+// the statements they keep open.
+//
+// The DB doesn't hold the statements themselves: a Stmt whose
+// references have all been lost is garbage collected, which logs a
+// warning with its query, sends a StmtLeaked event, closes it and
+// removes its query.
+
+// PreparedStatements 以不确定的顺序返回通过 DB 的 Prepare 方法准备（包括语句缓存中的）、
+// 且尚未关闭的语句的查询。被准备了多次的查询会为每个语句出现一次。由事务准备的语句不包括在内。
+// 它可让长时间运行的程序审查其保持打开的语句。
+//
+// DB 不会持有这些语句本身：所有引用都已丢失的 Stmt 会被垃圾回收，这会记录一条包含其查询的
+// 警告、发送一个 StmtLeaked 事件、关闭它并移除其查询。
+func (db *DB) PreparedStatements() []string {
+	var queries []string
+	db.mu.Lock()
+	for p := range db.prepared {
+		queries = append(queries, p.query)
+	}
+	db.mu.Unlock()
+	return queries
+}
+
+// ErrDraining is returned by operations that need a new connection
+// from a DB on which Drain has been called. Callers seeing it should
+// send their work to another database.
+
+// ErrDraining 会在已调用过 Drain 的 DB 上需要新连接的操作中返回。
+// 遇到该错误的调用者应当将其工作转交给其它数据库。
+var ErrDraining = errors.New("sql: database is draining; route requests elsewhere")
+
+// Drain stops the DB from handing out connections and waits for the
+// connections already in use, by transactions, statements being
+// executed and open Rows, to be returned. Idle connections are closed
+// immediately, and connections are closed as they are returned.
+//
+// Once Drain has been called, operations that need a new connection
+// fail with ErrDraining, while work already running continues
+// normally. Drain returns nil once no connection is in use, or
+// ctx.Err() if ctx is done first; in either case the DB stays
+// draining. Close should still be called to release the DB.
+
+// Drain 使 DB 停止分发连接，并等待正在使用中的连接（被事务、正在执行的语句以及
+// 打开的 Rows 占用的连接）被归还。空闲连接会被立即关闭，而其它连接会在归还时被关闭。
+//
+// 一旦调用了 Drain，需要新连接的操作会以 ErrDraining 失败，而已在运行的工作会正常继续。
+// 当没有连接在使用中时，Drain 返回 nil；若 ctx 先结束，则返回 ctx.Err()。
+// 无论哪种情况，DB 都会保持排空状态。之后仍应调用 Close 来释放该 DB。
+func (db *DB) Drain(ctx context.Context) error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return errDBClosed
+	}
+	if !db.draining {
+		db.draining = true
+		db.drainCh = make(chan struct{})
+		if db.numInUse == 0 {
+			close(db.drainCh)
+		}
+		for _, req := range db.connRequests {
+			req <- connRequest{err: ErrDraining}
+		}
+		db.connRequests = nil
+		db.waiterTags = nil
+	}
+	done := db.drainCh
+	fns := make([]func() error, 0, len(db.freeConn))
+	for _, dc := range db.freeConn {
+		fns = append(fns, dc.closeDBLocked())
+	}
+	db.freeConn = nil
+	db.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 const defaultMaxIdleConns = 2
 
+var (
+	defaultIdleMu sync.Mutex
+	defaultIdle   = defaultMaxIdleConns // see SetDefaultMaxIdleConns
+)
+
+// SetDefaultMaxIdleConns sets the maximum number of idle connections
+// of DBs that don't set their own with SetMaxIdleConns or
+// Options.MaxIdleConns. It replaces the initial default of 2. If n <= 0,
+// such DBs retain no idle connections.
+//
+// The default is recorded when a DB is opened, so it only affects DBs
+// opened after the call. It is meant for programs that open many DBs,
+// such as one per tenant; SetMaxIdleConns still overrides it per DB.
+
+// SetDefaultMaxIdleConns 设置未通过 SetMaxIdleConns 或 Options.MaxIdleConns 自行设置
+// 空闲连接数的 DB 的最大空闲连接数。它取代初始的默认值 2。若 n <= 0，这些 DB 不会保留
+// 任何空闲连接。
+//
+// 该默认值会在 DB 打开时被记录下来，因此它只影响在调用之后打开的 DB。它适用于会打开
+// 大量 DB 的程序，例如每个租户一个 DB；SetMaxIdleConns 仍然可以针对单个 DB 覆盖它。
+func SetDefaultMaxIdleConns(n int) {
+	if n < 0 {
+		n = 0
+	}
+	defaultIdleMu.Lock()
+	defaultIdle = n
+	defaultIdleMu.Unlock()
+}
+
+// defaultMaxIdle returns the default set by SetDefaultMaxIdleConns.
+func defaultMaxIdle() int {
+	defaultIdleMu.Lock()
+	defer defaultIdleMu.Unlock()
+	return defaultIdle
+}
+
 func (db *DB) maxIdleConnsLocked() int {
 	n := db.maxIdle
 	switch {
 	case n == 0:
 		// TODO(bradfitz): ask driver, if supported, for its default preference
-		return defaultMaxIdleConns
+		return db.defaultIdle
 	case n < 0:
 		return 0
 	default:
@@ -697,6 +1178,65 @@ func (db *DB) SetMaxOpenConns(n int) {
 	}
 }
 
+// SetMaxStmtConns sets the maximum number of connections that a single
+// prepared statement may use at the same time. Once a statement has n
+// executions or open Rows in progress, further callers of its Exec,
+// Query and QueryRow wait until one of them finishes, leaving the rest
+// of the pool to other queries. Statements prepared by a transaction
+// are not affected, as they always use the transaction's connection.
+//
+// The limit applies to statements prepared after the call. As with
+// SetMaxOpenConns, a goroutine that holds Rows from a statement and
+// queries it again may wait forever.
+//
+// If n <= 0, there is no limit per statement. The default is 0
+// (unlimited).
+
+// SetMaxStmtConns 设置单个预处理语句可同时使用的最大连接数。当某个语句已有 n 个执行
+// 或未关闭的 Rows 正在进行时，该语句的 Exec、Query 和 QueryRow 的后续调用者会等待，
+// 直到其中之一结束，从而将池中其余连接留给其它查询。由事务准备的语句不受影响，
+// 因为它们总是使用事务的连接。
+//
+// 该限制作用于调用之后准备的语句。与 SetMaxOpenConns 一样，持有某语句的 Rows
+// 并再次对其查询的 goroutine 可能会永远等待。
+//
+// 若 n <= 0，则不限制每个语句的连接数。默认为 0（无限制）。
+func (db *DB) SetMaxStmtConns(n int) {
+	if n < 0 {
+		n = 0
+	}
+	db.mu.Lock()
+	db.stmtConns = n
+	db.mu.Unlock()
+}
+
+// ErrPoolExhausted is returned, when SetNonBlocking is on, by
+// operations that need a connection while all connections allowed by
+// SetMaxOpenConns are in use.
+
+// ErrPoolExhausted 会在 SetNonBlocking 开启时，由需要连接而 SetMaxOpenConns
+// 所允许的连接均在使用中的操作返回。
+var ErrPoolExhausted = errors.New("sql: connection pool exhausted")
+
+// SetNonBlocking sets whether operations that need a connection fail
+// immediately with ErrPoolExhausted, instead of waiting for one to be
+// returned, when no connection is idle and the limit set by
+// SetMaxOpenConns has been reached. Callers can use it to apply their
+// own backpressure. It has no effect while the number of open
+// connections is unlimited. Likewise, calls over the limit set by
+// SetMaxConcurrentQueries fail with ErrTooManyQueries. The default is
+// false.
+
+// SetNonBlocking 设置在没有空闲连接且已达到 SetMaxOpenConns 所设置的上限时，
+// 需要连接的操作是否立即以 ErrPoolExhausted 失败，而非等待有连接被归还。
+// 调用者可借此实施自己的背压策略。当打开的连接数不受限制时，它不起作用。同样地，
+// 超出 SetMaxConcurrentQueries 所设置上限的调用会以 ErrTooManyQueries 失败。默认为 false。
+func (db *DB) SetNonBlocking(on bool) {
+	db.mu.Lock()
+	db.nonBlocking = on
+	db.mu.Unlock()
+}
+
 // SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
 //
 // Expired connections may be closed lazily before reuse.
@@ -719,78 +1259,623 @@ func (db *DB) SetConnMaxLifetime(d time.Duration) {
 	db.mu.Unlock()
 }
 
-// startCleanerLocked starts connectionCleaner if needed.
-func (db *DB) startCleanerLocked() {
-	if db.maxLifetime > 0 && db.numOpen > 0 && db.cleanerCh == nil {
-		db.cleanerCh = make(chan struct{}, 1)
-		go db.connectionCleaner(db.maxLifetime)
-	}
-}
+// ErrConnOpenTimeout is returned when opening a new connection takes
+// longer than the limit set by SetConnOpenTimeout.
 
-func (db *DB) connectionCleaner(d time.Duration) {
-	const minInterval = time.Second
+// ErrConnOpenTimeout 会在打开新连接所用的时间超过 SetConnOpenTimeout
+// 设置的限制时返回。
+var ErrConnOpenTimeout = errors.New("sql: timed out opening connection")
 
-	if d < minInterval {
-		d = minInterval
-	}
-	t := time.NewTimer(d)
+// SetConnOpenTimeout sets the maximum amount of time to wait for the
+// driver to open a new connection. An operation that needs a new
+// connection fails with ErrConnOpenTimeout once d has passed; if the
+// driver later succeeds, the connection is closed.
+//
+// If d <= 0, opening a connection may take as long as the driver
+// takes. The default is 0.
 
-	for {
-		select {
-		case <-t.C:
-		case <-db.cleanerCh: // maxLifetime was changed or db was closed.
-		}
+// SetConnOpenTimeout 设置等待驱动打开新连接的最长时间。需要新连接的操作在超过 d 之后
+// 会以 ErrConnOpenTimeout 失败；若驱动之后打开成功，该连接会被关闭。
+//
+// 若 d <= 0，打开连接所需的时间取决于驱动。默认为 0。
+func (db *DB) SetConnOpenTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	db.mu.Lock()
+	db.openTimeout = d
+	db.mu.Unlock()
+}
 
-		db.mu.Lock()
-		d = db.maxLifetime
-		if db.closed || db.numOpen == 0 || d <= 0 {
-			db.cleanerCh = nil
-			db.mu.Unlock()
-			return
-		}
+// ErrPrepareTimeout is returned when preparing a statement takes
+// longer than the limit set by SetPrepareTimeout.
 
-		expiredSince := nowFunc().Add(-d)
-		var closing []*driverConn
-		for i := 0; i < len(db.freeConn); i++ {
-			c := db.freeConn[i]
-			if c.createdAt.Before(expiredSince) {
-				closing = append(closing, c)
-				last := len(db.freeConn) - 1
-				db.freeConn[i] = db.freeConn[last]
-				db.freeConn[last] = nil
-				db.freeConn = db.freeConn[:last]
-				i--
-			}
-		}
-		db.mu.Unlock()
+// ErrPrepareTimeout 会在准备语句所用的时间超过 SetPrepareTimeout 设置的限制时返回。
+var ErrPrepareTimeout = errors.New("sql: timed out preparing statement")
 
-		for _, c := range closing {
-			c.Close()
-		}
+// SetPrepareTimeout sets the maximum amount of time to wait for the
+// driver to prepare a statement in Prepare, independently of the time
+// its executions take, so that a pathological statement can't hang,
+// for instance, a program's startup. Prepare fails with
+// ErrPrepareTimeout once d has passed. The driver's Prepare is left to
+// finish in the background, and its connection is then discarded
+// rather than returned to the pool, since it was busy past the
+// deadline.
+//
+// If d <= 0, preparing a statement may take as long as the driver
+// takes. The default is 0.
 
-		if d < minInterval {
-			d = minInterval
-		}
-		t.Reset(d)
+// SetPrepareTimeout 设置在 Prepare 中等待驱动准备语句的最长时间，它与语句执行所用的
+// 时间无关，从而使病态的语句无法让例如程序的启动过程挂起。超过 d 之后，Prepare 会以
+// ErrPrepareTimeout 失败。驱动的 Prepare 会留在后台完成，之后其连接会被丢弃而不是放回
+// 连接池，因为它在截止时间之后仍处于忙碌状态。
+//
+// 若 d <= 0，准备语句所需的时间取决于驱动。默认为 0。
+func (db *DB) SetPrepareTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
 	}
+	db.mu.Lock()
+	db.prepareTimeout = d
+	db.mu.Unlock()
+}
+
+// SetScanLocation sets the location of the time.Time values produced
+// by Scan. Each time provided by the driver is converted with
+// time.Time.In: it names the same instant, shown in loc. Use this when
+// the driver reports instants correctly but in varying locations.
+//
+// If the driver reads columns without a time zone as if they were in
+// the wrong zone, converting keeps the wrong instant; see
+// SetScanReinterpret.
+//
+// If loc is nil, times are scanned in whatever location the driver
+// provides. The default is nil.
+
+// SetScanLocation 设置 Scan 所产生的 time.Time 值的时区。驱动提供的每个时间都会
+// 通过 time.Time.In 转换：它表示的仍是同一时刻，只是以 loc 显示。当驱动报告的
+// 时刻正确但时区不一致时，请使用此方法。
+//
+// 若驱动将不带时区的列当作错误的时区读取，转换会保留错误的时刻；
+// 见 SetScanReinterpret。
+//
+// 若 loc 为 nil，时间会以驱动提供的时区被扫描。默认为 nil。
+func (db *DB) SetScanLocation(loc *time.Location) {
+	db.mu.Lock()
+	db.scanLoc = loc
+	db.mu.Unlock()
+}
+
+// SetScanReinterpret sets whether the location set by SetScanLocation
+// is applied by reinterpreting, rather than converting, scanned times.
+// Reinterpreting keeps the wall clock (year, month, day, hour, minute,
+// second and nanosecond) and replaces the location, which changes the
+// instant. It suits columns without a time zone, such as SQL
+// TIMESTAMP WITHOUT TIME ZONE, whose values the driver has placed in
+// an arbitrary zone. The default is false.
+
+// SetScanReinterpret 设置 SetScanLocation 所设置的时区是否通过重新解释，
+// 而非转换，应用于扫描得到的时间。重新解释会保留挂钟时间（年、月、日、时、分、秒
+// 和纳秒）并替换时区，这会改变所表示的时刻。它适用于不带时区的列，例如 SQL 的
+// TIMESTAMP WITHOUT TIME ZONE，其值被驱动放在了任意时区中。默认为 false。
+func (db *DB) SetScanReinterpret(on bool) {
+	db.mu.Lock()
+	db.reinterpret = on
+	db.mu.Unlock()
+}
+
+// ScanStrictness controls how Scan handles numeric conversions that
+// lose information. See SetScanStrictness.
+
+// ScanStrictness 控制 Scan 如何处理会丢失信息的数值转换。见 SetScanStrictness。
+type ScanStrictness int
+
+const (
+	// ScanStrict rejects lossy numeric conversions. It is the
+	// default.
+	ScanStrict ScanStrictness = iota
+
+	// ScanLenient truncates and clamps numeric values to fit
+	// their destinations.
+	ScanLenient
+)
+
+// SetScanStrictness sets how Scan treats numeric values that do not
+// fit their destination.
+//
+// With ScanStrict, the default, Scan returns an error when scanning a
+// value with a fractional part, such as float64(2.5) or "2.5", into an
+// integer; a value out of the integer's range, such as 300 into an
+// *int8; a negative value into an unsigned integer; or a value beyond
+// the largest finite float32 into a *float32.
+//
+// With ScanLenient, those conversions succeed instead: the fractional
+// part is truncated toward zero, so 2.9 and -2.9 become 2 and -2; a
+// value out of range, including an infinity, is clamped to the
+// nearest value the destination can hold, so negative values become 0
+// in unsigned integers. NaN and text that is not a number are still
+// rejected, and all other conversions are unchanged. The policy
+// applies to destinations of basic numeric kinds, to pointers to
+// them, and to the Null types, such as NullInt64; other Scanner
+// implementations receive the driver's value and apply their own
+// rules.
+//
+// ScanLenient also lets the Null types accept values drivers commonly
+// return in other forms: text with surrounding spaces, integers
+// written as "4.0" or "1e3", "yes", "no", "on" and "off" for NullBool,
+// booleans for NullFloat64, any fmt.Stringer for NullString, and times
+// such as "2006-01-02 15:04:05" or "2006-01-02" for NullTime.
+
+// SetScanStrictness 设置 Scan 如何处理无法放入目标的数值。
+//
+// 使用默认的 ScanStrict 时，以下情况 Scan 会返回错误：将带小数部分的值
+// （例如 float64(2.5) 或 "2.5"）扫描到整数中；值超出整数的范围（例如将 300 扫描到
+// *int8 中）；将负值扫描到无符号整数中；或将超出最大有限 float32 的值扫描到 *float32 中。
+//
+// 使用 ScanLenient 时，这些转换会成功：小数部分向零截断，因此 2.9 与 -2.9 分别变为
+// 2 与 -2；超出范围的值（包括无穷大）会被限制为目标所能容纳的最接近的值，因此负值
+// 在无符号整数中变为 0。NaN 以及非数字的文本仍会被拒绝，其它转换保持不变。该策略作用于
+// 基本数值类型的目标、指向它们的指针以及 NullInt64 等 Null 类型；其它 Scanner 实现
+// 会收到驱动提供的值，并应用其自身的规则。
+//
+// ScanLenient 还使 Null 类型能够接受驱动常以其它形式返回的值：带有前后空白的文本、
+// 写作 "4.0" 或 "1e3" 的整数、NullBool 的 "yes"、"no"、"on" 和 "off"、NullFloat64 的
+// 布尔值、NullString 的任意 fmt.Stringer，以及 NullTime 的 "2006-01-02 15:04:05" 或
+// "2006-01-02" 这样的时间。
+func (db *DB) SetScanStrictness(s ScanStrictness) {
+	db.mu.Lock()
+	db.strictness = s
+	db.mu.Unlock()
+}
+
+// SetStrictRowScan sets whether scanning the same row twice is an
+// error. When on, a second call to Scan, ScanPrefix, ScanSlice or
+// ScanStruct without an intervening call to Next fails with an error,
+// rather than returning the same row again. This catches iteration
+// loops that forget to advance. The default is false.
+
+// SetStrictRowScan 设置重复扫描同一行是否为错误。开启后，在两次调用之间未调用 Next
+// 的情况下，第二次调用 Scan、ScanPrefix、ScanSlice 或 ScanStruct 会返回错误，
+// 而不是再次返回同一行。这可以捕获忘记前进的迭代循环。默认为 false。
+func (db *DB) SetStrictRowScan(on bool) {
+	db.mu.Lock()
+	db.strictScan = on
+	db.mu.Unlock()
+}
+
+// ErrTooManyRows is returned by Rows.Err when iteration was stopped
+// because the query returned more rows than allowed by SetMaxRows or
+// QueryOptions.MaxRows.
+
+// ErrTooManyRows 会在查询返回的行数多于 SetMaxRows 或 QueryOptions.MaxRows
+// 所允许的行数、迭代因此被停止时由 Rows.Err 返回。
+var ErrTooManyRows = errors.New("sql: query returned too many rows")
+
+// SetMaxRows sets the maximum number of rows a query may return, as a
+// guard against runaway queries. Once a query's Rows have returned n
+// rows, Next closes them and returns false if there is another row,
+// and Err then returns ErrTooManyRows. The limit is enforced while
+// iterating; it isn't added to the query as a LIMIT, so the database
+// may still have produced the extra rows. QueryOptions.MaxRows
+// overrides it for a single query. It applies to queries run after the
+// call. If n <= 0, there is no limit, which is the default.
+
+// SetMaxRows 设置一个查询最多可返回的行数，以防范失控的查询。一旦某个查询的 Rows
+// 已返回 n 行，若还有下一行，Next 就会关闭该 Rows 并返回 false，此后 Err 会返回
+// ErrTooManyRows。该限制是在迭代时施加的；它不会以 LIMIT 的形式加入查询中，因此数据库
+// 仍可能已经产生了多余的行。QueryOptions.MaxRows 可针对单个查询覆盖它。它适用于调用之后
+// 执行的查询。若 n <= 0，则没有限制，这也是默认设置。
+func (db *DB) SetMaxRows(n int) {
+	db.mu.Lock()
+	db.maxRows = int64(n)
+	db.mu.Unlock()
+}
+
+// SetDiscardOnError sets a classifier for the errors that leave a
+// connection unfit for reuse, for drivers that don't reliably return
+// driver.ErrBadConn. When an operation on a connection fails with an
+// error for which discard returns true, the connection is closed rather
+// than returned to the idle pool; the error is still returned to the
+// caller, and the operation isn't retried. Connections failing with
+// driver.ErrBadConn are always discarded. A nil discard, the default,
+// discards only those.
+//
+// discard may be called concurrently, and must not use the DB.
+
+// SetDiscardOnError 为会使连接不再适合重用的错误设置一个分类器，用于不能可靠地返回
+// driver.ErrBadConn 的驱动。当连接上的操作因某个错误失败，且 discard 对该错误返回 true
+// 时，该连接会被关闭而不是放回空闲连接池；该错误仍会返回给调用者，且操作不会被重试。
+// 因 driver.ErrBadConn 失败的连接总是会被丢弃。discard 为 nil（默认）时只丢弃这些连接。
+//
+// discard 可能被并发调用，且不得使用该 DB。
+func (db *DB) SetDiscardOnError(discard func(error) bool) {
+	db.mu.Lock()
+	db.discardOnError = discard
+	db.mu.Unlock()
+}
+
+// SetCancelTimeout bounds how long closing Rows waits for the driver to
+// cancel their query, once the query's context is done and the driver
+// Rows, implementing driver.Interrupter, have been interrupted. If the
+// interrupt hasn't returned after d, as when the server doesn't
+// respond, the Rows are abandoned: Close returns without closing the
+// driver Rows, and the connection is discarded rather than pooled,
+// closing it in the background, or when its transaction ends. It
+// applies to queries run after the call. If d <= 0, Close waits for
+// the interrupt, which is the default.
+
+// SetCancelTimeout 限制在查询的上下文结束、且驱动的 Rows（实现了 driver.Interrupter）
+// 已被中断之后，关闭 Rows 时等待驱动取消其查询的时长。若中断在 d 之后仍未返回（例如
+// 服务器无响应），这些 Rows 就会被放弃：Close 会在不关闭驱动 Rows 的情况下返回，而该
+// 连接会被丢弃而不是放回连接池，并在后台关闭，或在其事务结束时关闭。它适用于调用之后
+// 执行的查询。若 d <= 0，Close 会等待中断完成，这也是默认设置。
+func (db *DB) SetCancelTimeout(d time.Duration) {
+	db.mu.Lock()
+	db.cancelTimeout = d
+	db.mu.Unlock()
+}
+
+func (db *DB) scanStrictness() ScanStrictness {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.strictness
+}
+
+func (db *DB) strictRowScan() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.strictScan
+}
+
+// scanTime applies the settings of SetScanLocation and
+// SetScanReinterpret to a time provided by the driver.
+func (db *DB) scanTime(t time.Time) time.Time {
+	db.mu.Lock()
+	loc, reinterpret := db.scanLoc, db.reinterpret
+	db.mu.Unlock()
+	if loc == nil {
+		return t
+	}
+	if reinterpret {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	}
+	return t.In(loc)
+}
+
+// openDriverConn opens a new driver connection, giving up after
+// db.openTimeout. db.mu must not be held.
+func (db *DB) openDriverConn() (driver.Conn, error) {
+	db.mu.Lock()
+	d := db.openTimeout
+	db.mu.Unlock()
+	if d <= 0 {
+		return db.driver.Open(db.dsn)
+	}
+
+	type result struct {
+		ci  driver.Conn
+		err error
+	}
+	resc := make(chan result)
+	abandon := make(chan struct{})
+	go func() {
+		ci, err := db.driver.Open(db.dsn)
+		select {
+		case resc <- result{ci, err}:
+		case <-abandon:
+			// Nobody is waiting for this connection any more.
+			if err == nil {
+				ci.Close()
+			}
+		}
+	}()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case r := <-resc:
+		return r.ci, r.err
+	case <-t.C:
+		close(abandon)
+		return nil, ErrConnOpenTimeout
+	}
+}
+
+// prepareConn prepares query on dc, giving up after db.prepareTimeout
+// with ErrPrepareTimeout. The Prepare given up on is left to finish in
+// the background, which then releases dc as a bad connection, closing
+// the statement along with it. db.mu must not be held.
+func (db *DB) prepareConn(dc *driverConn, query string) (driver.Stmt, error) {
+	db.mu.Lock()
+	d := db.prepareTimeout
+	db.mu.Unlock()
+	if d <= 0 {
+		dc.Lock()
+		defer dc.Unlock()
+		return dc.prepareLocked(query)
+	}
+
+	type result struct {
+		si  driver.Stmt
+		err error
+	}
+	resc := make(chan result)
+	abandon := make(chan struct{})
+	go func() {
+		dc.Lock()
+		si, err := dc.prepareLocked(query)
+		dc.Unlock()
+		select {
+		case resc <- result{si, err}:
+		case <-abandon:
+			// Nobody is waiting for this statement any more.
+			db.putConn(dc, driver.ErrBadConn)
+		}
+	}()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case r := <-resc:
+		return r.si, r.err
+	case <-t.C:
+		close(abandon)
+		return nil, ErrPrepareTimeout
+	}
+}
+
+// startCleanerLocked starts connectionCleaner if needed.
+func (db *DB) startCleanerLocked() {
+	if db.maxLifetime > 0 && db.numOpen > 0 && db.cleanerCh == nil {
+		db.cleanerCh = make(chan struct{}, 1)
+		go db.connectionCleaner(db.maxLifetime)
+	}
+}
+
+func (db *DB) connectionCleaner(d time.Duration) {
+	const minInterval = time.Second
+
+	if d < minInterval {
+		d = minInterval
+	}
+	t := time.NewTimer(d)
+
+	for {
+		select {
+		case <-t.C:
+		case <-db.cleanerCh: // maxLifetime was changed or db was closed.
+		}
+
+		db.mu.Lock()
+		d = db.maxLifetime
+		if db.closed || db.numOpen == 0 || d <= 0 {
+			db.cleanerCh = nil
+			db.mu.Unlock()
+			return
+		}
+
+		expiredSince := nowFunc().Add(-d)
+		var closing []*driverConn
+		for i := 0; i < len(db.freeConn); i++ {
+			c := db.freeConn[i]
+			if c.createdAt.Before(expiredSince) {
+				closing = append(closing, c)
+				last := len(db.freeConn) - 1
+				db.freeConn[i] = db.freeConn[last]
+				db.freeConn[last] = nil
+				db.freeConn = db.freeConn[:last]
+				i--
+			}
+		}
+		db.mu.Unlock()
+
+		for _, c := range closing {
+			c.Close()
+		}
+
+		if d < minInterval {
+			d = minInterval
+		}
+		t.Reset(d)
+	}
+}
+
+// DBStats contains database statistics.
+//
+// Some fields are gauges, describing the DB at the time of the call:
+// OpenConnections, InUse, QueriesInFlight, Circuit and StmtCacheSize.
+// The others, such as Reuses and StmtCacheEvictions, are counters,
+// totals accumulated since the DB was opened or since the last call to
+// ResetStats.
+type DBStats struct {
+	// OpenConnections is the number of open connections to the database.
+	OpenConnections int
+
+	// InUse is the number of connections currently handed out by
+	// the pool. DB.InUseByTag breaks it down by tag.
+	InUse int
+
+	// Reuses is the number of times a connection that had already
+	// been used was handed out again by the pool. It is a counter.
+	Reuses int64
+
+	// Circuit is the state of the circuit breaker set by
+	// SetCircuitBreaker. It is CircuitClosed if there is none.
+	Circuit CircuitState
+
+	// StmtCacheSize is the number of statements in the cache set
+	// up by SetMaxStmtCacheSize.
+	StmtCacheSize int
+
+	// StmtCacheEvictions is the number of statements closed to
+	// keep that cache within its limit. It is a counter.
+	StmtCacheEvictions int64
+
+	// EventsDropped is the number of events not delivered on the
+	// channel returned by Events because it was full. It is a
+	// counter.
+	EventsDropped int64
+
+	// QueriesInFlight is the number of Exec and Query calls, and
+	// the like, running on the DB; a query runs until its Rows are
+	// closed. SetMaxConcurrentQueries limits it.
+	QueriesInFlight int
+
+	// QueryWaits is the number of times an Exec or Query call had
+	// to wait for another to finish, because of the limit set by
+	// SetMaxConcurrentQueries. It is a counter.
+	QueryWaits int64
 }
 
-// DBStats contains database statistics.
-type DBStats struct {
-	// OpenConnections is the number of open connections to the database.
-	OpenConnections int
-}
-
 // Stats returns database statistics.
 func (db *DB) Stats() DBStats {
 	db.mu.Lock()
-	stats := DBStats{
-		OpenConnections: db.numOpen,
-	}
+	stats := db.statsLocked()
+	db.mu.Unlock()
+	db.addPoolStats(&stats)
+	return stats
+}
+
+// ResetStats returns the same statistics as Stats and sets the
+// counters in DBStats back to zero, as one atomic step, so that
+// successive calls report what happened in each interval. Gauges are
+// not affected.
+
+// ResetStats 返回与 Stats 相同的统计信息，并将 DBStats 中的计数器归零；这两步作为
+// 一个原子操作完成，因此连续的调用会报告每个时间间隔内发生的情况。计量值不受影响。
+func (db *DB) ResetStats() DBStats {
+	db.mu.Lock()
+	stats := db.statsLocked()
+	db.numReused = 0
+	db.stmtCache.evictions = 0
+	db.eventsDropped = 0
+	stats.QueryWaits = atomic.SwapInt64(&db.queryWaits, 0)
 	db.mu.Unlock()
+	db.addPoolStats(&stats)
 	return stats
 }
 
+// addPoolStats fills in stats from db.pool, if any. It is called
+// without db.mu, which the pool may need to call back into the DB.
+func (db *DB) addPoolStats(stats *DBStats) {
+	if db.pool != nil {
+		stats.OpenConnections = db.pool.Stats().OpenConnections
+	}
+}
+
+func (db *DB) statsLocked() DBStats {
+	return DBStats{
+		OpenConnections:    db.numOpen,
+		InUse:              db.numInUse,
+		Reuses:             db.numReused,
+		Circuit:            db.breaker.state(),
+		StmtCacheSize:      db.stmtCache.len(),
+		StmtCacheEvictions: db.stmtCache.evictions,
+		EventsDropped:      db.eventsDropped,
+		QueriesInFlight:    int(atomic.LoadInt64(&db.queriesInFlight)),
+		QueryWaits:         atomic.LoadInt64(&db.queryWaits),
+	}
+}
+
+// ConnInfo describes a physical connection to the database.
+
+// ConnInfo 描述了一个到数据库的物理连接。
+type ConnInfo struct {
+	// CreatedAt is when the connection was opened.
+	CreatedAt time.Time
+
+	// Reuses is the number of times the connection was handed out
+	// again after its first use. A consistently low count suggests
+	// the idle pool is too small; see SetMaxIdleConns.
+	Reuses int64
+
+	// ID identifies the connection among those the DB has opened.
+	// IDs are given in increasing order, starting at 1.
+	ID uint64
+
+	// CheckoutID identifies the current or, once the connection is
+	// back in the pool, the last time the connection was handed out.
+	// Each checkout from the DB gets a new, increasing ID, so the
+	// events and hook calls for one operation can be correlated.
+	// It is zero if the connection was never handed out.
+	CheckoutID uint64
+
+	// Tag is the tag of the handle, returned by WithTag, through
+	// which the connection was handed out, for the same checkout as
+	// CheckoutID. It is empty for an untagged handle.
+	Tag string
+}
+
+// newConnIDLocked returns the ID of a new connection. The db.mu must
+// be held.
+func (db *DB) newConnIDLocked() uint64 {
+	db.lastConnID++
+	return db.lastConnID
+}
+
+// SetConnCloseHook sets a function to be called, from the goroutine
+// that closed it, after each physical connection is closed.
+// A nil fn removes the hook.
+
+// SetConnCloseHook 设置一个在每个物理连接关闭后调用的函数，
+// 该函数在关闭连接的 goroutine 中调用。fn 为 nil 时会移除该钩子。
+func (db *DB) SetConnCloseHook(fn func(ConnInfo)) {
+	db.mu.Lock()
+	db.closeHook = fn
+	db.mu.Unlock()
+}
+
+// SetErrorHandler sets a function through which every error returned
+// by the DB and its transactions and statements is passed before it
+// reaches the caller. The handler is called with the failing
+// operation, the query, if any, and the error.
+//
+// The operations are "Exec", "Query", "QueryRow", "Prepare" and
+// "Begin" on the DB; "Tx.Exec", "Tx.Query", "Tx.QueryRow",
+// "Tx.Prepare", "Tx.Commit" and "Tx.Rollback" on a Tx; and
+// "Stmt.Exec", "Stmt.Query" and "Stmt.QueryRow" on a Stmt. Errors
+// from QueryRow, including ErrNoRows, are handled when Row.Scan
+// returns them.
+//
+// The error the handler returns is given to the caller in place of
+// err, so it may wrap or replace it. If the handler returns nil, err
+// is returned unchanged: a handler cannot turn a failure, such as
+// ErrNoRows or ErrTxDone, into a success. A nil fn removes the
+// handler.
+
+// SetErrorHandler 设置一个函数，DB 及其事务和语句返回的每个错误在到达调用者之前
+// 都会经过该函数。调用该处理函数时会传入失败的操作、查询（如果有的话）以及错误。
+//
+// 操作包括 DB 上的 "Exec"、"Query"、"QueryRow"、"Prepare" 和 "Begin"；
+// Tx 上的 "Tx.Exec"、"Tx.Query"、"Tx.QueryRow"、"Tx.Prepare"、"Tx.Commit" 和
+// "Tx.Rollback"；以及 Stmt 上的 "Stmt.Exec"、"Stmt.Query" 和 "Stmt.QueryRow"。
+// QueryRow 产生的错误（包括 ErrNoRows）会在 Row.Scan 返回它们时被处理。
+//
+// 处理函数返回的错误会代替 err 交给调用者，因此它可以包装或替换该错误。
+// 若处理函数返回 nil，则原样返回 err：处理函数无法将失败（例如 ErrNoRows 或
+// ErrTxDone）变为成功。fn 为 nil 时会移除该处理函数。
+func (db *DB) SetErrorHandler(fn func(op, query string, err error) error) {
+	db.mu.Lock()
+	db.errHandler = fn
+	db.mu.Unlock()
+}
+
+// handleErr passes a non-nil err through the handler set by
+// SetErrorHandler. db may be nil.
+func (db *DB) handleErr(op, query string, err error) error {
+	if err == nil || db == nil {
+		return err
+	}
+	db.mu.Lock()
+	fn := db.errHandler
+	db.mu.Unlock()
+	if fn == nil {
+		return err
+	}
+	if herr := fn(op, query, err); herr != nil {
+		return herr
+	}
+	return err
+}
+
 // Assumes db.mu is locked.
 // If there are connRequests and the connection limit hasn't been reached,
 // then tell the connectionOpener to open new connections.
@@ -821,9 +1906,18 @@ func (db *DB) openNewConnection() {
 	// maybeOpenNewConnctions has already executed db.numOpen++ before it sent
 	// on db.openerCh. This function must execute db.numOpen-- if the
 	// connection fails or is closed before returning.
-	ci, err := db.driver.Open(db.dsn)
+	db.mu.Lock()
+	trial, err := db.breaker.allow()
+	db.mu.Unlock()
+	var ci driver.Conn
+	if err == nil {
+		ci, err = db.openDriverConn()
+	}
 	db.mu.Lock()
 	defer db.mu.Unlock()
+	if err != ErrCircuitOpen {
+		db.breaker.done(trial, err)
+	}
 	if db.closed {
 		if err == nil {
 			ci.Close()
@@ -838,12 +1932,15 @@ func (db *DB) openNewConnection() {
 		return
 	}
 	dc := &driverConn{
-		db:        db,
-		createdAt: nowFunc(),
-		ci:        ci,
+		db:         db,
+		createdAt:  nowFunc(),
+		ci:         ci,
+		generation: db.generation,
+		id:         db.newConnIDLocked(),
 	}
 	if db.putConnDBLocked(dc, err) {
 		db.addDepLocked(dc, dc)
+		db.emitLocked(PoolEvent{Type: ConnectionOpened, Conn: dc.infoLocked()})
 	} else {
 		db.numOpen--
 		ci.Close()
@@ -864,11 +1961,45 @@ var errDBClosed = errors.New("sql: database is closed")
 
 // conn 返回新打开的或已缓存的 *driverConn。
 func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
+	return db.connContext(context.Background(), strategy)
+}
+
+// connContext is like conn, but gives up with ctx.Err() if ctx is done
+// before a connection is obtained, including while waiting for one to
+// be returned to a saturated pool.
+func (db *DB) connContext(ctx context.Context, strategy connReuseStrategy) (*driverConn, error) {
+	dc, err := db.checkoutConn(ctx, strategy)
+	if err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	query := db.validationQuery
+	db.mu.Unlock()
+	if query != "" && validateConn(dc, query) != nil {
+		db.putConn(dc, driver.ErrBadConn)
+		return nil, driver.ErrBadConn
+	}
+	return dc, nil
+}
+
+// checkoutConn takes a connection from the pool for connContext.
+func (db *DB) checkoutConn(ctx context.Context, strategy connReuseStrategy) (*driverConn, error) {
+	tag := contextTag(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	db.mu.Lock()
 	if db.closed {
 		db.mu.Unlock()
 		return nil, errDBClosed
 	}
+	if db.draining {
+		db.mu.Unlock()
+		return nil, ErrDraining
+	}
+	if db.pool != nil {
+		return db.poolConnLocked(ctx)
+	}
 	lifetime := db.maxLifetime
 
 	// Prefer a free connection, if possible.
@@ -877,10 +2008,10 @@ func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
 		conn := db.freeConn[0]
 		copy(db.freeConn, db.freeConn[1:])
 		db.freeConn = db.freeConn[:numFree-1]
-		conn.inUse = true
+		conn.checkoutLocked(tag)
 		db.mu.Unlock()
 		if conn.expired(lifetime) {
-			conn.Close()
+			db.putConn(conn, driver.ErrBadConn)
 			return nil, driver.ErrBadConn
 		}
 		return conn, nil
@@ -889,44 +2020,97 @@ func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
 	// Out of free connections or we were asked not to use one. If we're not
 	// allowed to open any more connections, make a request and wait.
 	if db.maxOpen > 0 && db.numOpen >= db.maxOpen {
+		if db.nonBlocking {
+			db.mu.Unlock()
+			return nil, ErrPoolExhausted
+		}
 		// Make the connRequest channel. It's buffered so that the
 		// connectionOpener doesn't block while waiting for the req to be read.
 		req := make(chan connRequest, 1)
 		db.connRequests = append(db.connRequests, req)
+		if tag != "" {
+			if db.waiterTags == nil {
+				db.waiterTags = make(map[chan connRequest]string)
+			}
+			db.waiterTags[req] = tag
+		}
+		db.emitLocked(PoolEvent{Type: WaitStarted, Conn: ConnInfo{Tag: tag}})
 		db.mu.Unlock()
-		ret, ok := <-req
+		waitStart := time.Now()
+		var ret connRequest
+		var ok bool
+		select {
+		case ret, ok = <-req:
+		case <-ctx.Done():
+			db.cancelConnRequest(req)
+			db.emit(PoolEvent{Type: WaitEnded, Conn: ConnInfo{Tag: tag}, Duration: time.Since(waitStart), Err: ctx.Err()})
+			return nil, ctx.Err()
+		}
 		if !ok {
 			return nil, errDBClosed
 		}
+		db.emit(PoolEvent{Type: WaitEnded, Conn: ConnInfo{Tag: tag}, Duration: time.Since(waitStart), Err: ret.err})
 		if ret.err == nil && ret.conn.expired(lifetime) {
-			ret.conn.Close()
+			db.putConn(ret.conn, driver.ErrBadConn)
 			return nil, driver.ErrBadConn
 		}
 		return ret.conn, ret.err
 	}
 
+	trial, err := db.breaker.allow()
+	if err != nil {
+		db.mu.Unlock()
+		return nil, err
+	}
 	db.numOpen++ // optimistically
 	db.mu.Unlock()
-	ci, err := db.driver.Open(db.dsn)
+	ci, err := db.openDriverConn()
 	if err != nil {
 		db.mu.Lock()
 		db.numOpen-- // correct for earlier optimism
+		db.breaker.done(trial, err)
 		db.maybeOpenNewConnections()
 		db.mu.Unlock()
 		return nil, err
 	}
 	db.mu.Lock()
+	db.breaker.done(trial, nil)
 	dc := &driverConn{
-		db:        db,
-		createdAt: nowFunc(),
-		ci:        ci,
+		db:         db,
+		createdAt:  nowFunc(),
+		ci:         ci,
+		generation: db.generation,
+		id:         db.newConnIDLocked(),
 	}
 	db.addDepLocked(dc, dc)
-	dc.inUse = true
+	db.emitLocked(PoolEvent{Type: ConnectionOpened, Conn: dc.infoLocked()})
+	dc.checkoutLocked(tag)
 	db.mu.Unlock()
 	return dc, nil
 }
 
+// cancelConnRequest withdraws req, made by a caller of connContext
+// that is no longer waiting. A connection already sent on req is
+// returned to the pool.
+func (db *DB) cancelConnRequest(req chan connRequest) {
+	db.mu.Lock()
+	for i, r := range db.connRequests {
+		if r == req {
+			db.connRequests = append(db.connRequests[:i], db.connRequests[i+1:]...)
+			break
+		}
+	}
+	delete(db.waiterTags, req)
+	db.mu.Unlock()
+	select {
+	case ret, ok := <-req:
+		if ok && ret.err == nil {
+			db.putConn(ret.conn, nil)
+		}
+	default:
+	}
+}
+
 // putConnHook is a hook for testing.
 
 // putConnHook是一个测试使用的钩子。
@@ -963,6 +2147,13 @@ const debugGetPut = false
 // putConn 将连接加入到数据库的空置池中。
 // err 是连接过程中最后遇到的错误。
 func (db *DB) putConn(dc *driverConn, err error) {
+	discard := err == driver.ErrBadConn
+	if err != nil && !discard {
+		db.mu.Lock()
+		fn := db.discardOnError
+		db.mu.Unlock()
+		discard = fn != nil && fn(err)
+	}
 	db.mu.Lock()
 	if !dc.inUse {
 		if debugGetPut {
@@ -973,15 +2164,17 @@ func (db *DB) putConn(dc *driverConn, err error) {
 	if debugGetPut {
 		db.lastPut[dc] = stack()
 	}
-	dc.inUse = false
+	dc.checkinLocked()
+	db.emitLocked(PoolEvent{Type: ConnectionReturned, Conn: dc.infoLocked(), Err: err})
 
 	for _, fn := range dc.onPut {
 		fn()
 	}
 	dc.onPut = nil
 
-	if err == driver.ErrBadConn {
-		// Don't reuse bad connections.
+	if discard || dc.generation != db.generation || dc.cancelHung {
+		// Don't reuse bad connections, nor those opened before a
+		// Reset, nor those stuck cancelling a query.
 		// Since the conn is considered bad and is being discarded, treat it
 		// as closed. Don't decrement the open count here, finalClose will
 		// take care of that.
@@ -990,6 +2183,14 @@ func (db *DB) putConn(dc *driverConn, err error) {
 		dc.Close()
 		return
 	}
+	if db.pool != nil {
+		// The DB drops its state for the connection, such as the
+		// statements prepared on it, as it passes it back.
+		dc.poolOK = !db.closed
+		db.mu.Unlock()
+		dc.Close()
+		return
+	}
 	if putConnHook != nil {
 		putConnHook(db, dc)
 	}
@@ -1021,15 +2222,17 @@ func (db *DB) putConnDBLocked(dc *driverConn, err error) bool {
 		// moving the base instead?
 		copy(db.connRequests, db.connRequests[1:])
 		db.connRequests = db.connRequests[:c-1]
+		tag := db.waiterTags[req]
+		delete(db.waiterTags, req)
 		if err == nil {
-			dc.inUse = true
+			dc.checkoutLocked(tag)
 		}
 		req <- connRequest{
 			conn: dc,
 			err:  err,
 		}
 		return true
-	} else if err == nil && !db.closed && db.maxIdleConnsLocked() > len(db.freeConn) {
+	} else if err == nil && !db.closed && !db.draining && db.maxIdleConnsLocked() > len(db.freeConn) {
 		db.freeConn = append(db.freeConn, dc)
 		db.startCleanerLocked()
 		return true
@@ -1052,34 +2255,53 @@ const maxBadConnRetries = 2
 // 多个查询或执行操作可在返回的语句中并发地运行。
 // 当不再需要该语句时，调用者必须调用其 Close 方法。
 func (db *DB) Prepare(query string) (*Stmt, error) {
+	return db.prepareContext(context.Background(), query)
+}
+
+// prepareContext is Prepare, giving up with ctx.Err() if ctx is done
+// before the statement is prepared; see Handle.
+func (db *DB) prepareContext(ctx context.Context, query string) (*Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, db.handleErr("Prepare", query, err)
+	}
+	if err := db.checkReadOnly(query); err != nil {
+		return nil, db.handleErr("Prepare", query, err)
+	}
+	stmt, err := db.prepareRetry(ctx, db.maybeRebind(query))
+	return stmt, db.handleErr("Prepare", query, err)
+}
+
+func (db *DB) prepareRetry(ctx context.Context, query string) (*Stmt, error) {
 	var stmt *Stmt
 	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		stmt, err = db.prepare(query, cachedOrNewConn)
+		stmt, err = db.prepare(ctx, query, cachedOrNewConn)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.prepare(query, alwaysNewConn)
+		stmt, err = db.prepare(ctx, query, alwaysNewConn)
 	}
 	return stmt, err
 }
 
-func (db *DB) prepare(query string, strategy connReuseStrategy) (*Stmt, error) {
+func (db *DB) prepare(ctx context.Context, query string, strategy connReuseStrategy) (*Stmt, error) {
 	// TODO: check if db.driver supports an optional
 	// driver.Preparer interface and call that instead, if so,
 	// otherwise we make a prepared statement that's bound
 	// to a connection, and to execute this prepared statement
 	// we either need to use this connection (if it's free), else
 	// get a new connection + re-prepare + execute on that one.
-	dc, err := db.conn(strategy)
+	dc, err := db.connContext(ctx, strategy)
 	if err != nil {
 		return nil, err
 	}
-	dc.Lock()
-	si, err := dc.prepareLocked(query)
-	dc.Unlock()
+	si, err := db.prepareConn(dc, query)
+	if err == ErrPrepareTimeout {
+		// dc is discarded once the driver returns.
+		return nil, err
+	}
 	if err != nil {
 		db.putConn(dc, err)
 		return nil, err
@@ -1090,7 +2312,12 @@ func (db *DB) prepare(query string, strategy connReuseStrategy) (*Stmt, error) {
 		css:           []connStmt{{dc, si}},
 		lastNumClosed: atomic.LoadUint64(&db.numClosed),
 	}
-	db.addDep(stmt, stmt)
+	db.mu.Lock()
+	if n := db.stmtConns; n > 0 {
+		stmt.connSem = make(chan struct{}, n)
+	}
+	db.mu.Unlock()
+	db.trackStmt(stmt)
 	db.putConn(dc, nil)
 	return stmt, nil
 }
@@ -1101,37 +2328,70 @@ func (db *DB) prepare(query string, strategy connReuseStrategy) (*Stmt, error) {
 // Exec 执行query操作，而不返回任何行。
 // args 为查询中的任意占位符形参。
 func (db *DB) Exec(query string, args ...interface{}) (Result, error) {
+	return db.execContext(context.Background(), query, args)
+}
+
+// execContext is Exec, giving up with ctx.Err() if ctx is done before
+// the statement is sent; see Handle.
+func (db *DB) execContext(ctx context.Context, query string, args []interface{}) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, db.handleErr("Exec", query, err)
+	}
+	if err := db.checkReadOnly(query); err != nil {
+		return nil, db.handleErr("Exec", query, err)
+	}
+	slot, err := db.acquireQuery(ctx)
+	if err != nil {
+		return nil, db.handleErr("Exec", query, err)
+	}
+	defer slot.release()
+	query = db.maybeRebind(query)
+	cs, err := db.cachedStmt(ctx, query)
+	if cs != nil {
+		defer db.releaseCachedStmt(cs)
+		var res Result
+		res, err = cs.stmt.exec(ctx, args)
+		return res, db.handleErr("Exec", query, err)
+	}
+	if err != nil {
+		return nil, db.handleErr("Exec", query, err)
+	}
 	var res Result
-	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		res, err = db.exec(query, args, cachedOrNewConn)
+		res, err = db.exec(ctx, query, args, cachedOrNewConn)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.exec(query, args, alwaysNewConn)
+		res, err = db.exec(ctx, query, args, alwaysNewConn)
 	}
-	return res, err
+	return res, db.handleErr("Exec", query, err)
 }
 
-func (db *DB) exec(query string, args []interface{}, strategy connReuseStrategy) (res Result, err error) {
-	dc, err := db.conn(strategy)
+func (db *DB) exec(ctx context.Context, query string, args []interface{}, strategy connReuseStrategy) (res Result, err error) {
+	dc, err := db.connContext(ctx, strategy)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		db.putConn(dc, err)
 	}()
+	return execDC(dc, query, args)
+}
 
+// execDC executes query on dc, which the caller holds.
+func execDC(dc *driverConn, query string, args []interface{}) (Result, error) {
 	if execer, ok := dc.ci.(driver.Execer); ok {
-		dargs, err := driverArgs(nil, args)
+		dargs, err := driverArgs(&driverStmt{Locker: dc}, args)
 		if err != nil {
 			return nil, err
 		}
+		start := dc.startQuery(query)
 		dc.Lock()
 		resi, err := execer.Exec(query, dargs)
 		dc.Unlock()
+		dc.noteQuery(query, args, start, err)
 		if err != driver.ErrSkip {
 			if err != nil {
 				return nil, err
@@ -1147,7 +2407,7 @@ func (db *DB) exec(query string, args []interface{}, strategy connReuseStrategy)
 		return nil, err
 	}
 	defer withLock(dc, func() { si.Close() })
-	return resultFromStatement(driverStmt{dc, si}, args...)
+	return resultFromStatement(driverStmt{dc, si}, query, args...)
 }
 
 // Query executes a query that returns rows, typically a SELECT.
@@ -1156,53 +2416,177 @@ func (db *DB) exec(query string, args []interface{}, strategy connReuseStrategy)
 // Query执行了一个有返回行的查询操作，比如SELECT。
 // args 形参为该查询中的任何占位符。
 func (db *DB) Query(query string, args ...interface{}) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// ExecReturning executes a statement that modifies rows and also
+// returns some, such as an INSERT, UPDATE or DELETE with a RETURNING
+// clause, saving the second query Exec followed by Query would need.
+// It runs the statement as Query does. Once the returned Rows have
+// been read, their RowsAffected method reports the number of rows the
+// statement affected.
+//
+// Drivers and databases that can't return rows from a write report
+// that as they would for Query: typically the statement fails, or
+// returns no rows.
+
+// ExecReturning 执行一个修改行并同时返回某些行的语句，例如带有 RETURNING 子句的
+// INSERT、UPDATE 或 DELETE，从而省去先 Exec 再 Query 所需的第二次查询。它像 Query
+// 一样运行该语句。在读取完返回的 Rows 之后，其 RowsAffected 方法会报告该语句所影响的行数。
+//
+// 无法从写操作返回行的驱动和数据库会像对待 Query 一样报告这种情况：通常该语句会失败，
+// 或不返回任何行。
+func (db *DB) ExecReturning(query string, args ...interface{}) (*Rows, error) {
+	return db.execReturning(context.Background(), query, args)
+}
+
+// execReturning is ExecReturning, with the statement and its Rows
+// bound to ctx as by QueryContext; see Handle.
+func (db *DB) execReturning(ctx context.Context, query string, args []interface{}) (*Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, db.handleErr("ExecReturning", query, err)
+	}
+	rows, err := db.queryRetry(ctx, query, args)
+	if err != nil {
+		return nil, db.handleErr("ExecReturning", query, err)
+	}
+	rows.watchContext(ctx)
+	return rows, nil
+}
+
+// QueryContext is like Query, but the query and the iteration over
+// its Rows are bound to ctx. The query is not run if ctx is already
+// done. Once ctx is done, Next returns false and Err reports ctx.Err(),
+// and the Rows are closed. If the driver's Rows implement
+// driver.Interrupter, a Next blocked waiting for the database is
+// interrupted; otherwise it completes before the cancellation is seen.
+
+// QueryContext 类似于 Query，但该查询及对其 Rows 的遍历都与 ctx 绑定。若 ctx
+// 已经结束，则不会运行该查询。一旦 ctx 结束，Next 会返回 false，Err 会报告
+// ctx.Err()，并且 Rows 会被关闭。若驱动的 Rows 实现了 driver.Interrupter，
+// 正在等待数据库而阻塞的 Next 会被中断；否则它会先完成，之后才能察觉到取消。
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, db.handleErr("Query", query, err)
+	}
+	rows, err := db.queryRetry(ctx, query, args)
+	if err != nil {
+		return nil, db.handleErr("Query", query, err)
+	}
+	rows.watchContext(ctx)
+	return rows, nil
+}
+
+// queryRetry runs query on db, waiting for a slot under
+// SetMaxConcurrentQueries' limit, or until ctx is done. The Rows hold
+// the slot until they are closed.
+func (db *DB) queryRetry(ctx context.Context, query string, args []interface{}) (*Rows, error) {
+	return db.queryRetryRow(ctx, query, args, nil)
+}
+
+// queryRetryRow is like queryRetry. If buf is non-nil the query is for
+// QueryRow, and its storage may be allocated in *buf; see queryConnRow.
+func (db *DB) queryRetryRow(ctx context.Context, query string, args []interface{}, buf **rowBuf) (*Rows, error) {
+	slot, err := db.acquireQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.queryRetrySlot(ctx, query, args, buf)
+	if err != nil {
+		slot.release()
+		return nil, err
+	}
+	rows.slot = slot
+	return rows, nil
+}
+
+// queryRetrySlot runs query for queryRetryRow, once it has a slot.
+func (db *DB) queryRetrySlot(ctx context.Context, query string, args []interface{}, buf **rowBuf) (*Rows, error) {
+	if err := db.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	query = db.maybeRebind(query)
+	cs, err := db.cachedStmt(ctx, query)
+	if cs != nil {
+		defer db.releaseCachedStmt(cs)
+		return cs.stmt.queryRows(ctx, args)
+	}
+	if err != nil {
+		return nil, err
+	}
 	var rows *Rows
-	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		rows, err = db.query(query, args, cachedOrNewConn)
+		rows, err = db.query(ctx, query, args, cachedOrNewConn, buf)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.query(query, args, alwaysNewConn)
+		return db.query(ctx, query, args, alwaysNewConn, buf)
 	}
 	return rows, err
 }
 
-func (db *DB) query(query string, args []interface{}, strategy connReuseStrategy) (*Rows, error) {
-	ci, err := db.conn(strategy)
+func (db *DB) query(ctx context.Context, query string, args []interface{}, strategy connReuseStrategy, buf **rowBuf) (*Rows, error) {
+	ci, err := db.connContext(ctx, strategy)
 	if err != nil {
 		return nil, err
 	}
 
+	if buf != nil {
+		// A nil releaseConn saves allocating the method value.
+		return db.queryConnRow(ci, nil, query, args, buf)
+	}
 	return db.queryConn(ci, ci.releaseConn, query, args)
 }
 
 // queryConn executes a query on the given connection.
 // The connection gets released by the releaseConn function.
 func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, args []interface{}) (*Rows, error) {
+	return db.queryConnRow(dc, releaseConn, query, args, nil)
+}
+
+// queryConnRow is like queryConn. If buf is non-nil and dc is a
+// driver.Queryer, the Row, the Rows, the driver arguments and the
+// row's values share a single rowBuf, sparing their separate
+// allocations. It is kept in *buf, where a retry finds it again. A nil
+// releaseConn means dc.releaseConn.
+func (db *DB) queryConnRow(dc *driverConn, releaseConn func(error), query string, args []interface{}, buf **rowBuf) (*Rows, error) {
+	release := releaseConn
+	if release == nil {
+		release = dc.releaseConn
+	}
+	var b *rowBuf
 	if queryer, ok := dc.ci.(driver.Queryer); ok {
-		dargs, err := driverArgs(nil, args)
+		var argsBuf []driver.Value
+		if buf != nil {
+			if *buf == nil {
+				*buf = new(rowBuf)
+			}
+			b = *buf
+			argsBuf = b.args[:]
+		}
+		dargs, err := driverArgsInto(argsBuf, &driverStmt{Locker: dc}, args)
 		if err != nil {
-			releaseConn(err)
+			release(err)
 			return nil, err
 		}
+		start := dc.startQuery(query)
 		dc.Lock()
 		rowsi, err := queryer.Query(query, dargs)
 		dc.Unlock()
+		dc.noteQuery(query, args, start, err)
 		if err != driver.ErrSkip {
 			if err != nil {
-				releaseConn(err)
+				release(err)
 				return nil, err
 			}
 			// Note: ownership of dc passes to the *Rows, to be freed
 			// with releaseConn.
-			rows := &Rows{
-				dc:          dc,
-				releaseConn: releaseConn,
-				rowsi:       rowsi,
-			}
+			rows := b.newRows()
+			rows.dc = dc
+			rows.releaseConn = releaseConn
+			rows.rowsi = rowsi
 			return rows, nil
 		}
 	}
@@ -1211,40 +2595,68 @@ func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, a
 	si, err := dc.ci.Prepare(query)
 	dc.Unlock()
 	if err != nil {
-		releaseConn(err)
+		release(err)
 		return nil, err
 	}
 
 	ds := driverStmt{dc, si}
-	rowsi, err := rowsiFromStatement(ds, args...)
+	rowsi, err := rowsiFromStatement(ds, query, args...)
 	if err != nil {
 		dc.Lock()
 		si.Close()
 		dc.Unlock()
-		releaseConn(err)
+		release(err)
 		return nil, err
 	}
 
 	// Note: ownership of ci passes to the *Rows, to be freed
 	// with releaseConn.
-	rows := &Rows{
-		dc:          dc,
-		releaseConn: releaseConn,
-		rowsi:       rowsi,
-		closeStmt:   si,
-	}
+	rows := b.newRows()
+	rows.dc = dc
+	rows.releaseConn = releaseConn
+	rows.rowsi = rowsi
+	rows.closeStmt = si
 	return rows, nil
 }
 
 // QueryRow executes a query that is expected to return at most one row.
 // QueryRow always returns a non-nil value. Errors are deferred until
 // Row's Scan method is called.
+//
+// On a driver.Queryer, a lookup of one or two columns by one or two
+// arguments allocates little beyond what the driver allocates.
 
 // QueryRow执行一个至多只返回一行记录的查询操作。
 // QueryRow总是返回一个非空值。Error只会在调用行的Scan方法的时候才返回。
+//
+// 在 driver.Queryer 上，按一两个参数查找一两列时，
+// 除驱动自身的分配之外几乎不再分配内存。
 func (db *DB) QueryRow(query string, args ...interface{}) *Row {
-	rows, err := db.Query(query, args...)
-	return &Row{rows: rows, err: err}
+	return db.queryRow(context.Background(), query, args)
+}
+
+// queryRow is QueryRow, with the query bound to ctx as by
+// QueryContext; see Handle.
+func (db *DB) queryRow(ctx context.Context, query string, args []interface{}) *Row {
+	var buf *rowBuf
+	var rows *Rows
+	err := ctx.Err()
+	if err == nil {
+		rows, err = db.queryRetryRow(ctx, query, args, &buf)
+	}
+	if buf == nil {
+		if err == nil {
+			rows.watchContext(ctx)
+		}
+		return db.newRow("QueryRow", query, rows, err)
+	}
+	if err != nil {
+		buf.row = Row{err: db.handleErr("QueryRow", query, err)}
+		return &buf.row
+	}
+	rows.watchContext(ctx)
+	buf.row = Row{rows: rows, db: db, op: "QueryRow", query: query}
+	return &buf.row
 }
 
 // Begin starts a transaction. The isolation level is dependent on
@@ -1252,23 +2664,80 @@ func (db *DB) QueryRow(query string, args ...interface{}) *Row {
 
 // Begin开始一个事务。事务的隔离级别是由驱动决定的。
 func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background())
+}
+
+// BeginTx is like Begin, but gives up with ctx.Err() if ctx is done
+// before the transaction has started, including while waiting for a
+// connection when the pool is saturated. Once started, the
+// transaction is not affected by ctx.
+
+// BeginTx 类似于 Begin，但若 ctx 在事务开始之前（包括在连接池饱和时等待连接期间）
+// 结束，它会放弃并返回 ctx.Err()。事务一旦开始，便不再受 ctx 影响。
+func (db *DB) BeginTx(ctx context.Context) (*Tx, error) {
 	var tx *Tx
 	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		tx, err = db.begin(cachedOrNewConn)
+		tx, err = db.begin(ctx, cachedOrNewConn)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.begin(alwaysNewConn)
+		tx, err = db.begin(ctx, alwaysNewConn)
 	}
-	return tx, err
+	return tx, db.handleErr("Begin", "", err)
 }
 
-func (db *DB) begin(strategy connReuseStrategy) (tx *Tx, err error) {
-	dc, err := db.conn(strategy)
+// WithTx runs fn in a transaction. The transaction is committed if fn
+// returns nil and rolled back if fn returns an error, which WithTx
+// then returns. If fn panics, the transaction is rolled back and the
+// panic continues, so the transaction never outlives the call.
+//
+// The transaction is not started if ctx is already done, and it is
+// rolled back rather than committed if ctx is done by the time fn
+// returns; WithTx returns ctx.Err() in both cases. Otherwise the
+// result is the error from Begin, fn or Commit.
+
+// WithTx 在一个事务中运行 fn。若 fn 返回 nil，则提交该事务；若 fn 返回错误，
+// 则回滚该事务，并由 WithTx 返回该错误。若 fn 发生 panic，事务会被回滚，
+// 而 panic 会继续传播，因此事务绝不会在调用结束后残留。
+//
+// 若 ctx 已经结束，则不会开始事务；若 fn 返回时 ctx 已经结束，则事务会被回滚而非提交。
+// 这两种情况下 WithTx 均返回 ctx.Err()。否则，其结果为 Begin、fn 或 Commit 返回的错误。
+func (db *DB) WithTx(ctx context.Context, fn func(*Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx)
 	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) begin(ctx context.Context, strategy connReuseStrategy) (tx *Tx, err error) {
+	dc, err := db.connContext(ctx, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		// Don't start a transaction only to abandon it.
+		db.putConn(dc, nil)
 		return nil, err
 	}
 	dc.Lock()
@@ -1278,11 +2747,13 @@ func (db *DB) begin(strategy connReuseStrategy) (tx *Tx, err error) {
 		db.putConn(dc, err)
 		return nil, err
 	}
-	return &Tx{
+	tx = &Tx{
 		db:  db,
 		dc:  dc,
 		txi: txi,
-	}, nil
+	}
+	runtime.SetFinalizer(tx, (*Tx).rollbackOrphan)
+	return tx, nil
 }
 
 // Driver returns the database's underlying driver.
@@ -1302,6 +2773,14 @@ func (db *DB) Driver() driver.Driver {
 // The statements prepared for a transaction by calling
 // the transaction's Prepare or Stmt methods are closed
 // by the call to Commit or Rollback.
+//
+// A transaction that becomes unreachable without Commit or Rollback
+// is rolled back when it is garbage collected, with a logged warning
+// and a TxLeaked event, so that its connection returns to the pool.
+// This is a safety net for bugs; it may happen much later, or not at
+// all. In particular, statements prepared for the transaction that
+// have not been closed refer to it and keep it from being garbage
+// collected.
 
 // Tx代表运行中的数据库事务。
 //
@@ -1311,6 +2790,10 @@ func (db *DB) Driver() driver.Driver {
 //
 // 该语句通过调用事务的 Prepare 或 Stmt 方法来准备，调用事务的 Commit 或 Rollback
 // 方法来结束。
+//
+// 未调用 Commit 或 Rollback 就变得不可达的事务会在被垃圾回收时回滚，同时记录一条警告并发送
+// 一个 TxLeaked 事件，以便其连接返回连接池。这是针对程序缺陷的安全措施；它可能发生得很晚，
+// 或根本不会发生。特别地，为该事务准备且尚未关闭的语句会引用它，从而使其不会被垃圾回收。
 type Tx struct {
 	db *DB
 
@@ -1331,24 +2814,246 @@ type Tx struct {
 	// 一旦这个标志位设置为true，所有事务的操作都会失败并返回ErrTxDone。
 	done bool
 
-	// All Stmts prepared for this transaction. These will be closed after the
-	// transaction has been committed or rolled back.
+	// All Stmts prepared for this transaction that are still open.
+	// These will be closed after the transaction has been committed
+	// or rolled back.
 	stmts struct {
 		sync.Mutex
 		v []*Stmt
 	}
+
+	// numStmts counts the statements run on dc; see StatementCount.
+	// It is accessed atomically.
+	numStmts int64
+
+	// openRows counts the Rows from the transaction that have not
+	// been closed; see trackRows. It is accessed atomically.
+	openRows int32
+
+	// leaked is set once rollbackOrphan has reported the transaction.
+	leaked bool
+
+	// stmtTimeout is set by SetStatementTimeout. If nativeTimeout,
+	// the driver enforces it and must be reset before dc is
+	// returned; otherwise it bounds the iteration of Rows.
+	stmtTimeout   time.Duration
+	nativeTimeout bool
 }
 
 var ErrTxDone = errors.New("sql: Transaction has already been committed or rolled back")
 
+// StatementCount returns the number of statements executed on the
+// transaction's connection since Begin: those run by the
+// transaction's Exec, Query and QueryRow and by its statements. It is
+// meant for debugging code that relies on a sequence of queries
+// sharing one connection and its session state.
+
+// StatementCount 返回自 Begin 以来在该事务的连接上执行的语句数：包括由事务的 Exec、
+// Query 和 QueryRow 以及其语句所执行的语句。它用于调试依赖一系列查询共享同一连接
+// 及其会话状态的代码。
+func (tx *Tx) StatementCount() int64 {
+	return atomic.LoadInt64(&tx.numStmts)
+}
+
+// SetStatementTimeout bounds the running time of each statement
+// subsequently executed in the transaction to d. If d <= 0, the bound
+// is removed.
+//
+// If the driver's connection implements driver.StatementTimeouter,
+// the timeout is enforced by the database for Exec and Query alike,
+// and it is reset before the connection is returned to the pool.
+// Otherwise the sql package enforces it only for the Rows returned by
+// the transaction's Query and QueryRow and by its statements: once d
+// has passed since the query, Next fails with
+// context.DeadlineExceeded, and a driver whose Rows implement
+// driver.Interrupter is interrupted. Exec is then not bounded.
+//
+// SetStatementTimeout must not be called concurrently with other
+// operations on the transaction.
+
+// SetStatementTimeout 将事务中随后执行的每条语句的运行时间限制为 d。若 d <= 0，
+// 则取消该限制。
+//
+// 若驱动的连接实现了 driver.StatementTimeouter，超时会由数据库对 Exec 和 Query
+// 同样地强制执行，并在连接归还连接池之前被重置。否则，sql 包只对由事务的 Query 和
+// QueryRow 以及其语句返回的 Rows 强制执行该超时：自查询起经过 d 之后，Next 会以
+// context.DeadlineExceeded 失败，且其 Rows 实现了 driver.Interrupter 的驱动会被中断。
+// 此时 Exec 不受限制。
+//
+// SetStatementTimeout 不得与该事务上的其它操作并发调用。
+func (tx *Tx) SetStatementTimeout(d time.Duration) error {
+	if d < 0 {
+		d = 0
+	}
+	dc, err := tx.grabConn()
+	if err != nil {
+		return err
+	}
+	if st, ok := dc.ci.(driver.StatementTimeouter); ok {
+		dc.Lock()
+		err := st.SetStatementTimeout(d)
+		dc.Unlock()
+		if err != nil {
+			return err
+		}
+		tx.nativeTimeout = true
+	}
+	tx.stmtTimeout = d
+	return nil
+}
+
+// ErrDeferConstraintsNotSupported is returned by DeferConstraints and
+// ImmediateConstraints when neither the driver nor the database's
+// dialect can set when constraints are checked.
+
+// ErrDeferConstraintsNotSupported 会在驱动和数据库方言都无法设置约束检查时机时，由
+// DeferConstraints 和 ImmediateConstraints 返回。
+var ErrDeferConstraintsNotSupported = errors.New("sql: driver does not support deferring constraints")
+
+// DeferConstraints makes the transaction's deferrable constraints,
+// such as foreign keys declared DEFERRABLE, checked only at Commit, so
+// that rows can be inserted in any order, as in bulk imports. It only
+// affects the current transaction; constraints not declared deferrable
+// are still checked after each statement.
+//
+// If the driver's connection implements driver.ConstraintDeferrer, it
+// makes the change. Otherwise the statement given by the SetConstraints
+// field of the database's dialect is executed, such as PostgreSQL's
+// SET CONSTRAINTS ALL DEFERRED. If the dialect has none,
+// DeferConstraints returns ErrDeferConstraintsNotSupported.
+
+// DeferConstraints 使事务中可延迟的约束（例如声明为 DEFERRABLE 的外键）只在 Commit
+// 时检查，从而可以按任意顺序插入行，例如在批量导入时。它只影响当前事务；未声明为可
+// 延迟的约束仍会在每条语句之后检查。
+//
+// 若驱动的连接实现了 driver.ConstraintDeferrer，则由它进行更改。否则会执行数据库方言
+// 的 SetConstraints 字段所给出的语句，例如 PostgreSQL 的 SET CONSTRAINTS ALL DEFERRED。
+// 若方言没有该语句，DeferConstraints 返回 ErrDeferConstraintsNotSupported。
+func (tx *Tx) DeferConstraints() error {
+	return tx.db.handleErr("Tx.DeferConstraints", "", tx.setConstraints(true))
+}
+
+// ImmediateConstraints undoes DeferConstraints: the transaction's
+// deferrable constraints are checked after each statement again, and
+// those violated by earlier statements fail it now.
+
+// ImmediateConstraints 撤销 DeferConstraints 的效果：事务中可延迟的约束会重新在每条
+// 语句之后检查，而之前的语句所违反的约束会在此时导致失败。
+func (tx *Tx) ImmediateConstraints() error {
+	return tx.db.handleErr("Tx.ImmediateConstraints", "", tx.setConstraints(false))
+}
+
+func (tx *Tx) setConstraints(deferred bool) error {
+	dc, err := tx.grabConn()
+	if err != nil {
+		return err
+	}
+	if cd, ok := dc.ci.(driver.ConstraintDeferrer); ok {
+		dc.Lock()
+		err := cd.SetConstraintsDeferred(deferred)
+		dc.Unlock()
+		return err
+	}
+	stmt := tx.db.Dialect().SetConstraints
+	if stmt == "" {
+		return ErrDeferConstraintsNotSupported
+	}
+	if deferred {
+		stmt += "DEFERRED"
+	} else {
+		stmt += "IMMEDIATE"
+	}
+	_, err = tx.exec(stmt, nil)
+	return err
+}
+
+// trackRows counts rows, from the transaction, in tx.openRows until it
+// is closed, and bounds it by the statement timeout.
+func (tx *Tx) trackRows(rows *Rows) {
+	atomic.AddInt32(&tx.openRows, 1)
+	release := rows.releaseConn
+	rows.releaseConn = func(err error) {
+		release(err)
+		atomic.AddInt32(&tx.openRows, -1)
+	}
+	tx.watchTimeout(rows)
+}
+
+// watchTimeout bounds the iteration of rows by the statement timeout,
+// when the driver doesn't enforce it.
+func (tx *Tx) watchTimeout(rows *Rows) {
+	if tx.nativeTimeout || tx.stmtTimeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tx.stmtTimeout)
+	rows.cancel = cancel
+	rows.watchContext(ctx)
+}
+
+// close releases the transaction's connection. err is the result of
+// the terminal Commit or Rollback; if it shows the connection was lost,
+// the connection is discarded rather than pooled.
 func (tx *Tx) close(err error) {
 	if tx.done {
 		panic("double close") // internal error
 	}
 	tx.done = true
+	if connLost(err) {
+		err = driver.ErrBadConn
+	}
+	if tx.nativeTimeout {
+		tx.dc.Lock()
+		rerr := tx.dc.ci.(driver.StatementTimeouter).SetStatementTimeout(0)
+		tx.dc.Unlock()
+		if rerr != nil && err == nil {
+			// Don't hand out a connection with a leftover limit.
+			err = driver.ErrBadConn
+		}
+	}
 	tx.db.putConn(tx.dc, err)
 	tx.dc = nil
 	tx.txi = nil
+	runtime.SetFinalizer(tx, nil)
+}
+
+// rollbackOrphan is the finalizer of a Tx. A transaction that is
+// garbage collected without Commit or Rollback would otherwise pin its
+// connection forever, so it is reported, rolled back and the
+// connection returned to the pool. Rows from the transaction keep it
+// reachable, but they may have been dropped without Close too. The
+// driver's rows may then still be using the connection, so the
+// finalizer is set again to retry after the next collection, in case
+// they are closed meanwhile, such as by the statement timeout.
+func (tx *Tx) rollbackOrphan() {
+	if tx.done {
+		return
+	}
+	if !tx.leaked {
+		tx.leaked = true
+		tx.db.mu.Lock()
+		tx.db.emitLocked(PoolEvent{Type: TxLeaked, Conn: tx.dc.infoLocked()})
+		tx.db.mu.Unlock()
+		log.Printf("sql: Tx garbage collected without Commit or Rollback; rolling it back")
+	}
+	if atomic.LoadInt32(&tx.openRows) > 0 {
+		runtime.SetFinalizer(tx, (*Tx).rollbackOrphan)
+		return
+	}
+	tx.dc.Lock()
+	err := tx.txi.Rollback()
+	tx.dc.Unlock()
+	if err != driver.ErrBadConn {
+		tx.closePrepared()
+	}
+	tx.close(err)
+}
+
+// connLost reports whether err, returned by a driver operation, means
+// the connection can no longer be used. A driver that gives up on a
+// statement because its context was cancelled may leave the
+// connection mid-protocol, so context errors count as lost too.
+func connLost(err error) bool {
+	return err == driver.ErrBadConn || err == context.Canceled || err == context.DeadlineExceeded
 }
 
 func (tx *Tx) grabConn() (*driverConn, error) {
@@ -1361,9 +3066,27 @@ func (tx *Tx) grabConn() (*driverConn, error) {
 // Closes all Stmts prepared for this transaction.
 func (tx *Tx) closePrepared() {
 	tx.stmts.Lock()
-	for _, stmt := range tx.stmts.v {
+	stmts := tx.stmts.v
+	tx.stmts.v = nil
+	tx.stmts.Unlock()
+	for _, stmt := range stmts {
 		stmt.Close()
 	}
+}
+
+// removeStmt removes stmt, which is closed, from tx.stmts, so that it
+// no longer keeps the transaction from being garbage collected.
+func (tx *Tx) removeStmt(stmt *Stmt) {
+	tx.stmts.Lock()
+	v := tx.stmts.v
+	for i := range v {
+		if v[i] == stmt {
+			copy(v[i:], v[i+1:])
+			v[len(v)-1] = nil // don't keep stmt reachable
+			tx.stmts.v = v[:len(v)-1]
+			break
+		}
+	}
 	tx.stmts.Unlock()
 }
 
@@ -1372,7 +3095,7 @@ func (tx *Tx) closePrepared() {
 // Commit提交事务。
 func (tx *Tx) Commit() error {
 	if tx.done {
-		return ErrTxDone
+		return tx.db.handleErr("Tx.Commit", "", ErrTxDone)
 	}
 	tx.dc.Lock()
 	err := tx.txi.Commit()
@@ -1381,7 +3104,7 @@ func (tx *Tx) Commit() error {
 		tx.closePrepared()
 	}
 	tx.close(err)
-	return err
+	return tx.db.handleErr("Tx.Commit", "", err)
 }
 
 // Rollback aborts the transaction.
@@ -1389,7 +3112,7 @@ func (tx *Tx) Commit() error {
 // Rollback回滚事务。
 func (tx *Tx) Rollback() error {
 	if tx.done {
-		return ErrTxDone
+		return tx.db.handleErr("Tx.Rollback", "", ErrTxDone)
 	}
 	tx.dc.Lock()
 	err := tx.txi.Rollback()
@@ -1398,7 +3121,7 @@ func (tx *Tx) Rollback() error {
 		tx.closePrepared()
 	}
 	tx.close(err)
-	return err
+	return tx.db.handleErr("Tx.Rollback", "", err)
 }
 
 // Prepare creates a prepared statement for use within a transaction.
@@ -1414,6 +3137,14 @@ func (tx *Tx) Rollback() error {
 //
 // 关于如何使用定义好的操作声明，请参考Tx.Stmt。
 func (tx *Tx) Prepare(query string) (*Stmt, error) {
+	stmt, err := tx.prepare(query)
+	return stmt, tx.db.handleErr("Tx.Prepare", query, err)
+}
+
+func (tx *Tx) prepare(query string) (*Stmt, error) {
+	if err := tx.db.checkReadOnly(query); err != nil {
+		return nil, err
+	}
 	// TODO(bradfitz): We could be more efficient here and either
 	// provide a method to take an existing Stmt (created on
 	// perhaps a different Conn), and re-create it on this Conn if
@@ -1432,6 +3163,7 @@ func (tx *Tx) Prepare(query string) (*Stmt, error) {
 		return nil, err
 	}
 
+	query = tx.db.maybeRebind(query)
 	dc.Lock()
 	si, err := dc.ci.Prepare(query)
 	dc.Unlock()
@@ -1478,11 +3210,6 @@ func (tx *Tx) Prepare(query string) (*Stmt, error) {
 //
 // 返回的语句用于在事务中进行操作。一旦该事务被提交或回滚，该语句便不再使用。
 func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
-	// TODO(bradfitz): optimize this. Currently this re-prepares
-	// each time. This is fine for now to illustrate the API but
-	// we should really cache already-prepared statements
-	// per-Conn. See also the big comment in Tx.Prepare.
-
 	if tx.db != stmt.db {
 		return &Stmt{stickyErr: errors.New("sql: Tx.Stmt: statement from different database used")}
 	}
@@ -1490,22 +3217,48 @@ func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 	if err != nil {
 		return &Stmt{stickyErr: err}
 	}
-	dc.Lock()
-	si, err := dc.ci.Prepare(stmt.query)
-	dc.Unlock()
 	txs := &Stmt{
-		db: tx.db,
-		tx: tx,
-		txsi: &driverStmt{
+		db:    tx.db,
+		tx:    tx,
+		query: stmt.query,
+	}
+
+	// If stmt is already prepared on the transaction's connection,
+	// reuse its driver statement rather than preparing it again.
+	// stmt then depends on txs's driver statement, so that stmt's
+	// driver statements stay open until txs is closed.
+	var si driver.Stmt
+	stmt.mu.Lock()
+	if !stmt.closed && stmt.tx == nil {
+		for _, v := range stmt.css {
+			if v.dc == dc {
+				si = v.si
+				txs.parentStmt = stmt
+				txs.txsi = &driverStmt{Locker: dc, si: v.si}
+				tx.db.addDep(stmt, txs.txsi)
+				break
+			}
+		}
+	}
+	stmt.mu.Unlock()
+
+	if si == nil {
+		dc.Lock()
+		si, err = dc.ci.Prepare(stmt.query)
+		dc.Unlock()
+	}
+	if txs.txsi == nil {
+		txs.txsi = &driverStmt{
 			Locker: dc,
 			si:     si,
-		},
-		query:     stmt.query,
-		stickyErr: err,
+		}
+	}
+	txs.stickyErr = err
+	if err == nil {
+		tx.stmts.Lock()
+		tx.stmts.v = append(tx.stmts.v, txs)
+		tx.stmts.Unlock()
 	}
-	tx.stmts.Lock()
-	tx.stmts.v = append(tx.stmts.v, txs)
-	tx.stmts.Unlock()
 	return txs
 }
 
@@ -1515,19 +3268,31 @@ func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 // Exec执行不返回任何行的操作。
 // 例如：INSERT和UPDATE操作。
 func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
+	res, err := tx.exec(query, args)
+	return res, tx.db.handleErr("Tx.Exec", query, err)
+}
+
+func (tx *Tx) exec(query string, args []interface{}) (Result, error) {
+	if err := tx.db.checkReadOnly(query); err != nil {
+		return nil, err
+	}
 	dc, err := tx.grabConn()
 	if err != nil {
 		return nil, err
 	}
+	atomic.AddInt64(&tx.numStmts, 1)
+	query = tx.db.maybeRebind(query)
 
 	if execer, ok := dc.ci.(driver.Execer); ok {
-		dargs, err := driverArgs(nil, args)
+		dargs, err := driverArgs(&driverStmt{Locker: dc}, args)
 		if err != nil {
 			return nil, err
 		}
+		start := dc.startQuery(query)
 		dc.Lock()
 		resi, err := execer.Exec(query, dargs)
 		dc.Unlock()
+		dc.noteQuery(query, args, start, err)
 		if err == nil {
 			return driverResult{dc, resi}, nil
 		}
@@ -1544,19 +3309,33 @@ func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
 	}
 	defer withLock(dc, func() { si.Close() })
 
-	return resultFromStatement(driverStmt{dc, si}, args...)
+	return resultFromStatement(driverStmt{dc, si}, query, args...)
 }
 
 // Query executes a query that returns rows, typically a SELECT.
 
 // Query执行哪些返回行的查询操作，比如SELECT。
 func (tx *Tx) Query(query string, args ...interface{}) (*Rows, error) {
+	rows, err := tx.query(query, args)
+	return rows, tx.db.handleErr("Tx.Query", query, err)
+}
+
+func (tx *Tx) query(query string, args []interface{}) (*Rows, error) {
+	if err := tx.db.checkReadOnly(query); err != nil {
+		return nil, err
+	}
 	dc, err := tx.grabConn()
 	if err != nil {
 		return nil, err
 	}
+	atomic.AddInt64(&tx.numStmts, 1)
 	releaseConn := func(error) {}
-	return tx.db.queryConn(dc, releaseConn, query, args)
+	rows, err := tx.db.queryConn(dc, releaseConn, tx.db.maybeRebind(query), args)
+	if err != nil {
+		return nil, err
+	}
+	tx.trackRows(rows)
+	return rows, nil
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -1566,8 +3345,36 @@ func (tx *Tx) Query(query string, args ...interface{}) (*Rows, error) {
 // QueryRow执行的查询至多返回一行数据。
 // QueryRow总是返回非空值。只有当执行行的Scan方法的时候，才会返回Error。
 func (tx *Tx) QueryRow(query string, args ...interface{}) *Row {
-	rows, err := tx.Query(query, args...)
-	return &Row{rows: rows, err: err}
+	rows, err := tx.query(query, args)
+	return tx.db.newRow("Tx.QueryRow", query, rows, err)
+}
+
+// Raw calls f with the driver.Conn that the transaction is running
+// on, so that f can use driver-specific features on exactly the
+// transaction's connection. No other use of the connection, by the
+// transaction or its statements, runs while f does.
+//
+// f must not commit or roll back the transaction through the driver,
+// close the connection, or retain it after returning. Nor may it use
+// the transaction or its statements: they wait for f to return, so
+// such a call deadlocks. Raw returns
+// ErrTxDone if the transaction has already been committed or rolled
+// back, and otherwise the error returned by f.
+
+// Raw 以事务所运行的 driver.Conn 调用 f，使 f 能够恰好在该事务的连接上使用
+// 驱动特有的功能。f 运行期间，事务及其语句对该连接的其它使用都不会运行。
+//
+// f 不得通过驱动提交或回滚事务、关闭连接，也不得在返回后继续持有该连接。
+// f 也不得使用该事务或其语句：它们会等待 f 返回，因此这样的调用会造成死锁。
+// 若事务已被提交或回滚，Raw 返回 ErrTxDone；否则返回 f 所返回的错误。
+func (tx *Tx) Raw(f func(driverConn interface{}) error) error {
+	dc, err := tx.grabConn()
+	if err != nil {
+		return err
+	}
+	dc.Lock()
+	defer dc.Unlock()
+	return f(dc.ci)
 }
 
 // connStmt is a prepared statement on a particular connection.
@@ -1592,15 +3399,33 @@ type Stmt struct {
 
 	closemu sync.RWMutex // held exclusively during close, for read otherwise.
 
+	// connSem, if non-nil, holds a token for each connection in use
+	// by the Stmt; see SetMaxStmtConns.
+	connSem chan struct{}
+
 	// If in a transaction, else both nil:
 
 	// 只有在事务中，者两个值才都非空，其他情况下都是空的：
 	tx   *Tx
 	txsi *driverStmt
 
+	// parentStmt, if non-nil, is the Stmt passed to Tx.Stmt whose
+	// driver statement txsi shares; it is closed by parentStmt,
+	// not by this Stmt.
+	parentStmt *Stmt
+
+	// prepared is the DB's record of a statement prepared on the DB;
+	// see PreparedStatements.
+	prepared *preparedStmt
+
 	mu     sync.Mutex // protects the rest of the fields // 保护其他字段
 	closed bool
 
+	// txRows counts the open Rows from a transaction's statement,
+	// which keep txsi open after Close. Those from a DB's statement
+	// are dependencies of it in db.dep instead.
+	txRows int
+
 	// css is a list of underlying driver statement interfaces
 	// that are valid on particular connections. This is only
 	// used if tx == nil and one is found that has idle
@@ -1620,20 +3445,36 @@ type Stmt struct {
 
 // Exec根据给出的参数执行定义好的声明，并返回Result来显示执行的结果。
 func (s *Stmt) Exec(args ...interface{}) (Result, error) {
+	res, err := s.exec(context.Background(), args)
+	return res, s.db.handleErr("Stmt.Exec", s.query, err)
+}
+
+// exec runs the statement, giving up with ctx.Err() if ctx is done
+// while it waits for a connection.
+func (s *Stmt) exec(ctx context.Context, args []interface{}) (Result, error) {
 	s.closemu.RLock()
 	defer s.closemu.RUnlock()
 
 	var res Result
-	for i := 0; i < maxBadConnRetries; i++ {
-		dc, releaseConn, si, err := s.connStmt()
+	for i := 0; i <= maxBadConnRetries; i++ {
+		// Once the pool has handed out only bad or expired
+		// connections, prepare the statement on a new one.
+		strategy := cachedOrNewConn
+		if i == maxBadConnRetries {
+			strategy = alwaysNewConn
+		}
+		dc, releaseConn, si, err := s.connStmt(ctx, strategy)
 		if err != nil {
 			if err == driver.ErrBadConn {
 				continue
 			}
 			return nil, err
 		}
+		if s.tx != nil {
+			atomic.AddInt64(&s.tx.numStmts, 1)
+		}
 
-		res, err = resultFromStatement(driverStmt{dc, si}, args...)
+		res, err = resultFromStatement(driverStmt{dc, si}, s.query, args...)
 		releaseConn(err)
 		if err != driver.ErrBadConn {
 			return res, err
@@ -1648,8 +3489,28 @@ func driverNumInput(ds driverStmt) int {
 	return ds.si.NumInput()
 }
 
-func resultFromStatement(ds driverStmt, args ...interface{}) (Result, error) {
+// numInput returns the number of placeholders of the statement ds,
+// prepared from query. If the statement doesn't know, the connection
+// is asked, if it implements driver.QueryInputCounter.
+func numInput(ds driverStmt, query string) int {
 	want := driverNumInput(ds)
+	if want != -1 {
+		return want
+	}
+	dc, ok := ds.Locker.(*driverConn)
+	if !ok {
+		return -1
+	}
+	if qc, ok := dc.ci.(driver.QueryInputCounter); ok {
+		dc.Lock()
+		want = qc.NumQueryInput(query)
+		dc.Unlock()
+	}
+	return want
+}
+
+func resultFromStatement(ds driverStmt, query string, args ...interface{}) (Result, error) {
+	want := numInput(ds, query)
 
 	// -1 means the driver doesn't know how to count the number of
 	// placeholders, so we won't sanity check input here and instead let the
@@ -1665,9 +3526,12 @@ func resultFromStatement(ds driverStmt, args ...interface{}) (Result, error) {
 		return nil, err
 	}
 
-	ds.Lock()
-	defer ds.Unlock()
-	resi, err := ds.si.Exec(dargs)
+	var resi driver.Result
+	start := startStmtQuery(ds, query)
+	withLock(ds, func() {
+		resi, err = ds.si.Exec(dargs)
+	})
+	noteStmtQuery(ds, query, args, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1687,7 +3551,12 @@ func (s *Stmt) removeClosedStmtLocked() {
 	if dbClosed-s.lastNumClosed < uint64(t) {
 		return
 	}
+	s.removeAllClosedStmtLocked(dbClosed)
+}
 
+// removeAllClosedStmtLocked removes closed conns in s.css
+// unconditionally, recording dbClosed as s.lastNumClosed.
+func (s *Stmt) removeAllClosedStmtLocked(dbClosed uint64) {
 	s.db.mu.Lock()
 	for i := 0; i < len(s.css); i++ {
 		if s.css[i].dc.dbmuClosed {
@@ -1700,19 +3569,54 @@ func (s *Stmt) removeClosedStmtLocked() {
 	s.lastNumClosed = dbClosed
 }
 
+// Reset forgets the connections the statement was prepared on that
+// have since been closed, such as after the database restarted, so
+// that later executions prepare it afresh on other connections without
+// first looking through the stale ones. The statement normally does
+// this lazily as connections close; Reset does it at once. It is a
+// no-op for a statement belonging to a transaction, and returns an
+// error if the statement is closed.
+
+// Reset 会忘掉该语句曾在其上准备、但此后已被关闭的连接（例如在数据库重启之后），
+// 这样之后的执行会在其它连接上重新准备该语句，而无需先查看那些失效的连接。
+// 语句通常会在连接关闭时惰性地完成这一工作；Reset 则立即完成。对于属于事务的语句，
+// 它不做任何事；若语句已关闭，则返回错误。
+func (s *Stmt) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("sql: statement is closed")
+	}
+	if s.tx == nil {
+		s.removeAllClosedStmtLocked(atomic.LoadUint64(&s.db.numClosed))
+	}
+	return nil
+}
+
 // connStmt returns a free driver connection on which to execute the
 // statement, a function to call to release the connection, and a
-// statement bound to that connection.
+// statement bound to that connection. Outside a transaction, the
+// connection is obtained with strategy, giving up with ctx.Err() if
+// ctx is done first, and the statement prepared on it if it isn't
+// already.
 
 // connStmt返回空闲的驱动连接，这个连接是用来执行这个声明的，并且同时定义一个函数来释放连接，
 // 定义一个声明绑定连接。
-func (s *Stmt) connStmt() (ci *driverConn, releaseConn func(error), si driver.Stmt, err error) {
+func (s *Stmt) connStmt(ctx context.Context, strategy connReuseStrategy) (ci *driverConn, releaseConn func(error), si driver.Stmt, err error) {
 	if err = s.stickyErr; err != nil {
 		return
 	}
+	if err = s.db.checkReadOnly(s.query); err != nil {
+		return
+	}
 	s.mu.Lock()
 	if s.closed {
 		s.mu.Unlock()
+		if s.tx != nil && s.tx.done {
+			// Closed by the transaction's Commit or Rollback.
+			err = ErrTxDone
+			return
+		}
 		err = errors.New("sql: statement is closed")
 		return
 	}
@@ -1734,17 +3638,33 @@ func (s *Stmt) connStmt() (ci *driverConn, releaseConn func(error), si driver.St
 	s.removeClosedStmtLocked()
 	s.mu.Unlock()
 
+	if s.connSem != nil {
+		select {
+		case s.connSem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		}
+	}
+
 	// TODO(bradfitz): or always wait for one? make configurable later?
-	dc, err := s.db.conn(cachedOrNewConn)
+	dc, err := s.db.connContext(ctx, strategy)
 	if err != nil {
+		s.releaseSem()
 		return nil, nil, nil, err
 	}
+	releaseConn = dc.releaseConn
+	if s.connSem != nil {
+		releaseConn = func(err error) {
+			dc.releaseConn(err)
+			s.releaseSem()
+		}
+	}
 
 	s.mu.Lock()
 	for _, v := range s.css {
 		if v.dc == dc {
 			s.mu.Unlock()
-			return dc, dc.releaseConn, v.si, nil
+			return dc, releaseConn, v.si, nil
 		}
 	}
 	s.mu.Unlock()
@@ -1754,7 +3674,7 @@ func (s *Stmt) connStmt() (ci *driverConn, releaseConn func(error), si driver.St
 	si, err = dc.prepareLocked(s.query)
 	dc.Unlock()
 	if err != nil {
-		s.db.putConn(dc, err)
+		releaseConn(err)
 		return nil, nil, nil, err
 	}
 	s.mu.Lock()
@@ -1762,7 +3682,14 @@ func (s *Stmt) connStmt() (ci *driverConn, releaseConn func(error), si driver.St
 	s.css = append(s.css, cs)
 	s.mu.Unlock()
 
-	return dc, dc.releaseConn, si, nil
+	return dc, releaseConn, si, nil
+}
+
+// releaseSem gives back a token taken from s.connSem, if any.
+func (s *Stmt) releaseSem() {
+	if s.connSem != nil {
+		<-s.connSem
+	}
 }
 
 // Query executes a prepared query statement with the given arguments
@@ -1770,20 +3697,36 @@ func (s *Stmt) connStmt() (ci *driverConn, releaseConn func(error), si driver.St
 
 // Query根据传递的参数执行一个声明的查询操作，然后以*Rows的结果返回查询结果。
 func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
+	rows, err := s.queryRows(context.Background(), args)
+	return rows, s.db.handleErr("Stmt.Query", s.query, err)
+}
+
+// queryRows runs the query, giving up with ctx.Err() if ctx is done
+// while it waits for a connection.
+func (s *Stmt) queryRows(ctx context.Context, args []interface{}) (*Rows, error) {
 	s.closemu.RLock()
 	defer s.closemu.RUnlock()
 
 	var rowsi driver.Rows
-	for i := 0; i < maxBadConnRetries; i++ {
-		dc, releaseConn, si, err := s.connStmt()
+	for i := 0; i <= maxBadConnRetries; i++ {
+		// Once the pool has handed out only bad or expired
+		// connections, prepare the statement on a new one.
+		strategy := cachedOrNewConn
+		if i == maxBadConnRetries {
+			strategy = alwaysNewConn
+		}
+		dc, releaseConn, si, err := s.connStmt(ctx, strategy)
 		if err != nil {
 			if err == driver.ErrBadConn {
 				continue
 			}
 			return nil, err
 		}
+		if s.tx != nil {
+			atomic.AddInt64(&s.tx.numStmts, 1)
+		}
 
-		rowsi, err = rowsiFromStatement(driverStmt{dc, si}, args...)
+		rowsi, err = rowsiFromStatement(driverStmt{dc, si}, s.query, args...)
 		if err == nil {
 			// Note: ownership of ci passes to the *Rows, to be freed
 			// with releaseConn.
@@ -1792,10 +3735,21 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 				rowsi: rowsi,
 				// releaseConn set below
 			}
-			s.db.addDep(s, rows)
-			rows.releaseConn = func(err error) {
-				releaseConn(err)
-				s.db.removeDep(s, rows)
+			if s.tx != nil {
+				s.mu.Lock()
+				s.txRows++
+				s.mu.Unlock()
+				rows.releaseConn = func(err error) {
+					releaseConn(err)
+					s.releaseTxRows()
+				}
+				s.tx.trackRows(rows)
+			} else {
+				s.db.addDep(s, rows)
+				rows.releaseConn = func(err error) {
+					releaseConn(err)
+					s.db.removeDep(s, rows)
+				}
 			}
 			return rows, nil
 		}
@@ -1808,10 +3762,8 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 	return nil, driver.ErrBadConn
 }
 
-func rowsiFromStatement(ds driverStmt, args ...interface{}) (driver.Rows, error) {
-	ds.Lock()
-	want := ds.si.NumInput()
-	ds.Unlock()
+func rowsiFromStatement(ds driverStmt, query string, args ...interface{}) (driver.Rows, error) {
+	want := numInput(ds, query)
 
 	// -1 means the driver doesn't know how to count the number of
 	// placeholders, so we won't sanity check input here and instead let the
@@ -1825,9 +3777,11 @@ func rowsiFromStatement(ds driverStmt, args ...interface{}) (driver.Rows, error)
 		return nil, err
 	}
 
+	start := startStmtQuery(ds, query)
 	ds.Lock()
 	rowsi, err := ds.si.Query(dargs)
 	ds.Unlock()
+	noteStmtQuery(ds, query, args, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1856,11 +3810,8 @@ func rowsiFromStatement(ds driverStmt, args ...interface{}) (driver.Rows, error)
 //  var name string
 //  err := nameByUseridStmt.QueryRow(id).Scan(&name)
 func (s *Stmt) QueryRow(args ...interface{}) *Row {
-	rows, err := s.Query(args...)
-	if err != nil {
-		return &Row{err: err}
-	}
-	return &Row{rows: rows}
+	rows, err := s.queryRows(context.Background(), args)
+	return s.db.newRow("Stmt.QueryRow", s.query, rows, err)
 }
 
 // Close closes the statement.
@@ -1879,20 +3830,100 @@ func (s *Stmt) Close() error {
 		return nil
 	}
 	s.closed = true
+	busy := s.txRows > 0
+	s.mu.Unlock()
 
 	if s.tx != nil {
-		err := s.txsi.Close()
-		s.mu.Unlock()
-		return err
+		s.tx.removeStmt(s)
+	} else {
+		s.db.untrackStmt(s)
+		// Rows from an in-flight Query, and transaction statements
+		// sharing a driver statement, hold dependencies on s.
+		s.db.mu.Lock()
+		_, busy = s.db.dep[s]
+		s.db.mu.Unlock()
+	}
+	// If busy, the driver statements are only closed by finalClose
+	// once the last dependency has been removed.
+	if busy {
+		return nil
 	}
-	s.mu.Unlock()
+	return s.finalClose()
+}
+
+// preparedStmt is the DB's record of a statement prepared on it, until
+// the statement is closed. It doesn't refer to the Stmt, so that a Stmt
+// whose references are lost can still be garbage collected.
+type preparedStmt struct {
+	query string
+}
+
+// trackStmt records s, prepared on db, for PreparedStatements, and
+// arranges for s to be reported and closed if it is garbage collected
+// without Close, since it would otherwise keep its driver statements
+// open for as long as db.
+func (db *DB) trackStmt(s *Stmt) {
+	s.prepared = &preparedStmt{query: s.query}
+	db.mu.Lock()
+	if db.prepared == nil {
+		db.prepared = make(map[*preparedStmt]bool)
+	}
+	db.prepared[s.prepared] = true
+	db.mu.Unlock()
+	runtime.SetFinalizer(s, (*Stmt).closeLost)
+}
+
+// closeLost is the finalizer of a tracked Stmt, which is garbage
+// collected without Close having been called. The leak is reported
+// unless the DB has been closed, which closed its driver statements.
+func (s *Stmt) closeLost() {
+	s.db.mu.Lock()
+	leaked := s.db.prepared[s.prepared]
+	if leaked {
+		s.db.emitLocked(PoolEvent{Type: StmtLeaked, Query: s.query})
+	}
+	s.db.mu.Unlock()
+	if leaked {
+		log.Printf("sql: Stmt garbage collected without Close; closing it: %q", s.query)
+	}
+	s.Close()
+}
+
+// untrackStmt undoes trackStmt, once s is closed.
+func (db *DB) untrackStmt(s *Stmt) {
+	db.mu.Lock()
+	delete(db.prepared, s.prepared)
+	db.mu.Unlock()
+	runtime.SetFinalizer(s, nil)
+}
 
-	return s.db.removeDep(s, s)
+// releaseTxRows is called as Rows from s, a transaction's statement,
+// are closed.
+func (s *Stmt) releaseTxRows() {
+	s.mu.Lock()
+	s.txRows--
+	last := s.closed && s.txRows == 0
+	s.mu.Unlock()
+	if last {
+		s.finalClose()
+	}
 }
 
+// finalClose closes the driver statements of s once it is closed and
+// its dependencies are gone. It is called by Close and when the last
+// dependency is removed, so it does nothing until both have happened.
 func (s *Stmt) finalClose() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if !s.closed {
+		return nil
+	}
+	if s.tx != nil {
+		if s.parentStmt != nil {
+			return s.db.removeDep(s.parentStmt, s.txsi)
+		}
+		return s.txsi.Close()
+	}
 	if s.css != nil {
 		for _, v := range s.css {
 			s.db.noteUnusedDriverStatement(v.dc, v.si)
@@ -1938,7 +3969,29 @@ type Rows struct {
 	closed    bool
 	lastcols  []driver.Value
 	lasterr   error       // non-nil only if closed is true // 仅当 closed 为 true 时非 nil
+	closeerr  error       // error from closing rowsi
 	closeStmt driver.Stmt // if non-nil, statement to Close on close// 若非 nil，该语句会在 Close 调用时关闭
+
+	ctx       context.Context // from QueryContext; nil otherwise
+	cancel    func()          // if non-nil, called by Close to release ctx
+	stopWatch chan struct{}   // if non-nil, closed by Close to stop watchContext
+	watchDone chan struct{}   // closed once watchContext's goroutine has exited
+
+	// interruptHung is set by watchContext's goroutine, before it
+	// exits, if Interrupt outlived SetCancelTimeout.
+	interruptHung bool
+
+	unsafeBytes bool // see SetAllowUnsafeBytes
+	scanned     bool // a Scan method was called since the last Next
+
+	scratch []driver.Value // if long enough, used by Next for lastcols
+
+	slot querySlot // released by Close; see SetMaxConcurrentQueries
+
+	numRows     int64 // rows read by Next
+	maxRows     int64 // see SetMaxRows; zero means the DB's, negative none
+	affected    int64 // see RowsAffected; set once Next reaches io.EOF
+	affectedErr error
 }
 
 // Next prepares the next result row for reading with the Scan method. It
@@ -1958,16 +4011,121 @@ func (rs *Rows) Next() bool {
 		return false
 	}
 	if rs.lastcols == nil {
-		rs.lastcols = make([]driver.Value, len(rs.rowsi.Columns()))
+		if n := len(rs.rowsi.Columns()); n <= len(rs.scratch) {
+			rs.lastcols = rs.scratch[:n:n]
+		} else {
+			rs.lastcols = make([]driver.Value, n)
+		}
+		if rs.maxRows == 0 {
+			rs.dc.db.mu.Lock()
+			rs.maxRows = rs.dc.db.maxRows
+			rs.dc.db.mu.Unlock()
+		}
+	}
+	if rs.ctx != nil {
+		if err := rs.ctx.Err(); err != nil {
+			rs.lasterr = err
+			rs.Close()
+			return false
+		}
 	}
+	rs.scanned = false
 	rs.lasterr = rs.rowsi.Next(rs.lastcols)
+	if rs.ctx != nil {
+		// The driver may have been interrupted, or finished
+		// just as the deadline passed; report the context's
+		// error either way.
+		if err := rs.ctx.Err(); err != nil {
+			rs.lasterr = err
+		}
+	}
 	if rs.lasterr != nil {
+		if rs.lasterr == io.EOF {
+			rs.affected = rs.numRows
+			if ra, ok := rs.rowsi.(driver.RowsAffecter); ok {
+				rs.affected, rs.affectedErr = ra.RowsAffected()
+			}
+		}
+		rs.Close()
+		return false
+	}
+	if rs.maxRows > 0 && rs.numRows >= rs.maxRows {
+		rs.lasterr = ErrTooManyRows
 		rs.Close()
 		return false
 	}
+	rs.numRows++
 	return true
 }
 
+// RowsAffected returns the number of rows affected by the statement
+// that produced rs, such as an UPDATE ... RETURNING run by
+// ExecReturning. It is available once Next has returned false after
+// reading all the rows; before that, or if iteration ended with an
+// error, RowsAffected returns an error.
+//
+// If the driver's Rows implement driver.RowsAffecter, the count comes
+// from the driver. Otherwise it is the number of rows read, which is
+// the number affected for statements that return each row they
+// modify.
+
+// RowsAffected 返回产生 rs 的语句所影响的行数，例如由 ExecReturning 执行的
+// UPDATE ... RETURNING。在读取完所有行、Next 返回 false 之后才可用；在此之前，
+// 或遍历因错误而结束时，RowsAffected 会返回错误。
+//
+// 若驱动的 Rows 实现了 driver.RowsAffecter，该计数来自驱动。否则它是已读取的行数，
+// 对于返回其所修改的每一行的语句而言，它就是受影响的行数。
+func (rs *Rows) RowsAffected() (int64, error) {
+	if rs.lasterr != io.EOF {
+		return 0, errors.New("sql: RowsAffected called before all rows were read")
+	}
+	return rs.affected, rs.affectedErr
+}
+
+// watchContext makes rs observe ctx: Next fails with ctx.Err() once
+// ctx is done, and a driver Rows implementing driver.Interrupter is
+// interrupted, so that a Next blocked in the driver returns. A ctx
+// that is never done, such as context.Background(), is ignored.
+func (rs *Rows) watchContext(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	rs.ctx = ctx
+	in, ok := rs.rowsi.(driver.Interrupter)
+	if !ok {
+		return
+	}
+	rs.dc.db.mu.Lock()
+	timeout := rs.dc.db.cancelTimeout
+	rs.dc.db.mu.Unlock()
+	rs.stopWatch = make(chan struct{})
+	rs.watchDone = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+		case <-stop:
+			return
+		}
+		if timeout <= 0 {
+			in.Interrupt()
+			return
+		}
+		interrupted := make(chan struct{})
+		go func() {
+			in.Interrupt()
+			close(interrupted)
+		}()
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		select {
+		case <-interrupted:
+		case <-t.C:
+			rs.interruptHung = true
+		}
+	}(rs.stopWatch, rs.watchDone)
+}
+
 // Err returns the error, if any, that was encountered during iteration.
 // Err may be called after an explicit or implicit Close.
 
@@ -2012,6 +4170,8 @@ func (rs *Rows) Columns() ([]string, error) {
 //    *interface{}
 //    *RawBytes
 //    any type implementing Scanner (see Scanner docs)
+//    any type implementing encoding.TextUnmarshaler,
+//    encoding.BinaryUnmarshaler or json.Unmarshaler
 //
 // In the most simple case, if the type of the value from the source
 // column is an integer, bool or string type T and dest is of type *T,
@@ -2027,11 +4187,26 @@ func (rs *Rows) Columns() ([]string, error) {
 // strings may lose information when stringifying. In general, scan
 // floating point columns into *float64.
 //
+// Pointers to user-defined types whose underlying type is a string,
+// bool, integer or floating point type, such as "type Status string",
+// are converted the same way as pointers to the underlying type. In
+// particular, enums backed by an integer type, such as
+// "type Status int" with iota constants, need no special handling:
+// integer sources, and strings holding integers, scan directly into
+// them, with the same range checks.
+//
+// Integer sources of any size or signedness, such as the int8 or
+// uint16 some drivers give TINYINT and SMALLINT columns, or non-nil
+// pointers to them, scan into any integer type into whose range their
+// value falls.
+//
 // If a dest argument has type *[]byte, Scan saves in that argument a
 // copy of the corresponding data. The copy is owned by the caller and
 // can be modified and held indefinitely. The copy can be avoided by
 // using an argument of type *RawBytes instead; see the documentation
-// for RawBytes for restrictions on its use.
+// for RawBytes for restrictions on its use. A NULL value is stored as a
+// nil slice; an empty but non-NULL value, such as an empty string or
+// BLOB, is stored as a non-nil slice of length zero.
 //
 // If an argument has type *interface{}, Scan copies the value
 // provided by the underlying driver without conversion. When scanning
@@ -2040,19 +4215,86 @@ func (rs *Rows) Columns() ([]string, error) {
 //
 // Source values of type time.Time may be scanned into values of type
 // *time.Time, *interface{}, *string, or *[]byte. When converting to
-// the latter two, time.Format3339Nano is used.
+// the latter two, time.Format3339Nano is used. Their location is set
+// by the DB's SetScanLocation, if any. They may also be scanned into
+// *int64, as Unix seconds, the zero time giving 0; UnixMilli and
+// UnixNano scan them in other units.
 //
 // Source values of type bool may be scanned into types *bool,
-// *interface{}, *string, *[]byte, or *RawBytes.
+// *interface{}, *string, *[]byte, or *RawBytes, and into integer
+// types, as 0 or 1.
 //
 // For scanning into *bool, the source may be true, false, 1, 0, or
 // string inputs parseable by strconv.ParseBool.
+//
+// Source values of type []byte or string are scanned into
+// *json.RawMessage as a copy the caller owns, so JSON columns can be
+// passed on verbatim; NULL is stored as a nil RawMessage, which
+// marshals as JSON null.
+//
+// Source values of type []byte or string may be scanned into
+// fixed-size byte arrays, such as *[16]byte, if their length matches
+// the array's exactly.
+//
+// For scanning into unsigned integer types, such as *uint64, the
+// source must not be negative and must fit in the destination; up to
+// the largest uint64, it may be given as a string or []byte.
+//
+// Numeric values with a fractional part, or out of the range of their
+// destination, are rejected unless the DB uses ScanLenient; see
+// DB.SetScanStrictness.
+//
+// The Null types, such as NullString and NullInt64, accept the sources
+// their value field does, and tolerate drivers returning other types:
+// text surrounded by spaces, as from CHAR columns, is trimmed for
+// numbers, booleans, durations and times; integers may be given as
+// text such as "4.0"; NullBool accepts yes, no, y, n, on and off in any
+// case; NullFloat64 accepts booleans as 0 or 1; NullString accepts any
+// fmt.Stringer; and NullTime accepts text such as "2006-01-02
+// 15:04:05" and "2006-01-02", in UTC unless a zone is given. A failed
+// Scan leaves them invalid.
+//
+// For scanning into *time.Duration, an integer source, or a string
+// holding an integer, is a count of nanoseconds; other strings are
+// parsed with time.ParseDuration. Columns storing another unit, such
+// as seconds, should be scanned into an integer and converted by the
+// caller.
+//
+// A dest that doesn't implement Scanner but implements
+// encoding.TextUnmarshaler is given string and []byte sources through
+// UnmarshalText. Failing that, []byte sources are given to a dest
+// implementing encoding.BinaryUnmarshaler through UnmarshalBinary, and
+// string and []byte sources to one implementing json.Unmarshaler
+// through UnmarshalJSON. This takes precedence over the conversions
+// for user-defined types above.
+//
+// As a result, columns holding text can be scanned into the following
+// standard library types, among others:
+//
+//    *time.Time, from RFC 3339 text
+//    *time.Duration, as described above
+//    *net.IP, from IPv4 or IPv6 text
+//    *big.Int, from decimal text
+//    *url.URL, from text parsed by url.Parse
+//
+// Failing all of the above, a []byte or string source is given to the
+// codec registered with RegisterScanCodec for the column's database
+// type, if the driver reports it; see RegisterScanCodec.
 
 // Scan将当前行的列输出到dest指向的目标值中。
 // TODO(osc): 完善翻译
+// 底层类型为 string、bool、整数或浮点数类型的用户自定义类型（如 "type Status string"）
+// 的指针，会按照其底层类型的指针相同的方式进行转换。特别地，以整数类型为基础的
+// 枚举（例如使用 iota 常量的 "type Status int"）无需特殊处理：整数来源值以及
+// 包含整数的字符串可直接扫描到其中，并进行同样的范围检查。
+//
+// 任意大小或符号的整数来源值（例如某些驱动为 TINYINT 和 SMALLINT 列给出的 int8 或
+// uint16），以及指向它们的非 nil 指针，都可以扫描到其值处于范围之内的任意整数类型中。
+//
 // 如果有个参数是*[]byte的类型，Scan在这个参数里面存放的是相关数据的拷贝。
 // 这个拷贝是调用函数的人所拥有的，并且可以随时被修改和存取。这个拷贝能避免使用*RawBytes；
-// 关于这个类型的使用限制请参考文档。
+// 关于这个类型的使用限制请参考文档。NULL 值会被存储为 nil 切片；而空的非 NULL 值，
+// 例如空字符串或空 BLOB，会被存储为长度为零的非 nil 切片。
 //
 // 如果有个参数是*interface{}类型，Scan会将底层驱动提供的这个值不做任何转换直接拷贝返回。
 // 当从一个 []byte 类型的来源值扫描到 *interface{} 时，就会创建该切片的一份副本，
@@ -2060,12 +4302,55 @@ func (rs *Rows) Columns() ([]string, error) {
 //
 // 类型为 time.Time 的来源值可被扫描到类型为 *time.Time、*interface{}、*string
 // 或 *[]byte 的值中。当转换为后面两个类型时，time.Format3339Nano 会被使用。
+// 若 DB 设置了 SetScanLocation，它们的时区由其决定。它们也可以作为 Unix 秒数被扫描到
+// *int64 中，零值时间会得到 0；UnixMilli 和 UnixNano 以其它单位扫描它们。
 //
 // 类型为 bool 的来源值可被扫描到类型为 *bool、*interface{}、*string、*[]byte
-// 或 *RawBytes 的值中。
+// 或 *RawBytes 的值中，也可作为 0 或 1 被扫描到整数类型中。
 //
 // 扫描到 *bool 中时，来源值可为 true、false、1、0 或可被 strconv.ParseBool
 // 解析的字符串输入。
+//
+// 类型为 []byte 或 string 的来源值会以调用者拥有的副本的形式被扫描到 *json.RawMessage
+// 中，从而可以原样转发 JSON 列；NULL 会被存储为 nil 的 RawMessage，它会被编码为
+// JSON null。
+//
+// 类型为 []byte 或 string 的来源值可被扫描到固定大小的字节数组（例如 *[16]byte）中，
+// 前提是其长度与数组长度完全一致。
+//
+// 扫描到无符号整数类型（例如 *uint64）中时，来源值不得为负数，且必须能放入目标类型；
+// 直到 uint64 的最大值，它都可以以字符串或 []byte 的形式给出。
+//
+// 带小数部分或超出目标范围的数值会被拒绝，除非 DB 使用 ScanLenient；
+// 见 DB.SetScanStrictness。
+//
+// NullString 和 NullInt64 等 Null 类型接受其值字段所接受的来源值，并能容忍返回其它
+// 类型的驱动：对于数字、布尔值、时长和时间，两端带空格的文本（例如来自 CHAR 列的文本）
+// 会被去除空格；整数可以以 "4.0" 这样的文本给出；NullBool 接受任意大小写的 yes、no、
+// y、n、on 和 off；NullFloat64 接受布尔值，作为 0 或 1；NullString 接受任何
+// fmt.Stringer；NullTime 接受 "2006-01-02 15:04:05" 和 "2006-01-02" 这样的文本，
+// 除非给出了时区，否则按 UTC 解析。Scan 失败时它们会是无效的。
+//
+// 扫描到 *time.Duration 中时，整数来源值或包含整数的字符串表示纳秒数；
+// 其它字符串会用 time.ParseDuration 解析。以其它单位（例如秒）存储的列，
+// 应当扫描到整数中，再由调用者自行转换。
+//
+// 对于未实现 Scanner 但实现了 encoding.TextUnmarshaler 的 dest，string 和 []byte
+// 来源值会通过 UnmarshalText 传给它。否则，[]byte 来源值会通过 UnmarshalBinary
+// 传给实现了 encoding.BinaryUnmarshaler 的 dest，而 string 和 []byte 来源值会通过
+// UnmarshalJSON 传给实现了 json.Unmarshaler 的 dest。这优先于上述针对用户自定义
+// 类型的转换。
+//
+// 因此，存放文本的列可被扫描到以下标准库类型（以及其它类型）中：
+//
+//    *time.Time，来自 RFC 3339 文本
+//    *time.Duration，如上所述
+//    *net.IP，来自 IPv4 或 IPv6 文本
+//    *big.Int，来自十进制文本
+//    *url.URL，来自由 url.Parse 解析的文本
+//
+// 若以上转换均不适用，且驱动报告了该列的数据库类型，则 []byte 或 string 来源值会被交给
+// 通过 RegisterScanCodec 为该类型注册的编解码器；见 RegisterScanCodec。
 func (rs *Rows) Scan(dest ...interface{}) error {
 	if rs.closed {
 		return errors.New("sql: Rows are closed")
@@ -2076,8 +4361,77 @@ func (rs *Rows) Scan(dest ...interface{}) error {
 	if len(dest) != len(rs.lastcols) {
 		return fmt.Errorf("sql: expected %d destination arguments in Scan, not %d", len(rs.lastcols), len(dest))
 	}
-	for i, sv := range rs.lastcols {
-		err := convertAssign(dest[i], sv)
+	if err := rs.markScanned(); err != nil {
+		return err
+	}
+	return rs.scanColumns(dest)
+}
+
+// ScanPrefix is like Scan but only copies the first len(dest) columns,
+// ignoring the rest. It suits a query that selects more columns than
+// the caller needs. It returns an error if there are fewer columns
+// than destinations.
+
+// ScanPrefix 类似于 Scan，但只复制前 len(dest) 列，而忽略其余的列。
+// 它适用于查询所选的列多于调用者所需的情况。若列数少于目标值的个数，它会返回错误。
+func (rs *Rows) ScanPrefix(dest ...interface{}) error {
+	if rs.closed {
+		return errors.New("sql: Rows are closed")
+	}
+	if rs.lastcols == nil {
+		return errors.New("sql: Scan called without calling Next")
+	}
+	if len(dest) > len(rs.lastcols) {
+		return fmt.Errorf("sql: expected at most %d destination arguments in ScanPrefix, not %d", len(rs.lastcols), len(dest))
+	}
+	if err := rs.markScanned(); err != nil {
+		return err
+	}
+	return rs.scanColumns(dest)
+}
+
+// ScanWithNulls is like Scan, but also reports which columns of the
+// current row are NULL. nullMask has one element per column, in column
+// order, and is taken from the values returned by the driver, before
+// they are converted into dest. The mask is returned even if scanning
+// into dest fails.
+
+// ScanWithNulls 类似于 Scan，但还会报告当前行中哪些列为 NULL。nullMask 中每列对应
+// 一个元素，顺序与列的顺序一致，它取自驱动返回的值，即在这些值被转换到 dest 之前。
+// 即使扫描到 dest 失败，也会返回该掩码。
+func (rs *Rows) ScanWithNulls(dest ...interface{}) (nullMask []bool, err error) {
+	if rs.closed {
+		return nil, errors.New("sql: Rows are closed")
+	}
+	if rs.lastcols == nil {
+		return nil, errors.New("sql: Scan called without calling Next")
+	}
+	nullMask = make([]bool, len(rs.lastcols))
+	for i, v := range rs.lastcols {
+		nullMask[i] = v == nil
+	}
+	return nullMask, rs.Scan(dest...)
+}
+
+// markScanned records that the current row has been scanned, failing
+// if it already was and the DB uses SetStrictRowScan.
+func (rs *Rows) markScanned() error {
+	if rs.scanned && rs.dc.db.strictRowScan() {
+		return errors.New("sql: Scan called twice without Next")
+	}
+	rs.scanned = true
+	return nil
+}
+
+// scanColumns copies the first len(dest) columns into dest.
+func (rs *Rows) scanColumns(dest []interface{}) error {
+	for i := range dest {
+		sv, err := rs.columnValue(dest[i], i)
+		if err == nil {
+			if err = rs.assign(dest[i], sv); err != nil {
+				err = rs.decodeColumn(dest[i], sv, i, err)
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("sql: Scan error on column index %d: %v", i, err)
 		}
@@ -2085,30 +4439,178 @@ func (rs *Rows) Scan(dest ...interface{}) error {
 	return nil
 }
 
+// SetAllowUnsafeBytes sets whether Scan may store []byte column values
+// into *[]byte destinations without copying them. This saves an
+// allocation and a copy per column, for programs that consume each
+// row before moving to the next.
+//
+// WARNING: with it enabled, the []byte values stored by Scan behave
+// like RawBytes: they refer to memory owned by the driver and are only
+// valid until the next call to Next, Scan or Close. After that their
+// contents may change without notice. Callers that keep a value
+// longer must copy it. The default is false, which makes Scan return
+// copies that the caller owns.
+
+// SetAllowUnsafeBytes 设置 Scan 是否可以不经复制地将 []byte 类型的列值存储到
+// *[]byte 类型的目标中。对于在移动到下一行之前就处理完每一行的程序，
+// 这可以为每一列省去一次内存分配和复制。
+//
+// 警告：启用后，Scan 存储的 []byte 值的行为与 RawBytes 一样：它们引用驱动所拥有的
+// 内存，仅在下一次调用 Next、Scan 或 Close 之前有效。此后其内容可能会在没有任何
+// 通知的情况下改变。需要更长时间保留该值的调用者必须自行复制。默认为 false，
+// 此时 Scan 返回的是归调用者所有的副本。
+func (rs *Rows) SetAllowUnsafeBytes(allow bool) {
+	rs.unsafeBytes = allow
+}
+
+// assign stores the column value sv into dest, like convertAssign,
+// honoring SetAllowUnsafeBytes and SetScanStrictness.
+func (rs *Rows) assign(dest interface{}, sv driver.Value) error {
+	if rs.unsafeBytes {
+		if d, ok := dest.(*[]byte); ok && d != nil {
+			if b, ok := sv.([]byte); ok {
+				*d = b
+				return nil
+			}
+		}
+	}
+	return convertAssignWith(dest, sv, rs.dc.db.scanStrictness())
+}
+
+// columnValue returns the driver's value for column i of the current
+// row, to be stored into dest, adjusted as set by SetScanLocation. A
+// value the driver provides as an io.Reader is read into a []byte,
+// unless dest is a WriterTo; see streamColumn.
+func (rs *Rows) columnValue(dest interface{}, i int) (driver.Value, error) {
+	switch sv := rs.lastcols[i].(type) {
+	case time.Time:
+		return rs.dc.db.scanTime(sv), nil
+	case io.Reader, streamedColumn:
+		return rs.streamColumn(dest, i)
+	default:
+		return sv, nil
+	}
+}
+
+// ScanSlice is like Scan but takes its destinations as a slice, which
+// suits callers that build dest dynamically, with one entry per
+// column.
+
+// ScanSlice 类似于 Scan，但以切片的形式接受目标值，适用于动态构建 dest
+// （每列一项）的调用者。
+func (rs *Rows) ScanSlice(dest []interface{}) error {
+	return rs.Scan(dest...)
+}
+
+// MakeDestinations allocates one scan destination per column, ready to
+// be passed to ScanSlice. Each destination is a *interface{}, which
+// receives the value provided by the driver without conversion, so it
+// can hold any column type, including NULL. The destinations may be
+// reused for every row. MakeDestinations returns nil if the rows are
+// closed.
+
+// MakeDestinations 为每一列分配一个扫描目标值，可直接传给 ScanSlice。
+// 每个目标值都是 *interface{}，它会不做转换地接收驱动提供的值，因此能容纳
+// 任何列类型，包括 NULL。这些目标值可以在每一行中重复使用。
+// 若 rows 已关闭，MakeDestinations 返回 nil。
+func (rs *Rows) MakeDestinations() []interface{} {
+	if rs.closed || rs.rowsi == nil {
+		return nil
+	}
+	n := len(rs.rowsi.Columns())
+	vals := make([]interface{}, n)
+	dest := make([]interface{}, n)
+	for i := range dest {
+		dest[i] = &vals[i]
+	}
+	return dest
+}
+
 var rowsCloseHook func(*Rows, *error)
 
 // Close closes the Rows, preventing further enumeration. If Next returns
 // false, the Rows are closed automatically and it will suffice to check the
 // result of Err. Close is idempotent and does not affect the result of Err.
+//
+// Close returns the error, if any, encountered during iteration, as
+// Err does, combined with any error from closing the Rows. Err remains
+// the canonical way to check for iteration errors, but a caller that
+// only checks Close will not miss one.
 
 // Close 关闭 Rows，阻止了进一步枚举。若 Next 返回 false，则 Rows 会自动关闭并能够检查
 // Err 的结果。Close 是幂等的，并不会影响 Err 的结果。
+//
+// Close 会像 Err 一样返回循环过程中遇到的错误（如果有的话），并与关闭 Rows 时的错误合并。
+// Err 仍是检查循环错误的规范方式，但只检查 Close 的调用者也不会遗漏这类错误。
 func (rs *Rows) Close() error {
-	if rs.closed {
-		return nil
+	if !rs.closed {
+		rs.closed = true
+		if rs.stopWatch != nil {
+			// Don't let Interrupt race with the driver's Close.
+			close(rs.stopWatch)
+			<-rs.watchDone
+		}
+		if rs.cancel != nil {
+			rs.cancel()
+		}
+		if rs.interruptHung {
+			rs.abandon()
+		} else {
+			err := rs.rowsi.Close()
+			if fn := rowsCloseHook; fn != nil {
+				err = runRowsCloseHook(fn, rs, err)
+			}
+			if rs.closeStmt != nil {
+				rs.closeStmt.Close()
+			}
+			rs.release(err)
+			rs.closeerr = err
+		}
+		rs.slot.release()
 	}
-	rs.closed = true
-	err := rs.rowsi.Close()
-	if fn := rowsCloseHook; fn != nil {
-		fn(rs, &err)
+	iterErr := rs.Err()
+	switch {
+	case iterErr == nil:
+		return rs.closeerr
+	case rs.closeerr == nil:
+		return iterErr
 	}
-	if rs.closeStmt != nil {
-		rs.closeStmt.Close()
+	return fmt.Errorf("%v; closing rows: %v", iterErr, rs.closeerr)
+}
+
+// release releases rs's connection, with rs.releaseConn if set and
+// with rs.dc.releaseConn otherwise.
+func (rs *Rows) release(err error) {
+	if rs.releaseConn == nil {
+		rs.dc.releaseConn(err)
+		return
 	}
 	rs.releaseConn(err)
+}
+
+// runRowsCloseHook runs fn on the error from closing rs's driver Rows.
+// It is apart from Close so that err only escapes when a hook is set.
+func runRowsCloseHook(fn func(*Rows, *error), rs *Rows, err error) error {
+	fn(rs, &err)
 	return err
 }
 
+// abandon gives up on rs, whose driver is stuck cancelling the query:
+// the connection is discarded once released, and the driver Rows and
+// statement are left to close with it in the background.
+func (rs *Rows) abandon() {
+	db := rs.dc.db
+	db.mu.Lock()
+	rs.dc.cancelHung = true
+	db.mu.Unlock()
+	go func() {
+		if rs.closeStmt != nil {
+			rs.closeStmt.Close()
+		}
+		rs.release(driver.ErrBadConn)
+	}()
+}
+
 // Row is the result of calling QueryRow to select a single row.
 
 // Row是调用QueryRow的结果，代表了查询操作的一行数据。
@@ -2118,6 +4620,43 @@ type Row struct {
 	// 这两个中的一个必须是非空：
 	err  error // deferred error for easy chaining  // 将error保存从而延迟返回，这样能保证Row链表的简易实现
 	rows *Rows
+
+	// For SetErrorHandler.
+	db        *DB
+	op, query string
+}
+
+// A rowBuf holds the storage of a DB.QueryRow query on a
+// driver.Queryer, including when it returns driver.ErrSkip; see
+// queryConnRow.
+type rowBuf struct {
+	row  Row
+	rows Rows
+	args [rowBufLen]driver.Value
+	cols [rowBufLen]driver.Value
+}
+
+// newRows returns fresh Rows, kept in b if b is non-nil.
+func (b *rowBuf) newRows() *Rows {
+	if b == nil {
+		return new(Rows)
+	}
+	b.rows = Rows{scratch: b.cols[:]}
+	return &b.rows
+}
+
+// rowBufLen is the number of arguments and of columns a rowBuf has
+// storage for.
+const rowBufLen = 2
+
+// newRow returns the Row for a QueryRow call that produced rows and
+// err. The error, if any, is passed through db's error handler now;
+// errors found by Scan are passed through it then.
+func (db *DB) newRow(op, query string, rows *Rows, err error) *Row {
+	if err != nil {
+		return &Row{err: db.handleErr(op, query, err)}
+	}
+	return &Row{rows: rows, db: db, op: op, query: query}
 }
 
 // Scan copies the columns from the matched row into the values
@@ -2133,7 +4672,45 @@ func (r *Row) Scan(dest ...interface{}) error {
 	if r.err != nil {
 		return r.err
 	}
+	return r.db.handleErr(r.op, r.query, r.scan(dest, (*Rows).Scan))
+}
+
+// ScanPrefix is like Scan but only copies the first len(dest) columns
+// of the matched row, ignoring the rest. See Rows.ScanPrefix.
+
+// ScanPrefix 类似于 Scan，但只复制符合的行的前 len(dest) 列，而忽略其余的列。
+// 见 Rows.ScanPrefix。
+func (r *Row) ScanPrefix(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.db.handleErr(r.op, r.query, r.scan(dest, (*Rows).ScanPrefix))
+}
+
+// ScanOrZero is like Scan, but treats a query matching no rows as a
+// result rather than an error: it then returns false and a nil error,
+// leaving dest untouched. It returns true once the row has been
+// copied into dest, and any other error as Scan does. It suits
+// optional lookups, whose destinations can be left at their zero
+// values when nothing was found.
+
+// ScanOrZero 类似于 Scan，但将查询不匹配任何行视作一种结果而非错误：此时它返回 false
+// 和 nil 错误，并且不改动 dest。当该行被复制到 dest 之后，它返回 true；
+// 其它错误会像 Scan 一样返回。它适用于可选的查找，当没有找到任何内容时，
+// 其目标值可以保持为零值。
+func (r *Row) ScanOrZero(dest ...interface{}) (found bool, err error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	err = r.scan(dest, (*Rows).Scan)
+	if err == ErrNoRows {
+		return false, nil
+	}
+	return err == nil, r.db.handleErr(r.op, r.query, err)
+}
 
+// scan reads the first row and copies it into dest with scanRow.
+func (r *Row) scan(dest []interface{}, scanRow func(*Rows, ...interface{}) error) error {
 	// TODO(bradfitz): for now we need to defensively clone all
 	// []byte that the driver returned (not permitting
 	// *RawBytes in Rows.Scan), since we're about to close
@@ -2160,7 +4737,7 @@ func (r *Row) Scan(dest ...interface{}) error {
 		}
 		return ErrNoRows
 	}
-	err := r.rows.Scan(dest...)
+	err := scanRow(r.rows, dest...)
 	if err != nil {
 		return err
 	}
@@ -2172,9 +4749,35 @@ func (r *Row) Scan(dest ...interface{}) error {
 	return nil
 }
 
+// Err returns the error, if any, that was encountered while running
+// the query, such as ErrTxDone from a finished transaction. If Err is
+// non-nil, Scan returns the same error.
+
+// Err 返回运行查询时遇到的错误（如果有的话），例如来自已结束事务的 ErrTxDone。
+// 若 Err 非 nil，Scan 也会返回同样的错误。
+func (r *Row) Err() error {
+	return r.err
+}
+
 // A Result summarizes an executed SQL command.
+//
+// The Results returned by this package also have a method
+//
+//	Err() error
+//
+// which returns any error the driver deferred about the completion of
+// the command, or nil if it completed successfully. It is not part of
+// the interface, so that other implementations of Result stay valid;
+// call it through a type assertion.
 
 // 一个Result结构代表了一个执行过的SQL命令。
+//
+// 本包返回的 Result 还拥有一个方法
+//
+//	Err() error
+//
+// 它返回驱动推迟报告的、关于该命令是否完成的任何错误；若命令成功完成则返回 nil。
+// 该方法并不属于此接口，因此 Result 的其它实现依然有效；请通过类型断言来调用它。
 type Result interface {
 	// LastInsertId returns the integer generated by the database
 	// in response to a command. Typically this will be from an
@@ -2206,6 +4809,16 @@ func (dr driverResult) RowsAffected() (int64, error) {
 	return dr.resi.RowsAffected()
 }
 
+func (dr driverResult) Err() error {
+	re, ok := dr.resi.(driver.ResultErrer)
+	if !ok {
+		return nil
+	}
+	dr.Lock()
+	defer dr.Unlock()
+	return re.Err()
+}
+
 func stack() string {
 	var buf [2 << 10]byte
 	return string(buf[:runtime.Stack(buf[:], false)])
@@ -5,12 +5,17 @@
 package sql
 
 import (
+	"bytes"
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"log"
 	"math/rand"
+	"os"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -367,6 +372,298 @@ func TestQueryRow(t *testing.T) {
 	}
 }
 
+// TestQueryRowQueryer checks QueryRow on a driver.Queryer, whose Row
+// and rows share a rowBuf.
+func TestQueryRowQueryer(t *testing.T) {
+	db, err := Open("test", "queryrow;queryer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|a=int64,b=int64,c=int64,d=int64,e=string")
+	exec(t, db, "INSERT|t|a=?,b=?,c=?,d=?,e=?", 1, 2, 3, 4, "one")
+	exec(t, db, "INSERT|t|a=?,b=?,c=?,d=?,e=?", 5, 6, 7, 8, "two")
+
+	r1 := db.QueryRow("SELECT|t|e|a=?", 1)
+	r2 := db.QueryRow("SELECT|t|e|a=?", 5)
+	var e1, e2 string
+	if err := r2.Scan(&e2); err != nil || e2 != "two" {
+		t.Errorf("Scan = %q, %v; want two", e2, err)
+	}
+	if err := r1.Scan(&e1); err != nil || e1 != "one" {
+		t.Errorf("Scan = %q, %v; want one", e1, err)
+	}
+
+	// As many columns and arguments as a rowBuf has storage for.
+	var a, b, c, d int64
+	if err := db.QueryRow("SELECT|t|b,c|a=?,b=?", 1, 2).Scan(&b, &c); err != nil || b != 2 || c != 3 {
+		t.Errorf("Scan = %d, %d, %v; want 2, 3", b, c, err)
+	}
+
+	// More columns and arguments than a rowBuf has storage for.
+	var e string
+	err = db.QueryRow("SELECT|t|a,b,c,d,e|a=?,b=?,c=?,d=?,e=?", 5, 6, 7, 8, "two").Scan(&a, &b, &c, &d, &e)
+	if err != nil || a != 5 || b != 6 || c != 7 || d != 8 || e != "two" {
+		t.Errorf("Scan = %d, %d, %d, %d, %q, %v", a, b, c, d, e, err)
+	}
+
+	if err := db.QueryRow("SELECT|t|e|a=?", 9).Scan(&e); err != ErrNoRows {
+		t.Errorf("Scan of no rows = %v; want ErrNoRows", err)
+	}
+
+	row := db.QueryRow("SELECT|t|nope|a=?", 1)
+	if row.Err() == nil {
+		t.Fatal("Err = nil for a bad query")
+	}
+	if err := row.Scan(&e); err != row.Err() {
+		t.Errorf("Scan = %v; want %v", err, row.Err())
+	}
+
+	// r1 and r2 were open together.
+	if n := db.numFreeConns(); n != 2 {
+		t.Errorf("free conns = %d; want 2", n)
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var name string
+	var age int
+	err := db.QueryRow("SELECT|people|name,age,dead|age=?", 2).ScanPrefix(&name, &age)
+	if err != nil || name != "Bob" || age != 2 {
+		t.Errorf("Row.ScanPrefix = %q, %d, %v; want Bob, 2", name, age, err)
+	}
+	if err := db.QueryRow("SELECT|people|name|age=?", 2).ScanPrefix(&name, &age); err == nil ||
+		!strings.Contains(err.Error(), "expected at most 1 destination arguments") {
+		t.Errorf("Row.ScanPrefix with too many destinations = %v", err)
+	}
+	if err := db.QueryRow("SELECT|people|name|age=?", 99).ScanPrefix(&name); err != ErrNoRows {
+		t.Errorf("Row.ScanPrefix with no rows = %v; want ErrNoRows", err)
+	}
+
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	sum := 0
+	for rows.Next() {
+		if err := rows.ScanPrefix(&age); err != nil {
+			t.Fatal(err)
+		}
+		sum += age
+		if err := rows.ScanPrefix(); err != nil {
+			t.Errorf("ScanPrefix with no destinations = %v", err)
+		}
+		// Scan stays strict.
+		if err := rows.Scan(&age); err == nil {
+			t.Error("Scan with too few destinations succeeded")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 6 {
+		t.Errorf("sum of ages = %d; want 6", sum)
+	}
+}
+
+func TestScanWithNulls(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|id=int32,name=nullstring,nick=nullstring,ok=nullbool")
+	exec(t, db, "INSERT|t|id=1,name=?,nick=?,ok=?", "Alice", nil, true)
+	exec(t, db, "INSERT|t|id=2,name=?,nick=?,ok=?", nil, "bee", nil)
+	exec(t, db, "INSERT|t|id=3,name=?,nick=?,ok=?", nil, nil, nil)
+
+	want := [][]bool{
+		{false, false, true, false},
+		{false, true, false, true},
+		{false, true, true, true},
+	}
+	rows, err := db.Query("SELECT|t|id,name,nick,ok|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	n := 0
+	for ; rows.Next(); n++ {
+		var id int
+		var name, nick, ok interface{}
+		mask, err := rows.ScanWithNulls(&id, &name, &nick, &ok)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != n+1 {
+			t.Errorf("row %d: id = %d; want %d", n, id, n+1)
+		}
+		if !reflect.DeepEqual(mask, want[n]) {
+			t.Errorf("row %d: nullMask = %v; want %v", n, mask, want[n])
+		}
+		if (name == nil) != mask[1] || (nick == nil) != mask[2] || (ok == nil) != mask[3] {
+			t.Errorf("row %d: nullMask %v disagrees with scanned %v, %v, %v", n, mask, name, nick, ok)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) {
+		t.Fatalf("got %d rows; want %d", n, len(want))
+	}
+
+	// The mask is reported even when a NULL can't be scanned.
+	rows, err = db.Query("SELECT|t|name,nick|id=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("no row")
+	}
+	var name, nick string
+	mask, err := rows.ScanWithNulls(&name, &nick)
+	if err == nil {
+		t.Error("scanning NULL into a string succeeded")
+	}
+	if !reflect.DeepEqual(mask, []bool{true, false}) {
+		t.Errorf("nullMask = %v; want [true false]", mask)
+	}
+}
+
+func TestRowScanOrZero(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	// No row: not found, no error, dest untouched.
+	age := 42
+	found, err := db.QueryRow("SELECT|people|age|name=?", "Nobody").ScanOrZero(&age)
+	if found || err != nil || age != 42 {
+		t.Errorf("ScanOrZero with no rows = %v, %v, age %d; want false, nil, 42", found, err, age)
+	}
+
+	// One row.
+	found, err = db.QueryRow("SELECT|people|age|name=?", "Bob").ScanOrZero(&age)
+	if !found || err != nil || age != 2 {
+		t.Errorf("ScanOrZero = %v, %v, age %d; want true, nil, 2", found, err, age)
+	}
+
+	// Errors are still reported.
+	var name string
+	found, err = db.QueryRow("SELECT|people|age|name=?", "Bob").ScanOrZero(&age, &name)
+	if found || err == nil {
+		t.Errorf("ScanOrZero with too many destinations = %v, %v; want false, error", found, err)
+	}
+	found, err = db.QueryRow("SELECT|nosuchtable|age|").ScanOrZero(&age)
+	if found || err == nil {
+		t.Errorf("ScanOrZero on missing table = %v, %v; want false, error", found, err)
+	}
+}
+
+func TestStrictRowScan(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var name string
+	scanTwice := func() error {
+		rows, err := db.Query("SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			t.Fatal("no rows")
+		}
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		return rows.Scan(&name)
+	}
+
+	// Off by default.
+	if err := scanTwice(); err != nil {
+		t.Errorf("second Scan with strict scanning off: %v", err)
+	}
+
+	db.SetStrictRowScan(true)
+	if err := scanTwice(); err == nil || err.Error() != "sql: Scan called twice without Next" {
+		t.Errorf("second Scan without Next = %v; want error", err)
+	}
+
+	// The normal Next/Scan sequence is unaffected.
+	rows, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("scanned %d rows; want 3", n)
+	}
+	if err := db.QueryRow("SELECT|people|name|age=?", 2).Scan(&name); err != nil || name != "Bob" {
+		t.Errorf("QueryRow = %q, %v; want Bob", name, err)
+	}
+}
+
+func TestScanLocation(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t1|ts=datetime")
+	zone := time.FixedZone("UTC+1", 3600)
+	stored := time.Date(2016, 1, 2, 3, 4, 5, 6, zone)
+	exec(t, db, "INSERT|t1|ts=?", stored)
+
+	scan := func() (time.Time, string) {
+		var ts time.Time
+		var s string
+		if err := db.QueryRow("SELECT|t1|ts|").Scan(&ts); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.QueryRow("SELECT|t1|ts|").Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		return ts, s
+	}
+
+	// By default the driver's location is kept.
+	if ts, _ := scan(); ts != stored {
+		t.Errorf("default scan = %v; want %v", ts, stored)
+	}
+
+	db.SetScanLocation(time.UTC)
+	ts, s := scan()
+	if want := stored.In(time.UTC); ts != want {
+		t.Errorf("converted scan = %v; want %v", ts, want)
+	}
+	if want := "2016-01-02T02:04:05.000000006Z"; s != want {
+		t.Errorf("converted scan into string = %q; want %q", s, want)
+	}
+
+	db.SetScanReinterpret(true)
+	ts, s = scan()
+	if want := time.Date(2016, 1, 2, 3, 4, 5, 6, time.UTC); ts != want {
+		t.Errorf("reinterpreted scan = %v; want %v", ts, want)
+	}
+	if want := "2016-01-02T03:04:05.000000006Z"; s != want {
+		t.Errorf("reinterpreted scan into string = %q; want %q", s, want)
+	}
+
+	db.SetScanLocation(nil)
+	if ts, _ := scan(); ts != stored {
+		t.Errorf("scan after reset = %v; want %v", ts, stored)
+	}
+}
+
 func TestStatementErrorAfterClose(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
@@ -430,6 +727,40 @@ func (s stubDriverStmt) Query(args []driver.Value) (driver.Rows, error) {
 	return nil, nil
 }
 
+func TestQueryInputCounter(t *testing.T) {
+	db, err := Open("test", fakeDBName+";countInputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+	exec(t, db, "CREATE|t|name=string,age=int32")
+
+	// The fake driver panics if it gets the wrong number of
+	// arguments, so these must be caught before reaching it.
+	if _, err := db.Exec("INSERT|t|name=?,age=?", "Alice"); err == nil || err.Error() != "sql: expected 2 arguments, got 1" {
+		t.Errorf("Exec with too few arguments = %v", err)
+	}
+	stmt, err := db.Prepare("INSERT|t|name=?,age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec("Alice", 1, 2); err == nil || err.Error() != "sql: expected 2 arguments, got 3" {
+		t.Errorf("Stmt.Exec with too many arguments = %v", err)
+	}
+	if _, err := stmt.Exec("Alice", 1); err != nil {
+		t.Errorf("Stmt.Exec: %v", err)
+	}
+	if _, err := db.Query("SELECT|t|age|name=?"); err == nil || err.Error() != "sql: statement expects 1 inputs; got 0" {
+		t.Errorf("Query with too few arguments = %v", err)
+	}
+	var age int
+	if err := db.QueryRow("SELECT|t|age|name=?", "Alice").Scan(&age); err != nil || age != 1 {
+		t.Errorf("QueryRow = %d, %v; want 1", age, err)
+	}
+}
+
 // golang.org/issue/12798
 func TestStatementClose(t *testing.T) {
 	want := errors.New("STMT ERROR")
@@ -448,6 +779,84 @@ func TestStatementClose(t *testing.T) {
 	}
 }
 
+// Tests that closing a Stmt while other goroutines are still
+// iterating over its Rows doesn't close the driver statement out
+// from under them, both for DB and Tx statements.
+func TestStmtCloseConcurrentQuery(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	prepares := map[string]func(string) (*Stmt, error){
+		"DB": db.Prepare,
+		"Tx": tx.Prepare,
+	}
+	for name, prepare := range prepares {
+		stmt, err := prepare("SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := stmt.Query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rows.Next() {
+			t.Fatalf("%s: no first row: %v", name, rows.Err())
+		}
+		if err := stmt.Close(); err != nil {
+			t.Fatalf("%s: stmt.Close = %v", name, err)
+		}
+		for rows.Next() {
+		}
+		if err := rows.Err(); err != nil {
+			t.Errorf("%s: after stmt.Close, rows.Err = %v", name, err)
+		}
+		rows.Close()
+
+		for iter := 0; iter < 20; iter++ {
+			stmt, err := prepare("SELECT|people|name|")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rows, err := stmt.Query()
+					if err != nil {
+						// Lost the race with Close.
+						return
+					}
+					defer rows.Close()
+					n := 0
+					for rows.Next() {
+						n++
+					}
+					if err := rows.Err(); err != nil {
+						t.Errorf("%s: rows.Err = %v", name, err)
+					} else if n != 3 {
+						t.Errorf("%s: got %d rows; want 3", name, n)
+					}
+				}()
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := stmt.Close(); err != nil {
+					t.Errorf("%s: stmt.Close = %v", name, err)
+				}
+			}()
+			wg.Wait()
+		}
+	}
+}
+
 // golang.org/issue/3734
 func TestStatementQueryRowConcurrent(t *testing.T) {
 	db := newTestDB(t, "people")
@@ -533,6 +942,41 @@ func TestExec(t *testing.T) {
 	}
 }
 
+// deferredErrResult is a driver.Result whose command failed after
+// Exec returned.
+type deferredErrResult struct {
+	driver.Result
+	err error
+}
+
+func (r deferredErrResult) Err() error { return r.err }
+
+func TestResultErr(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t1|name=string")
+
+	res, err := db.Exec("INSERT|t1|name=?", "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, ok := res.(interface {
+		Err() error
+	})
+	if !ok {
+		t.Fatalf("Result %T has no Err method", res)
+	}
+	if err := re.Err(); err != nil {
+		t.Errorf("Err = %v; want nil", err)
+	}
+
+	want := errors.New("constraint violated at commit")
+	dr := driverResult{&sync.Mutex{}, deferredErrResult{driver.RowsAffected(1), want}}
+	if err := dr.Err(); err != want {
+		t.Errorf("Err = %v; want %v", err, want)
+	}
+}
+
 func TestTxPrepare(t *testing.T) {
 	db := newTestDB(t, "")
 	defer closeDB(t, db)
@@ -560,36 +1004,295 @@ func TestTxPrepare(t *testing.T) {
 	}
 }
 
-func TestTxStmt(t *testing.T) {
+func TestTxQueryRowDone(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	for _, end := range []string{"Commit", "Rollback"} {
+		stmt, err := db.Prepare("SELECT|people|age|name=?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		txPrepared, err := tx.Prepare("SELECT|people|age|name=?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if end == "Commit" {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatalf("%s = %v", end, err)
+		}
+
+		rows := map[string]*Row{
+			"Tx.QueryRow":         tx.QueryRow("SELECT|people|age|name=?", "Alice"),
+			"Tx.Stmt.QueryRow":    tx.Stmt(stmt).QueryRow("Alice"),
+			"Tx.Prepare.QueryRow": txPrepared.QueryRow("Alice"),
+		}
+		for name, row := range rows {
+			if err := row.Err(); err != ErrTxDone {
+				t.Errorf("after %s, %s: Row.Err = %v; want ErrTxDone", end, name, err)
+			}
+			var age int
+			if err := row.Scan(&age); err != ErrTxDone {
+				t.Errorf("after %s, %s: Row.Scan = %v; want ErrTxDone", end, name, err)
+			}
+		}
+		stmt.Close()
+	}
+}
+
+func TestWithTx(t *testing.T) {
 	db := newTestDB(t, "")
 	defer closeDB(t, db)
-	exec(t, db, "CREATE|t1|name=string,age=int32,dead=bool")
-	stmt, err := db.Prepare("INSERT|t1|name=?,age=?")
-	if err != nil {
-		t.Fatalf("Stmt, err = %v, %v", stmt, err)
+	exec(t, db, "CREATE|t1|name=string")
+
+	// The fake driver doesn't undo rolled back work, so record how
+	// each transaction ended instead.
+	var ended string
+	defer func() {
+		hookCommitBadConn = nil
+		hookRollbackBadConn = nil
+	}()
+	hookCommitBadConn = func() bool {
+		ended = "commit"
+		return false
 	}
-	defer stmt.Close()
-	tx, err := db.Begin()
-	if err != nil {
-		t.Fatalf("Begin = %v", err)
+	hookRollbackBadConn = func() bool {
+		ended = "rollback"
+		return false
 	}
-	txs := tx.Stmt(stmt)
-	defer txs.Close()
-	_, err = txs.Exec("Bobby", 7)
-	if err != nil {
-		t.Fatalf("Exec = %v", err)
+
+	insert := func(tx *Tx) error {
+		_, err := tx.Exec("INSERT|t1|name=?", "x")
+		return err
 	}
-	err = tx.Commit()
-	if err != nil {
-		t.Fatalf("Commit = %v", err)
+	ctx := context.Background()
+
+	if err := db.WithTx(ctx, insert); err != nil || ended != "commit" {
+		t.Errorf("WithTx = %v, ended with %s; want nil, commit", err, ended)
 	}
-	// Commit() should have closed the statement
-	if !txs.closed {
-		t.Fatal("Stmt not closed after Commit")
+
+	ended = ""
+	errFn := errors.New("fn failed")
+	err := db.WithTx(ctx, func(tx *Tx) error {
+		insert(tx)
+		return errFn
+	})
+	if err != errFn || ended != "rollback" {
+		t.Errorf("WithTx = %v, ended with %s; want %v, rollback", err, ended, errFn)
 	}
-}
 
-// Issue: https://golang.org/issue/2784
+	ended = ""
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v; want boom", p)
+			}
+		}()
+		db.WithTx(ctx, func(tx *Tx) error {
+			insert(tx)
+			panic("boom")
+		})
+	}()
+	if ended != "rollback" {
+		t.Errorf("after panic, ended with %q; want rollback", ended)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("after panic, %d connections in use; want 0", n)
+	}
+
+	ended = ""
+	cctx, cancel := context.WithCancel(ctx)
+	err = db.WithTx(cctx, func(tx *Tx) error {
+		cancel()
+		return insert(tx)
+	})
+	if err != context.Canceled || ended != "rollback" {
+		t.Errorf("WithTx canceled during fn = %v, ended with %s; want %v, rollback", err, ended, context.Canceled)
+	}
+	called := false
+	err = db.WithTx(cctx, func(*Tx) error {
+		called = true
+		return nil
+	})
+	if err != context.Canceled || called {
+		t.Errorf("WithTx with done ctx = %v, called = %v; want %v, false", err, called, context.Canceled)
+	}
+}
+
+func TestTxRaw(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errRaw := errors.New("raw failed")
+	err = tx.Raw(func(driverConn interface{}) error {
+		fc, ok := driverConn.(*fakeConn)
+		if !ok {
+			t.Fatalf("Raw got %T; want *fakeConn", driverConn)
+		}
+		if fc.currTx == nil || fc.currTx != tx.txi {
+			t.Error("Raw connection is not running the transaction")
+		}
+		return errRaw
+	})
+	if err != errRaw {
+		t.Errorf("Raw = %v; want %v", err, errRaw)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	err = tx.Raw(func(interface{}) error {
+		called = true
+		return nil
+	})
+	if err != ErrTxDone || called {
+		t.Errorf("Raw after Commit = %v, called = %v; want ErrTxDone, false", err, called)
+	}
+}
+
+func TestTxStmt(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t1|name=string,age=int32,dead=bool")
+	stmt, err := db.Prepare("INSERT|t1|name=?,age=?")
+	if err != nil {
+		t.Fatalf("Stmt, err = %v, %v", stmt, err)
+	}
+	defer stmt.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin = %v", err)
+	}
+	txs := tx.Stmt(stmt)
+	defer txs.Close()
+	_, err = txs.Exec("Bobby", 7)
+	if err != nil {
+		t.Fatalf("Exec = %v", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("Commit = %v", err)
+	}
+	// Commit() should have closed the statement
+	if !txs.closed {
+		t.Fatal("Stmt not closed after Commit")
+	}
+}
+
+func TestTxStatementCount(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if n := tx.StatementCount(); n != 0 {
+		t.Errorf("StatementCount after Begin = %d; want 0", n)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Fatal(err)
+	}
+	var age int
+	if err := tx.QueryRow("SELECT|people|age|name=?", "Dave").Scan(&age); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.Prepare("SELECT|people|age|name=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Alice", "Bob"} {
+		if err := stmt.QueryRow(name).Scan(&age); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Queries outside the transaction don't count.
+	if err := db.QueryRow("SELECT|people|age|name=?", "Alice").Scan(&age); err != nil {
+		t.Fatal(err)
+	}
+	if n := tx.StatementCount(); n != 4 {
+		t.Errorf("StatementCount = %d; want 4", n)
+	}
+}
+
+func TestTxStmtReusesPrepared(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+	exec(t, db, "CREATE|t1|name=string,age=int32")
+	stmt, err := db.Prepare("INSERT|t1|name=?,age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := tx.dc.ci.(*fakeConn)
+	prepares := fc.numPrepare
+	txs := tx.Stmt(stmt)
+	if n := fc.numPrepare - prepares; n != 0 {
+		t.Errorf("Tx.Stmt prepared %d times; want 0", n)
+	}
+	if _, err := txs.Exec("Bobby", 7); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing the origin Stmt mustn't close the driver statement
+	// the transaction is still using.
+	stmt.Close()
+	if _, err := txs.Exec("Bobby", 8); err != nil {
+		t.Fatalf("Exec after closing origin Stmt: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.freeConn[0].openStmt); n != 0 {
+		t.Errorf("%d driver statements open after Commit; want 0", n)
+	}
+
+	// A transaction on another connection still prepares.
+	stmt, err = db.Prepare("INSERT|t1|name=?,age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	db.SetMaxOpenConns(2)
+	dc, err := db.conn(cachedOrNewConn) // hold the statement's connection
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc = tx.dc.ci.(*fakeConn)
+	prepares = fc.numPrepare
+	tx.Stmt(stmt)
+	if n := fc.numPrepare - prepares; n != 1 {
+		t.Errorf("Tx.Stmt on another connection prepared %d times; want 1", n)
+	}
+	tx.Rollback()
+	db.putConn(dc, nil)
+}
+
+// Issue: https://golang.org/issue/2784
 // This test didn't fail before because we got lucky with the fakedb driver.
 // It was failing, and now not, in github.com/bradfitz/go-sql-test
 func TestTxQuery(t *testing.T) {
@@ -738,6 +1441,84 @@ func TestNullByteSlice(t *testing.T) {
 	}
 }
 
+// Tests that an empty value scanned into a []byte or RawBytes is a
+// non-nil, zero-length slice, while only NULL yields nil.
+func TestEmptyByteSliceNotNull(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|id=int32,name=nullstring")
+	exec(t, db, "INSERT|t|id=1,name=?", "")
+	exec(t, db, "INSERT|t|id=2,name=?", []byte{})
+	exec(t, db, "INSERT|t|id=3,name=?", nil)
+
+	tests := []struct {
+		id      int
+		wantNil bool
+	}{
+		{1, false}, // empty string
+		{2, false}, // empty blob
+		{3, true},  // NULL
+	}
+	for _, tt := range tests {
+		var b []byte
+		if err := db.QueryRow("SELECT|t|name|id=?", tt.id).Scan(&b); err != nil {
+			t.Fatal(err)
+		}
+		if (b == nil) != tt.wantNil || len(b) != 0 {
+			t.Errorf("id %d: scanned []byte %#v; want nil = %v", tt.id, b, tt.wantNil)
+		}
+
+		rows, err := db.Query("SELECT|t|name|id=?", tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rows.Next() {
+			t.Fatalf("id %d: no row: %v", tt.id, rows.Err())
+		}
+		var raw RawBytes
+		if err := rows.Scan(&raw); err != nil {
+			t.Fatal(err)
+		}
+		if (raw == nil) != tt.wantNil || len(raw) != 0 {
+			t.Errorf("id %d: scanned RawBytes %#v; want nil = %v", tt.id, raw, tt.wantNil)
+		}
+		rows.Close()
+	}
+}
+
+func TestRowsScanSlice(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|age,name,photo|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := rows.MakeDestinations()
+	if len(dest) != 3 {
+		t.Fatalf("MakeDestinations returned %d destinations; want 3", len(dest))
+	}
+	var got []string
+	for rows.Next() {
+		if err := rows.ScanSlice(dest); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%v/%s", *dest[0].(*interface{}), *dest[1].(*interface{})))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1/Alice", "2/Bob", "3/Chris"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %q; want %q", got, want)
+	}
+	if dest := rows.MakeDestinations(); dest != nil {
+		t.Errorf("MakeDestinations on closed Rows = %v; want nil", dest)
+	}
+	if err := rows.ScanSlice(make([]interface{}, 3)); err == nil {
+		t.Error("ScanSlice on closed Rows succeeded")
+	}
+}
+
 func TestPointerParamsAndScans(t *testing.T) {
 	db := newTestDB(t, "")
 	defer closeDB(t, db)
@@ -788,6 +1569,60 @@ func TestQueryRowClosingStmt(t *testing.T) {
 	}
 }
 
+func TestRowsCloseReportsIterationError(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	iterErr := errors.New("error in rows.Next")
+	rowsCursorNextHook = func(dest []driver.Value) error {
+		return iterErr
+	}
+	defer func() { rowsCursorNextHook = nil }()
+
+	rows, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	// Close is called without checking Err first.
+	if err := rows.Close(); err != iterErr {
+		t.Errorf("Close = %v; want %v", err, iterErr)
+	}
+	if err := rows.Close(); err != iterErr {
+		t.Errorf("second Close = %v; want %v", err, iterErr)
+	}
+	if err := rows.Err(); err != iterErr {
+		t.Errorf("Err = %v; want %v", err, iterErr)
+	}
+
+	rowsCloseHook = func(rows *Rows, err *error) {
+		*err = errors.New("error in rows.Close")
+	}
+	defer func() { rowsCloseHook = nil }()
+	rows, err = db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	want := "error in rows.Next; closing rows: error in rows.Close"
+	if err := rows.Close(); err == nil || err.Error() != want {
+		t.Errorf("Close = %v; want %q", err, want)
+	}
+
+	// A clean end of iteration reports only the close error.
+	rowsCursorNextHook = nil
+	rows, err = db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Next()
+	if err := rows.Close(); err == nil || err.Error() != "error in rows.Close" {
+		t.Errorf("Close = %v; want %q", err, "error in rows.Close")
+	}
+}
+
 // Test issue 6651
 func TestIssue6651(t *testing.T) {
 	db := newTestDB(t, "people")
@@ -817,6 +1652,45 @@ func TestIssue6651(t *testing.T) {
 	}
 }
 
+func TestRowsAllowUnsafeBytes(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	buf := []byte("driver memory")
+	rowsCursorNextHook = func(dest []driver.Value) error {
+		dest[0] = buf
+		return nil
+	}
+	defer func() { rowsCursorNextHook = nil }()
+
+	for _, allow := range []bool{false, true} {
+		rows, err := db.Query("SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.SetAllowUnsafeBytes(allow)
+		if !rows.Next() {
+			t.Fatal(rows.Err())
+		}
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		if string(b) != "driver memory" || s != "driver memory" {
+			t.Errorf("allow=%v: scanned %q, %q", allow, b, s)
+		}
+		if shared := &b[0] == &buf[0]; shared != allow {
+			t.Errorf("allow=%v: result shares driver memory = %v", allow, shared)
+		}
+	}
+}
+
 type nullTestRow struct {
 	nullParam    interface{}
 	notNullParam interface{}
@@ -1027,33 +1901,123 @@ func TestMaxIdleConns(t *testing.T) {
 	}
 }
 
-func TestMaxOpenConns(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping in short mode")
+func TestOpenWithOptions(t *testing.T) {
+	bad := []Options{
+		{MaxOpenConns: -1},
+		{ConnMaxLifetime: -time.Second},
+		{ConnOpenTimeout: -time.Second},
+		{MaxIdleConns: 5, MaxOpenConns: 2},
+	}
+	for _, opts := range bad {
+		if db, err := OpenWithOptions("test", fakeDBName, opts); err == nil {
+			db.Close()
+			t.Errorf("OpenWithOptions(%+v) succeeded; want error", opts)
+		}
 	}
-	defer setHookpostCloseConn(nil)
-	setHookpostCloseConn(func(_ *fakeConn, err error) {
+
+	tests := []struct {
+		opts     Options
+		wantIdle int
+	}{
+		{Options{}, defaultMaxIdleConns},
+		{Options{MaxIdleConns: -1}, 0},
+		{Options{MaxIdleConns: 3, MaxOpenConns: 3, ConnMaxLifetime: time.Hour}, 3},
+		{Options{MaxOpenConns: 1}, 1},
+	}
+	for _, tt := range tests {
+		db, err := OpenWithOptions("test", fakeDBName, tt.opts)
 		if err != nil {
-			t.Errorf("Error closing fakeConn: %v", err)
+			t.Fatalf("OpenWithOptions(%+v): %v", tt.opts, err)
 		}
-	})
-
-	db := newTestDB(t, "magicquery")
-	defer closeDB(t, db)
+		db.mu.Lock()
+		idle, open, lifetime := db.maxIdleConnsLocked(), db.maxOpen, db.maxLifetime
+		db.mu.Unlock()
+		if idle != tt.wantIdle || open != tt.opts.MaxOpenConns || lifetime != tt.opts.ConnMaxLifetime {
+			t.Errorf("OpenWithOptions(%+v) = idle %d, open %d, lifetime %v; want idle %d",
+				tt.opts, idle, open, lifetime, tt.wantIdle)
+		}
+		db.Close()
+	}
 
-	driver := db.driver.(*fakeDriver)
+	if _, err := OpenWithOptions("nosuchdriver", fakeDBName, Options{}); err == nil {
+		t.Error("OpenWithOptions with unknown driver succeeded")
+	}
+}
 
-	// Force the number of open connections to 0 so we can get an accurate
-	// count for the test
-	db.clearAllConns(t)
+func TestSetDefaultMaxIdleConns(t *testing.T) {
+	defer SetDefaultMaxIdleConns(defaultMaxIdleConns)
 
-	driver.mu.Lock()
-	opens0 := driver.openCount
-	closes0 := driver.closeCount
-	driver.mu.Unlock()
+	maxIdle := func(db *DB) int {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return db.maxIdleConnsLocked()
+	}
+	before := newTestDB(t, "")
+	defer closeDB(t, before)
 
-	db.SetMaxIdleConns(10)
-	db.SetMaxOpenConns(10)
+	SetDefaultMaxIdleConns(5)
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	if n := maxIdle(db); n != 5 {
+		t.Errorf("maxIdle of DB opened after SetDefaultMaxIdleConns(5) = %d; want 5", n)
+	}
+	if n := maxIdle(before); n != defaultMaxIdleConns {
+		t.Errorf("maxIdle of DB opened before = %d; want %d", n, defaultMaxIdleConns)
+	}
+	db.SetMaxIdleConns(1)
+	if n := maxIdle(db); n != 1 {
+		t.Errorf("maxIdle after SetMaxIdleConns(1) = %d; want 1", n)
+	}
+	limited, err := OpenWithOptions("test", fakeDBName, Options{MaxOpenConns: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limited.Close()
+	if n := maxIdle(limited); n != 3 {
+		t.Errorf("maxIdle with MaxOpenConns 3 = %d; want 3", n)
+	}
+
+	SetDefaultMaxIdleConns(-1)
+	none := newTestDB(t, "")
+	defer closeDB(t, none)
+	if n := maxIdle(none); n != 0 {
+		t.Errorf("maxIdle after SetDefaultMaxIdleConns(-1) = %d; want 0", n)
+	}
+	if err := none.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if n := none.Stats().OpenConnections; n != 0 {
+		t.Errorf("%d connections open with no idle connections allowed; want 0", n)
+	}
+}
+
+func TestMaxOpenConns(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	defer setHookpostCloseConn(nil)
+	setHookpostCloseConn(func(_ *fakeConn, err error) {
+		if err != nil {
+			t.Errorf("Error closing fakeConn: %v", err)
+		}
+	})
+
+	db := newTestDB(t, "magicquery")
+	defer closeDB(t, db)
+
+	driver := db.driver.(*fakeDriver)
+
+	// Force the number of open connections to 0 so we can get an accurate
+	// count for the test
+	db.clearAllConns(t)
+
+	driver.mu.Lock()
+	opens0 := driver.openCount
+	closes0 := driver.closeCount
+	driver.mu.Unlock()
+
+	db.SetMaxIdleConns(10)
+	db.SetMaxOpenConns(10)
 
 	stmt, err := db.Prepare("SELECT|magicquery|op|op=?,millis=?")
 	if err != nil {
@@ -1299,6 +2263,394 @@ func TestStats(t *testing.T) {
 	}
 }
 
+func TestErrorHandler(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	type call struct {
+		op, query string
+		err       error
+	}
+	var calls []call
+	errWrapped := errors.New("wrapped")
+	db.SetErrorHandler(func(op, query string, err error) error {
+		calls = append(calls, call{op, query, err})
+		if op == "Exec" {
+			return errWrapped
+		}
+		return nil
+	})
+	expect := func(op, query string, err error) {
+		if len(calls) != 1 {
+			t.Fatalf("handler called %d times for %s; want once", len(calls), op)
+		}
+		c := calls[0]
+		calls = nil
+		if c.op != op || c.query != query || (err != nil && c.err != err) {
+			t.Errorf("handler called with %q, %q, %v; want %q, %q, %v", c.op, c.query, c.err, op, query, err)
+		}
+	}
+
+	var name string
+	if err := db.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("handler called on success: %v", calls)
+	}
+
+	if _, err := db.Exec("INSERT|nosuchtable|name=?", "x"); err != errWrapped {
+		t.Errorf("Exec = %v; want the handler's error", err)
+	}
+	expect("Exec", "INSERT|nosuchtable|name=?", nil)
+
+	// Returning nil from the handler keeps the original error.
+	err := db.QueryRow("SELECT|people|name|age=?", 99).Scan(&name)
+	if err != ErrNoRows {
+		t.Errorf("QueryRow.Scan = %v; want ErrNoRows", err)
+	}
+	expect("QueryRow", "SELECT|people|name|age=?", ErrNoRows)
+
+	if _, err := db.Query("SELECT|nosuchtable|name|"); err == nil {
+		t.Error("Query on a missing table succeeded")
+	}
+	expect("Query", "SELECT|nosuchtable|name|", nil)
+
+	stmt, err := db.Prepare("SELECT|people|name|age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec(); err == nil {
+		t.Error("Stmt.Exec with missing argument succeeded")
+	}
+	expect("Stmt.Exec", "SELECT|people|name|age=?", nil)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT|nosuchtable|name=?", "x"); err == nil {
+		t.Error("Tx.Exec on a missing table succeeded")
+	}
+	expect("Tx.Exec", "INSERT|nosuchtable|name=?", nil)
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != ErrTxDone {
+		t.Errorf("Rollback after Commit = %v; want ErrTxDone", err)
+	}
+	expect("Tx.Rollback", "", ErrTxDone)
+	if err := tx.QueryRow("SELECT|people|name|").Scan(&name); err != ErrTxDone {
+		t.Errorf("Tx.QueryRow.Scan after Commit = %v; want ErrTxDone", err)
+	}
+	expect("Tx.QueryRow", "SELECT|people|name|", ErrTxDone)
+
+	db.SetErrorHandler(nil)
+	if _, err := db.Exec("INSERT|nosuchtable|name=?", "x"); err == nil || err == errWrapped {
+		t.Errorf("Exec without handler = %v; want the driver's error", err)
+	}
+}
+
+func TestStatsReuses(t *testing.T) {
+	db := newTestDB(t, "people")
+	db.SetMaxOpenConns(1)
+
+	before := db.Stats().Reuses
+	for i := 0; i < 3; i++ {
+		var name string
+		if err := db.QueryRow("SELECT|people|name|name=?", "Alice").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := db.Stats().Reuses - before; got != 3 {
+		t.Errorf("Reuses increased by %d; want 3", got)
+	}
+
+	var closed []ConnInfo
+	db.SetConnCloseHook(func(info ConnInfo) {
+		closed = append(closed, info)
+	})
+	closeDB(t, db)
+	if len(closed) != 1 {
+		t.Fatalf("close hook called %d times; want 1", len(closed))
+	}
+	// One checkout for each of the WIPE, CREATE and three INSERTs
+	// in newTestDB, plus the three queries above.
+	if got, want := closed[0].Reuses, int64(7); got != want {
+		t.Errorf("ConnInfo.Reuses = %d; want %d", got, want)
+	}
+	if closed[0].CreatedAt.IsZero() {
+		t.Error("ConnInfo.CreatedAt is zero")
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+	db.SetMaxStmtCacheSize(1)
+	defer db.SetMaxStmtCacheSize(0)
+
+	query := func(q string) {
+		var v interface{}
+		if err := db.QueryRow(q, "Alice").Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query("SELECT|people|name|name=?")
+	query("SELECT|people|age|name=?")
+
+	before := db.Stats()
+	got := db.ResetStats()
+	if got != before {
+		t.Errorf("ResetStats = %+v; want %+v", got, before)
+	}
+	if got.Reuses == 0 || got.StmtCacheEvictions != 1 {
+		t.Errorf("counters before reset = %d reuses, %d evictions; want some reuses, 1 eviction",
+			got.Reuses, got.StmtCacheEvictions)
+	}
+
+	after := db.Stats()
+	if after.Reuses != 0 || after.StmtCacheEvictions != 0 {
+		t.Errorf("counters after reset = %d reuses, %d evictions; want 0, 0",
+			after.Reuses, after.StmtCacheEvictions)
+	}
+	if after.OpenConnections != before.OpenConnections || after.StmtCacheSize != before.StmtCacheSize {
+		t.Errorf("gauges changed by reset: %+v -> %+v", before, after)
+	}
+
+	query("SELECT|people|age|name=?") // cached, so one checkout
+	if n := db.Stats().Reuses; n != 1 {
+		t.Errorf("Reuses after reset and one query = %d; want 1", n)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Stats().InUse; got != 2 {
+		t.Fatalf("InUse = %d; want 2", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := db.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Drain with busy connections = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if n := db.numFreeConns(); n != 0 {
+		t.Errorf("free conns after Drain = %d; want 0", n)
+	}
+	if _, err := db.Exec("INSERT|people|name=Dave"); err != ErrDraining {
+		t.Errorf("Exec on draining DB = %v; want ErrDraining", err)
+	}
+
+	// Work already holding a connection runs to completion.
+	if _, err := tx.Exec("INSERT|people|name=Eve,age=5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain = %v", err)
+	}
+	if s := db.Stats(); s.InUse != 0 || s.OpenConnections != 0 {
+		t.Errorf("after Drain, InUse = %d, OpenConnections = %d; want 0, 0", s.InUse, s.OpenConnections)
+	}
+}
+
+func TestDrainWaitingRequest(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := db.Exec("INSERT|people|name=Dave")
+		errc <- err
+	}()
+	for {
+		db.mu.Lock()
+		n := len(db.connRequests)
+		db.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	db.Drain(ctx)
+	if err := <-errc; err != ErrDraining {
+		t.Errorf("waiting Exec = %v; want ErrDraining", err)
+	}
+	tx.Rollback()
+	if err := db.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain = %v", err)
+	}
+
+	db.Close()
+	if err := db.Drain(context.Background()); err != errDBClosed {
+		t.Errorf("Drain on closed DB = %v; want %v", err, errDBClosed)
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	db, err := Open("test", fakeDBName+";version=9.6.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	for i := 0; i < 3; i++ {
+		v, err := db.ServerVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != "9.6.1" {
+			t.Errorf("ServerVersion = %q; want 9.6.1", v)
+		}
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Fatalf("free conns = %d; want 1", n)
+	}
+	vc := db.freeConn[0].ci.(*versionedFakeConn)
+	if vc.numVersion != 1 {
+		t.Errorf("driver ServerVersion called %d times; want 1", vc.numVersion)
+	}
+
+	db2 := newTestDB(t, "")
+	defer closeDB(t, db2)
+	if _, err := db2.ServerVersion(); err != ErrServerVersionUnsupported {
+		t.Errorf("ServerVersion without driver support = %v; want ErrServerVersionUnsupported", err)
+	}
+	if n := db2.Stats().InUse; n != 0 {
+		t.Errorf("%d connections in use; want 0", n)
+	}
+}
+
+func TestConnOpenTimeout(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetConnOpenTimeout(10 * time.Millisecond)
+
+	driver := db.driver.(*fakeDriver)
+	driver.mu.Lock()
+	opens0, closes0 := driver.openCount, driver.closeCount
+	driver.mu.Unlock()
+
+	release := make(chan struct{})
+	defer setHookOpenErr(nil)
+	setHookOpenErr(func() error {
+		<-release
+		return nil
+	})
+	if err := db.Ping(); err != ErrConnOpenTimeout {
+		t.Fatalf("Ping with a hung driver = %v; want ErrConnOpenTimeout", err)
+	}
+
+	// The connection the driver eventually opens is closed rather
+	// than leaked.
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		driver.mu.Lock()
+		opens, closes := driver.openCount-opens0, driver.closeCount-closes0
+		driver.mu.Unlock()
+		if opens == 1 && closes == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("late connection: %d opened, %d closed; want 1, 1", opens, closes)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := db.Stats().OpenConnections; n != 0 {
+		t.Errorf("OpenConnections = %d; want 0", n)
+	}
+
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Ping with a responsive driver = %v", err)
+	}
+}
+
+func TestPrepareTimeout(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetPrepareTimeout(10 * time.Millisecond)
+
+	driver := db.driver.(*fakeDriver)
+	driver.mu.Lock()
+	closes0 := driver.closeCount
+	driver.mu.Unlock()
+
+	const slow = "SELECT|people|name|age=?"
+	release := make(chan struct{})
+	defer func() { hookPrepareErr = nil }()
+	hookPrepareErr = func(c *fakeConn, query string) error {
+		if query == slow {
+			<-release
+		}
+		return nil
+	}
+	if _, err := db.Prepare(slow); err != ErrPrepareTimeout {
+		t.Fatalf("Prepare with a hung driver = %v; want ErrPrepareTimeout", err)
+	}
+	stmt, err := db.Prepare("SELECT|people|age|name=?")
+	if err != nil {
+		t.Fatalf("Prepare with a responsive driver = %v", err)
+	}
+	stmt.Close()
+
+	// The connection of the abandoned Prepare is closed once the
+	// driver returns, rather than pooled.
+	if n := db.Stats().InUse; n != 1 {
+		t.Errorf("InUse during the hung Prepare = %d; want 1", n)
+	}
+	close(release)
+	waitCondition(t, "the abandoned connection to close", func() bool {
+		driver.mu.Lock()
+		defer driver.mu.Unlock()
+		return driver.closeCount-closes0 == 1
+	})
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("InUse = %d; want 0", n)
+	}
+
+	stmt, err = db.Prepare(slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var name string
+	if err := stmt.QueryRow(1).Scan(&name); err != nil || name != "Alice" {
+		t.Errorf("QueryRow = %q, %v; want Alice", name, err)
+	}
+}
+
 func TestConnMaxLifetime(t *testing.T) {
 	t0 := time.Unix(1000000, 0)
 	offset := time.Duration(0)
@@ -1383,22 +2735,70 @@ func TestConnMaxLifetime(t *testing.T) {
 	}
 }
 
-// golang.org/issue/5323
-func TestStmtCloseDeps(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping in short mode")
-	}
-	defer setHookpostCloseConn(nil)
-	setHookpostCloseConn(func(_ *fakeConn, err error) {
-		if err != nil {
-			t.Errorf("Error closing fakeConn: %v", err)
-		}
-	})
+func TestStmtConnMaxLifetime(t *testing.T) {
+	t0 := time.Unix(1000000, 0)
+	offset := time.Duration(0)
 
-	db := newTestDB(t, "magicquery")
-	defer closeDB(t, db)
+	nowFunc = func() time.Time { return t0.Add(offset) }
+	defer func() { nowFunc = time.Now }()
 
-	driver := db.driver.(*fakeDriver)
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetMaxIdleConns(3)
+	db.SetConnMaxLifetime(10 * time.Second)
+
+	stmt, err := db.Prepare("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	// Prepare the statement on three idle connections.
+	var open []*Rows
+	for i := 0; i < 3; i++ {
+		rows, err := stmt.Query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		open = append(open, rows)
+	}
+	for _, rows := range open {
+		rows.Close()
+	}
+	if g, w := db.numFreeConns(), 3; g != w {
+		t.Fatalf("free conns = %d; want %d", g, w)
+	}
+
+	// Expire them all. The next use prepares the statement on a new
+	// connection instead of failing with ErrBadConn.
+	offset = 11 * time.Second
+	rows, err := stmt.Query()
+	if err != nil {
+		t.Fatalf("Query after expiry: %v", err)
+	}
+	if rows.dc.createdAt != t0.Add(offset) {
+		t.Error("Query used an expired connection")
+	}
+	rows.Close()
+}
+
+// golang.org/issue/5323
+func TestStmtCloseDeps(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	defer setHookpostCloseConn(nil)
+	setHookpostCloseConn(func(_ *fakeConn, err error) {
+		if err != nil {
+			t.Errorf("Error closing fakeConn: %v", err)
+		}
+	})
+
+	db := newTestDB(t, "magicquery")
+	defer closeDB(t, db)
+
+	driver := db.driver.(*fakeDriver)
 
 	driver.mu.Lock()
 	opens0 := driver.openCount
@@ -1462,136 +2862,714 @@ func TestStmtCloseDeps(t *testing.T) {
 	if len(stmt.css) > nquery {
 		t.Errorf("len(stmt.css) = %d; want <= %d", len(stmt.css), nquery)
 	}
-
-	if err := stmt.Close(); err != nil {
+
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, w := db.numFreeConns(), 2; g != w {
+		t.Errorf("free conns = %d; want %d", g, w)
+	}
+
+	if n := db.numDepsPollUntil(2, time.Second); n > 2 {
+		t.Errorf("number of dependencies = %d; expected <= 2", n)
+		db.dumpDeps(t)
+	}
+
+	db.clearAllConns(t)
+}
+
+// golang.org/issue/5046
+func TestCloseConnBeforeStmts(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	defer setHookpostCloseConn(nil)
+	setHookpostCloseConn(func(_ *fakeConn, err error) {
+		if err != nil {
+			t.Errorf("Error closing fakeConn: %v; from %s", err, stack())
+			db.dumpDeps(t)
+			t.Errorf("DB = %#v", db)
+		}
+	})
+
+	stmt, err := db.Prepare("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.freeConn) != 1 {
+		t.Fatalf("expected 1 freeConn; got %d", len(db.freeConn))
+	}
+	dc := db.freeConn[0]
+	if dc.closed {
+		t.Errorf("conn shouldn't be closed")
+	}
+
+	if n := len(dc.openStmt); n != 1 {
+		t.Errorf("driverConn num openStmt = %d; want 1", n)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Errorf("db Close = %v", err)
+	}
+	if !dc.closed {
+		t.Errorf("after db.Close, driverConn should be closed")
+	}
+	if n := len(dc.openStmt); n != 0 {
+		t.Errorf("driverConn num openStmt = %d; want 0", n)
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		t.Errorf("Stmt close = %v", err)
+	}
+
+	if !dc.closed {
+		t.Errorf("conn should be closed")
+	}
+	if dc.ci != nil {
+		t.Errorf("after Stmt Close, driverConn's Conn interface should be nil")
+	}
+}
+
+// golang.org/issue/5283: don't release the Rows' connection in Close
+// before calling Stmt.Close.
+func TestRowsCloseOrder(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	db.SetMaxIdleConns(0)
+	setStrictFakeConnClose(t)
+	defer setStrictFakeConnClose(nil)
+
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRowsImplicitClose(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, fail := 2, errors.New("fail")
+	r := rows.rowsi.(*rowsCursor)
+	r.errPos, r.err = want, fail
+
+	got := 0
+	for rows.Next() {
+		got++
+	}
+	if got != want {
+		t.Errorf("got %d rows, want %d", got, want)
+	}
+	if err := rows.Err(); err != fail {
+		t.Errorf("got error %v, want %v", err, fail)
+	}
+	if !r.closed {
+		t.Errorf("r.closed is false, want true")
+	}
+}
+
+func TestPreparedStatements(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	s1, err := db.Prepare("SELECT|people|name|age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := db.Prepare("SELECT|people|age|name=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Prepare("SELECT|people|name|"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := db.PreparedStatements()
+	sort.Strings(got)
+	if want := []string{s2.query, s1.query}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("PreparedStatements = %q; want %q", got, want)
+	}
+	s1.Close()
+	if got, want := db.PreparedStatements(), []string{s2.query}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("PreparedStatements after Close = %q; want %q", got, want)
+	}
+
+	tx.Rollback()
+	db.mu.Lock()
+	dc := db.freeConn[0]
+	db.mu.Unlock()
+	fc := dc.ci.(*fakeConn)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fc.mu.Lock()
+	made, closed := fc.stmtsMade, fc.stmtsClosed
+	fc.mu.Unlock()
+	if made != closed {
+		t.Errorf("driver statements made = %d, closed = %d after DB.Close; want equal", made, closed)
+	}
+	if _, err := s2.Exec(1); err == nil {
+		t.Error("Exec on a statement of a closed DB succeeded")
+	}
+	if got := db.PreparedStatements(); len(got) != 0 {
+		t.Errorf("PreparedStatements after DB.Close = %v; want none", got)
+	}
+}
+
+// Tests that a statement whose references are lost is reported and
+// closed when it is garbage collected.
+func TestPreparedStatementLost(t *testing.T) {
+	logs, restore := captureLog()
+	defer restore()
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+	events := db.Events()
+
+	if _, err := db.Prepare("SELECT|people|name|age=?"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.PreparedStatements()); n != 1 {
+		t.Fatalf("PreparedStatements = %d statements; want 1", n)
+	}
+	db.mu.Lock()
+	dc := db.freeConn[0]
+	db.mu.Unlock()
+
+	waitCondition(t, "lost statement to be closed", func() bool {
+		runtime.GC()
+		dc.Lock()
+		defer dc.Unlock()
+		return len(dc.openStmt) == 0
+	})
+	fc := dc.ci.(*fakeConn)
+	fc.mu.Lock()
+	made, closed := fc.stmtsMade, fc.stmtsClosed
+	fc.mu.Unlock()
+	if made != closed {
+		t.Errorf("driver statements made = %d, closed = %d; want equal", made, closed)
+	}
+	if got := db.PreparedStatements(); len(got) != 0 {
+		t.Errorf("PreparedStatements after GC = %v; want none", got)
+	}
+	if got := logs.String(); !strings.Contains(got, `"SELECT|people|name|age=?"`) {
+		t.Errorf("log = %q; want a warning with the query", got)
+	}
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != StmtLeaked {
+				continue
+			}
+			if ev.Query != "SELECT|people|name|age=?" {
+				t.Errorf("StmtLeaked query = %q", ev.Query)
+			}
+		default:
+			t.Fatal("no StmtLeaked event")
+		}
+		break
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatalf("first Next failed: %v", rows.Err())
+	}
+	cancel()
+	if rows.Next() {
+		t.Fatal("Next succeeded after cancel")
+	}
+	if err := rows.Err(); err != context.Canceled {
+		t.Errorf("Err = %v; want %v", err, context.Canceled)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("InUse after cancel = %d; want 0", n)
+	}
+
+	if _, err := db.QueryContext(ctx, "SELECT|people|name|"); err != context.Canceled {
+		t.Errorf("QueryContext with done ctx = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestQueryContextInterruptsNext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	unblock := make(chan struct{})
+	blocked := make(chan struct{})
+	rowsCursorNextHook = func(dest []driver.Value) error {
+		close(blocked)
+		<-unblock
+		return errors.New("fakedb: interrupted")
+	}
+	rowsCursorInterruptHook = func() { close(unblock) }
+	defer func() {
+		rowsCursorNextHook = nil
+		rowsCursorInterruptHook = nil
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() { done <- rows.Next() }()
+	<-blocked
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Next succeeded after the deadline")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next still blocked after the deadline")
+	}
+	if err := rows.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Err = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("InUse after deadline = %d; want 0", n)
+	}
+}
+
+func TestCancelTimeout(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetCancelTimeout(20 * time.Millisecond)
+
+	interrupting := make(chan struct{})
+	hung := make(chan struct{})
+	rowsCursorInterruptHook = func() {
+		close(interrupting)
+		<-hung
+	}
+	defer func() {
+		close(hung)
+		rowsCursorInterruptHook = nil
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	<-interrupting
+	closed := make(chan error)
+	go func() { closed <- rows.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close still blocked on a hung cancel")
+	}
+	waitCondition(t, "the connection to be discarded", func() bool {
+		s := db.Stats()
+		return s.OpenConnections == 0 && s.InUse == 0
+	})
+
+	// A prompt cancel keeps the connection.
+	rowsCursorInterruptHook = nil
+	ctx, cancel = context.WithCancel(context.Background())
+	rows, err = db.QueryContext(ctx, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	rows.Close()
+	if n := db.Stats().OpenConnections; n != 1 {
+		t.Errorf("after a prompt cancel, %d open connections; want 1", n)
+	}
+}
+
+func TestDiscardOnError(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	defer func() { hookPrepareErr = nil }()
+
+	errFlaky := errors.New("flaky: connection reset by peer")
+	errSyntax := errors.New("syntax error")
+	var fail error
+	hookPrepareErr = func(c *fakeConn, query string) error {
+		return fail
+	}
+	run := func(err error) int {
+		fail = err
+		if _, got := db.Exec("INSERT|people|name=?,age=?", "Ann", 1); got != err {
+			t.Fatalf("Exec = %v; want %v", got, err)
+		}
+		fail = nil
+		return db.Stats().OpenConnections
+	}
+
+	if n := run(errFlaky); n != 1 {
+		t.Errorf("by default, %d open connections after an error; want it pooled", n)
+	}
+	db.SetDiscardOnError(func(err error) bool { return err == errFlaky })
+	if n := run(errSyntax); n != 1 {
+		t.Errorf("%d open connections after an unclassified error; want it pooled", n)
+	}
+	if n := run(errFlaky); n != 0 {
+		t.Errorf("%d open connections after a classified error; want it discarded", n)
+	}
+	db.SetDiscardOnError(nil)
+	run(errSyntax)
+	if n := run(errFlaky); n != 1 {
+		t.Errorf("after SetDiscardOnError(nil), %d open connections; want it pooled", n)
+	}
+}
+
+func TestTxDeferConstraints(t *testing.T) {
+	db, err := Open("test", fakeDBName+";deferrable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := tx.dc.ci.(deferrableFakeConn).fakeConn
+	deferred := func() bool {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		return fc.constraintsDeferred
+	}
+	if err := tx.DeferConstraints(); err != nil {
+		t.Fatal(err)
+	}
+	if !deferred() {
+		t.Error("constraints not deferred after DeferConstraints")
+	}
+	if err := tx.ImmediateConstraints(); err != nil {
+		t.Fatal(err)
+	}
+	if deferred() {
+		t.Error("constraints still deferred after ImmediateConstraints")
+	}
+	if err := tx.DeferConstraints(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if deferred() {
+		t.Error("constraints still deferred after Commit")
+	}
+	if err := tx.DeferConstraints(); err != ErrTxDone {
+		t.Errorf("DeferConstraints after Commit = %v; want ErrTxDone", err)
+	}
+}
+
+func TestTxDeferConstraintsDialect(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|modes|mode=string")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := tx.DeferConstraints(); err != ErrDeferConstraintsNotSupported {
+		t.Errorf("DeferConstraints with no dialect statement = %v; want ErrDeferConstraintsNotSupported", err)
+	}
+
+	// The fake database can't set constraints, so record the
+	// statement's mode instead.
+	db.SetDialect(&Dialect{Name: "fake", SetConstraints: "INSERT|modes|mode="})
+	if err := tx.DeferConstraints(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.ImmediateConstraints(); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := tx.Query("SELECT|modes|mode|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var modes []string
+	for rows.Next() {
+		var m string
+		if err := rows.Scan(&m); err != nil {
+			t.Fatal(err)
+		}
+		modes = append(modes, m)
+	}
+	if want := []string{"DEFERRED", "IMMEDIATE"}; !reflect.DeepEqual(modes, want) {
+		t.Errorf("statements set modes %q; want %q", modes, want)
+	}
+}
+
+func TestTxStatementTimeoutNative(t *testing.T) {
+	db, err := Open("test", fakeDBName+";stmtTimeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db)
+	exec(t, db, "WIPE")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := tx.dc.ci.(timeoutFakeConn).fakeConn
+	timeout := func() time.Duration {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		return fc.stmtTimeout
+	}
+	if err := tx.SetStatementTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d := timeout(); d != time.Second {
+		t.Errorf("driver timeout = %v; want 1s", d)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if d := timeout(); d != 0 {
+		t.Errorf("driver timeout after Commit = %v; want it reset to 0", d)
+	}
+}
+
+func TestTxStatementTimeoutInterruptsNext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	unblock := make(chan struct{})
+	blocked := make(chan struct{})
+	rowsCursorNextHook = func(dest []driver.Value) error {
+		close(blocked)
+		<-unblock
+		return errors.New("fakedb: interrupted")
+	}
+	rowsCursorInterruptHook = func() { close(unblock) }
+	defer func() {
+		rowsCursorNextHook = nil
+		rowsCursorInterruptHook = nil
+	}()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := tx.SetStatementTimeout(50 * time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
-
-	if g, w := db.numFreeConns(), 2; g != w {
-		t.Errorf("free conns = %d; want %d", g, w)
+	rows, err := tx.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	if n := db.numDepsPollUntil(2, time.Second); n > 2 {
-		t.Errorf("number of dependencies = %d; expected <= 2", n)
-		db.dumpDeps(t)
+	done := make(chan bool)
+	go func() { done <- rows.Next() }()
+	<-blocked
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Next succeeded after the statement timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next still blocked after the statement timeout")
+	}
+	if err := rows.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Err = %v; want %v", err, context.DeadlineExceeded)
 	}
-
-	db.clearAllConns(t)
 }
 
-// golang.org/issue/5046
-func TestCloseConnBeforeStmts(t *testing.T) {
+func TestStmtCloseOrder(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
 
-	defer setHookpostCloseConn(nil)
-	setHookpostCloseConn(func(_ *fakeConn, err error) {
-		if err != nil {
-			t.Errorf("Error closing fakeConn: %v; from %s", err, stack())
-			db.dumpDeps(t)
-			t.Errorf("DB = %#v", db)
-		}
-	})
+	db.SetMaxIdleConns(0)
+	setStrictFakeConnClose(t)
+	defer setStrictFakeConnClose(nil)
+
+	_, err := db.Query("SELECT|non_existent|name|")
+	if err == nil {
+		t.Fatal("Querying non-existent table should fail")
+	}
+}
+
+func TestMaxStmtConns(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxStmtConns(2)
 
 	stmt, err := db.Prepare("SELECT|people|name|")
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer stmt.Close()
 
-	if len(db.freeConn) != 1 {
-		t.Fatalf("expected 1 freeConn; got %d", len(db.freeConn))
-	}
-	dc := db.freeConn[0]
-	if dc.closed {
-		t.Errorf("conn shouldn't be closed")
+	var held []*Rows
+	for i := 0; i < 2; i++ {
+		rows, err := stmt.Query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, rows)
 	}
 
-	if n := len(dc.openStmt); n != 1 {
-		t.Errorf("driverConn num openStmt = %d; want 1", n)
-	}
-	err = db.Close()
-	if err != nil {
-		t.Errorf("db Close = %v", err)
-	}
-	if !dc.closed {
-		t.Errorf("after db.Close, driverConn should be closed")
+	done := make(chan error, 1)
+	go func() {
+		rows, err := stmt.Query()
+		if err == nil {
+			err = rows.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("third concurrent Query returned (%v); want it to wait", err)
+	case <-time.After(50 * time.Millisecond):
 	}
-	if n := len(dc.openStmt); n != 0 {
-		t.Errorf("driverConn num openStmt = %d; want 0", n)
+	if n := db.Stats().InUse; n != 2 {
+		t.Errorf("InUse = %d; want 2", n)
 	}
 
-	err = stmt.Close()
-	if err != nil {
-		t.Errorf("Stmt close = %v", err)
+	// Other queries still get connections from the pool.
+	var name string
+	if err := db.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
 	}
 
-	if !dc.closed {
-		t.Errorf("conn should be closed")
+	held[0].Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("third Query still waiting after a connection was released")
 	}
-	if dc.ci != nil {
-		t.Errorf("after Stmt Close, driverConn's Conn interface should be nil")
+	held[1].Close()
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("InUse after closing rows = %d; want 0", n)
 	}
 }
 
-// golang.org/issue/5283: don't release the Rows' connection in Close
-// before calling Stmt.Close.
-func TestRowsCloseOrder(t *testing.T) {
+func TestNonBlocking(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
+	db.SetMaxOpenConns(2)
+	db.SetNonBlocking(true)
 
-	db.SetMaxIdleConns(0)
-	setStrictFakeConnClose(t)
-	defer setStrictFakeConnClose(nil)
+	var held []*Rows
+	for i := 0; i < 2; i++ {
+		rows, err := db.Query("SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, rows)
+	}
 
-	rows, err := db.Query("SELECT|people|age,name|")
-	if err != nil {
-		t.Fatal(err)
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.Exec("INSERT|people|name=Dave,age=?", 4)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != ErrPoolExhausted {
+			t.Fatalf("Exec on exhausted pool = %v; want ErrPoolExhausted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Exec on exhausted pool is waiting; want it to fail immediately")
 	}
-	err = rows.Close()
-	if err != nil {
-		t.Fatal(err)
+
+	held[0].Close()
+	if _, err := db.Exec("INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Errorf("Exec after a connection was released: %v", err)
 	}
+	held[1].Close()
 }
 
-func TestRowsImplicitClose(t *testing.T) {
+func TestBeginTxCancelWhileWaiting(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
 
-	rows, err := db.Query("SELECT|people|age,name|")
+	tx, err := db.Begin()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	want, fail := 2, errors.New("fail")
-	r := rows.rowsi.(*rowsCursor)
-	r.errPos, r.err = want, fail
-
-	got := 0
-	for rows.Next() {
-		got++
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		tx, err := db.BeginTx(ctx)
+		if err == nil {
+			tx.Rollback()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("BeginTx on saturated pool returned (%v); want it to wait", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("BeginTx error = %v; want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("BeginTx still waiting after its context was cancelled")
 	}
-	if got != want {
-		t.Errorf("got %d rows, want %d", got, want)
+	db.mu.Lock()
+	n := len(db.connRequests)
+	db.mu.Unlock()
+	if n != 0 {
+		t.Errorf("%d connection requests pending after cancellation; want 0", n)
 	}
-	if err := rows.Err(); err != fail {
-		t.Errorf("got error %v, want %v", err, fail)
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
 	}
-	if !r.closed {
-		t.Errorf("r.closed is false, want true")
+	tx, err = db.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("BeginTx after the connection was released: %v", err)
 	}
-}
-
-func TestStmtCloseOrder(t *testing.T) {
-	db := newTestDB(t, "people")
-	defer closeDB(t, db)
-
-	db.SetMaxIdleConns(0)
-	setStrictFakeConnClose(t)
-	defer setStrictFakeConnClose(nil)
+	tx.Rollback()
 
-	_, err := db.Query("SELECT|non_existent|name|")
-	if err == nil {
-		t.Fatal("Querying non-existent table should fail")
+	// An already-cancelled context starts no transaction.
+	if _, err := db.BeginTx(ctx); err != context.Canceled {
+		t.Errorf("BeginTx with cancelled context = %v; want context.Canceled", err)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("InUse = %d; want 0", n)
 	}
 }
 
@@ -1774,6 +3752,203 @@ func TestErrBadConnReconnect(t *testing.T) {
 	simulateBadConn("stmt.Query exec", &hookQueryBadConn, stmtQuery)
 }
 
+// Tests that a Tx dropped without Commit or Rollback is rolled back
+// once garbage collected, returning its connection to the pool.
+func TestTxOrphanRolledBack(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		use  func(tx *Tx, shared *Stmt) error
+	}{
+		{"Exec", func(tx *Tx, _ *Stmt) error {
+			_, err := tx.Exec("INSERT|people|name=Dave,age=?", 4)
+			return err
+		}},
+		// The transaction's closed statements don't keep it
+		// reachable.
+		{"Prepare", func(tx *Tx, _ *Stmt) error {
+			stmt, err := tx.Prepare("SELECT|people|name|age=?")
+			if err != nil {
+				return err
+			}
+			return stmt.Close()
+		}},
+		{"Stmt", func(tx *Tx, shared *Stmt) error {
+			stmt := tx.Stmt(shared)
+			defer stmt.Close()
+			var name string
+			return stmt.QueryRow(1).Scan(&name)
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, restore := captureLog()
+			defer restore()
+			db := newTestDB(t, "people")
+			defer closeDB(t, db)
+			db.SetMaxOpenConns(1)
+			events := db.Events()
+
+			// Prepared on the connection the transaction gets, so
+			// that Tx.Stmt shares its driver statement.
+			shared, err := db.Prepare("SELECT|people|name|age=?")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer shared.Close()
+
+			func() {
+				tx, err := db.Begin()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := tt.use(tx, shared); err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			waitCondition(t, "orphaned Tx to be rolled back", func() bool {
+				runtime.GC()
+				db.mu.Lock()
+				defer db.mu.Unlock()
+				return db.numInUse == 0 && len(db.freeConn) == 1
+			})
+			if !receivedEvent(events, TxLeaked) {
+				t.Error("no TxLeaked event")
+			}
+			if got := logs.String(); !strings.Contains(got, "Tx garbage collected") {
+				t.Errorf("log = %q; want a warning", got)
+			}
+		})
+	}
+}
+
+// Tests that an orphaned Tx's connection isn't released while Rows
+// from it are open, and is once they are closed.
+func TestTxOrphanOpenRows(t *testing.T) {
+	logs, restore := captureLog()
+	defer restore()
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+	events := db.Events()
+
+	func() {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := tx.Query("SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		// Run the finalizer as the garbage collector would on a Tx
+		// that is dropped while its Rows aren't.
+		runtime.SetFinalizer(tx, nil)
+		tx.rollbackOrphan()
+
+		if !receivedEvent(events, TxLeaked) {
+			t.Error("no TxLeaked event")
+		}
+		if tx.done {
+			t.Fatal("Tx rolled back while its Rows are open")
+		}
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		if err := rows.Err(); err != nil || n != 3 {
+			t.Fatalf("read %d rows, err %v; want 3 rows", n, err)
+		}
+	}()
+
+	// The finalizer was set again, so the Tx is rolled back once it
+	// is collected with its Rows closed, and reported only once.
+	waitCondition(t, "orphaned Tx to be rolled back", func() bool {
+		runtime.GC()
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return db.numInUse == 0 && len(db.freeConn) == 1
+	})
+	if receivedEvent(events, TxLeaked) {
+		t.Error("TxLeaked sent again")
+	}
+	if n := strings.Count(logs.String(), "Tx garbage collected"); n != 1 {
+		t.Errorf("logged %d warnings; want 1", n)
+	}
+}
+
+// logBuffer collects the output of the log package, which finalizers
+// may write to concurrently.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog redirects the output of the log package to a logBuffer
+// until restore is called.
+func captureLog() (logs *logBuffer, restore func()) {
+	logs = new(logBuffer)
+	log.SetOutput(logs)
+	return logs, func() { log.SetOutput(os.Stderr) }
+}
+
+// receivedEvent reports whether an event of type typ is waiting on
+// events, discarding those before it.
+func receivedEvent(events <-chan PoolEvent, typ PoolEventType) bool {
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == typ {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// Tests that a connection whose Rollback reports it lost is not
+// returned to the pool.
+func TestTxRollbackLostConnNotPooled(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxIdleConns(1)
+	defer func() { hookRollbackErr = nil }()
+
+	for _, lost := range []error{driver.ErrBadConn, context.Canceled} {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dc := tx.dc
+		hookRollbackErr = func() error { return lost }
+		if err := tx.Rollback(); err != lost {
+			t.Fatalf("Rollback = %v; want %v", err, lost)
+		}
+		hookRollbackErr = nil
+
+		db.mu.Lock()
+		for _, c := range db.freeConn {
+			if c == dc {
+				t.Errorf("after Rollback error %v, connection was returned to freeConn", lost)
+			}
+		}
+		db.mu.Unlock()
+	}
+}
+
 // golang.org/issue/11264
 func TestTxEndBadConn(t *testing.T) {
 	db := newTestDB(t, "foo")
@@ -2189,6 +4364,52 @@ func doConcurrentTest(t testing.TB, ct concurrentTest) {
 	wg.Wait()
 }
 
+func TestStmtReset(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	stmt, err := db.Prepare("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Prepare the statement on two connections.
+	rows1, err := stmt.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows2, err := stmt.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows1.Close()
+	rows2.Close()
+	if n := len(stmt.css); n != 2 {
+		t.Fatalf("len(css slice) = %d; want 2", n)
+	}
+
+	// Invalidate both, as a database restart would.
+	db.SetMaxIdleConns(0)
+	if err := stmt.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stmt.css); n != 0 {
+		t.Errorf("len(css slice) after Reset = %d; want 0", n)
+	}
+
+	var name string
+	if err := stmt.QueryRow().Scan(&name); err != nil {
+		t.Fatalf("QueryRow after Reset: %v", err)
+	}
+	if n := len(stmt.css); n != 1 {
+		t.Errorf("len(css slice) after QueryRow = %d; want 1", n)
+	}
+
+	stmt.Close()
+	if err := stmt.Reset(); err == nil {
+		t.Error("Reset of closed statement succeeded")
+	}
+}
+
 func TestIssue6081(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
@@ -2388,3 +4609,26 @@ func BenchmarkManyConcurrentQueries(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkQueryRowLookup measures a hot primary-key lookup through
+// QueryRow, on a driver whose Queryer runs queries without preparing.
+func BenchmarkQueryRowLookup(b *testing.B) {
+	db, err := Open("test", "lookup;queryer")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	db.Exec("WIPE")
+	db.Exec("CREATE|users|id=int64,name=string")
+	for i := 0; i < 4; i++ {
+		db.Exec("INSERT|users|id=?,name=?", i, fmt.Sprintf("user%d", i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var name string
+		if err := db.QueryRow("SELECT|users|name|id=?", 2).Scan(&name); err != nil || name != "user2" {
+			b.Fatalf("Scan = %q, %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"container/list"
+	"context"
+)
+
+// stmtCache is a least-recently-used cache of the statements prepared
+// for the DB's Exec, Query and QueryRow, keyed by query. It is guarded
+// by db.mu.
+type stmtCache struct {
+	max       int                      // 0 disables the cache; < 0 means unbounded
+	lru       list.List                // of *cachedStmt, most recently used first
+	m         map[string]*list.Element // query -> element of lru
+	evictions int64
+}
+
+// cachedStmt is a statement in a stmtCache.
+type cachedStmt struct {
+	query   string
+	stmt    *Stmt
+	refs    int  // callers currently using stmt
+	evicted bool // removed from the cache; closed once refs drops to 0
+}
+
+func (c *stmtCache) len() int {
+	return len(c.m)
+}
+
+// evictLocked removes the least recently used statements until at
+// most n remain, returning those that are no longer in use and should
+// be closed.
+func (c *stmtCache) evictLocked(n int) (closing []*Stmt) {
+	for len(c.m) > n {
+		e := c.lru.Back()
+		cs := e.Value.(*cachedStmt)
+		c.lru.Remove(e)
+		delete(c.m, cs.query)
+		cs.evicted = true
+		c.evictions++
+		if cs.refs == 0 {
+			closing = append(closing, cs.stmt)
+		}
+	}
+	return closing
+}
+
+// clear forgets every statement in the cache, returning those that are
+// not in use and should be closed.
+func (c *stmtCache) clear() (closing []*Stmt) {
+	for _, e := range c.m {
+		cs := e.Value.(*cachedStmt)
+		cs.evicted = true
+		if cs.refs == 0 {
+			closing = append(closing, cs.stmt)
+		}
+	}
+	c.lru.Init()
+	c.m = nil
+	return closing
+}
+
+// SetMaxStmtCacheSize sets the maximum number of statements that Exec,
+// Query and QueryRow keep prepared for reuse. When it is enabled, the
+// first call with a given query prepares it and later calls with the
+// same query reuse the statement, like a Stmt the caller prepared.
+// Once the cache holds more than n statements, the least recently used
+// ones are closed.
+//
+// If n == 0, statements are not cached and each call prepares its
+// query again, unless the driver can run it directly. If n < 0, the
+// cache is unbounded, which suits only programs running a fixed set
+// of queries. The default is 0.
+
+// SetMaxStmtCacheSize 设置 Exec、Query 和 QueryRow 为复用而保留的已准备语句的
+// 最大数量。启用后，对某个查询的首次调用会准备该查询，之后使用相同查询的调用会复用
+// 该语句，如同调用者自行准备的 Stmt 一样。一旦缓存中的语句超过 n 个，最久未使用的
+// 语句会被关闭。
+//
+// 若 n == 0，则不缓存语句，每次调用都会重新准备其查询，除非驱动能够直接运行它。
+// 若 n < 0，则缓存不受限制，这只适用于运行固定查询集合的程序。默认为 0。
+func (db *DB) SetMaxStmtCacheSize(n int) {
+	db.mu.Lock()
+	db.stmtCache.max = n
+	var closing []*Stmt
+	if n >= 0 {
+		closing = db.stmtCache.evictLocked(n)
+	}
+	db.mu.Unlock()
+	for _, s := range closing {
+		s.Close()
+	}
+}
+
+// cachedStmt returns the cached statement for query, preparing it if
+// needed. It returns nil, nil if the cache is disabled. The caller
+// must pass the result to releaseCachedStmt when done with it.
+func (db *DB) cachedStmt(ctx context.Context, query string) (*cachedStmt, error) {
+	db.mu.Lock()
+	c := &db.stmtCache
+	if c.max == 0 {
+		db.mu.Unlock()
+		return nil, nil
+	}
+	if e, ok := c.m[query]; ok {
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		db.mu.Unlock()
+		return cs, nil
+	}
+	db.mu.Unlock()
+
+	stmt, err := db.prepareRetry(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	if e, ok := c.m[query]; ok {
+		// Another caller cached the same query meanwhile.
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		db.mu.Unlock()
+		stmt.Close()
+		return cs, nil
+	}
+	cs := &cachedStmt{query: query, stmt: stmt, refs: 1}
+	if c.max == 0 || db.closed {
+		// Disabled while we were preparing; use the statement once.
+		cs.evicted = true
+		db.mu.Unlock()
+		return cs, nil
+	}
+	if c.m == nil {
+		c.m = make(map[string]*list.Element)
+	}
+	c.m[query] = c.lru.PushFront(cs)
+	var closing []*Stmt
+	if c.max > 0 {
+		closing = c.evictLocked(c.max)
+	}
+	db.mu.Unlock()
+	for _, s := range closing {
+		s.Close()
+	}
+	return cs, nil
+}
+
+// releaseCachedStmt ends a use of cs begun by cachedStmt, closing its
+// statement if it was evicted in the meantime.
+func (db *DB) releaseCachedStmt(cs *cachedStmt) {
+	db.mu.Lock()
+	cs.refs--
+	closing := cs.evicted && cs.refs == 0
+	db.mu.Unlock()
+	if closing {
+		cs.stmt.Close()
+	}
+}
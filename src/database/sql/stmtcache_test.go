@@ -0,0 +1,159 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStmtCache(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxIdleConns(1)
+	db.SetMaxStmtCacheSize(2)
+
+	queries := []string{
+		"SELECT|people|name|age=?",
+		"SELECT|people|age|name=?",
+		"SELECT|people|dead|name=?",
+	}
+	ages := []interface{}{1, "Alice", "Alice"}
+	run := func(i int) {
+		var v interface{}
+		if err := db.QueryRow(queries[i], ages[i]).Scan(&v); err != nil {
+			t.Fatalf("query %d: %v", i, err)
+		}
+	}
+	check := func(wantPrepares, wantSize int, wantEvictions int64) {
+		if n := numPrepares(t, db); n != wantPrepares {
+			t.Errorf("prepares = %d; want %d", n, wantPrepares)
+		}
+		st := db.Stats()
+		if st.StmtCacheSize != wantSize || st.StmtCacheEvictions != wantEvictions {
+			t.Errorf("cache size, evictions = %d, %d; want %d, %d",
+				st.StmtCacheSize, st.StmtCacheEvictions, wantSize, wantEvictions)
+		}
+	}
+
+	prepares0 := numPrepares(t, db)
+	run(0)
+	run(0)
+	rows, err := db.Query(queries[0], ages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	check(prepares0+1, 1, 0)
+
+	run(1)
+	run(0) // now most recently used
+	check(prepares0+2, 2, 0)
+
+	// A third query evicts the least recently used one, queries[1].
+	run(2)
+	check(prepares0+3, 2, 1)
+	if got := len(db.PreparedStatements()); got != 2 {
+		t.Errorf("open statements = %d; want 2", got)
+	}
+	run(0)
+	check(prepares0+3, 2, 1)
+	run(1)
+	check(prepares0+4, 2, 2)
+
+	// Disabling the cache closes its statements.
+	db.SetMaxStmtCacheSize(0)
+	check(prepares0+4, 0, 4)
+	if got := len(db.PreparedStatements()); got != 0 {
+		t.Errorf("open statements after disabling = %d; want 0", got)
+	}
+}
+
+func TestStmtCacheUnbounded(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxStmtCacheSize(-1)
+	defer db.SetMaxStmtCacheSize(0) // closeDB wants no open statements
+
+	exec(t, db, "CREATE|t1|name=string") // cached too
+	for _, col := range []string{"name", "age", "dead", "bdate"} {
+		rows, err := db.Query("SELECT|people|"+col+"|name=?", "Alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if _, err := db.Exec("INSERT|t1|name=?", col); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := db.Stats(); st.StmtCacheSize != 6 || st.StmtCacheEvictions != 0 {
+		t.Errorf("cache size, evictions = %d, %d; want 6, 0", st.StmtCacheSize, st.StmtCacheEvictions)
+	}
+}
+
+// Tests that a cached statement gives up waiting for a connection when
+// the context is done, as an uncached query does.
+func TestStmtCacheContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+	db.SetMaxStmtCacheSize(2)
+	defer db.SetMaxStmtCacheSize(0) // closeDB wants no open statements
+
+	const (
+		query  = "SELECT|people|name|age=?"
+		insert = "INSERT|people|name=?,age=?"
+	)
+	rows, err := db.Query(query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := db.Exec(insert, "Zed", 9); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin() // holds the only connection
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	ops := map[string]func(ctx context.Context) error{
+		"QueryContext": func(ctx context.Context) error {
+			rows, err := db.QueryContext(ctx, query, 1)
+			if err == nil {
+				rows.Close()
+			}
+			return err
+		},
+		"Handle.Query": func(ctx context.Context) error {
+			rows, err := db.WithContext(ctx).Query(query, 1)
+			if err == nil {
+				rows.Close()
+			}
+			return err
+		},
+		"Handle.Exec": func(ctx context.Context) error {
+			_, err := db.WithContext(ctx).Exec(insert, "Zed", 9)
+			return err
+		},
+	}
+	for name, op := range ops {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		errc := make(chan error, 1)
+		go func() { errc <- op(ctx) }()
+		select {
+		case err := <-errc:
+			if err != context.DeadlineExceeded {
+				t.Errorf("%s = %v; want %v", name, err, context.DeadlineExceeded)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s still waiting for a connection after its deadline", name)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Callback-driven iteration over Rows.
+
+// 基于回调的 Rows 迭代。
+
+package sql
+
+import "context"
+
+// Stream iterates over the rows, calling fn once per row with a scan
+// function bound to the current row; scan behaves like Rows.Scan.
+// Stream stops at the end of the rows, when fn returns an error, or
+// when ctx is done, and returns the first error encountered: fn's
+// error, ctx.Err(), or the error from iteration or from closing. The
+// rows are always closed when Stream returns.
+//
+// Stream checks ctx between rows with a single non-blocking channel
+// receive, which makes it cheaper than an equivalent Next/Scan loop
+// for large result sets. Calling scan with a destination slice built
+// once outside fn, as in scan(dest...), avoids an allocation per row.
+// Stream does not interrupt a driver blocked fetching a row; use
+// QueryContext for that.
+
+// Stream 对行进行迭代，每一行调用一次 fn，并传入一个绑定到当前行的 scan 函数；
+// scan 的行为与 Rows.Scan 一致。Stream 会在遍历完所有行、fn 返回错误或 ctx
+// 结束时停止，并返回所遇到的第一个错误：fn 的错误、ctx.Err()，或迭代及关闭时的错误。
+// Stream 返回时总会关闭 Rows。
+//
+// Stream 在行之间仅通过一次非阻塞的信道接收来检查 ctx，
+// 因此对于大型结果集，它比等价的 Next/Scan 循环开销更小。以在 fn 之外构建一次的
+// 目标切片调用 scan（如 scan(dest...)），可以避免每行一次的内存分配。
+// Stream 不会中断阻塞在获取行数据中的驱动；若需如此，请使用 QueryContext。
+func (rs *Rows) Stream(ctx context.Context, fn func(scan func(dest ...interface{}) error) error) (err error) {
+	defer func() {
+		if cerr := rs.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	done := ctx.Done()
+	scan := rs.Scan
+	for rs.Next() {
+		if done != nil {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+		}
+		if err := fn(scan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
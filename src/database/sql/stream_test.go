@@ -0,0 +1,148 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRowsStream(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	sum := 0
+	err = rows.Stream(context.Background(), func(scan func(dest ...interface{}) error) error {
+		var age int
+		var name string
+		if err := scan(&age, &name); err != nil {
+			return err
+		}
+		sum += age
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if sum != 6 || len(names) != 3 {
+		t.Errorf("sum = %d, names = %q; want 6 and 3 names", sum, names)
+	}
+	if !rows.closed {
+		t.Error("rows not closed after Stream")
+	}
+}
+
+func TestRowsStreamStops(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	stop := errors.New("stop")
+	rows, err := db.Query("SELECT|people|age|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	err = rows.Stream(context.Background(), func(scan func(dest ...interface{}) error) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Stream = %v after %d calls; want stop after 1", err, calls)
+	}
+	if !rows.closed {
+		t.Error("rows not closed after fn error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err = db.Query("SELECT|people|age|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = 0
+	err = rows.Stream(ctx, func(scan func(dest ...interface{}) error) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Errorf("Stream = %v after %d calls; want context.Canceled after 1", err, calls)
+	}
+
+	rows, err = db.Query("SELECT|people|age|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rows.Stream(context.Background(), func(scan func(dest ...interface{}) error) error {
+		var a, b int
+		return scan(&a, &b)
+	})
+	if err == nil {
+		t.Error("Stream with wrong number of destinations succeeded")
+	}
+}
+
+const benchStreamRows = 1000
+
+func newStreamBenchDB(b *testing.B) *DB {
+	db := newTestDB(b, "")
+	exec(b, db, "CREATE|t|id=int64,name=nullstring")
+	for i := 0; i < benchStreamRows; i++ {
+		exec(b, db, "INSERT|t|id=?,name=?", i, "name")
+	}
+	return db
+}
+
+func BenchmarkRowsNextScan(b *testing.B) {
+	db := newStreamBenchDB(b)
+	defer closeDB(b, db)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.QueryContext(ctx, "SELECT|t|id,name|")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var id int64
+		var name string
+		for rows.Next() {
+			if err := rows.Scan(&id, &name); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkRowsStream(b *testing.B) {
+	db := newStreamBenchDB(b)
+	defer closeDB(b, db)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT|t|id,name|")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var id int64
+		var name string
+		dest := []interface{}{&id, &name}
+		err = rows.Stream(ctx, func(scan func(dest ...interface{}) error) error {
+			return scan(dest...)
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
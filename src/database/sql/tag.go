@@ -0,0 +1,77 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import "context"
+
+// WithTag returns a handle on db whose connection checkouts carry
+// tag, to attribute the use of a shared pool to the parts of a
+// program, such as the features of a large application. The tag is
+// reported as ConnInfo.Tag in the events delivered by Events and to
+// the hooks given a ConnInfo, and counted per tag in InUseByTag.
+//
+// The tag belongs to a checkout, not to the physical connection, which
+// the pool hands out to handles with any tag. The handle's context is
+// context.Background(); see Handle. An empty tag means no tag.
+
+// WithTag 返回 db 上的一个句柄，经由它检出的连接都带有 tag，用于将共享连接池的
+// 使用归属到程序的各个部分，例如大型应用中的各项功能。该标签以 ConnInfo.Tag 的形式出现在
+// Events 所传递的事件中以及接收 ConnInfo 的钩子中，并在 InUseByTag 中按标签计数。
+//
+// 标签属于一次检出，而不属于物理连接；连接池会将物理连接分发给带有任意标签的句柄。
+// 该句柄的上下文为 context.Background()；见 Handle。空标签表示没有标签。
+func (db *DB) WithTag(tag string) *Handle {
+	return &Handle{db: db, ctx: withTag(context.Background(), tag)}
+}
+
+// WithTag returns a handle on the same DB and context as h whose
+// connection checkouts carry tag, replacing h's.
+
+// WithTag 返回一个与 h 使用同一 DB 和上下文的句柄，经由它检出的连接都带有 tag，
+// 它会取代 h 的标签。
+func (h *Handle) WithTag(tag string) *Handle {
+	return &Handle{db: h.db, ctx: withTag(h.ctx, tag)}
+}
+
+// Tag returns the tag given to h by WithTag, or "" if it has none.
+
+// Tag 返回由 WithTag 赋予 h 的标签；若没有，则返回 ""。
+func (h *Handle) Tag() string {
+	return contextTag(h.ctx)
+}
+
+// tagKey is the key of the tag in the context of a Handle.
+type tagKey struct{}
+
+// withTag returns ctx carrying tag, for the connections checked out
+// with it.
+func withTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// contextTag returns the tag carried by ctx, or "" if it has none.
+func contextTag(ctx context.Context) string {
+	tag, _ := ctx.Value(tagKey{}).(string)
+	return tag
+}
+
+// InUseByTag returns the number of connections currently handed out
+// through the handles returned by WithTag, by tag, like DBStats.InUse.
+// It returns nil if there are none. The map is the caller's.
+
+// InUseByTag 按标签返回当前经由 WithTag 所返回的句柄分发出去的连接数，
+// 与 DBStats.InUse 类似。若没有这样的连接，则返回 nil。返回的 map 归调用者所有。
+func (db *DB) InUseByTag() map[string]int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.inUseByTag) == 0 {
+		return nil
+	}
+	m := make(map[string]int, len(db.inUseByTag))
+	for tag, n := range db.inUseByTag {
+		m[tag] = n
+	}
+	return m
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWithTagInUse(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	billing := db.WithTag("billing")
+	search := db.WithTag("search")
+	if search.Tag() != "search" || search.WithContext(context.Background()).Tag() != "search" {
+		t.Fatalf("Tag = %q; want search", search.Tag())
+	}
+
+	tx, err := billing.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []*Rows
+	for i := 0; i < 2; i++ {
+		r, err := search.Query("SELECT|people|name|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, r)
+	}
+	untagged, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"billing": 1, "search": 2}
+	if got, inUse := db.InUseByTag(), db.Stats().InUse; !reflect.DeepEqual(got, want) || inUse != 4 {
+		t.Errorf("InUse = %d, InUseByTag = %v; want 4, %v", inUse, got, want)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	rows[0].Close()
+	want = map[string]int{"search": 1}
+	if got := db.InUseByTag(); !reflect.DeepEqual(got, want) {
+		t.Errorf("InUseByTag = %v; want %v", got, want)
+	}
+
+	// With the last tagged checkout returned, no tags remain.
+	rows[1].Close()
+	untagged.Close()
+	if got := db.InUseByTag(); got != nil {
+		t.Errorf("InUseByTag = %v with nothing in use; want nil", got)
+	}
+}
+
+func TestWithTagEvents(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	events := db.Events()
+	tagged := db.WithTag("reports").WithContext(context.Background())
+
+	var name string
+	if err := tagged.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, ev := range drainEvents(events) {
+		if ev.Type == ConnectionReturned {
+			tags = append(tags, ev.Conn.Tag)
+		}
+	}
+	if want := []string{"reports", ""}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags of returned connections = %q; want %q", tags, want)
+	}
+}
+
+// TestWithTagWaiter checks that a connection handed to a waiting
+// tagged handle is counted under its tag.
+func TestWithTagWaiter(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.WithTag("a").Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan *Tx)
+	go func() {
+		tx, err := db.WithTag("b").Begin()
+		if err != nil {
+			t.Error(err)
+		}
+		got <- tx
+	}()
+	waitCondition(t, "the waiter", func() bool {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return len(db.connRequests) == 1
+	})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx = <-got
+	if tx == nil {
+		return
+	}
+	want := map[string]int{"b": 1}
+	if got := db.InUseByTag(); !reflect.DeepEqual(got, want) {
+		t.Errorf("InUseByTag = %v; want %v", got, want)
+	}
+	tx.Rollback()
+}
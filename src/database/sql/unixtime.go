@@ -0,0 +1,102 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Unix timestamps held in integers.
+
+// 以整数保存的 Unix 时间戳。
+
+package sql
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// UnixTimestamp scans a time.Time column into an int64 holding a Unix
+// timestamp, and sends that int64 back as a time.Time. Make one with
+// UnixSeconds, UnixMilli or UnixNano:
+//
+//	var created int64
+//	err := row.Scan(sql.UnixMilli(&created))
+//	...
+//	_, err = db.Exec("UPDATE t SET created = ?", sql.UnixMilli(&created))
+//
+// The zero time is scanned as 0, and 0 is sent as the zero time, so an
+// unset timestamp survives a round trip. Integer sources are stored as
+// they are, being taken to be in the timestamp's unit already. Times
+// outside the years 1678 to 2262 overflow UnixNano.
+
+// UnixTimestamp 将 time.Time 列扫描到保存 Unix 时间戳的 int64 中，并将该 int64 作为
+// time.Time 发送回去。使用 UnixSeconds、UnixMilli 或 UnixNano 创建它：
+//
+//	var created int64
+//	err := row.Scan(sql.UnixMilli(&created))
+//	...
+//	_, err = db.Exec("UPDATE t SET created = ?", sql.UnixMilli(&created))
+//
+// 零值时间会被扫描为 0，而 0 会被作为零值时间发送，因此未设置的时间戳在往返后保持不变。
+// 整数来源值会被原样存储，视其已采用该时间戳的单位。超出 1678 到 2262 年范围的时间
+// 会使 UnixNano 溢出。
+type UnixTimestamp struct {
+	p    *int64
+	unit time.Duration
+}
+
+// UnixSeconds returns a UnixTimestamp holding seconds in *p. Scanning
+// into a plain *int64 does the same.
+
+// UnixSeconds 返回一个在 *p 中保存秒数的 UnixTimestamp。直接扫描到 *int64 中
+// 效果相同。
+func UnixSeconds(p *int64) UnixTimestamp { return UnixTimestamp{p, time.Second} }
+
+// UnixMilli returns a UnixTimestamp holding milliseconds in *p.
+
+// UnixMilli 返回一个在 *p 中保存毫秒数的 UnixTimestamp。
+func UnixMilli(p *int64) UnixTimestamp { return UnixTimestamp{p, time.Millisecond} }
+
+// UnixNano returns a UnixTimestamp holding nanoseconds in *p.
+
+// UnixNano 返回一个在 *p 中保存纳秒数的 UnixTimestamp。
+func UnixNano(p *int64) UnixTimestamp { return UnixTimestamp{p, time.Nanosecond} }
+
+// Scan implements the Scanner interface.
+
+// Scan 实现了 Scanner 接口。
+func (u UnixTimestamp) Scan(src interface{}) error {
+	if u.p == nil {
+		return errNilPtr
+	}
+	if t, ok := src.(time.Time); ok {
+		*u.p = unixTime(t, u.unit)
+		return nil
+	}
+	return convertAssign(u.p, src)
+}
+
+// Value implements the driver Valuer interface.
+
+// Value 实现了 driver.Valuer 接口。
+func (u UnixTimestamp) Value() (driver.Value, error) {
+	if u.p == nil || *u.p == 0 {
+		return time.Time{}, nil
+	}
+	n := *u.p
+	unit := int64(u.unit)
+	return time.Unix(n/(int64(time.Second)/unit), n%(int64(time.Second)/unit)*unit).UTC(), nil
+}
+
+// unixTime returns t as a count of unit since the Unix epoch, or 0 for
+// the zero time.
+func unixTime(t time.Time, unit time.Duration) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	switch unit {
+	case time.Second:
+		return t.Unix()
+	case time.Nanosecond:
+		return t.UnixNano()
+	}
+	return t.Unix()*int64(time.Second/unit) + int64(t.Nanosecond())/int64(unit)
+}
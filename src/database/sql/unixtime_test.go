@@ -0,0 +1,90 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnixTimestamp(t *testing.T) {
+	tm := time.Date(2016, 3, 4, 5, 6, 7, 891234567, time.UTC)
+	tests := []struct {
+		name string
+		wrap func(*int64) UnixTimestamp
+		want int64
+		back time.Time
+	}{
+		{"seconds", UnixSeconds, 1457067967, tm.Truncate(time.Second)},
+		{"milli", UnixMilli, 1457067967891, tm.Truncate(time.Millisecond)},
+		{"nano", UnixNano, 1457067967891234567, tm},
+	}
+	for _, tt := range tests {
+		var n int64
+		if err := tt.wrap(&n).Scan(tm); err != nil {
+			t.Errorf("%s: Scan: %v", tt.name, err)
+			continue
+		}
+		if n != tt.want {
+			t.Errorf("%s: scanned %d; want %d", tt.name, n, tt.want)
+		}
+		v, err := tt.wrap(&n).Value()
+		if err != nil {
+			t.Errorf("%s: Value: %v", tt.name, err)
+			continue
+		}
+		if got := v.(time.Time); !got.Equal(tt.back) {
+			t.Errorf("%s: Value = %v; want %v", tt.name, got, tt.back)
+		}
+
+		n = 42
+		if err := tt.wrap(&n).Scan(time.Time{}); err != nil || n != 0 {
+			t.Errorf("%s: scanning the zero time = %d, %v; want 0", tt.name, n, err)
+		}
+		if v, _ := tt.wrap(&n).Value(); !v.(time.Time).IsZero() {
+			t.Errorf("%s: Value of 0 = %v; want the zero time", tt.name, v)
+		}
+		if err := tt.wrap(&n).Scan(int64(7)); err != nil || n != 7 {
+			t.Errorf("%s: scanning int64(7) = %d, %v; want 7", tt.name, n, err)
+		}
+	}
+
+	before := time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC)
+	var n int64
+	if err := UnixMilli(&n).Scan(before); err != nil || n != -500 {
+		t.Errorf("scanning %v as milliseconds = %d, %v; want -500", before, n, err)
+	}
+	if v, _ := UnixMilli(&n).Value(); !v.(time.Time).Equal(before) {
+		t.Errorf("Value of -500ms = %v; want %v", v, before)
+	}
+}
+
+func TestScanTimeIntoInt64(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	tm := time.Date(2016, 3, 4, 5, 6, 7, 891234567, time.UTC)
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?", "Dan", 5, tm)
+
+	var secs, millis int64
+	err := db.QueryRow("SELECT|people|bdate,bdate|name=?", "Dan").Scan(&secs, UnixMilli(&millis))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secs != tm.Unix() {
+		t.Errorf("secs = %d; want %d", secs, tm.Unix())
+	}
+	if millis != 1457067967891 {
+		t.Errorf("millis = %d; want 1457067967891", millis)
+	}
+
+	exec(t, db, "INSERT|people|name=?,age=?,bdate=?", "Eve", 6, UnixMilli(&millis))
+	var got time.Time
+	if err := db.QueryRow("SELECT|people|bdate|name=?", "Eve").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := tm.Truncate(time.Millisecond); !got.Equal(want) {
+		t.Errorf("round trip = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import "database/sql/driver"
+
+// SetValidationQuery sets a query that is run on each connection
+// before it is handed out by the pool, whether it was idle or newly
+// opened. Its rows are discarded. A connection on which the query
+// fails is closed, and another connection is used instead, as for
+// connections that report driver.ErrBadConn.
+//
+// A validation query costs a round trip per checkout, but it catches
+// problems a ping does not, such as missing permissions or schema. It
+// is meant for tests and for diagnosing misconfiguration. An empty
+// query, the default, disables validation.
+
+// SetValidationQuery 设置一个查询，在连接池交出每个连接之前在该连接上运行，
+// 无论该连接原本是空闲的还是新打开的。其结果行会被丢弃。若该查询在某个连接上失败，
+// 该连接会被关闭并改用另一个连接，与报告 driver.ErrBadConn 的连接相同。
+//
+// 验证查询在每次取出连接时都需要一次往返，但它能发现 ping 所无法发现的问题，
+// 例如缺失的权限或模式。它适用于测试及诊断错误配置。空查询（默认值）会禁用验证。
+func (db *DB) SetValidationQuery(query string) {
+	db.mu.Lock()
+	db.validationQuery = query
+	db.mu.Unlock()
+}
+
+// validateConn runs query on dc, discarding its rows.
+func validateConn(dc *driverConn, query string) error {
+	dc.Lock()
+	defer dc.Unlock()
+	if queryer, ok := dc.ci.(driver.Queryer); ok {
+		rowsi, err := queryer.Query(query, nil)
+		if err != driver.ErrSkip {
+			if err != nil {
+				return err
+			}
+			return rowsi.Close()
+		}
+	}
+	si, err := dc.ci.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer si.Close()
+	rowsi, err := si.Query(nil)
+	if err != nil {
+		return err
+	}
+	return rowsi.Close()
+}
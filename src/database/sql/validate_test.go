@@ -0,0 +1,61 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidationQuery(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	defer func() { hookPrepareErr = nil }()
+
+	const validation = "SELECT|people|name|"
+	db.mu.Lock()
+	if len(db.freeConn) != 1 {
+		t.Fatalf("freeConn = %d; want 1", len(db.freeConn))
+	}
+	bad := db.freeConn[0].ci.(*fakeConn)
+	db.mu.Unlock()
+
+	validated := 0
+	hookPrepareErr = func(c *fakeConn, query string) error {
+		if query != validation {
+			return nil
+		}
+		validated++
+		if c == bad {
+			return errors.New("permission denied")
+		}
+		return nil
+	}
+	db.SetValidationQuery(validation)
+
+	var age int
+	if err := db.QueryRow("SELECT|people|age|name=?", "Alice").Scan(&age); err != nil {
+		t.Fatal(err)
+	}
+	if validated != 2 {
+		t.Errorf("validation query ran %d times; want 2", validated)
+	}
+	db.mu.Lock()
+	for _, dc := range db.freeConn {
+		if dc.ci == bad {
+			t.Error("connection that failed validation was returned to the pool")
+		}
+	}
+	db.mu.Unlock()
+
+	db.SetValidationQuery("")
+	validated = 0
+	if err := db.QueryRow("SELECT|people|age|name=?", "Alice").Scan(&age); err != nil {
+		t.Fatal(err)
+	}
+	if validated != 0 {
+		t.Errorf("validation query ran %d times after being disabled", validated)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import "context"
+
+// A Handle is a DB together with a default context, used by the
+// operations that take no context of their own. Query and QueryRow
+// behave like QueryContext with the context, Begin like BeginTx, and
+// Exec, ExecReturning, ExecBatch and Prepare give up with the
+// context's error once it is done, including while waiting for a
+// connection. Handles are returned by DB.WithContext and DB.WithTag.
+//
+// A Handle uses the connection pool, settings and statistics of its
+// DB, and is cheap to create, for instance once per request. It has
+// no Close method: the DB is closed through the DB itself.
+
+// Handle 是一个 DB 连同一个默认上下文，该上下文由自身不接受上下文的操作使用。
+// Query 和 QueryRow 的行为如同以该上下文调用 QueryContext，Begin 如同调用 BeginTx；
+// 一旦该上下文结束，Exec、ExecReturning、ExecBatch 和 Prepare 就会放弃并返回
+// 该上下文的错误，包括在等待连接期间。Handle 由 DB.WithContext 和 DB.WithTag 返回。
+//
+// Handle 使用其 DB 的连接池、设置和统计信息，且创建开销很小，例如可为每个请求创建一次。
+// 它没有 Close 方法：DB 需通过其自身关闭。
+type Handle struct {
+	db  *DB
+	ctx context.Context
+}
+
+// WithContext returns a handle on db that uses ctx for the operations
+// that take no context of their own. Operations given a context
+// explicitly, such as QueryContext on the DB, use that one.
+
+// WithContext 返回 db 上的一个句柄，它对自身不接受上下文的操作使用 ctx。
+// 显式给定上下文的操作，例如 DB 上的 QueryContext，会使用所给定的上下文。
+func (db *DB) WithContext(ctx context.Context) *Handle {
+	if ctx == nil {
+		panic("sql: nil Context")
+	}
+	return &Handle{db: db, ctx: ctx}
+}
+
+// WithContext returns a handle on the same DB that uses ctx, keeping
+// h's tag.
+
+// WithContext 返回同一 DB 上使用 ctx 的句柄，并保留 h 的标签。
+func (h *Handle) WithContext(ctx context.Context) *Handle {
+	if ctx == nil {
+		panic("sql: nil Context")
+	}
+	if tag := h.Tag(); tag != "" {
+		ctx = withTag(ctx, tag)
+	}
+	return &Handle{db: h.db, ctx: ctx}
+}
+
+// DB returns the database h is a handle on.
+
+// DB 返回 h 所指向的数据库。
+func (h *Handle) DB() *DB {
+	return h.db
+}
+
+// Context returns h's default context.
+
+// Context 返回 h 的默认上下文。
+func (h *Handle) Context() context.Context {
+	return h.ctx
+}
+
+// Exec is like DB.Exec, using h's context.
+
+// Exec 与 DB.Exec 类似，但使用 h 的上下文。
+func (h *Handle) Exec(query string, args ...interface{}) (Result, error) {
+	return h.db.execContext(h.ctx, query, args)
+}
+
+// ExecReturning is like DB.ExecReturning, using h's context.
+
+// ExecReturning 与 DB.ExecReturning 类似，但使用 h 的上下文。
+func (h *Handle) ExecReturning(query string, args ...interface{}) (*Rows, error) {
+	return h.db.execReturning(h.ctx, query, args)
+}
+
+// ExecBatch is like DB.ExecBatchContext with h's context.
+
+// ExecBatch 如同以 h 的上下文调用 DB.ExecBatchContext。
+func (h *Handle) ExecBatch(stmts []BatchStmt) ([]Result, error) {
+	return h.db.ExecBatchContext(h.ctx, stmts)
+}
+
+// Query is like DB.QueryContext with h's context.
+
+// Query 如同以 h 的上下文调用 DB.QueryContext。
+func (h *Handle) Query(query string, args ...interface{}) (*Rows, error) {
+	return h.db.QueryContext(h.ctx, query, args...)
+}
+
+// QueryRow is like DB.QueryRow, with the query bound to h's context
+// as by QueryContext.
+
+// QueryRow 与 DB.QueryRow 类似，但该查询如同 QueryContext 一样与 h 的上下文绑定。
+func (h *Handle) QueryRow(query string, args ...interface{}) *Row {
+	return h.db.queryRow(h.ctx, query, args)
+}
+
+// Prepare is like DB.Prepare, using h's context. The statement is not
+// bound to it.
+
+// Prepare 与 DB.Prepare 类似，但使用 h 的上下文。该语句并不与该上下文绑定。
+func (h *Handle) Prepare(query string) (*Stmt, error) {
+	return h.db.prepareContext(h.ctx, query)
+}
+
+// Begin is like DB.BeginTx with h's context.
+
+// Begin 如同以 h 的上下文调用 DB.BeginTx。
+func (h *Handle) Begin() (*Tx, error) {
+	return h.db.BeginTx(h.ctx)
+}
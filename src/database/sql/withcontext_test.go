@@ -0,0 +1,110 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDBWithContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cdb := db.WithContext(ctx)
+
+	var age int
+	if err := cdb.QueryRow("SELECT|people|age|name=?", "Alice").Scan(&age); err != nil || age != 1 {
+		t.Fatalf("QueryRow = %d, %v; want 1, nil", age, err)
+	}
+	if db.Stats().OpenConnections != cdb.DB().Stats().OpenConnections {
+		t.Error("derived handle doesn't share the pool")
+	}
+
+	rows, err := cdb.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != context.Canceled {
+		t.Errorf("Rows.Err after cancel = %v; want context.Canceled", err)
+	}
+
+	if _, err := cdb.Query("SELECT|people|age|"); err != context.Canceled {
+		t.Errorf("Query = %v; want context.Canceled", err)
+	}
+	if err := cdb.QueryRow("SELECT|people|age|").Scan(&age); err != context.Canceled {
+		t.Errorf("QueryRow = %v; want context.Canceled", err)
+	}
+	if _, err := cdb.Exec("INSERT|people|name=Dave,age=?", 4); err != context.Canceled {
+		t.Errorf("Exec = %v; want context.Canceled", err)
+	}
+	if _, err := cdb.Prepare("SELECT|people|age|"); err != context.Canceled {
+		t.Errorf("Prepare = %v; want context.Canceled", err)
+	}
+	if _, err := cdb.Begin(); err != context.Canceled {
+		t.Errorf("Begin = %v; want context.Canceled", err)
+	}
+	if _, err := cdb.ExecReturning("INSERT|people|name=Dave,age=?", 4); err != context.Canceled {
+		t.Errorf("ExecReturning = %v; want context.Canceled", err)
+	}
+
+	// An explicit context wins, and the DB is unaffected.
+	rows, err = cdb.DB().QueryContext(context.Background(), "SELECT|people|age|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := db.QueryRow("SELECT|people|age|name=?", "Bob").Scan(&age); err != nil || age != 2 {
+		t.Errorf("parent QueryRow = %d, %v; want 2, nil", age, err)
+	}
+
+	// A statement prepared on the DB can join a transaction begun
+	// on a handle.
+	stmt, err := db.Prepare("SELECT|people|age|name=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	tx, err := db.WithContext(context.Background()).Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := tx.Stmt(stmt).QueryRow("Chris").Scan(&age); err != nil || age != 3 {
+		t.Errorf("tx.Stmt QueryRow = %d, %v; want 3, nil", age, err)
+	}
+}
+
+// Tests that the operations of a Handle give up waiting for a
+// connection once its context is done.
+func TestHandleConnWait(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	h := db.WithTag("waiting").WithContext(ctx)
+	if _, err := h.Exec("INSERT|people|name=Dave,age=?", 4); err != context.DeadlineExceeded {
+		t.Errorf("Exec = %v; want context.DeadlineExceeded", err)
+	}
+	if _, err := h.Prepare("SELECT|people|age|"); err != context.DeadlineExceeded {
+		t.Errorf("Prepare = %v; want context.DeadlineExceeded", err)
+	}
+	if h.Tag() != "waiting" {
+		t.Errorf("Tag = %q; want waiting", h.Tag())
+	}
+}
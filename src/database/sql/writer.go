@@ -0,0 +1,94 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Streaming column values to writers.
+
+// 将列值流式写入 writer。
+
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// WriterTo returns a Scan destination that writes the column's value
+// to w instead of storing it, for large BLOB or BYTEA columns served
+// to a file or an HTTP response. A NULL value writes nothing.
+//
+// How much memory this saves depends on the driver. A driver that
+// provides large values as an io.Reader, as allowed by driver.Rows,
+// has the value copied to w as it is read, without holding all of it.
+// For other drivers, which provide a []byte or string, the value has
+// already been read into memory by the driver, and WriterTo only
+// avoids the copy that scanning into a *[]byte makes.
+//
+// A column streamed from a reader can be scanned only once per row;
+// scanning it again, into any destination, fails.
+
+// WriterTo 返回一个 Scan 目标，它会将列值写入 w 而非存储该值，适用于将大型 BLOB 或
+// BYTEA 列提供给文件或 HTTP 响应的场景。NULL 值不写入任何内容。
+//
+// 这能节省多少内存取决于驱动。若驱动按照 driver.Rows 所允许的那样以 io.Reader 提供
+// 大型值，该值会边读取边复制到 w 中，而不会整个保存在内存里。对于其它提供 []byte 或
+// string 的驱动，值已经由驱动读入内存，WriterTo 只是省去了扫描到 *[]byte 时所做的
+// 那次复制。
+//
+// 从 reader 流式传输的列在每一行中只能被扫描一次；再次扫描它（无论目标为何）都会失败。
+func WriterTo(w io.Writer) Scanner {
+	return &columnWriter{w: w}
+}
+
+// columnWriter is the Scanner returned by WriterTo.
+type columnWriter struct {
+	w io.Writer
+}
+
+func (cw *columnWriter) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		_, err = cw.w.Write(v)
+	case string:
+		_, err = io.WriteString(cw.w, v)
+	case io.Reader:
+		_, err = io.Copy(cw.w, v)
+	default:
+		err = fmt.Errorf("converting driver.Value type %T to a WriterTo is unsupported", src)
+	}
+	return err
+}
+
+// streamedColumn replaces in Rows.lastcols a reader that has been
+// copied to a WriterTo.
+type streamedColumn struct{}
+
+var errColumnStreamed = errors.New("column already streamed to a WriterTo")
+
+// streamColumn returns column i of the current row, provided by the
+// driver as an io.Reader, for storing into dest. A WriterTo gets the
+// reader itself, which can't be read again; any other dest gets the
+// value read into a []byte, which is kept for later scans.
+func (rs *Rows) streamColumn(dest interface{}, i int) (interface{}, error) {
+	r, ok := rs.lastcols[i].(io.Reader)
+	if !ok {
+		return nil, errColumnStreamed
+	}
+	if _, ok := dest.(*columnWriter); ok {
+		rs.lastcols[i] = streamedColumn{}
+		return r, nil
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	rs.lastcols[i] = b
+	return b, nil
+}
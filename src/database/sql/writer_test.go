@@ -0,0 +1,79 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriterTo(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t|name=string,data=nullstring")
+	exec(t, db, "INSERT|t|name=a,data=?", []byte("large binary"))
+	exec(t, db, "INSERT|t|name=b")
+
+	var buf bytes.Buffer
+	if err := db.QueryRow("SELECT|t|data|name=?", "a").Scan(WriterTo(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "large binary" {
+		t.Errorf("wrote %q; want %q", buf.String(), "large binary")
+	}
+
+	buf.Reset()
+	if err := db.QueryRow("SELECT|t|data|name=?", "b").Scan(WriterTo(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("NULL wrote %q; want nothing", buf.String())
+	}
+}
+
+func TestWriterToReader(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	// Stand in for a driver that streams large values.
+	const blob = "streamed value"
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	rows.lastcols[0] = strings.NewReader(blob)
+	var buf bytes.Buffer
+	if err := rows.Scan(WriterTo(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != blob {
+		t.Errorf("wrote %q; want %q", buf.String(), blob)
+	}
+	var b []byte
+	if err := rows.Scan(&b); err == nil {
+		t.Error("scan of a streamed column succeeded")
+	}
+
+	// Other destinations get the value read into memory, which
+	// later scans can use.
+	if !rows.Next() {
+		t.Fatal("no second row")
+	}
+	rows.lastcols[0] = strings.NewReader(blob)
+	var s string
+	if err := rows.Scan(&s); err != nil || s != blob {
+		t.Fatalf("Scan into string = %q, %v; want %q, nil", s, err, blob)
+	}
+	buf.Reset()
+	if err := rows.Scan(WriterTo(&buf)); err != nil || buf.String() != blob {
+		t.Errorf("WriterTo after string scan wrote %q, %v; want %q, nil", buf.String(), err, blob)
+	}
+}
@@ -228,7 +228,7 @@ var pkgDeps = map[string][]string{
 	"compress/lzw":             {"L4"},
 	"compress/zlib":            {"L4", "compress/flate"},
 	"context":                  {"errors", "fmt", "reflect", "sync", "time"},
-	"database/sql":             {"L4", "container/list", "database/sql/driver"},
+	"database/sql":             {"L4", "container/list", "context", "database/sql/driver", "encoding", "encoding/json", "io/ioutil", "math/big", "net/url"},
 	"database/sql/driver":      {"L4", "time"},
 	"debug/dwarf":              {"L4"},
 	"debug/elf":                {"L4", "OS", "debug/dwarf", "compress/zlib"},